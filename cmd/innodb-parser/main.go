@@ -3,8 +3,12 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"strings"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/analyzer"
 )
 
 var (
@@ -13,14 +17,26 @@ var (
 	date    = "unknown"
 )
 
+// Exit codes reported to scripts and health checks
+const (
+	exitClean      = 0  // No problems found
+	exitWarnings   = 1  // File readable, but with warnings
+	exitCorruption = 2  // Corruption detected in the record stream
+	exitUnreadable = 3  // File could not be opened or its header is unreadable
+	exitUsage      = 64 // Invalid command line (EX_USAGE)
+)
+
 func main() {
 	var (
 		inputFile    = flag.String("file", "", "Path to InnoDB redo log file")
 		outputFormat = flag.String("format", "text", "Output format: text, json, csv")
 		verbose      = flag.Bool("verbose", false, "Enable verbose output")
 		showVersion  = flag.Bool("version", false, "Show version information")
-		analyze      = flag.Bool("analyze", false, "Perform full analysis")
+		analyze      = flag.Bool("analyze", false, "Perform full analysis (transactions and corruption details)")
+		showRecords  = flag.Bool("records", false, "Include per-record output (csv output then lists records instead of the summary)")
+		maxRecords   = flag.Int("max-records", 0, "Maximum number of records to read (0 = no limit)")
 	)
+	flag.Usage = usage
 	flag.Parse()
 
 	if *showVersion {
@@ -34,22 +50,94 @@ func main() {
 	if *inputFile == "" {
 		fmt.Fprintf(os.Stderr, "Error: --file is required\n")
 		flag.Usage()
-		os.Exit(1)
+		os.Exit(exitUsage)
+	}
+
+	format := strings.ToLower(*outputFormat)
+	if format != "text" && format != "json" && format != "csv" {
+		fmt.Fprintf(os.Stderr, "Error: unsupported format %q (supported: text, json, csv)\n", *outputFormat)
+		os.Exit(exitUsage)
 	}
 
 	if *verbose {
 		log.Printf("Starting analysis of: %s", *inputFile)
-		log.Printf("Output format: %s", *outputFormat)
+		log.Printf("Output format: %s", format)
 		log.Printf("Full analysis: %t", *analyze)
 	}
 
-	// TODO: Implement actual parsing logic
-	// This is a placeholder that will be implemented through TDD
-	fmt.Printf("InnoDB Redo Log Parser (TDD Implementation Pending)\n")
-	fmt.Printf("File: %s\n", *inputFile)
-	fmt.Printf("Format: %s\n", *outputFormat)
-	fmt.Printf("Analysis: %t\n", *analyze)
-	
-	// Exit with error code to indicate not implemented
-	os.Exit(2)
-}
\ No newline at end of file
+	os.Exit(run(os.Stdout, *inputFile, format, reportOptions{
+		Analyze:    *analyze,
+		Records:    *showRecords,
+		MaxRecords: *maxRecords,
+		Verbose:    *verbose,
+	}))
+}
+
+// run analyzes filename, writes the report to w and returns the exit code
+func run(w io.Writer, filename, format string, opts reportOptions) int {
+	redoAnalyzer := analyzer.NewRedoLogAnalyzerWithConfig(analyzer.Config{MaxRecords: opts.MaxRecords})
+	result, err := redoAnalyzer.AnalyzeFile(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUnreadable
+	}
+
+	if opts.Verbose {
+		log.Printf("Analysis complete: %s", result.Summary)
+	}
+
+	code := exitCode(result)
+	report := newReport(filename, result, code, opts)
+
+	switch format {
+	case "json":
+		err = writeJSONReport(w, report)
+	case "csv":
+		err = writeCSVReport(w, report)
+	default:
+		err = writeTextReport(w, report)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		return exitUnreadable
+	}
+
+	return code
+}
+
+// exitCode maps an analysis result to the process exit code
+func exitCode(result *analyzer.AnalysisResult) int {
+	if result.Corruption != nil && result.Corruption.HasCorruption {
+		return exitCorruption
+	}
+	if len(result.Warnings) > 0 {
+		return exitWarnings
+	}
+	return exitClean
+}
+
+// statusName returns the human readable name of an exit code
+func statusName(code int) string {
+	switch code {
+	case exitClean:
+		return "clean"
+	case exitWarnings:
+		return "warnings"
+	case exitCorruption:
+		return "corruption"
+	default:
+		return "unreadable"
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s --file <redo_log_file> [options]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Options:\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\nExit codes:\n")
+	fmt.Fprintf(os.Stderr, "  %d  clean: no problems found\n", exitClean)
+	fmt.Fprintf(os.Stderr, "  %d  warnings: file readable, but with warnings\n", exitWarnings)
+	fmt.Fprintf(os.Stderr, "  %d  corruption: corruption detected in the record stream\n", exitCorruption)
+	fmt.Fprintf(os.Stderr, "  %d  unreadable: file or header could not be read\n", exitUnreadable)
+	fmt.Fprintf(os.Stderr, "  %d usage: invalid command line\n", exitUsage)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yamaru/innodb-redolog-tool/test/fixtures"
+)
+
+func TestRunExitCodes(t *testing.T) {
+	dir := t.TempDir()
+
+	sample, err := fixtures.CreateSampleLogFile(dir)
+	require.NoError(t, err)
+	corrupted, err := fixtures.CreateCorruptedLogFile(dir)
+	require.NoError(t, err)
+	truncated, err := fixtures.CreateTruncatedLogFile(dir)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		filename string
+		expected int
+	}{
+		{"clean file", sample, exitClean},
+		{"corrupted file", corrupted, exitCorruption},
+		{"truncated header", truncated, exitUnreadable},
+		{"missing file", filepath.Join(dir, "missing.log"), exitUnreadable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			assert.Equal(t, tt.expected, run(&out, tt.filename, "text", reportOptions{}))
+		})
+	}
+}
+
+func TestRunJSONOutput(t *testing.T) {
+	filename, err := fixtures.CreateSampleLogFile(t.TempDir())
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	code := run(&out, filename, "json", reportOptions{Analyze: true, Records: true})
+	require.Equal(t, exitClean, code)
+
+	var rep report
+	require.NoError(t, json.Unmarshal(out.Bytes(), &rep))
+	assert.Equal(t, "clean", rep.Status)
+	assert.Equal(t, uint64(3), rep.Stats.TotalRecords)
+	assert.Len(t, rep.Records, 3)
+	assert.Len(t, rep.Transactions, 1)
+}
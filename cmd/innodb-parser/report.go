@@ -0,0 +1,356 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/analyzer"
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// dataPreviewLength limits record data shown in text and csv output
+const dataPreviewLength = 100
+
+// reportOptions selects which parts of the analysis are written
+type reportOptions struct {
+	Analyze    bool
+	Records    bool
+	MaxRecords int
+	Verbose    bool
+}
+
+// report is the serializable form of an analysis result
+type report struct {
+	File         string              `json:"file"`
+	Status       string              `json:"status"`
+	ExitCode     int                 `json:"exit_code"`
+	Summary      string              `json:"summary"`
+	Header       *headerReport       `json:"header,omitempty"`
+	Stats        statsReport         `json:"stats"`
+	Warnings     []string            `json:"warnings"`
+	Corruption   corruptionReport    `json:"corruption"`
+	Transactions []transactionReport `json:"transactions,omitempty"`
+	Records      []recordReport      `json:"records,omitempty"`
+}
+
+type headerReport struct {
+	LogGroupID     uint64    `json:"log_group_id"`
+	StartLSN       uint64    `json:"start_lsn"`
+	FileNo         uint32    `json:"file_no"`
+	Created        time.Time `json:"created"`
+	LastCheckpoint uint64    `json:"last_checkpoint"`
+	Format         uint32    `json:"format"`
+}
+
+type statsReport struct {
+	TotalRecords     uint64            `json:"total_records"`
+	SizeInBytes      uint64            `json:"size_bytes"`
+	TransactionCount uint64            `json:"transaction_count"`
+	RecordsByType    []typeCountReport `json:"records_by_type"`
+}
+
+type typeCountReport struct {
+	Type    string  `json:"type"`
+	TypeID  uint8   `json:"type_id"`
+	Count   uint64  `json:"count"`
+	Percent float64 `json:"percent"`
+}
+
+type corruptionReport struct {
+	HasCorruption bool          `json:"has_corruption"`
+	Severity      string        `json:"severity"`
+	Recoverable   bool          `json:"recoverable"`
+	IssueCount    int           `json:"issue_count"`
+	Issues        []issueReport `json:"issues,omitempty"`
+}
+
+type issueReport struct {
+	LSN         uint64 `json:"lsn"`
+	RecordIndex int    `json:"record_index"`
+	IssueType   string `json:"issue_type"`
+	Description string `json:"description"`
+	Severity    string `json:"severity"`
+}
+
+type transactionReport struct {
+	ID          uint64   `json:"id"`
+	StartLSN    uint64   `json:"start_lsn"`
+	EndLSN      uint64   `json:"end_lsn"`
+	Status      string   `json:"status"`
+	RecordCount int      `json:"record_count"`
+	Tables      []uint32 `json:"tables"`
+}
+
+type recordReport struct {
+	Number  int    `json:"number"`
+	LSN     uint64 `json:"lsn"`
+	Type    string `json:"type"`
+	TypeID  uint8  `json:"type_id"`
+	Length  uint32 `json:"length"`
+	SpaceID uint32 `json:"space_id"`
+	PageNo  uint32 `json:"page_no"`
+	TableID uint32 `json:"table_id"`
+	Group   int    `json:"group"`
+	Data    string `json:"data"`
+}
+
+// newReport builds the serializable report for an analysis result
+func newReport(filename string, result *analyzer.AnalysisResult, code int, opts reportOptions) *report {
+	rep := &report{
+		File:     filename,
+		Status:   statusName(code),
+		ExitCode: code,
+		Summary:  result.Summary,
+		Warnings: result.Warnings,
+	}
+	if rep.Warnings == nil {
+		rep.Warnings = []string{}
+	}
+
+	if h := result.Header; h != nil {
+		rep.Header = &headerReport{
+			LogGroupID:     h.LogGroupID,
+			StartLSN:       h.StartLSN,
+			FileNo:         h.FileNo,
+			Created:        h.Created,
+			LastCheckpoint: h.LastCheckpoint,
+			Format:         h.Format,
+		}
+	}
+
+	if s := result.Stats; s != nil {
+		rep.Stats = statsReport{
+			TotalRecords:     s.TotalRecords,
+			SizeInBytes:      s.SizeInBytes,
+			TransactionCount: s.TransactionCount,
+			RecordsByType:    make([]typeCountReport, 0, len(s.RecordsByType)),
+		}
+		for _, tc := range analyzer.SortedTypeCounts(s) {
+			rep.Stats.RecordsByType = append(rep.Stats.RecordsByType, typeCountReport{
+				Type:    tc.Type.String(),
+				TypeID:  uint8(tc.Type),
+				Count:   tc.Count,
+				Percent: float64(tc.Count) * 100 / float64(s.TotalRecords),
+			})
+		}
+	}
+
+	if c := result.Corruption; c != nil {
+		rep.Corruption = corruptionReport{
+			HasCorruption: c.HasCorruption,
+			Severity:      c.Severity.String(),
+			Recoverable:   c.Recoverable,
+			IssueCount:    len(c.CorruptedRecords),
+		}
+		if opts.Analyze {
+			for _, issue := range c.CorruptedRecords {
+				rep.Corruption.Issues = append(rep.Corruption.Issues, issueReport{
+					LSN:         issue.LSN,
+					RecordIndex: issue.RecordIndex,
+					IssueType:   issue.IssueType,
+					Description: issue.Description,
+					Severity:    issue.Severity.String(),
+				})
+			}
+		}
+	}
+
+	if opts.Analyze {
+		for _, txn := range result.Transactions {
+			rep.Transactions = append(rep.Transactions, transactionReport{
+				ID:          txn.ID,
+				StartLSN:    txn.StartLSN,
+				EndLSN:      txn.EndLSN,
+				Status:      transactionStatusName(txn.Status),
+				RecordCount: len(txn.Records),
+				Tables:      txn.TableAffected,
+			})
+		}
+	}
+
+	if opts.Records {
+		rep.Records = make([]recordReport, 0, len(result.Records))
+		for i, record := range result.Records {
+			rep.Records = append(rep.Records, newRecordReport(i, record))
+		}
+	}
+
+	return rep
+}
+
+// newRecordReport converts a log record for output
+func newRecordReport(index int, record *types.LogRecord) recordReport {
+	return recordReport{
+		Number:  index + 1,
+		LSN:     record.LSN,
+		Type:    record.Type.String(),
+		TypeID:  uint8(record.Type),
+		Length:  record.Length,
+		SpaceID: record.SpaceID,
+		PageNo:  record.PageNo,
+		TableID: record.TableID,
+		Group:   record.MultiRecordGroup,
+		Data:    string(record.Data),
+	}
+}
+
+// transactionStatusName returns the output name of a transaction status
+func transactionStatusName(status analyzer.TransactionStatus) string {
+	switch status {
+	case analyzer.TransactionCommitted:
+		return "committed"
+	case analyzer.TransactionRolledBack:
+		return "rolled_back"
+	case analyzer.TransactionIncomplete:
+		return "incomplete"
+	default:
+		return "pending"
+	}
+}
+
+// writeJSONReport writes the report as an indented JSON document
+func writeJSONReport(w io.Writer, rep *report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rep)
+}
+
+// writeTextReport writes the report in human readable form
+func writeTextReport(w io.Writer, rep *report) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "InnoDB Redo Log Analysis: %s\n", rep.File)
+	fmt.Fprintf(&b, "Status: %s (exit code %d)\n", strings.ToUpper(rep.Status), rep.ExitCode)
+	fmt.Fprintf(&b, "Summary: %s\n", rep.Summary)
+
+	if h := rep.Header; h != nil {
+		fmt.Fprintf(&b, "\nHeader:\n")
+		fmt.Fprintf(&b, "  Log Group ID:    %d\n", h.LogGroupID)
+		fmt.Fprintf(&b, "  Start LSN:       %d\n", h.StartLSN)
+		fmt.Fprintf(&b, "  File Number:     %d\n", h.FileNo)
+		fmt.Fprintf(&b, "  Created:         %s\n", h.Created.Format("2006-01-02 15:04:05"))
+		fmt.Fprintf(&b, "  Last Checkpoint: %d\n", h.LastCheckpoint)
+		fmt.Fprintf(&b, "  Format:          %d\n", h.Format)
+	}
+
+	fmt.Fprintf(&b, "\nStatistics:\n")
+	fmt.Fprintf(&b, "  Total Records:   %d\n", rep.Stats.TotalRecords)
+	fmt.Fprintf(&b, "  Size:            %d bytes\n", rep.Stats.SizeInBytes)
+	fmt.Fprintf(&b, "  Transactions:    %d\n", rep.Stats.TransactionCount)
+	if len(rep.Stats.RecordsByType) > 0 {
+		fmt.Fprintf(&b, "\nRecord Type Distribution:\n")
+		for _, tc := range rep.Stats.RecordsByType {
+			fmt.Fprintf(&b, "  %-40s %8d (%5.1f%%)\n", tc.Type, tc.Count, tc.Percent)
+		}
+	}
+
+	fmt.Fprintf(&b, "\nCorruption: ")
+	if rep.Corruption.HasCorruption {
+		fmt.Fprintf(&b, "%d issues (max severity %s, recoverable: %t)\n",
+			rep.Corruption.IssueCount, rep.Corruption.Severity, rep.Corruption.Recoverable)
+		for _, issue := range rep.Corruption.Issues {
+			fmt.Fprintf(&b, "  [%s] record %d LSN %d %s: %s\n",
+				issue.Severity, issue.RecordIndex+1, issue.LSN, issue.IssueType, issue.Description)
+		}
+	} else {
+		fmt.Fprintf(&b, "none detected\n")
+	}
+
+	if len(rep.Warnings) > 0 {
+		fmt.Fprintf(&b, "\nWarnings:\n")
+		for _, warning := range rep.Warnings {
+			fmt.Fprintf(&b, "  - %s\n", warning)
+		}
+	}
+
+	if len(rep.Transactions) > 0 {
+		fmt.Fprintf(&b, "\nTransactions:\n")
+		for _, txn := range rep.Transactions {
+			fmt.Fprintf(&b, "  %d: %s, %d records, LSN %d-%d, tables %v\n",
+				txn.ID, txn.Status, txn.RecordCount, txn.StartLSN, txn.EndLSN, txn.Tables)
+		}
+	}
+
+	if len(rep.Records) > 0 {
+		fmt.Fprintf(&b, "\nRecords:\n")
+		for _, record := range rep.Records {
+			fmt.Fprintf(&b, "  #%-6d LSN=%-12d %-36s len=%-5d space=%-6d page=%-8d group=%-5d %s\n",
+				record.Number, record.LSN, record.Type, record.Length, record.SpaceID, record.PageNo,
+				record.Group, previewData(record.Data))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeCSVReport writes the per-record table when records were requested,
+// otherwise a section,key,value summary table
+func writeCSVReport(w io.Writer, rep *report) error {
+	writer := csv.NewWriter(w)
+
+	if rep.Records != nil {
+		writer.Write([]string{
+			"Record_Number", "LSN", "Type", "Type_ID", "Length",
+			"Space_ID", "Page_No", "Table_ID", "Group", "Data_Preview", "Data_Length",
+		})
+		for _, record := range rep.Records {
+			writer.Write([]string{
+				fmt.Sprintf("%d", record.Number),
+				fmt.Sprintf("%d", record.LSN),
+				record.Type,
+				fmt.Sprintf("%d", record.TypeID),
+				fmt.Sprintf("%d", record.Length),
+				fmt.Sprintf("%d", record.SpaceID),
+				fmt.Sprintf("%d", record.PageNo),
+				fmt.Sprintf("%d", record.TableID),
+				fmt.Sprintf("%d", record.Group),
+				previewData(record.Data),
+				fmt.Sprintf("%d", len(record.Data)),
+			})
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+
+	writer.Write([]string{"section", "key", "value"})
+	writer.Write([]string{"file", "path", rep.File})
+	writer.Write([]string{"file", "status", rep.Status})
+	writer.Write([]string{"file", "exit_code", fmt.Sprintf("%d", rep.ExitCode)})
+	if h := rep.Header; h != nil {
+		writer.Write([]string{"header", "start_lsn", fmt.Sprintf("%d", h.StartLSN)})
+		writer.Write([]string{"header", "last_checkpoint", fmt.Sprintf("%d", h.LastCheckpoint)})
+		writer.Write([]string{"header", "format", fmt.Sprintf("%d", h.Format)})
+	}
+	writer.Write([]string{"stats", "total_records", fmt.Sprintf("%d", rep.Stats.TotalRecords)})
+	writer.Write([]string{"stats", "size_bytes", fmt.Sprintf("%d", rep.Stats.SizeInBytes)})
+	writer.Write([]string{"stats", "transaction_count", fmt.Sprintf("%d", rep.Stats.TransactionCount)})
+	for _, tc := range rep.Stats.RecordsByType {
+		writer.Write([]string{"records_by_type", tc.Type, fmt.Sprintf("%d", tc.Count)})
+	}
+	writer.Write([]string{"corruption", "issue_count", fmt.Sprintf("%d", rep.Corruption.IssueCount)})
+	writer.Write([]string{"corruption", "severity", rep.Corruption.Severity})
+	for _, issue := range rep.Corruption.Issues {
+		writer.Write([]string{"corruption_issue", issue.IssueType,
+			fmt.Sprintf("record %d LSN %d: %s", issue.RecordIndex+1, issue.LSN, issue.Description)})
+	}
+	for _, warning := range rep.Warnings {
+		writer.Write([]string{"warning", "", warning})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// previewData shortens record data and escapes line breaks for single-line output
+func previewData(data string) string {
+	if len(data) > dataPreviewLength {
+		data = data[:dataPreviewLength] + "..."
+	}
+	data = strings.ReplaceAll(data, "\n", "\\n")
+	return strings.ReplaceAll(data, "\r", "\\r")
+}
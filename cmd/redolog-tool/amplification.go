@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/analyzer"
+)
+
+// defaultAmplificationTop is the number of tablespaces listed by default
+const defaultAmplificationTop = 20
+
+// amplificationReport is the result of the amplification subcommand
+type amplificationReport struct {
+	File string `json:"file"`
+	*analyzer.WriteAmplificationReport
+}
+
+// runAmplification implements the amplification subcommand
+func runAmplification(args []string) error {
+	fs := newFlagSet("amplification", "[flags] <redo_log_file>")
+	in := addInputFlags(fs, 0)
+	selector := addSelectorFlags(fs)
+	out := addOutputFlags(fs, "text", "json", "csv")
+	dictionaryFile := fs.String("dictionary", "", "JSON file mapping space IDs to table names")
+	top := fs.Int("top", defaultAmplificationTop, "Number of tablespaces to list (0 = all)")
+	if _, err := in.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := validateAll(out.validate, selector.validate); err != nil {
+		return err
+	}
+	if *top < 0 {
+		return newUsageError("--top must not be negative")
+	}
+
+	dict, err := loadDictionary(*dictionaryFile)
+	if err != nil {
+		return err
+	}
+
+	records, _, err := in.load()
+	if err != nil {
+		return err
+	}
+
+	result := analyzer.AnalyzeWriteAmplification(selector.apply(records), dict)
+	if *top > 0 && len(result.Spaces) > *top {
+		result.Spaces = result.Spaces[:*top]
+	}
+	report := &amplificationReport{File: in.file, WriteAmplificationReport: result}
+
+	return out.write(func(w io.Writer) error {
+		switch out.format {
+		case "json":
+			return writeJSON(w, report)
+		case "csv":
+			return writeCSVRows(w, amplificationCSVHeader(), amplificationCSVRows(result))
+		default:
+			fmt.Fprintf(w, "File: %s\n\n", report.File)
+			return writeAmplificationText(w, result)
+		}
+	})
+}
+
+// loadDictionary loads the dictionary file, if one is given
+func loadDictionary(filename string) (*analyzer.Dictionary, error) {
+	if filename == "" {
+		return nil, nil
+	}
+	return analyzer.LoadDictionary(filename)
+}
+
+// writeAmplificationText writes the totals and the ranked tablespace table
+func writeAmplificationText(w io.Writer, report *analyzer.WriteAmplificationReport) error {
+	fmt.Fprintf(w, "Total: %d bytes, %d records, %d MTRs\n", report.Total.Bytes, report.Total.Records, report.Total.MTRs)
+	fmt.Fprintf(w, "No page: %d bytes, %d records\n", report.NoPage.Bytes, report.NoPage.Records)
+	fmt.Fprintln(w, "Per tablespace only: redo records do not log the index they change")
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "%4s %-32s %10s %6s %8s %6s", "RANK", "TABLE", "BYTES", "SHARE", "RECORDS", "MTRS")
+	for _, op := range analyzer.OperationClasses() {
+		fmt.Fprintf(w, " %10s", op)
+	}
+	fmt.Fprintln(w)
+
+	for i, space := range report.Spaces {
+		fmt.Fprintf(w, "%4d %-32s %10d %5.1f%% %8d %6d", i+1, tableLabel(space),
+			space.Bytes, space.Share*100, space.Records, space.MTRs)
+		for _, op := range analyzer.OperationClasses() {
+			fmt.Fprintf(w, " %10d", space.ByOperation[op].Bytes)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// amplificationCSVHeader returns the CSV columns, with a bytes/records/mtrs
+// triple per operation class
+func amplificationCSVHeader() []string {
+	header := []string{"rank", "space_id", "table", "bytes", "share", "records", "mtrs"}
+	for _, op := range analyzer.OperationClasses() {
+		header = append(header, op+"_bytes", op+"_records", op+"_mtrs")
+	}
+	return header
+}
+
+// amplificationCSVRows returns one row per tablespace
+func amplificationCSVRows(report *analyzer.WriteAmplificationReport) [][]string {
+	u := func(v uint64) string { return strconv.FormatUint(v, 10) }
+
+	rows := make([][]string, 0, len(report.Spaces))
+	for i, space := range report.Spaces {
+		row := []string{
+			strconv.Itoa(i + 1),
+			u(uint64(space.SpaceID)),
+			space.Table,
+			u(space.Bytes),
+			strconv.FormatFloat(space.Share, 'f', 4, 64),
+			u(space.Records),
+			u(space.MTRs),
+		}
+		for _, op := range analyzer.OperationClasses() {
+			volume := space.ByOperation[op]
+			row = append(row, u(volume.Bytes), u(volume.Records), u(volume.MTRs))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// tableLabel names the table of a tablespace, falling back to its ID
+func tableLabel(space *analyzer.SpaceWrites) string {
+	if space.Table != "" {
+		return space.Table
+	}
+	return fmt.Sprintf("space %d", space.SpaceID)
+}
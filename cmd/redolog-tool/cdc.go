@@ -0,0 +1,61 @@
+package main
+
+import (
+	"github.com/yamaru/innodb-redolog-tool/internal/cdc"
+)
+
+// runCDC implements the cdc subcommand
+func runCDC(args []string) error {
+	fs := newFlagSet("cdc", "[flags] <redo_log_file>")
+	in := addInputFlags(fs, 0)
+	selector := addSelectorFlags(fs)
+	sink := fs.String("sink", "", "Where to write events: a file, - for stdout or unix:<socket path> (default: stdout)")
+	serverName := fs.String("server-name", cdc.DefaultServerName, "Logical server name written to source.name")
+	dictionaryFile := fs.String("dictionary", "", "JSON file mapping space IDs to table names (names source.db and source.table)")
+	var anchors anchorList
+	fs.Var(&anchors, "anchor", "LSN@TIME pair mapping an LSN to RFC 3339 time for source.ts_ms; repeat for at least two")
+	if _, err := in.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := selector.validate(); err != nil {
+		return err
+	}
+
+	dict, err := loadDictionary(*dictionaryFile)
+	if err != nil {
+		return err
+	}
+	records, _, err := in.load()
+	if err != nil {
+		return err
+	}
+	clock, err := anchors.clock()
+	if err != nil {
+		return err
+	}
+
+	w, err := cdc.OpenSink(*sink)
+	if err != nil {
+		return err
+	}
+	converter := cdc.NewConverter(cdc.Options{ServerName: *serverName, File: in.file, Dictionary: dict, Clock: clock})
+	encoder := cdc.NewEncoder(w)
+	for i, record := range records {
+		if !selector.matches(record) {
+			continue
+		}
+		event := converter.Event(i+1, record)
+		if event == nil {
+			continue
+		}
+		if err := encoder.Encode(event); err != nil {
+			w.Close()
+			return err
+		}
+	}
+	if err := encoder.Flush(); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
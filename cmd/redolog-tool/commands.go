@@ -0,0 +1,382 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/filter"
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// Exit codes shared by all subcommands
+const (
+	exitOK           = 0 // Command succeeded
+	exitError        = 1 // Command failed
+	exitUsage        = 2 // Invalid command line
+	exitVerifyFailed = 3 // verify found corruption, or xtrabackup found problems
+)
+
+// defaultTUIMaxRecords keeps the interactive view responsive on large files
+const defaultTUIMaxRecords = 10000
+
+// errVerifyFailed is returned by verify and xtrabackup after their report
+// has been written
+var errVerifyFailed = errors.New("verification failed")
+
+// command is a redolog-tool subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+// commandList returns the subcommands in the order they are listed in help
+func commandList() []*command {
+	return []*command{
+		{"header", "Show the redo log file header", runHeader},
+		{"dump", "List records", runDump},
+		{"stats", "Summarize record types and operations", runStats},
+		{"grep", "List records whose data matches a regular expression", runGrep},
+		{"verify", "Check the record stream for corruption", runVerify},
+		{"xtrabackup", "Check an xtrabackup_logfile against xtrabackup_checkpoints", runXtraBackup},
+		{"diff", "Compare two files or two LSN windows of one file", runDiff},
+		{"amplification", "Rank tablespaces by the redo they generate", runAmplification},
+		{"throughput", "Redo bytes, MTRs and operations per LSN or time window", runThroughput},
+		{"tui", "Browse records interactively", runTUI},
+		{"export", "Export records to a JSON, NDJSON, CSV, SQL or Parquet file", runExport},
+		{"cdc", "Write row changes as Debezium-style change events", runCDC},
+		{"schema", "Print the JSON Schema of the NDJSON export", runSchema},
+		{"serve", "Serve files, headers, records, stats and page history as a JSON API", runServe},
+		{"metrics", "Follow the redo log and serve Prometheus metrics of its activity", runMetrics},
+		{"debug", "Print parser diagnostics", runDebug},
+	}
+}
+
+// usageError marks errors caused by an invalid command line
+type usageError struct {
+	err      error
+	reported bool // The flag package already printed the error and usage
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+// newUsageError creates a usage error with a formatted message
+func newUsageError(format string, args ...interface{}) error {
+	return &usageError{err: fmt.Errorf(format, args...)}
+}
+
+// runCommand dispatches args to a subcommand and returns the exit code
+func runCommand(args []string) int {
+	if len(args) == 0 {
+		printUsage(os.Stderr)
+		return exitUsage
+	}
+
+	name := args[0]
+	switch {
+	case name == "help" || name == "-h" || name == "--help":
+		if len(args) > 1 {
+			return runCommand([]string{args[1], "-h"})
+		}
+		printUsage(os.Stdout)
+		return exitOK
+	case strings.HasPrefix(name, "-"):
+		// Flags without a subcommand keep the old "-file <log>" invocation working
+		name = "tui"
+	default:
+		args = args[1:]
+	}
+
+	for _, cmd := range commandList() {
+		if cmd.name == name {
+			return exitStatus(cmd.run(args))
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Error: unknown command %q\n\n", name)
+	printUsage(os.Stderr)
+	return exitUsage
+}
+
+// exitStatus reports err and maps it to an exit code
+func exitStatus(err error) int {
+	var usageErr *usageError
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errVerifyFailed):
+		return exitVerifyFailed
+	case errors.As(err, &usageErr):
+		if !usageErr.reported {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		return exitUsage
+	default:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+}
+
+// printUsage lists the subcommands
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: redolog-tool <command> [flags] <redo_log_file>\n\n")
+	fmt.Fprintf(w, "Commands:\n")
+	for _, cmd := range commandList() {
+		fmt.Fprintf(w, "  %-13s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nRun 'redolog-tool help <command>' for the flags of a command.\n")
+	fmt.Fprintf(w, "\nExit codes:\n")
+	fmt.Fprintf(w, "  %d  success\n", exitOK)
+	fmt.Fprintf(w, "  %d  error\n", exitError)
+	fmt.Fprintf(w, "  %d  invalid command line\n", exitUsage)
+	fmt.Fprintf(w, "  %d  verify found corruption, or xtrabackup found problems\n", exitVerifyFailed)
+}
+
+// newFlagSet creates the flag set of a subcommand
+func newFlagSet(name, arguments string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: redolog-tool %s %s\n\nFlags:\n", name, arguments)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// stdinFile is the file name that reads the redo log from stdin
+const stdinFile = "-"
+
+// inputFlags are the flags selecting and loading the input file
+type inputFlags struct {
+	file       string
+	maxRecords int
+	verbose    bool
+	keyring    string
+}
+
+// addFileFlags registers --file and -v on fs
+func addFileFlags(fs *flag.FlagSet) *inputFlags {
+	in := &inputFlags{}
+	fs.StringVar(&in.file, "file", "", "InnoDB redo log file, or - for stdin (or pass it as the last argument)")
+	fs.BoolVar(&in.verbose, "v", false, "Print loading progress to stderr")
+	return in
+}
+
+// addInputFlags registers --file, --max-records, --keyring and -v on fs
+func addInputFlags(fs *flag.FlagSet, defaultMaxRecords int) *inputFlags {
+	in := addFileFlags(fs)
+	fs.IntVar(&in.maxRecords, "max-records", defaultMaxRecords, "Maximum number of records to read (0 = no limit)")
+	addKeyringFlag(fs, &in.keyring)
+	return in
+}
+
+// addKeyringFlag registers --keyring on fs
+func addKeyringFlag(fs *flag.FlagSet, keyring *string) {
+	fs.StringVar(keyring, "keyring", "", "keyring_file data file or component_keyring_file JSON holding the master key of an encrypted redo log")
+}
+
+// parse parses args and returns the positional arguments other than the
+// input file. A trailing positional argument is taken as the file when
+// --file is not set.
+func (in *inputFlags) parse(fs *flag.FlagSet, args []string, positional int) ([]string, error) {
+	if err := parseFlags(fs, args); err != nil {
+		return nil, err
+	}
+
+	rest := fs.Args()
+	if in.file == "" && len(rest) == positional+1 {
+		in.file = rest[positional]
+		rest = rest[:positional]
+	}
+	if in.file == "" {
+		return nil, newUsageError("no redo log file given (see 'redolog-tool help %s')", fs.Name())
+	}
+	if len(rest) != positional {
+		return nil, newUsageError("unexpected arguments: %s", strings.Join(rest[positional:], " "))
+	}
+	return rest, nil
+}
+
+// load reads the input file
+func (in *inputFlags) load() ([]*types.LogRecord, *types.RedoLogHeader, error) {
+	return loadRedoLogData(in.file, loadOptions{MaxRecords: in.maxRecords, Verbose: in.verbose, Keyring: in.keyring})
+}
+
+// loadHeader reads only the header of the input file
+func (in *inputFlags) loadHeader() (*types.RedoLogHeader, error) {
+	_, header, err := loadRedoLogData(in.file, loadOptions{HeaderOnly: true, Verbose: in.verbose})
+	return header, err
+}
+
+// parseFlags parses args into fs, marking parse errors as usage errors
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &usageError{err: err, reported: true}
+	}
+	return nil
+}
+
+// lsnValue is a flag.Value accepting decimal or 0x-prefixed hexadecimal LSNs
+type lsnValue struct {
+	value uint64
+	set   bool
+}
+
+func (v *lsnValue) String() string {
+	if !v.set {
+		return ""
+	}
+	return strconv.FormatUint(v.value, 10)
+}
+
+func (v *lsnValue) Set(s string) error {
+	value, err := strconv.ParseUint(s, 0, 64)
+	if err != nil {
+		return fmt.Errorf("invalid LSN %q", s)
+	}
+	v.value = value
+	v.set = true
+	return nil
+}
+
+// recordSelector selects records by LSN range and filter expression; both
+// LSN bounds are inclusive
+type recordSelector struct {
+	from   lsnValue
+	to     lsnValue
+	where  string
+	filter *filter.Filter
+}
+
+// addSelectorFlags registers --from-lsn, --to-lsn and --where on fs
+func addSelectorFlags(fs *flag.FlagSet) *recordSelector {
+	s := &recordSelector{}
+	fs.Var(&s.from, "from-lsn", "Only records with LSN >= this value (decimal or 0x hex)")
+	fs.Var(&s.to, "to-lsn", "Only records with LSN <= this value (decimal or 0x hex)")
+	fs.StringVar(&s.where, "where", "", "Only records matching this filter expression, e.g. 'op == insert && space == 42'")
+	return s
+}
+
+// validate checks the range and parses the filter expression
+func (s *recordSelector) validate() error {
+	if s.from.set && s.to.set && s.from.value > s.to.value {
+		return newUsageError("--from-lsn %d is greater than --to-lsn %d", s.from.value, s.to.value)
+	}
+	if strings.TrimSpace(s.where) == "" {
+		return nil
+	}
+
+	f, err := filter.Parse(s.where)
+	if err != nil {
+		var syntaxErr *filter.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return newUsageError("invalid --where expression: %v\n%s", err, syntaxErr.Context())
+		}
+		return newUsageError("invalid --where expression: %v", err)
+	}
+	s.filter = f
+	return nil
+}
+
+// contains reports whether lsn lies inside the range
+func (s *recordSelector) contains(lsn uint64) bool {
+	if s.from.set && lsn < s.from.value {
+		return false
+	}
+	if s.to.set && lsn > s.to.value {
+		return false
+	}
+	return true
+}
+
+// matches reports whether record is selected
+func (s *recordSelector) matches(record *types.LogRecord) bool {
+	return s.contains(record.LSN) && (s.filter == nil || s.filter.Match(record))
+}
+
+// String describes the selection, e.g. "lsn >= 1000 && op == insert"
+func (s *recordSelector) String() string {
+	parts := make([]string, 0, 3)
+	if s.from.set {
+		parts = append(parts, fmt.Sprintf("lsn >= %d", s.from.value))
+	}
+	if s.to.set {
+		parts = append(parts, fmt.Sprintf("lsn <= %d", s.to.value))
+	}
+	if s.filter != nil {
+		if len(parts) > 0 {
+			parts = append(parts, "("+s.filter.String()+")")
+		} else {
+			parts = append(parts, s.filter.String())
+		}
+	}
+	return strings.Join(parts, " && ")
+}
+
+// apply returns the selected records
+func (s *recordSelector) apply(records []*types.LogRecord) []*types.LogRecord {
+	if !s.from.set && !s.to.set && s.filter == nil {
+		return records
+	}
+	selected := make([]*types.LogRecord, 0, len(records))
+	for _, record := range records {
+		if s.matches(record) {
+			selected = append(selected, record)
+		}
+	}
+	return selected
+}
+
+// outputFlags are the --format and --output flags
+type outputFlags struct {
+	format  string
+	output  string
+	formats []string
+}
+
+// addOutputFlags registers --format and --output on fs. The first format
+// is the default.
+func addOutputFlags(fs *flag.FlagSet, formats ...string) *outputFlags {
+	out := &outputFlags{formats: formats}
+	fs.StringVar(&out.format, "format", formats[0], "Output format: "+strings.Join(formats, ", "))
+	fs.StringVar(&out.output, "output", "", "Output file (default: stdout)")
+	return out
+}
+
+// validate checks the requested format
+func (out *outputFlags) validate() error {
+	out.format = strings.ToLower(out.format)
+	for _, format := range out.formats {
+		if out.format == format {
+			return nil
+		}
+	}
+	return newUsageError("unsupported format %q (supported: %s)", out.format, strings.Join(out.formats, ", "))
+}
+
+// write opens the output destination and passes it to fn
+func (out *outputFlags) write(fn func(w io.Writer) error) error {
+	if out.output == "" {
+		return fn(os.Stdout)
+	}
+
+	file, err := os.Create(out.output)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	if err := fn(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
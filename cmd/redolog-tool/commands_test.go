@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/analyzer"
+	"github.com/yamaru/innodb-redolog-tool/internal/reader"
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+	"github.com/yamaru/innodb-redolog-tool/test/fixtures"
+)
+
+func TestRecordSelector(t *testing.T) {
+	records := []*types.LogRecord{
+		{LSN: 100, SpaceID: 1},
+		{LSN: 200, SpaceID: 2},
+		{LSN: 300, SpaceID: 2},
+	}
+
+	var s recordSelector
+	require.NoError(t, s.from.Set("0xc8"))
+	require.NoError(t, s.to.Set("300"))
+	require.NoError(t, s.validate())
+
+	selected := s.apply(records)
+	require.Len(t, selected, 2)
+	assert.Equal(t, uint64(200), selected[0].LSN)
+	assert.Equal(t, uint64(300), selected[1].LSN)
+
+	s.where = "space == 2 && lsn != 300"
+	require.NoError(t, s.validate())
+	selected = s.apply(records)
+	require.Len(t, selected, 1)
+	assert.Equal(t, uint64(200), selected[0].LSN)
+	assert.Equal(t, "lsn >= 200 && lsn <= 300 && (space == 2 && lsn != 300)", s.String())
+
+	assert.Error(t, s.from.Set("abc"))
+
+	s.where = "space =="
+	assert.Error(t, s.validate())
+
+	s.where = ""
+	require.NoError(t, s.from.Set("400"))
+	assert.Error(t, s.validate())
+}
+
+func TestRunCommandUsage(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{"no arguments", nil, exitUsage},
+		{"unknown command", []string{"nosuch"}, exitUsage},
+		{"missing file", []string{"dump"}, exitUsage},
+		{"unsupported format", []string{"stats", "--format", "xml", "x.log"}, exitUsage},
+		{"invalid where", []string{"dump", "--where", "space = 1", "x.log"}, exitUsage},
+		{"command help", []string{"help", "dump"}, exitOK},
+		{"missing input", []string{"dump", "does-not-exist.log"}, exitError},
+		{"tui from stdin", []string{"tui", "-"}, exitUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, runCommand(tt.args))
+		})
+	}
+}
+
+func TestSelectDebugSections(t *testing.T) {
+	all, err := selectDebugSections("all")
+	require.NoError(t, err)
+	for _, section := range all {
+		assert.NotEqual(t, "sakila", section.name, "sakila only runs when requested")
+	}
+
+	selected, err := selectDebugSections("hex, sakila")
+	require.NoError(t, err)
+	require.Len(t, selected, 2)
+	assert.Equal(t, "hex", selected[0].name)
+	assert.Equal(t, "sakila", selected[1].name)
+
+	_, err = selectDebugSections("nope")
+	assert.Error(t, err)
+}
+
+func TestLSNWindow(t *testing.T) {
+	var w lsnWindow
+	require.NoError(t, w.Set("0x10:"))
+	assert.True(t, w.from.set)
+	assert.Equal(t, uint64(16), w.from.value)
+	assert.False(t, w.to.set)
+
+	records := []*types.LogRecord{{LSN: 8}, {LSN: 16}, {LSN: 32}}
+	assert.Len(t, w.selector().apply(records), 2)
+
+	assert.Error(t, w.Set("100"))
+	assert.Error(t, w.Set("200:100"))
+	assert.Error(t, w.Set("x:1"))
+}
+
+func TestRunDiffWindows(t *testing.T) {
+	dir := t.TempDir()
+	filename, err := fixtures.CreateSampleLogFile(dir)
+	require.NoError(t, err)
+	output := filepath.Join(dir, "diff.json")
+
+	code := runCommand([]string{"diff", "--window-a", ":1001", "--window-b", "1002:", "--format", "json", "--output", output, filename})
+	require.Equal(t, exitOK, code)
+
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	var report diffReport
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, 1, report.A.Records)
+	assert.Equal(t, 2, report.B.Records)
+	assert.Len(t, report.Diff.Types, 3)
+
+	assert.Equal(t, exitUsage, runCommand([]string{"diff", "--window-a", ":1001", filename}))
+}
+
+func TestRunExportSQL(t *testing.T) {
+	dir := t.TempDir()
+	filename, err := fixtures.CreateSampleLogFile(dir)
+	require.NoError(t, err)
+	output := filepath.Join(dir, "records.sql")
+
+	code := runCommand([]string{"export", "--format", "sql", "--where", "lsn > 1001", "--output", output, filename})
+	require.Equal(t, exitOK, code)
+
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "-- source: "+filename+"\n-- filter: lsn > 1001\n")
+	assert.Equal(t, 2, strings.Count(string(data), "INSERT INTO redo_records"))
+}
+
+func TestRunExportStdin(t *testing.T) {
+	dir := t.TempDir()
+	filename, err := fixtures.CreateSampleLogFile(dir)
+	require.NoError(t, err)
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	output := filepath.Join(dir, "records.csv")
+
+	// A pipe, so the log is read as a stream
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	go func() {
+		w.Write(data)
+		w.Close()
+	}()
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin; r.Close() }()
+
+	code := runCommand([]string{"export", "--format", "csv", "--output", output, "-"})
+	require.Equal(t, exitOK, code)
+
+	exported, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(string(exported), "MLOG_"))
+}
+
+func TestRunExportParquet(t *testing.T) {
+	dir := t.TempDir()
+	filename, err := fixtures.CreateSampleLogFile(dir)
+	require.NoError(t, err)
+	output := filepath.Join(dir, "records.parquet")
+
+	code := runCommand([]string{"export", "--format", "parquet", "--row-group-rows", "1", "--compression", "none", "--output", output, filename})
+	require.Equal(t, exitOK, code)
+
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(data, []byte("PAR1")))
+	assert.True(t, bytes.HasSuffix(data, []byte("PAR1")))
+
+	assert.Equal(t, exitUsage, runCommand([]string{"export", "--format", "parquet", "--compression", "snappy", filename}))
+	assert.Equal(t, exitUsage, runCommand([]string{"export", "--format", "parquet", "--row-group-rows", "0", filename}))
+}
+
+// createRowChangeLogFile writes a log file with an insert, an update, a
+// commit and a delete record
+func createRowChangeLogFile(t *testing.T, dir string) string {
+	data := fixtures.BinaryRedoLogHeader()
+	records := []*types.LogRecord{
+		{Type: types.LogType(9), LSN: 1001, SpaceID: 5, TransactionID: 7, Data: []byte("hex=00 | parsed=(innodb_record=[header_skip=5 field1_int=42 field2_str='ACADEMY'])")},
+		{Type: types.LogType(13), LSN: 1002, SpaceID: 5, TransactionID: 7, Data: []byte("update")},
+		{Type: types.LogType(31), LSN: 1003, Data: []byte("commit")},
+		{Type: types.LogType(10), LSN: 1004, SpaceID: 5, Data: []byte("delete")},
+	}
+	for _, record := range records {
+		record.Length = uint32(len(record.Data))
+		data = append(data, fixtures.BinaryLogRecord(record)...)
+	}
+	filename := filepath.Join(dir, "rows.log")
+	require.NoError(t, os.WriteFile(filename, data, 0o644))
+	return filename
+}
+
+func TestRunCDC(t *testing.T) {
+	dir := t.TempDir()
+	filename := createRowChangeLogFile(t, dir)
+	sink := filepath.Join(dir, "events.json")
+
+	code := runCommand([]string{"cdc", "--sink", sink, "--server-name", "inventory", filename})
+	require.Equal(t, exitOK, code)
+
+	data, err := os.ReadFile(sink)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 3)
+
+	var event struct {
+		Op     string         `json:"op"`
+		Before map[string]any `json:"before"`
+		After  map[string]any `json:"after"`
+		Source map[string]any `json:"source"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &event))
+	assert.Equal(t, "c", event.Op)
+	assert.Equal(t, map[string]any{"field1": float64(42), "field2": "ACADEMY"}, event.After)
+	assert.Equal(t, "inventory", event.Source["name"])
+	assert.Equal(t, float64(1001), event.Source["lsn"])
+	assert.Equal(t, "space_5", event.Source["table"])
+	assert.Equal(t, filename, event.Source["file"])
+	event.After = nil
+	require.NoError(t, json.Unmarshal([]byte(lines[2]), &event))
+	assert.Equal(t, "d", event.Op)
+	assert.Nil(t, event.After)
+	assert.Equal(t, float64(4), event.Source["record"])
+
+	require.Equal(t, exitOK, runCommand([]string{"cdc", "--sink", sink, "--where", "op == update", filename}))
+	data, err = os.ReadFile(sink)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), "\n"))
+	assert.Contains(t, string(data), `"op":"u"`)
+	assert.Contains(t, string(data), `"record":2`)
+
+	assert.Equal(t, exitUsage, runCommand([]string{"cdc", "--anchor", "bogus", filename}))
+}
+
+func TestRunXtraBackup(t *testing.T) {
+	dir := t.TempDir()
+	logfile, err := fixtures.CreateXtraBackupLogFile(dir, 8804, 3, 112)
+	require.NoError(t, err)
+	output := filepath.Join(dir, "report.json")
+
+	// Without xtrabackup_checkpoints only the log is described
+	require.Equal(t, exitOK, runCommand([]string{"xtrabackup", "--format", "json", "--output", output, logfile}))
+	var report xtrabackupReport
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, uint64(8704), report.Log.StartLSN)
+	assert.Equal(t, uint64(9840), report.Log.EndLSN)
+	assert.Equal(t, 3, report.Log.Blocks)
+	assert.Nil(t, report.Checkpoints)
+
+	_, err = fixtures.CreateXtraBackupCheckpointsFile(dir, 8804, 9840)
+	require.NoError(t, err)
+	require.Equal(t, exitOK, runCommand([]string{"xtrabackup", "--output", output, logfile}))
+	data, err = os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "The copied redo covers to_lsn through last_lsn")
+
+	// A redo copy ending before last_lsn fails the check
+	_, err = fixtures.CreateXtraBackupCheckpointsFile(dir, 8804, 12000)
+	require.NoError(t, err)
+	require.Equal(t, exitVerifyFailed, runCommand([]string{"xtrabackup", "--output", output, logfile}))
+	data, err = os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "the redo copy is truncated")
+
+	sample, err := fixtures.CreateSampleLogFile(dir)
+	require.NoError(t, err)
+	assert.Equal(t, exitError, runCommand([]string{"xtrabackup", sample}))
+	assert.Equal(t, exitError, runCommand([]string{"xtrabackup", "--checkpoints", filepath.Join(dir, "missing"), logfile}))
+
+	// A corrupt xtrabackup_checkpoints next to the log is reported, not skipped
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "xtrabackup_checkpoints"), []byte("to_lsn = garbage\n"), 0644))
+	assert.Equal(t, exitError, runCommand([]string{"xtrabackup", "--output", output, logfile}))
+}
+
+func TestDefaultCheckpointsPath(t *testing.T) {
+	assert.Equal(t, filepath.Join("backup", "xtrabackup_checkpoints"), defaultCheckpointsPath(filepath.Join("backup", "xtrabackup_logfile")))
+	assert.Equal(t, "backup.tar!full/xtrabackup_checkpoints", defaultCheckpointsPath("backup.tar!full/xtrabackup_logfile"))
+	assert.Equal(t, "backup.tar!xtrabackup_checkpoints", defaultCheckpointsPath("backup.tar!xtrabackup_logfile"))
+}
+
+func TestRunSchema(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "schema.json")
+	require.Equal(t, exitOK, runCommand([]string{"schema", "--output", output}))
+
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.True(t, json.Valid(data))
+	assert.Contains(t, string(data), `"schema_version": {"const": 1}`)
+
+	assert.Equal(t, exitUsage, runCommand([]string{"schema", "extra"}))
+}
+
+func TestRunVerify(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "verify.json")
+
+	// MLOG_ZIP_PAGE_COMPRESS_NO_DATA with compression level 10 stops reading
+	filename, err := fixtures.CreateMySQLLogFile(dir, reader.LogHeaderFormat8030, 8192, []byte{
+		1, 5, 3, 0x00, 0x10, 0x2B,
+		74, 5, 4, 1, reader.IndexLogCompact, 0x00, 0x01, 0x00, 0x01, 0x80, 0x04, 10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, exitVerifyFailed, runCommand([]string{"verify", "--format", "json", "--output", output, filename}))
+
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	var report verifyReport
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, 1, report.Records)
+	require.Len(t, report.Corruption.CorruptedRecords, 1)
+	assert.Equal(t, "read_error", report.Corruption.CorruptedRecords[0].IssueType)
+	assert.Equal(t, analyzer.SeverityHigh, report.Corruption.Severity)
+	assert.True(t, report.Corruption.Recoverable)
+}
+
+func TestRunAmplification(t *testing.T) {
+	dir := t.TempDir()
+	filename, err := fixtures.CreateSampleLogFile(dir)
+	require.NoError(t, err)
+	output := filepath.Join(dir, "amplification.json")
+
+	code := runCommand([]string{"amplification", "--top", "1", "--format", "json", "--output", output, filename})
+	require.Equal(t, exitOK, code)
+
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	var report amplificationReport
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, filename, report.File)
+	assert.Equal(t, uint64(3), report.Total.Records)
+	assert.Len(t, report.Spaces, 1)
+
+	assert.Equal(t, exitUsage, runCommand([]string{"amplification", "--top", "-1", filename}))
+	assert.Equal(t, exitError, runCommand([]string{"amplification", "--dictionary", filepath.Join(dir, "missing.json"), filename}))
+}
+
+func TestRunThroughput(t *testing.T) {
+	dir := t.TempDir()
+	filename, err := fixtures.CreateSampleLogFile(dir)
+	require.NoError(t, err)
+	output := filepath.Join(dir, "throughput.json")
+
+	code := runCommand([]string{"throughput", "--window-lsn", "1", "--format", "json", "--output", output, filename})
+	require.Equal(t, exitOK, code)
+
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	var report throughputReport
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, uint64(1), report.WindowLSN)
+	require.NotEmpty(t, report.Windows)
+
+	var records uint64
+	for _, window := range report.Windows {
+		records += window.Records
+	}
+	assert.Equal(t, uint64(3), records)
+
+	assert.Equal(t, exitUsage, runCommand([]string{"throughput", "--window-lsn", "10", "--interval", "1s", filename}))
+	assert.Equal(t, exitUsage, runCommand([]string{"throughput", "--anchor", "1000", filename}))
+	assert.Equal(t, exitUsage, runCommand([]string{"throughput", "--anchor", "1000@2024-08-24T12:00:00Z", filename}))
+}
+
+func TestSparkline(t *testing.T) {
+	assert.Equal(t, "▁ ▄█", sparkline([]uint64{1, 0, 4, 8}))
+	assert.Equal(t, "", sparkline(nil))
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/analyzer"
+)
+
+// dashboardBarWidth is the width of the longest bar of the dashboard
+const dashboardBarWidth = 30
+
+// dashboardFilterActions are the actions that change the filters without
+// leaving the dashboard
+var dashboardFilterActions = []string{"insert", "update", "delete"}
+
+// initializeDashboard creates the statistics dashboard
+func (app *RedoLogApp) initializeDashboard() {
+	app.dashboardView = tview.NewTextView()
+	app.dashboardView.SetBorder(true)
+	app.dashboardView.SetDynamicColors(true)
+	app.dashboardView.SetScrollable(true)
+	app.dashboardView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if app.closesPanel(event, "stats") {
+			app.app.SetRoot(app.mainLayout(), true)
+			app.app.SetFocus(app.recordList)
+			return nil
+		}
+		for _, name := range dashboardFilterActions {
+			if app.keymap.matches(event, name) {
+				action, _ := findKeyAction(name)
+				action.run(app)
+				app.refreshDashboard()
+				return nil
+			}
+		}
+		return event
+	})
+}
+
+// showDashboard shows the statistics of the filtered records
+func (app *RedoLogApp) showDashboard() {
+	app.refreshDashboard()
+	app.dashboardView.ScrollToBeginning()
+	app.app.SetRoot(app.dashboardView, true)
+	app.app.SetFocus(app.dashboardView)
+}
+
+// refreshDashboard recomputes the dashboard from the filtered records
+func (app *RedoLogApp) refreshDashboard() {
+	keys := make([]string, 0, len(dashboardFilterActions))
+	for _, name := range dashboardFilterActions {
+		keys = append(keys, app.keymap.keys(name))
+	}
+	app.dashboardView.SetTitle(fmt.Sprintf(" Statistics (%s filter, Esc to close) ", strings.Join(keys, " ")))
+
+	var text strings.Builder
+	report, err := analyzer.BuildStatsReport(app.filteredRecords, app.header, analyzer.DefaultStatsTop)
+	if err != nil {
+		fmt.Fprintf(&text, "[red]Error: %s[white]\n", tview.Escape(err.Error()))
+	} else {
+		app.writeDashboard(&text, report)
+	}
+	app.dashboardView.SetText(app.theme.colorize(text.String()))
+}
+
+// writeDashboard writes the sections of the dashboard
+func (app *RedoLogApp) writeDashboard(b *strings.Builder, report *analyzer.StatsReport) {
+	filter := app.operationFilter
+	if filter == "" {
+		filter = "all"
+	}
+	if app.whereFilter != nil {
+		filter += ", " + app.whereFilter.String()
+	}
+	fmt.Fprintf(b, "Records: [cyan]%d[white] of %d (filter: %s)  Size: %d bytes  MTRs: %d\n",
+		report.TotalRecords, len(app.records), tview.Escape(filter), report.SizeInBytes, report.MTRGroups)
+	fmt.Fprintf(b, "Operations: insert=%d update=%d delete=%d other=%d\n",
+		report.Operations["insert"], report.Operations["update"], report.Operations["delete"], report.Operations["other"])
+
+	b.WriteString("\n[yellow]Record types[white]\n")
+	var maxCount uint64
+	for _, tc := range report.Types {
+		maxCount = max(maxCount, tc.Count)
+	}
+	for _, tc := range report.Types {
+		fmt.Fprintf(b, "  %-36s %8d %s\n", tc.Type, tc.Count, bar(tc.Count, maxCount, "green"))
+	}
+
+	b.WriteString("\n[yellow]Top spaces[white]              records      bytes\n")
+	for _, space := range report.Spaces {
+		fmt.Fprintf(b, "  space %-16d %8d %10d\n", space.SpaceID, space.Records, space.Bytes)
+	}
+	b.WriteString("\n[yellow]Top pages[white]               records      bytes\n")
+	for _, page := range report.Pages {
+		fmt.Fprintf(b, "  %-22s %8d %10d\n", fmt.Sprintf("%d:%d", page.SpaceID, page.PageNo), page.Records, page.Bytes)
+	}
+
+	b.WriteString("\n[yellow]MTR sizes[white] (records per MTR)\n")
+	var maxMTRs uint64
+	for _, bucket := range report.MTRSizes {
+		maxMTRs = max(maxMTRs, bucket.MTRs)
+	}
+	for _, bucket := range report.MTRSizes {
+		fmt.Fprintf(b, "  %-6s %8d %s\n", bucket.Label(), bucket.MTRs, bar(bucket.MTRs, maxMTRs, "cyan"))
+	}
+
+	b.WriteString("\n[yellow]Checkpoint[white]\n")
+	if cp := report.Checkpoint; cp != nil {
+		fmt.Fprintf(b, "  LSN %d, last record LSN %d, age %d bytes\n", cp.CheckpointLSN, cp.LastLSN, cp.Age)
+	} else {
+		b.WriteString("  unknown\n")
+	}
+
+	cov := report.Coverage
+	b.WriteString("\n[yellow]LSN coverage[white]\n")
+	fmt.Fprintf(b, "  %d - %d: %d of %d bytes in records (%.1f%%)\n", report.FirstLSN, report.LastLSN, cov.RecordBytes, cov.SpanBytes, cov.Percent)
+	fmt.Fprintf(b, "  %d gaps, largest %d bytes\n", cov.Gaps, cov.LargestGap)
+
+	b.WriteString("\n[yellow]Corruption[white]\n")
+	if report.Corruption.Issues == 0 {
+		b.WriteString("  [green]none found[white]\n")
+		return
+	}
+	fmt.Fprintf(b, "  [red]%d issues[white] (severity %s)\n", report.Corruption.Issues, report.Corruption.Severity)
+	issueTypes := make([]string, 0, len(report.Corruption.ByType))
+	for issueType := range report.Corruption.ByType {
+		issueTypes = append(issueTypes, issueType)
+	}
+	sort.Strings(issueTypes)
+	for _, issueType := range issueTypes {
+		fmt.Fprintf(b, "  %-20s %d\n", issueType, report.Corruption.ByType[issueType])
+	}
+	for _, issue := range report.Corruption.Examples {
+		fmt.Fprintf(b, "  LSN %d: %s\n", issue.LSN, tview.Escape(issue.Description))
+	}
+}
+
+// bar returns a bar of value relative to maxValue
+func bar(value, maxValue uint64, color string) string {
+	if maxValue == 0 || value == 0 {
+		return ""
+	}
+	width := int(value * dashboardBarWidth / maxValue)
+	if width == 0 {
+		width = 1
+	}
+	return fmt.Sprintf("[%s]%s[white]", color, strings.Repeat("█", width))
+}
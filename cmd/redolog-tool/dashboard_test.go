@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+func TestDashboard(t *testing.T) {
+	records := []*types.LogRecord{
+		{Type: types.LogType(9), Length: 20, LSN: 100, SpaceID: 5, PageNo: 3, MultiRecordGroup: 1, IsGroupStart: true},
+		{Type: types.LogType(13), Length: 10, LSN: 120, SpaceID: 5, PageNo: 4, MultiRecordGroup: 1},
+		{Type: types.LogType(31), Length: 1, LSN: 130, MultiRecordGroup: 1, IsGroupEnd: true},
+		{Type: types.LogType(14), Length: 4, LSN: 131, SpaceID: 6, PageNo: 1},
+	}
+	app := NewRedoLogApp(records, &types.RedoLogHeader{LastCheckpoint: 110})
+	app.showTableID0 = true
+	app.updateFilteredRecords()
+
+	app.showDashboard()
+	text := app.dashboardView.GetText(true)
+	assert.Contains(t, text, "Records: 4 of 4 (filter: all)")
+	assert.Contains(t, text, "MLOG_REC_INSERT_8027")
+	assert.Contains(t, text, "space 5")
+	assert.Contains(t, text, "LSN 110, last record LSN 131, age 21 bytes")
+	assert.Contains(t, text, "none found")
+
+	// The filter keys recompute the dashboard in place
+	capture := app.dashboardView.GetInputCapture()
+	assert.Nil(t, capture(tcell.NewEventKey(tcell.KeyRune, 'd', tcell.ModNone)))
+	text = app.dashboardView.GetText(true)
+	assert.Contains(t, text, "Records: 1 of 4 (filter: delete)")
+	assert.NotContains(t, text, "MLOG_REC_INSERT_8027")
+	assert.Contains(t, text, "space 6")
+}
@@ -0,0 +1,470 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/reader"
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// defaultDebugHex is the sample used by the hex parsing section
+const defaultDebugHex = "000000000503c20000"
+
+// debugSection is one diagnostic dump of the debug subcommand
+type debugSection struct {
+	name    string
+	summary string
+	run     func(records []*types.LogRecord, opts debugOptions)
+}
+
+// debugOptions holds the flags of the debug subcommand
+type debugOptions struct {
+	hex string
+}
+
+// debugSections lists the sections in the order "all" runs them; sakila is
+// only run when asked for explicitly
+var debugSections = []debugSection{
+	{"dynamic-meta", "MLOG_TABLE_DYNAMIC_META records with table IDs", func(records []*types.LogRecord, _ debugOptions) { debugDynamicMeta(records) }},
+	{"filter", "Table ID 0 filter effectiveness", func(records []*types.LogRecord, _ debugOptions) { debugFilter(records) }},
+	{"inserts", "first MLOG_REC_INSERT_8027 records", func(records []*types.LogRecord, _ debugOptions) { debugInserts(records) }},
+	{"hex", "field parser run against --hex", func(_ []*types.LogRecord, opts debugOptions) { debugHexParsing(opts.hex) }},
+	{"footer", "TUI footer simulation", func(records []*types.LogRecord, _ debugOptions) { debugFooter(records) }},
+	{"groups", "multi-record group boundaries", func(records []*types.LogRecord, _ debugOptions) { debugGroups(records) }},
+	{"group-visual", "TUI group markers for the first 20 records", func(records []*types.LogRecord, _ debugOptions) { debugGroupVisual(records) }},
+	{"sakila", "search for sakila-data.sql VARCHAR content", func(records []*types.LogRecord, _ debugOptions) { debugSakila(records) }},
+}
+
+// runDebug implements the debug subcommand
+func runDebug(args []string) error {
+	fs := newFlagSet("debug", "[flags] <redo_log_file>")
+	in := addInputFlags(fs, 0)
+	selector := addSelectorFlags(fs)
+	sections := fs.String("section", "all", "Comma separated sections to run: all, "+debugSectionNames())
+	opts := debugOptions{}
+	fs.StringVar(&opts.hex, "hex", defaultDebugHex, "Hex bytes for the hex section")
+	if _, err := in.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := selector.validate(); err != nil {
+		return err
+	}
+
+	selected, err := selectDebugSections(*sections)
+	if err != nil {
+		return err
+	}
+
+	records, _, err := in.load()
+	if err != nil {
+		return err
+	}
+	records = selector.apply(records)
+
+	for _, section := range selected {
+		section.run(records, opts)
+	}
+	return nil
+}
+
+// selectDebugSections resolves a comma separated list of section names
+func selectDebugSections(spec string) ([]debugSection, error) {
+	var selected []debugSection
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "all" {
+			for _, section := range debugSections {
+				if section.name != "sakila" {
+					selected = append(selected, section)
+				}
+			}
+			continue
+		}
+		found := false
+		for _, section := range debugSections {
+			if section.name == name {
+				selected = append(selected, section)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, newUsageError("unknown debug section %q (available: all, %s)", name, debugSectionNames())
+		}
+	}
+	return selected, nil
+}
+
+// debugSectionNames returns the section names as a comma separated list
+func debugSectionNames() string {
+	names := make([]string, 0, len(debugSections))
+	for _, section := range debugSections {
+		names = append(names, section.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// debugDynamicMeta reports MLOG_TABLE_DYNAMIC_META records and their table IDs
+func debugDynamicMeta(records []*types.LogRecord) {
+	// Search for MLOG_TABLE_DYNAMIC_META records (type 62) to show Table IDs
+	fmt.Printf("\nSearching for MLOG_TABLE_DYNAMIC_META records with Table IDs:\n")
+	count := 0
+	for i, record := range records {
+		if uint8(record.Type) == 62 { // MLOG_TABLE_DYNAMIC_META
+			fmt.Printf("Record %d: %s TableID=%d Data=%s\n", i+1, record.Type.String(), record.TableID, string(record.Data))
+			count++
+			if count >= 10 { // Limit output
+				break
+			}
+		}
+	}
+	fmt.Printf("Found %d MLOG_TABLE_DYNAMIC_META records\n\n", count)
+}
+
+// debugFilter reports how many records the Table ID 0 filter would hide
+func debugFilter(records []*types.LogRecord) {
+	// Show filtering statistics
+	fmt.Printf("Filtering analysis:\n")
+	totalRecords := len(records)
+	tableID0Records := 0
+	for _, record := range records {
+		if record.TableID == 0 && record.SpaceID == 0 {
+			tableID0Records++
+		}
+	}
+	nonZeroIDRecords := totalRecords - tableID0Records
+	fmt.Printf("Total records: %d\n", totalRecords)
+	fmt.Printf("Table ID 0 records (would be hidden): %d\n", tableID0Records)
+	fmt.Printf("Non-zero ID records (would be shown): %d\n", nonZeroIDRecords)
+	fmt.Printf("Filter effectiveness: %.1f%% reduction\n\n", float64(tableID0Records)/float64(totalRecords)*100)
+}
+
+// debugInserts dumps the first MLOG_REC_INSERT_8027 records
+func debugInserts(records []*types.LogRecord) {
+	// Show MLOG_REC_INSERT_8027 record analysis
+	fmt.Printf("MLOG_REC_INSERT_8027 record analysis:\n")
+	insertCount := 0
+	for i, record := range records {
+		if uint8(record.Type) == 9 { // MLOG_REC_INSERT_8027
+			fmt.Printf("Record %d: %s\n", i+1, record.Type.String())
+			fmt.Printf("  LSN: %d\n", record.LSN)
+			fmt.Printf("  Length: %d\n", record.Length)
+			fmt.Printf("  Space ID: %d\n", record.SpaceID)
+			fmt.Printf("  Page No: %d\n", record.PageNo)
+			fmt.Printf("  Data: %s\n", string(record.Data))
+			fmt.Printf("  Group: %d\n", record.MultiRecordGroup)
+			fmt.Printf("\n")
+			insertCount++
+			if insertCount >= 3 { // Limit to 3 records for readability
+				break
+			}
+		}
+	}
+	if insertCount == 0 {
+		fmt.Printf("No MLOG_REC_INSERT_8027 records found\n")
+	}
+	fmt.Printf("Found %d MLOG_REC_INSERT_8027 records\n\n", insertCount)
+}
+
+// debugHexParsing runs the field parser against a hex sample
+func debugHexParsing(testHex string) {
+	fmt.Printf("Hex parsing test:\n")
+	hexBytes, err := hex.DecodeString(testHex)
+	if err != nil {
+		fmt.Printf("Invalid hex input %q: %v\n\n", testHex, err)
+		return
+	}
+	if len(hexBytes) > 0 {
+		// Test our parsing functions
+		fmt.Printf("Input: hex=%s\n", testHex)
+
+		// Test as different field types
+		if len(hexBytes) >= 4 {
+			// Test integer parsing
+			fmt.Printf("As 4-byte integer: %d\n", binary.BigEndian.Uint32(hexBytes[:4]))
+		}
+
+		// Test field parsing with simple heuristics
+		fieldResult := testParseFields(hexBytes)
+		fmt.Printf("Field parsing result: %s\n", fieldResult)
+	}
+	fmt.Printf("\n")
+}
+
+// debugFooter simulates the TUI footer line
+func debugFooter(records []*types.LogRecord) {
+	// Show footer simulation
+	fmt.Printf("Footer display simulation:\n")
+	// Simulate what would appear in the footer
+	showTableID0 := true // Default: show all records
+	filteredRecords := make([]*types.LogRecord, 0)
+	for _, record := range records {
+		if !showTableID0 && record.TableID == 0 && record.SpaceID == 0 {
+			continue // Skip Table ID 0 records when filter is enabled
+		}
+		filteredRecords = append(filteredRecords, record)
+	}
+
+	var filterStatus, filterColor string
+	if showTableID0 {
+		filterStatus = "OFF"
+		filterColor = "[green]"
+	} else {
+		filterStatus = "ON"
+		filterColor = "[red]"
+	}
+	fmt.Printf("Footer: Press 's' to toggle Table ID 0 filter | Filter: %s%s | Records: %d/%d\n\n",
+		filterColor, filterStatus, len(filteredRecords), len(records))
+}
+
+// debugGroups reports multi-record group boundaries and MLOG_MULTI_REC_END records
+func debugGroups(records []*types.LogRecord) {
+	// Analyze multi-record groups
+	fmt.Printf("Multi-record group analysis:\n")
+	groupCount := 0
+	for i, record := range records {
+		if record.MultiRecordGroup > 0 {
+			if record.IsGroupStart {
+				groupCount++
+				fmt.Printf("Group %d starts at Record %d (%s)\n", record.MultiRecordGroup, i+1, record.Type.String())
+			}
+			if record.IsGroupEnd {
+				fmt.Printf("Group %d ends at Record %d (%s)\n", record.MultiRecordGroup, i+1, record.Type.String())
+			}
+		}
+	}
+	fmt.Printf("Found %d multi-record groups\n\n", groupCount)
+
+	// Show MLOG_MULTI_REC_END records and their context
+	fmt.Printf("MLOG_MULTI_REC_END records (type 31) for group detection:\n")
+	multiRecEndCount := 0
+	for i, record := range records {
+		if uint8(record.Type) == 31 { // MLOG_MULTI_REC_END
+			fmt.Printf("Record %d: MLOG_MULTI_REC_END Group=%d IsGroupEnd=%v\n", i+1, record.MultiRecordGroup, record.IsGroupEnd)
+			multiRecEndCount++
+			if multiRecEndCount >= 5 { // Limit output
+				break
+			}
+		}
+	}
+	fmt.Printf("Found %d MLOG_MULTI_REC_END records total\n\n", multiRecEndCount)
+}
+
+// debugGroupVisual prints the TUI group markers for the first 20 records
+func debugGroupVisual(records []*types.LogRecord) {
+	// Show visual group representation (like in TUI)
+	fmt.Printf("Visual group representation (first 20 records):\n")
+	groupColors := []string{"[white]", "[cyan]", "[yellow]", "[green]", "[magenta]", "[blue]"}
+	for i := 0; i < 20 && i < len(records); i++ {
+		record := records[i]
+		recordNum := fmt.Sprintf("%d", i+1)
+		recordType := record.Type.String()
+
+		// Add visual grouping indicators like in TUI
+		var groupIndicator string
+		var colorPrefix string
+
+		if record.MultiRecordGroup > 0 {
+			// Use different colors for different groups
+			colorIndex := (record.MultiRecordGroup - 1) % len(groupColors)
+			colorPrefix = groupColors[colorIndex]
+
+			if record.IsGroupStart {
+				groupIndicator = "┌─ "
+			} else if record.IsGroupEnd {
+				groupIndicator = "└─ "
+			} else {
+				groupIndicator = "├─ "
+			}
+		} else {
+			colorPrefix = "[white]"
+			groupIndicator = "   "
+		}
+
+		// Show SpaceID if available, otherwise TableID
+		var idInfo string
+		if record.SpaceID != 0 {
+			idInfo = fmt.Sprintf("(S:%d)", record.SpaceID)
+		} else if record.TableID != 0 {
+			idInfo = fmt.Sprintf("(T:%d)", record.TableID)
+		} else {
+			idInfo = "(0)"
+		}
+
+		fmt.Printf("%s%s%-6s %s%s Group=%d\n", colorPrefix, groupIndicator, recordNum, recordType, idInfo, record.MultiRecordGroup)
+	}
+}
+
+// debugSakila searches records for sakila-data.sql VARCHAR content
+func debugSakila(records []*types.LogRecord) {
+	fmt.Printf("Test mode: Searching for sakila-data.sql VARCHAR content in redo log\n\n")
+
+	// Expected unique sakila data strings from sakila-data.sql
+	sakilaStrings := []string{
+		"sakila", "SAKILA", // Database name
+		"PENELOPE", "GUINESS", "WAHLBERG", "LOLLOBRIGIDA",
+		"ACADEMY DINOSAUR", "AFRICAN EGG", "AGENT TRUMAN",
+		"AIRPLANE SIERRA", "ALABAMA DEVIL", "ALADDIN CALENDAR",
+		"Epic Drama of a Feminist", "Astounding Documentary",
+		"Fanciful Documentary", "Fast-Paced Documentary",
+		"Canadian Rockies", "Mad Scientist", "Battle a Teacher",
+		"actor", "film", "rental", "customer", "payment", // Table names
+	}
+
+	fmt.Printf("Looking for these sakila-data.sql VARCHAR strings:\n")
+	for i, str := range sakilaStrings {
+		fmt.Printf("  %d. '%s'\n", i+1, str)
+	}
+	fmt.Print("\n" + strings.Repeat("-", 60) + "\n")
+
+	foundSakilaCount := 0
+	foundSystemCount := 0
+	foundAnyStrings := 0
+
+	for i, record := range records {
+		// Search both ASCII data and raw binary data
+		recordData := string(record.Data)
+		rawData := record.Data // Raw binary data
+		recordHasSakila := false
+		recordHasSystem := false
+
+		// Check for sakila strings in both ASCII and binary data
+		for _, sakilaStr := range sakilaStrings {
+			foundInAscii := strings.Contains(recordData, sakilaStr)
+			foundInBinary := strings.Contains(string(rawData), sakilaStr)
+
+			if foundInAscii || foundInBinary {
+				if !recordHasSakila {
+					dataSource := "ASCII"
+					if foundInBinary && !foundInAscii {
+						dataSource = "BINARY"
+					} else if foundInBinary && foundInAscii {
+						dataSource = "ASCII+BINARY"
+					}
+
+					fmt.Printf("🎯 Record %d: %s - FOUND SAKILA DATA! [%s]\n", i+1, record.Type.String(), dataSource)
+					fmt.Printf("   LSN: %d, TableID: %d, SpaceID: %d\n", record.LSN, record.TableID, record.SpaceID)
+					recordHasSakila = true
+					foundSakilaCount++
+				}
+
+				// Show context around the found string (prefer binary if found there)
+				searchData := recordData
+				if foundInBinary {
+					searchData = string(rawData)
+				}
+
+				index := strings.Index(searchData, sakilaStr)
+				start := index - 30
+				end := index + len(sakilaStr) + 30
+				if start < 0 {
+					start = 0
+				}
+				if end > len(searchData) {
+					end = len(searchData)
+				}
+
+				context := searchData[start:end]
+				// Replace non-printable characters for display
+				displayContext := strings.Map(func(r rune) rune {
+					if r >= 32 && r < 127 {
+						return r
+					}
+					return '.'
+				}, context)
+
+				fmt.Printf("   Found: '%s' in context: ...%s...\n", sakilaStr, displayContext)
+
+				// Show hex dump of the area around the found string
+				fmt.Printf("   Hex context: ")
+				hexStart := start
+				hexEnd := end
+				if hexEnd-hexStart > 60 { // Limit hex display
+					hexEnd = hexStart + 60
+				}
+				for j := hexStart; j < hexEnd && j < len(rawData); j++ {
+					fmt.Printf("%02x ", rawData[j])
+				}
+				fmt.Printf("\n")
+
+				// Show full record details for sakila records
+				fmt.Printf("   Full record details:\n")
+				fmt.Printf("     Type: %s (ID: %d)\n", record.Type.String(), uint8(record.Type))
+				fmt.Printf("     Length: %d bytes\n", record.Length)
+				if record.SpaceID != 0 {
+					fmt.Printf("     SpaceID: %d\n", record.SpaceID)
+				}
+				if record.PageNo != 0 {
+					fmt.Printf("     PageNo: %d\n", record.PageNo)
+				}
+				if record.TableID != 0 {
+					fmt.Printf("     TableID: %d\n", record.TableID)
+				}
+				fmt.Printf("     Group: %d\n", record.MultiRecordGroup)
+
+				// Show more data context for sakila records (first 200 bytes)
+				dataLen := len(rawData)
+				if dataLen > 200 {
+					dataLen = 200
+				}
+				fmt.Printf("   Raw data (first %d bytes): ", dataLen)
+				for k := 0; k < dataLen; k++ {
+					if k%16 == 0 {
+						fmt.Printf("\n     ")
+					}
+					fmt.Printf("%02x ", rawData[k])
+				}
+				fmt.Printf("\n")
+			}
+		}
+
+		// Check for system strings (for comparison)
+		systemStrings := []string{"statement_analysis", "host_summary", "schema_unused", "sys_config", "setup_actors"}
+		for _, sysStr := range systemStrings {
+			foundSysAscii := strings.Contains(recordData, sysStr)
+			foundSysBinary := strings.Contains(string(rawData), sysStr)
+
+			if (foundSysAscii || foundSysBinary) && !recordHasSystem {
+				recordHasSystem = true
+				foundSystemCount++
+				// Also show system string details
+				dataSource := "ASCII"
+				if foundSysBinary && !foundSysAscii {
+					dataSource = "BINARY"
+				}
+				fmt.Printf("📊 Record %d: %s - Found system string '%s' [%s]\n", i+1, record.Type.String(), sysStr, dataSource)
+				break
+			}
+		}
+
+		// Check for any VARCHAR strings found
+		if strings.Contains(recordData, "found_strings=") {
+			foundAnyStrings++
+		}
+	}
+
+	fmt.Print("\n" + strings.Repeat("=", 60) + "\n")
+	fmt.Printf("SEARCH RESULTS:\n")
+	fmt.Printf("- Records with sakila data: %d\n", foundSakilaCount)
+	fmt.Printf("- Records with system data: %d\n", foundSystemCount)
+	fmt.Printf("- Records with any VARCHAR strings: %d\n", foundAnyStrings)
+
+	if foundSakilaCount > 0 {
+		fmt.Printf("\n✅ SUCCESS! Found actual sakila-data.sql VARCHAR content!\n")
+	} else if foundAnyStrings > 0 {
+		fmt.Printf("\n⚠️  Found VARCHAR strings, but they are system DB data, not sakila data\n")
+		fmt.Printf("   This suggests:\n")
+		fmt.Printf("   - The redo log was captured during MySQL system operations\n")
+		fmt.Printf("   - Sakila data inserts may be in a different redo log file/timeframe\n")
+	} else {
+		fmt.Printf("\n❌ No VARCHAR strings found at all\n")
+	}
+}
+
+// testParseFields is a simple test function for parsing hex data
+func testParseFields(data []byte) string {
+	return reader.ParseRecordDataAsFields(data)
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/analyzer"
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// diffTextListLimit is the number of spaces or pages listed per side in text output
+const diffTextListLimit = 20
+
+// lsnWindow is a flag.Value for an inclusive FROM:TO LSN range; either end
+// may be omitted
+type lsnWindow struct {
+	from lsnValue
+	to   lsnValue
+	text string
+}
+
+func (w *lsnWindow) String() string {
+	return w.text
+}
+
+func (w *lsnWindow) Set(s string) error {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid LSN window %q (expected FROM:TO)", s)
+	}
+
+	window := lsnWindow{text: s}
+	if parts[0] != "" {
+		if err := window.from.Set(parts[0]); err != nil {
+			return err
+		}
+	}
+	if parts[1] != "" {
+		if err := window.to.Set(parts[1]); err != nil {
+			return err
+		}
+	}
+	if window.from.set && window.to.set && window.from.value > window.to.value {
+		return fmt.Errorf("invalid LSN window %q: start is greater than end", s)
+	}
+
+	*w = window
+	return nil
+}
+
+// isSet reports whether the window was given on the command line
+func (w *lsnWindow) isSet() bool {
+	return w.text != ""
+}
+
+// selector returns a record selector for the window
+func (w *lsnWindow) selector() *recordSelector {
+	return &recordSelector{from: w.from, to: w.to}
+}
+
+// diffSide describes one side of a comparison
+type diffSide struct {
+	File    string `json:"file"`
+	Window  string `json:"window,omitempty"`
+	Records int    `json:"records"`
+}
+
+// diffReport is the result of the diff subcommand
+type diffReport struct {
+	A    diffSide             `json:"a"`
+	B    diffSide             `json:"b"`
+	Diff *analyzer.DiffResult `json:"diff"`
+}
+
+// runDiff implements the diff subcommand. It compares two files, or two LSN
+// windows of one file given with --window-a and --window-b.
+func runDiff(args []string) error {
+	fs := newFlagSet("diff", "[flags] <redo_log_file_a> <redo_log_file_b>\n       redolog-tool diff [flags] --window-a FROM:TO --window-b FROM:TO <redo_log_file>")
+	maxRecords := fs.Int("max-records", 0, "Maximum number of records to read per file (0 = no limit)")
+	verbose := fs.Bool("v", false, "Print loading progress to stderr")
+	var keyring string
+	addKeyringFlag(fs, &keyring)
+	var windowA, windowB lsnWindow
+	fs.Var(&windowA, "window-a", "LSN window FROM:TO of side A (either end may be omitted)")
+	fs.Var(&windowB, "window-b", "LSN window FROM:TO of side B (either end may be omitted)")
+	selector := addSelectorFlags(fs)
+	out := addOutputFlags(fs, "text", "json", "csv")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := validateAll(out.validate, selector.validate); err != nil {
+		return err
+	}
+
+	var fileA, fileB string
+	switch fs.NArg() {
+	case 1:
+		if !windowA.isSet() || !windowB.isSet() {
+			return newUsageError("comparing a single file needs --window-a and --window-b")
+		}
+		fileA, fileB = fs.Arg(0), fs.Arg(0)
+	case 2:
+		fileA, fileB = fs.Arg(0), fs.Arg(1)
+	default:
+		return newUsageError("diff needs two redo log files, or one file with --window-a and --window-b")
+	}
+
+	opts := loadOptions{MaxRecords: *maxRecords, Verbose: *verbose, Keyring: keyring}
+	recordsA, _, err := loadRedoLogData(fileA, opts)
+	if err != nil {
+		return err
+	}
+	recordsB := recordsA
+	if fileB != fileA {
+		if recordsB, _, err = loadRedoLogData(fileB, opts); err != nil {
+			return err
+		}
+	}
+
+	recordsA = windowA.selector().apply(selector.apply(recordsA))
+	recordsB = windowB.selector().apply(selector.apply(recordsB))
+
+	diff, err := analyzer.NewRedoLogAnalyzer().Compare(recordsA, recordsB)
+	if err != nil {
+		return err
+	}
+	report := &diffReport{
+		A:    diffSide{File: fileA, Window: windowA.String(), Records: len(recordsA)},
+		B:    diffSide{File: fileB, Window: windowB.String(), Records: len(recordsB)},
+		Diff: diff,
+	}
+
+	return out.write(func(w io.Writer) error {
+		switch out.format {
+		case "json":
+			return writeJSON(w, report)
+		case "csv":
+			return writeCSVRows(w, []string{"section", "key", "a", "b", "delta"}, diffCSVRows(report.Diff))
+		default:
+			return writeDiffText(w, report)
+		}
+	})
+}
+
+// writeDiffText writes a diff report as text
+func writeDiffText(w io.Writer, report *diffReport) error {
+	for _, side := range []struct {
+		label string
+		side  diffSide
+		stats *types.RedoLogStats
+	}{{"A", report.A, report.Diff.StatsA}, {"B", report.B, report.Diff.StatsB}} {
+		window := ""
+		if side.side.Window != "" {
+			window = " [LSN " + side.side.Window + "]"
+		}
+		fmt.Fprintf(w, "%s: %s%s: %d records, %d bytes\n", side.label, side.side.File, window, side.stats.TotalRecords, side.stats.SizeInBytes)
+	}
+
+	fmt.Fprintf(w, "\nRecord types:\n")
+	fmt.Fprintf(w, "%3s %-36s %8s %8s %8s\n", "ID", "TYPE", "A", "B", "DELTA")
+	for _, d := range report.Diff.Types {
+		fmt.Fprintf(w, "%3d %-36s %8d %8d %+8d\n", uint8(d.Type), d.Name, d.CountA, d.CountB, d.Delta)
+	}
+
+	fmt.Fprintf(w, "\nTables (by table ID, else by tablespace):\n")
+	fmt.Fprintf(w, "%-16s %10s %10s %10s %10s %10s %10s\n", "TABLE", "RECORDS_A", "RECORDS_B", "BYTES_A", "BYTES_B", "CHANGES_A", "CHANGES_B")
+	for _, d := range report.Diff.Tables {
+		fmt.Fprintf(w, "%-16s %10d %10d %10d %10d %10d %10d\n", d.Label(), d.RecordsA, d.RecordsB, d.BytesA, d.BytesB, d.ChangesA, d.ChangesB)
+	}
+
+	fmt.Fprintf(w, "\nSpaces only in A: %s\n", limitedList(spaceStrings(report.Diff.SpacesOnlyInA)))
+	fmt.Fprintf(w, "Spaces only in B: %s\n", limitedList(spaceStrings(report.Diff.SpacesOnlyInB)))
+	fmt.Fprintf(w, "Pages only in A:  %s\n", limitedList(pageStrings(report.Diff.PagesOnlyInA)))
+	fmt.Fprintf(w, "Pages only in B:  %s\n", limitedList(pageStrings(report.Diff.PagesOnlyInB)))
+	return nil
+}
+
+// diffCSVRows flattens a diff into section/key/a/b/delta rows
+func diffCSVRows(diff *analyzer.DiffResult) [][]string {
+	var rows [][]string
+	u := func(v uint64) string { return strconv.FormatUint(v, 10) }
+	delta := func(a, b uint64) string { return strconv.FormatInt(int64(b)-int64(a), 10) }
+
+	for _, d := range diff.Types {
+		rows = append(rows, []string{"type", d.Name, u(d.CountA), u(d.CountB), strconv.FormatInt(d.Delta, 10)})
+	}
+	for _, d := range diff.Tables {
+		table := d.Label()
+		rows = append(rows,
+			[]string{"table_records", table, u(d.RecordsA), u(d.RecordsB), delta(d.RecordsA, d.RecordsB)},
+			[]string{"table_bytes", table, u(d.BytesA), u(d.BytesB), delta(d.BytesA, d.BytesB)},
+			[]string{"table_changes", table, u(d.ChangesA), u(d.ChangesB), delta(d.ChangesA, d.ChangesB)},
+		)
+	}
+	for _, space := range spaceStrings(diff.SpacesOnlyInA) {
+		rows = append(rows, []string{"space_only_in_a", space, "", "", ""})
+	}
+	for _, space := range spaceStrings(diff.SpacesOnlyInB) {
+		rows = append(rows, []string{"space_only_in_b", space, "", "", ""})
+	}
+	for _, page := range pageStrings(diff.PagesOnlyInA) {
+		rows = append(rows, []string{"page_only_in_a", page, "", "", ""})
+	}
+	for _, page := range pageStrings(diff.PagesOnlyInB) {
+		rows = append(rows, []string{"page_only_in_b", page, "", "", ""})
+	}
+	return rows
+}
+
+func spaceStrings(spaces []uint32) []string {
+	values := make([]string, 0, len(spaces))
+	for _, space := range spaces {
+		values = append(values, strconv.FormatUint(uint64(space), 10))
+	}
+	return values
+}
+
+// pageStrings formats pages as space:page
+func pageStrings(pages []analyzer.PageRef) []string {
+	values := make([]string, 0, len(pages))
+	for _, page := range pages {
+		values = append(values, fmt.Sprintf("%d:%d", page.SpaceID, page.PageNo))
+	}
+	return values
+}
+
+// limitedList joins at most diffTextListLimit values
+func limitedList(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	if len(values) <= diffTextListLimit {
+		return strings.Join(values, ", ")
+	}
+	return fmt.Sprintf("%s ... (%d total)", strings.Join(values[:diffTextListLimit], ", "), len(values))
+}
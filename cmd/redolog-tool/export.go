@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/export"
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// defaultExportFile is the file name first offered by the export prompt
+const defaultExportFile = "redo-export.json"
+
+// exportSelection is a set of records the export prompt can write
+type exportSelection struct {
+	name    string // Selection written to the metadata ("" = all filtered records)
+	label   string // Description in the prompt
+	records []*types.LogRecord
+}
+
+// initializeExportPrompt creates the export prompt
+func (app *RedoLogApp) initializeExportPrompt() {
+	app.exportInput = tview.NewInputField()
+	app.exportInput.SetLabel("Export to: ")
+	app.exportInput.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEnter {
+			app.applyExport(app.exportInput.GetText())
+		} else if key == tcell.KeyEscape {
+			app.hideExportPrompt()
+		}
+	})
+	app.exportInput.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyTab {
+			app.exportChoice++
+			app.showExportHelp("")
+			return nil
+		}
+		return event
+	})
+
+	app.exportHelp = tview.NewTextView()
+	app.exportHelp.SetBorder(true)
+	app.exportHelp.SetTitle(" Export ")
+	app.exportHelp.SetDynamicColors(true)
+}
+
+// showExportPrompt asks for the file to export to, offering the marked
+// range first if there is one
+func (app *RedoLogApp) showExportPrompt() {
+	if app.exportInput.GetText() == "" {
+		app.exportInput.SetText(defaultExportFile)
+	}
+	app.exportChoice = 0
+	app.showExportHelp("")
+
+	flex := tview.NewFlex().SetDirection(tview.FlexRow)
+	flex.AddItem(app.exportInput, 1, 0, true)
+	flex.AddItem(app.exportHelp, 0, 1, false)
+
+	app.app.SetRoot(flex, true)
+	app.app.SetFocus(app.exportInput)
+}
+
+// hideExportPrompt returns to the main layout
+func (app *RedoLogApp) hideExportPrompt() {
+	app.app.SetRoot(app.mainLayout(), true)
+	app.app.SetFocus(app.recordList)
+}
+
+// showExportHelp lists the selections, highlighting the chosen one, after
+// status if set
+func (app *RedoLogApp) showExportHelp(status string) {
+	selections := app.exportSelections()
+	chosen := app.exportChoice % len(selections)
+
+	var b strings.Builder
+	if status != "" {
+		b.WriteString(status + "\n\n")
+	}
+	b.WriteString("Enter writes the file, Tab changes what is exported, Esc closes.\n")
+	fmt.Fprintf(&b, "The extension picks the format: .%s\n\n", strings.Join(export.Formats, ", ."))
+	for i, selection := range selections {
+		marker := "  "
+		if i == chosen {
+			marker = "[yellow]> "
+		}
+		fmt.Fprintf(&b, "%s%s (%d records)[white]\n", marker, tview.Escape(selection.label), len(selection.records))
+	}
+	if filter := app.filterDescription(); filter != "" {
+		fmt.Fprintf(&b, "\n[gray]Filter: %s[white]", tview.Escape(filter))
+	}
+	if app.markPosition < 0 {
+		fmt.Fprintf(&b, "\n[gray]Press %s on a record to mark the start of a range.[white]", tview.Escape(app.keymap.keys("mark")))
+	}
+	app.exportHelp.SetText(app.theme.colorize(b.String()))
+}
+
+// applyExport writes the chosen selection to filename, keeping the prompt
+// open to show the result
+func (app *RedoLogApp) applyExport(filename string) {
+	selections := app.exportSelections()
+	selection := selections[app.exportChoice%len(selections)]
+
+	count, err := app.exportSelection(selection, strings.TrimSpace(filename))
+	if err != nil {
+		app.showExportHelp(fmt.Sprintf("[red]Export failed: %s[white]", tview.Escape(err.Error())))
+		return
+	}
+	app.showExportHelp(fmt.Sprintf("[green]Exported %d records to %s[white]", count, tview.Escape(filename)))
+}
+
+// exportSelection writes selection to filename in the format of its extension
+func (app *RedoLogApp) exportSelection(selection exportSelection, filename string) (int, error) {
+	if filename == "" {
+		return 0, fmt.Errorf("no file name")
+	}
+	format, err := export.FormatFromName(filename)
+	if err != nil {
+		return 0, err
+	}
+	if err := export.WriteFile(filename, format, selection.records, app.header, app.exportMetadata(selection.name)); err != nil {
+		return 0, err
+	}
+	return len(selection.records), nil
+}
+
+// exportSelections returns what can be exported: the filtered records, the
+// marked range and the MTR of the selected record. The marked range comes
+// first when there is one.
+func (app *RedoLogApp) exportSelections() []exportSelection {
+	selections := []exportSelection{{label: "Filtered records", records: app.filteredRecords}}
+
+	current := app.recordList.GetCurrentItem()
+	if current < 0 || current >= len(app.filteredRecords) {
+		return selections
+	}
+	position := app.recordIndices[current]
+
+	if app.markPosition >= 0 {
+		from, to := app.markPosition, position
+		if from > to {
+			from, to = to, from
+		}
+		records := make([]*types.LogRecord, 0)
+		for i, p := range app.recordIndices {
+			if p >= from && p <= to {
+				records = append(records, app.filteredRecords[i])
+			}
+		}
+		name := fmt.Sprintf("records #%d-#%d", from+1, to+1)
+		selections = append([]exportSelection{{name: name, label: "Marked range, " + name, records: records}}, selections...)
+	}
+
+	record := app.filteredRecords[current]
+	if group := record.MultiRecordGroup; group > 0 {
+		records := make([]*types.LogRecord, 0)
+		for _, r := range app.records {
+			if r.MultiRecordGroup == group {
+				records = append(records, r)
+			}
+		}
+		name := fmt.Sprintf("MTR group %d", group)
+		selections = append(selections, exportSelection{name: name, label: name + " of the selected record", records: records})
+	} else {
+		name := fmt.Sprintf("record #%d", position+1)
+		selections = append(selections, exportSelection{name: name, label: "Selected record, " + name, records: []*types.LogRecord{record}})
+	}
+	return selections
+}
+
+// exportMetadata describes the source and filters of exported records
+func (app *RedoLogApp) exportMetadata(selection string) *export.Metadata {
+	return &export.Metadata{
+		Source:    app.filename,
+		Filter:    app.filterDescription(),
+		Selection: selection,
+		Exported:  time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+// filterDescription returns the active filters as a filter expression
+func (app *RedoLogApp) filterDescription() string {
+	parts := make([]string, 0, 3)
+	if !app.showTableID0 {
+		parts = append(parts, "!(table == 0 && space == 0)")
+	}
+	if app.operationFilter != "all" && app.operationFilter != "" {
+		parts = append(parts, "op == "+app.operationFilter)
+	}
+	if app.whereFilter != nil {
+		if len(parts) > 0 {
+			parts = append(parts, "("+app.whereFilter.String()+")")
+		} else {
+			parts = append(parts, app.whereFilter.String())
+		}
+	}
+	return strings.Join(parts, " && ")
+}
+
+// toggleMark marks the selected record as one end of the export range, or
+// clears the mark if it is already there
+func (app *RedoLogApp) toggleMark() {
+	current := app.recordList.GetCurrentItem()
+	if current < 0 || current >= len(app.recordIndices) {
+		return
+	}
+	if app.markPosition == app.recordIndices[current] {
+		app.markPosition = -1
+	} else {
+		app.markPosition = app.recordIndices[current]
+	}
+	app.updateFooter()
+}
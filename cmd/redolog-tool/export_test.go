@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/filter"
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+func TestExportPrompt(t *testing.T) {
+	records := []*types.LogRecord{
+		{Type: types.LogType(9), Length: 20, LSN: 100, SpaceID: 5, PageNo: 3, MultiRecordGroup: 1, IsGroupStart: true},
+		{Type: types.LogType(13), Length: 10, LSN: 120, SpaceID: 5, PageNo: 4, MultiRecordGroup: 1},
+		{Type: types.LogType(31), Length: 1, LSN: 130, MultiRecordGroup: 1, IsGroupEnd: true},
+		{Type: types.LogType(9), Length: 4, LSN: 131, SpaceID: 6, PageNo: 1},
+		{Type: types.LogType(9), Length: 4, LSN: 135, SpaceID: 6, PageNo: 2},
+	}
+	app := NewRedoLogApp(records, &types.RedoLogHeader{})
+	app.filename = "ib_logfile0"
+	app.showTableID0 = true
+	app.operationFilter = "insert"
+	where, err := filter.Parse("space >= 5")
+	require.NoError(t, err)
+	app.setWhereFilter(where)
+	require.Len(t, app.filteredRecords, 3)
+	assert.Equal(t, "op == insert && (space >= 5)", app.filterDescription())
+
+	// Without a mark: the filtered records and the MTR of the selected record
+	app.recordList.SetCurrentItem(0)
+	selections := app.exportSelections()
+	require.Len(t, selections, 2)
+	assert.Len(t, selections[0].records, 3)
+	assert.Equal(t, "MTR group 1", selections[1].name)
+	assert.Len(t, selections[1].records, 3) // The whole MTR, filtered or not
+
+	// A mark offers the range from the mark to the cursor first
+	app.recordList.SetCurrentItem(2)
+	app.toggleMark()
+	assert.Equal(t, 4, app.markPosition)
+	assert.Contains(t, app.footer.GetText(true), "Mark: #5")
+	app.recordList.SetCurrentItem(1)
+	selections = app.exportSelections()
+	require.Len(t, selections, 3)
+	assert.Equal(t, "records #4-#5", selections[0].name)
+	assert.Len(t, selections[0].records, 2)
+	assert.Equal(t, "record #4", selections[2].name)
+
+	// The range is written with the source and filters as metadata
+	dir := t.TempDir()
+	app.showExportPrompt()
+	app.applyExport(filepath.Join(dir, "range.json"))
+	assert.Contains(t, app.exportHelp.GetText(true), "Exported 2 records to")
+
+	data, err := os.ReadFile(filepath.Join(dir, "range.json"))
+	require.NoError(t, err)
+	var doc struct {
+		Metadata map[string]string  `json:"metadata"`
+		Records  []*types.LogRecord `json:"records"`
+	}
+	require.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, "ib_logfile0", doc.Metadata["source"])
+	assert.Equal(t, "op == insert && (space >= 5)", doc.Metadata["filter"])
+	assert.Equal(t, "records #4-#5", doc.Metadata["selection"])
+	assert.Len(t, doc.Records, 2)
+
+	// The format comes from the extension
+	app.applyExport(filepath.Join(dir, "range.txt"))
+	assert.Contains(t, app.exportHelp.GetText(true), "Export failed: cannot tell the format")
+
+	// The mark is cleared by marking the same record again
+	app.recordList.SetCurrentItem(2)
+	app.toggleMark()
+	assert.Equal(t, -1, app.markPosition)
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/reader"
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// hexBytesPerRow is the width of the hex dump
+const hexBytesPerRow = 16
+
+// spanColors are the hex dump colours of each span kind
+var spanColors = map[types.SpanKind]string{
+	types.SpanBlockHeader:   "blue",
+	types.SpanBlockTrailer:  "red",
+	types.SpanRecordType:    "yellow",
+	types.SpanCompressedInt: "green",
+	types.SpanFixedInt:      "aqua",
+	types.SpanPayload:       "white",
+}
+
+// hexInspector shows the raw bytes around a record, with the block header,
+// block trailer and every decoded record field colour coded. Moving the
+// cursor in the hex dump selects the field under it and selecting a field
+// moves the cursor to it.
+type hexInspector struct {
+	app       *RedoLogApp
+	layout    *tview.Flex
+	hexView   *tview.TextView
+	fieldList *tview.List
+	info      *tview.TextView
+
+	offset  int64             // File offset of data[0]
+	data    []byte            // Whole blocks containing the record
+	spans   []types.FieldSpan // Block and record fields, ordered by offset
+	cursor  int64             // File offset under the cursor
+	syncing bool              // Set while the field list follows the cursor
+}
+
+// newHexInspector creates the inspector widgets
+func newHexInspector(app *RedoLogApp) *hexInspector {
+	h := &hexInspector{app: app}
+
+	h.hexView = tview.NewTextView()
+	h.hexView.SetBorder(true)
+	h.hexView.SetDynamicColors(true)
+	h.hexView.SetWrap(false)
+	h.hexView.SetInputCapture(h.handleHexKey)
+
+	h.fieldList = tview.NewList()
+	h.fieldList.SetBorder(true)
+	h.fieldList.SetTitle(" Fields ")
+	h.fieldList.ShowSecondaryText(false)
+	h.fieldList.SetChangedFunc(func(index int, _ string, _ string, _ rune) {
+		if !h.syncing && index < len(h.spans) {
+			h.moveCursor(h.spans[index].Offset)
+		}
+	})
+	h.fieldList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		return h.handleCommonKey(event, h.hexView)
+	})
+
+	h.info = tview.NewTextView()
+	h.info.SetBorder(true)
+	h.info.SetDynamicColors(true)
+
+	columns := tview.NewFlex()
+	// 10 offset + 3 per byte + 2 gaps + ASCII column + borders
+	columns.AddItem(h.hexView, 10+3*hexBytesPerRow+2+hexBytesPerRow+4, 0, true)
+	columns.AddItem(h.fieldList, 0, 1, false)
+
+	h.layout = tview.NewFlex().SetDirection(tview.FlexRow)
+	h.layout.AddItem(columns, 0, 1, true)
+	h.layout.AddItem(h.info, 4, 0, false)
+	return h
+}
+
+// open loads the blocks containing record and shows them
+func (h *hexInspector) open(record *types.LogRecord, filename string, blockFormat bool) error {
+	start, end, ok := record.ByteRange()
+	if !ok {
+		return fmt.Errorf("the reader did not record byte positions for this record")
+	}
+
+	offset, data, err := reader.ReadBlocks(filename, start, end)
+	if err != nil {
+		return err
+	}
+	h.offset, h.data, h.cursor = offset, data, start
+
+	h.spans = append([]types.FieldSpan(nil), record.Spans...)
+	if blockFormat {
+		for block := offset; block < offset+int64(len(data)); block += reader.OSFileLogBlockSize {
+			h.spans = append(h.spans, reader.BlockSpans(block)...)
+		}
+	}
+	sort.SliceStable(h.spans, func(i, j int) bool { return h.spans[i].Offset < h.spans[j].Offset })
+
+	h.syncing = true
+	h.fieldList.Clear()
+	for _, span := range h.spans {
+		label := fmt.Sprintf("[%s]%-16s[white] +%-5d %3d  %s", spanColors[span.Kind], span.Name,
+			span.Offset-offset, span.Length, tview.Escape(reader.DescribeSpan(span, h.bytes(span))))
+		h.fieldList.AddItem(h.app.theme.colorize(label), "", 0, nil)
+	}
+	h.syncing = false
+
+	h.hexView.SetTitle(fmt.Sprintf(" %s at file offset %d (%d block(s) from %d) ", record.Type, start, (len(data)+reader.OSFileLogBlockSize-1)/reader.OSFileLogBlockSize, offset))
+	h.moveCursor(start)
+	return nil
+}
+
+// bytes returns the bytes of span that were loaded
+func (h *hexInspector) bytes(span types.FieldSpan) []byte {
+	from := span.Offset - h.offset
+	to := span.End() - h.offset
+	if from < 0 || to > int64(len(h.data)) {
+		return nil
+	}
+	return h.data[from:to]
+}
+
+// spanAt returns the index of the span containing offset, or -1
+func (h *hexInspector) spanAt(offset int64) int {
+	for i, span := range h.spans {
+		if span.Contains(offset) {
+			return i
+		}
+	}
+	return -1
+}
+
+// moveCursor moves the cursor to offset and selects the field under it
+func (h *hexInspector) moveCursor(offset int64) {
+	if len(h.data) == 0 {
+		return
+	}
+	if offset < h.offset {
+		offset = h.offset
+	}
+	if last := h.offset + int64(len(h.data)) - 1; offset > last {
+		offset = last
+	}
+	h.cursor = offset
+
+	selected := h.spanAt(offset)
+	if selected >= 0 && h.fieldList.GetCurrentItem() != selected {
+		h.syncing = true
+		h.fieldList.SetCurrentItem(selected)
+		h.syncing = false
+	}
+
+	h.render(selected)
+	// One line per row plus a separator line before each further block
+	pos := int(offset - h.offset)
+	row := pos/hexBytesPerRow + pos/reader.OSFileLogBlockSize
+	_, _, _, height := h.hexView.GetInnerRect()
+	if top, _ := h.hexView.GetScrollOffset(); row < top || (height > 0 && row >= top+height) {
+		h.hexView.ScrollTo(row, 0)
+	}
+}
+
+// render redraws the hex dump and the field details
+func (h *hexInspector) render(selected int) {
+	owner := make([]int, len(h.data))
+	for i := range owner {
+		owner[i] = -1
+	}
+	for i, span := range h.spans {
+		for offset := span.Offset; offset < span.End(); offset++ {
+			if pos := offset - h.offset; pos >= 0 && pos < int64(len(owner)) {
+				owner[pos] = i
+			}
+		}
+	}
+
+	var b strings.Builder
+	for row := 0; row < len(h.data); row += hexBytesPerRow {
+		if row > 0 && row%reader.OSFileLogBlockSize == 0 {
+			b.WriteString("[gray]---------- next block ----------[white]\n")
+		}
+		fmt.Fprintf(&b, "[gray]%08x[white]  ", h.offset+int64(row))
+
+		var ascii strings.Builder
+		for col := 0; col < hexBytesPerRow; col++ {
+			pos := row + col
+			if pos >= len(h.data) {
+				b.WriteString("   ")
+				continue
+			}
+			if col == hexBytesPerRow/2 {
+				b.WriteString(" ")
+			}
+
+			style := h.byteStyle(owner[pos], selected, h.offset+int64(pos) == h.cursor)
+			fmt.Fprintf(&b, "%s%02x[-:-:-] ", style, h.data[pos])
+
+			c := h.data[pos]
+			if c < 32 || c > 126 {
+				c = '.'
+			}
+			fmt.Fprintf(&ascii, "%s%s[-:-:-]", style, tview.Escape(string(c)))
+		}
+		fmt.Fprintf(&b, " %s\n", ascii.String())
+	}
+	h.hexView.SetText(h.app.theme.colorize(b.String()))
+
+	h.info.SetText(h.app.theme.colorize(h.describeCursor(selected)))
+}
+
+// byteStyle returns the colour tag of a byte owned by span index owner
+func (h *hexInspector) byteStyle(owner, selected int, cursor bool) string {
+	color := "gray" // Bytes of other records
+	if owner >= 0 {
+		color = spanColors[h.spans[owner].Kind]
+	}
+	switch {
+	case cursor:
+		return "[black:" + color + ":b]"
+	case owner >= 0 && owner == selected:
+		return "[" + color + "::ru]"
+	default:
+		return "[" + color + ":-:-]"
+	}
+}
+
+// describeCursor describes the position of the cursor and the field under it
+func (h *hexInspector) describeCursor(selected int) string {
+	block := reader.BlockOffset(h.cursor)
+	text := fmt.Sprintf("Offset %d (0x%x), block %d byte %d",
+		h.cursor, h.cursor, block/reader.OSFileLogBlockSize, h.cursor-block)
+	if selected < 0 {
+		return text + "\n[gray]Not part of the selected record[white]  |  arrows/PgUp/PgDn move, Tab switches pane, Esc closes"
+	}
+
+	span := h.spans[selected]
+	return fmt.Sprintf("%s\n[%s]%s[white] (%s), %d byte(s) at +%d: %s", text, spanColors[span.Kind], span.Name,
+		span.Kind, span.Length, span.Offset-h.offset, tview.Escape(reader.DescribeSpan(span, h.bytes(span))))
+}
+
+// handleHexKey moves the cursor in the hex dump
+func (h *hexInspector) handleHexKey(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Key() {
+	case tcell.KeyLeft:
+		h.moveCursor(h.cursor - 1)
+	case tcell.KeyRight:
+		h.moveCursor(h.cursor + 1)
+	case tcell.KeyUp:
+		h.moveCursor(h.cursor - hexBytesPerRow)
+	case tcell.KeyDown:
+		h.moveCursor(h.cursor + hexBytesPerRow)
+	case tcell.KeyPgUp:
+		h.moveCursor(h.cursor - reader.OSFileLogBlockSize)
+	case tcell.KeyPgDn:
+		h.moveCursor(h.cursor + reader.OSFileLogBlockSize)
+	case tcell.KeyHome:
+		h.moveCursor(h.offset)
+	case tcell.KeyEnd:
+		h.moveCursor(h.offset + int64(len(h.data)) - 1)
+	default:
+		return h.handleCommonKey(event, h.fieldList)
+	}
+	return nil
+}
+
+// handleCommonKey handles the keys shared by both panes
+func (h *hexInspector) handleCommonKey(event *tcell.EventKey, other tview.Primitive) *tcell.EventKey {
+	switch {
+	case event.Key() == tcell.KeyTab:
+		h.app.app.SetFocus(other)
+	case h.app.closesPanel(event, "hex"):
+		h.app.app.SetRoot(h.app.mainLayout(), true)
+		h.app.app.SetFocus(h.app.recordList)
+	default:
+		return event
+	}
+	return nil
+}
+
+// showHexInspector opens the hex inspector on the selected record
+func (app *RedoLogApp) showHexInspector() {
+	index := app.recordList.GetCurrentItem()
+	if index < 0 || index >= len(app.filteredRecords) {
+		return
+	}
+
+	if err := app.inspector.open(app.filteredRecords[index], app.filename, app.blockFormat); err != nil {
+		app.detailsText.SetText(app.theme.colorize(fmt.Sprintf("[red]Hex inspector: %s[white]", tview.Escape(err.Error()))))
+		return
+	}
+	app.app.SetRoot(app.inspector.layout, true)
+	app.app.SetFocus(app.inspector.hexView)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/reader"
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+func TestHexInspector(t *testing.T) {
+	block := make([]byte, reader.OSFileLogBlockSize)
+	binary.BigEndian.PutUint16(block[reader.LogBlockHdrDataLen:], 20)
+	binary.BigEndian.PutUint16(block[reader.LogBlockFirstRecGroup:], reader.LogBlockHdrSize)
+	copy(block[reader.LogBlockHdrSize:], []byte{1, 5, 3, 0x00, 0x10, 0x2B})
+
+	filename := filepath.Join(t.TempDir(), "ib_logfile0")
+	require.NoError(t, os.WriteFile(filename, append(make([]byte, reader.LogFileHdrSize), block...), 0644))
+
+	r := reader.NewMySQLRedoLogReader()
+	require.NoError(t, r.Open(filename))
+	defer r.Close()
+	_, err := r.ReadHeader()
+	require.NoError(t, err)
+	record, err := r.ReadRecord()
+	require.NoError(t, err)
+
+	app := NewRedoLogApp([]*types.LogRecord{record}, &types.RedoLogHeader{})
+	inspector := app.inspector
+	require.NoError(t, inspector.open(record, filename, true))
+
+	assert.Equal(t, int64(reader.LogFileHdrSize), inspector.offset)
+	assert.Len(t, inspector.data, reader.OSFileLogBlockSize)
+	names := make([]string, 0, len(inspector.spans))
+	for _, span := range inspector.spans {
+		names = append(names, span.Name)
+	}
+	assert.Equal(t, []string{"hdr_no", "data_len", "first_rec_group", "epoch_no", "type", "space_id", "page_no", "offset", "value", "checksum"}, names)
+
+	// The cursor starts on the type byte
+	assert.Equal(t, record.FileOffset, inspector.cursor)
+	assert.Equal(t, 4, inspector.fieldList.GetCurrentItem())
+
+	// Moving the cursor selects the field under it
+	inspector.moveCursor(reader.LogFileHdrSize + reader.LogBlockHdrDataLen + 1)
+	assert.Equal(t, 1, inspector.fieldList.GetCurrentItem())
+	assert.Contains(t, inspector.info.GetText(true), "data_len (block header), 2 byte(s) at +4: 20")
+
+	// Selecting a field moves the cursor to it
+	inspector.fieldList.SetCurrentItem(8)
+	assert.Equal(t, record.FileOffset+5, inspector.cursor)
+	assert.Contains(t, inspector.info.GetText(true), "value (compressed int), 1 byte(s) at +17: 43")
+
+	// Records without byte positions cannot be inspected
+	assert.Error(t, inspector.open(&types.LogRecord{}, filename, true))
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// keyAction is a TUI command that can be bound to keys
+type keyAction struct {
+	name   string // Name used in the keymap config
+	footer string // Footer label (empty = listed in the help overlay only)
+	help   string
+	keys   []string // Default bindings
+	run    func(app *RedoLogApp)
+}
+
+// keyActions are the bindable commands, in the order they are listed in
+// the footer and the help overlay
+var keyActions []keyAction
+
+// init fills keyActions; a variable initializer would form an
+// initialization cycle, as several actions render the footer and help
+// from keyActions
+func init() {
+	keyActions = []keyAction{
+		{"insert", "INSERT", "Show only insert records (again: show all)", []string{"i", "I"}, func(app *RedoLogApp) { app.toggleOperationFilter("insert") }},
+		{"update", "UPDATE", "Show only update records (again: show all)", []string{"u", "U"}, func(app *RedoLogApp) { app.toggleOperationFilter("update") }},
+		{"delete", "DELETE", "Show only delete records (again: show all)", []string{"d", "D"}, func(app *RedoLogApp) { app.toggleOperationFilter("delete") }},
+		{"filter", "FILTER", "Edit the filter expression", []string{"f", "F"}, (*RedoLogApp).showFilterPrompt},
+		{"search", "", "Search record data, LSN, type and IDs", []string{"/"}, (*RedoLogApp).showSearchModal},
+		{"search_next", "", "Next search match", []string{"n"}, (*RedoLogApp).nextSearchResult},
+		{"search_prev", "", "Previous search match", []string{"N"}, (*RedoLogApp).prevSearchResult},
+		{"goto", "GOTO", "Go to an LSN, file offset, record number or page", []string{"g", "G"}, (*RedoLogApp).showGotoPrompt},
+		{"bookmark", "BOOKMARK", "Bookmark the selected record", []string{"b"}, (*RedoLogApp).showBookmarkPrompt},
+		{"bookmarks", "", "List bookmarks", []string{"B"}, (*RedoLogApp).showBookmarkList},
+		{"mark", "", "Mark the selected record as one end of the export range (again: clear)", []string{"v", "V"}, (*RedoLogApp).toggleMark},
+		{"export", "EXPORT", "Export the filtered records, marked range or selected MTR", []string{"e", "E"}, (*RedoLogApp).showExportPrompt},
+		{"mtr_tree", "MTR TREE", "Transaction/MTR tree", []string{"m", "M"}, (*RedoLogApp).showMTRTree},
+		{"hex", "HEX", "Hex inspector of the selected record", []string{"x", "X"}, (*RedoLogApp).showHexInspector},
+		{"writes", "WRITES", "Redo volume by tablespace", []string{"w", "W"}, (*RedoLogApp).showAmplificationPanel},
+		{"throughput", "THROUGHPUT", "Redo throughput per window", []string{"t", "T"}, (*RedoLogApp).showThroughputPanel},
+		{"stats", "STATS", "Statistics dashboard of the filtered records", []string{"s", "S"}, (*RedoLogApp).showDashboard},
+		{"reference", "REFERENCE", "Record type reference", []string{"r", "R"}, (*RedoLogApp).showReferenceModal},
+		{"switch_pane", "Switch Panes", "Switch between the record list and the details", []string{"Tab"}, (*RedoLogApp).switchPane},
+		{"help", "HELP", "Show this help", []string{"?", "F1"}, (*RedoLogApp).showHelp},
+		{"quit", "", "Quit, or close the open panel", []string{"q", "Q", "Esc"}, func(app *RedoLogApp) { app.app.Stop() }},
+	}
+}
+
+// findKeyAction returns the action called name
+func findKeyAction(name string) (keyAction, bool) {
+	for _, action := range keyActions {
+		if action.name == name {
+			return action, true
+		}
+	}
+	return keyAction{}, false
+}
+
+// keySpec is a key a command is bound to: a rune, or a named key such as
+// Esc, Tab, F1 or Ctrl-F
+type keySpec struct {
+	key tcell.Key
+	ch  rune
+}
+
+// parseKeySpec parses a single character or a tcell key name
+func parseKeySpec(s string) (keySpec, error) {
+	if utf8.RuneCountInString(s) == 1 {
+		ch, _ := utf8.DecodeRuneInString(s)
+		return keySpec{key: tcell.KeyRune, ch: ch}, nil
+	}
+	for key, name := range tcell.KeyNames {
+		if strings.EqualFold(name, s) {
+			return keySpec{key: key}, nil
+		}
+	}
+	return keySpec{}, fmt.Errorf("unknown key %q: use a single character or a key name such as Esc, Tab, F1 or Ctrl-F", s)
+}
+
+// String returns the key as written in the keymap config
+func (k keySpec) String() string {
+	if k.key == tcell.KeyRune {
+		return string(k.ch)
+	}
+	return tcell.KeyNames[k.key]
+}
+
+// keySpecOf returns the key of event
+func keySpecOf(event *tcell.EventKey) keySpec {
+	if event.Key() == tcell.KeyRune {
+		return keySpec{key: tcell.KeyRune, ch: event.Rune()}
+	}
+	return keySpec{key: event.Key()}
+}
+
+// keymap maps keys to actions
+type keymap struct {
+	bindings map[string][]keySpec // Keys of each action
+	actions  map[keySpec]string   // Action of each key
+}
+
+// newKeymap creates a keymap from the default bindings, replacing those of
+// the actions in overrides. A key may only be bound to one action.
+func newKeymap(overrides map[string][]string) (*keymap, error) {
+	for name := range overrides {
+		if _, ok := findKeyAction(name); !ok {
+			return nil, fmt.Errorf("unknown key action %q (actions: %s)", name, strings.Join(keyActionNames(), ", "))
+		}
+	}
+
+	k := &keymap{bindings: make(map[string][]keySpec), actions: make(map[keySpec]string)}
+	for _, action := range keyActions {
+		keys, ok := overrides[action.name]
+		if !ok {
+			keys = action.keys
+		}
+		for _, s := range keys {
+			spec, err := parseKeySpec(s)
+			if err != nil {
+				return nil, fmt.Errorf("key action %q: %w", action.name, err)
+			}
+			if other, bound := k.actions[spec]; bound {
+				return nil, fmt.Errorf("key %q is bound to both %q and %q", spec, other, action.name)
+			}
+			k.actions[spec] = action.name
+			k.bindings[action.name] = append(k.bindings[action.name], spec)
+		}
+	}
+	return k, nil
+}
+
+// keyActionNames returns the names of all actions, sorted
+func keyActionNames() []string {
+	names := make([]string, 0, len(keyActions))
+	for _, action := range keyActions {
+		names = append(names, action.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// action returns the action event is bound to
+func (k *keymap) action(event *tcell.EventKey) (keyAction, bool) {
+	name, ok := k.actions[keySpecOf(event)]
+	if !ok {
+		return keyAction{}, false
+	}
+	return findKeyAction(name)
+}
+
+// matches reports whether event is bound to the action called name
+func (k *keymap) matches(event *tcell.EventKey, name string) bool {
+	return k.actions[keySpecOf(event)] == name
+}
+
+// keys returns the keys of the action called name, e.g. "q/Q/Esc"
+func (k *keymap) keys(name string) string {
+	names := make([]string, 0, len(k.bindings[name]))
+	for _, spec := range k.bindings[name] {
+		names = append(names, spec.String())
+	}
+	return strings.Join(names, "/")
+}
+
+// handleActionKey runs the action bound to event, if any
+func (app *RedoLogApp) handleActionKey(event *tcell.EventKey) *tcell.EventKey {
+	action, ok := app.keymap.action(event)
+	if !ok {
+		return event
+	}
+	action.run(app)
+	return nil
+}
+
+// closesPanel reports whether event closes a panel opened by the action
+// called name: the quit keys and the keys that opened it do
+func (app *RedoLogApp) closesPanel(event *tcell.EventKey, name string) bool {
+	return app.keymap.matches(event, "quit") || app.keymap.matches(event, name)
+}
+
+// switchPane moves the focus between the record list and the details
+func (app *RedoLogApp) switchPane() {
+	if app.app.GetFocus() == app.detailsText {
+		app.app.SetFocus(app.recordList)
+	} else {
+		app.app.SetFocus(app.detailsText)
+	}
+}
+
+// footerKeys lists the footer actions with their first key
+func (app *RedoLogApp) footerKeys() string {
+	items := make([]string, 0, len(keyActions))
+	for _, action := range keyActions {
+		if action.footer == "" || len(app.keymap.bindings[action.name]) == 0 {
+			continue
+		}
+		key := app.keymap.bindings[action.name][0].String()
+		if len(key) == 1 {
+			key = "'" + key + "'"
+		}
+		items = append(items, fmt.Sprintf("[::b]%s[::-]=%s", tview.Escape(key), action.footer))
+	}
+	return strings.Join(items, ", ")
+}
+
+// initializeHelp creates the help overlay
+func (app *RedoLogApp) initializeHelp() {
+	app.helpView = tview.NewTextView()
+	app.helpView.SetBorder(true)
+	app.helpView.SetTitle(" Keys ")
+	app.helpView.SetDynamicColors(true)
+	app.helpView.SetScrollable(true)
+	app.helpView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if app.closesPanel(event, "help") {
+			app.app.SetRoot(app.mainLayout(), true)
+			app.app.SetFocus(app.recordList)
+			return nil
+		}
+		return event
+	})
+}
+
+// showHelp shows the active key bindings
+func (app *RedoLogApp) showHelp() {
+	var b strings.Builder
+	b.WriteString("[yellow]Record list[white]\n")
+	fmt.Fprintf(&b, "  %-16s %s\n", "Up/Down", "Previous/next record")
+	fmt.Fprintf(&b, "  %-16s %s\n\n", "Enter", "Focus the details pane")
+	b.WriteString("[yellow]Commands[white]\n")
+	for _, action := range keyActions {
+		keys := app.keymap.keys(action.name)
+		if keys == "" {
+			keys = "(unbound)"
+		}
+		fmt.Fprintf(&b, "  %-16s %-44s [gray]%s[white]\n", tview.Escape(keys), action.help, action.name)
+	}
+	b.WriteString("\n[gray]Keys, theme and pane sizes can be changed in a config file, see 'tui --help'.[white]")
+
+	app.helpView.SetText(app.theme.colorize(b.String()))
+	app.helpView.ScrollToBeginning()
+	app.app.SetRoot(app.helpView, true)
+	app.app.SetFocus(app.helpView)
+}
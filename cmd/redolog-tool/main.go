@@ -1,31 +1,21 @@
 package main
 
 import (
-	"encoding/binary"
-	"encoding/csv"
-	"encoding/hex"
-	"encoding/json"
-	"flag"
+	"errors"
 	"fmt"
-	"io"
 	"os"
 	"regexp"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
+	"github.com/yamaru/innodb-redolog-tool/internal/analyzer"
+	"github.com/yamaru/innodb-redolog-tool/internal/filter"
+	"github.com/yamaru/innodb-redolog-tool/internal/index"
 	"github.com/yamaru/innodb-redolog-tool/internal/reader"
 	"github.com/yamaru/innodb-redolog-tool/internal/types"
-)
-
-var (
-	filename = flag.String("file", "", "InnoDB redo log file to analyze")
-	verbose  = flag.Bool("v", false, "Verbose output")
-	testMode = flag.Bool("test", false, "Test hex parsing without TUI")
-	exportFormat = flag.String("export", "", "Export format: json, csv (skips TUI)")
-	exportFile = flag.String("output", "", "Export output file (default: stdout)")
+	"github.com/yamaru/innodb-redolog-tool/pkg/redolog"
 )
 
 type RedoLogApp struct {
@@ -45,11 +35,40 @@ type RedoLogApp struct {
 	header        *types.RedoLogHeader
 	showTableID0  bool // Toggle for showing Table ID 0 records
 	operationFilter string // "all", "insert", "update", "delete"
+	whereFilter   *filter.Filter // Filter expression entered with 'f' (nil = none)
+	filterInput   *tview.InputField
+	filterHelp    *tview.TextView
+	amplificationView *tview.TextView // Write-amplification panel opened with 'w'
+	dictionary    *analyzer.Dictionary // Table names of spaces (nil = IDs only)
+	throughputView *tview.TextView // Throughput panel opened with 't'
+	dashboardView *tview.TextView // Statistics dashboard opened with 's'
+	exportInput   *tview.InputField // Export prompt opened with 'e'
+	exportHelp    *tview.TextView
+	exportChoice  int // Selection chosen in the export prompt, cycled with Tab
+	markPosition  int // Record marked with 'v' as one end of the export range (-1 = none)
+	clock         *analyzer.LSNClock // Maps LSNs to time (nil = unknown)
+	inspector     *hexInspector // Hex inspector opened with 'x'
+	mtrTree       *mtrTree      // Transaction/MTR tree opened with 'm'
+	recordIndex   *index.Index      // Locates records for the goto prompt
+	gotoInput     *tview.InputField // Goto prompt opened with 'g'
+	gotoHelp      *tview.TextView
+	bookmarks     *index.Bookmarks  // Bookmarks of the log (nil = unavailable)
+	bookmarkInput *tview.InputField // Bookmark prompt opened with 'b'
+	bookmarkList  *tview.List       // Bookmark list opened with 'B'
+	theme         *theme      // Colours of the views
+	keymap        *keymap     // Key bindings of the commands
+	layout        paneLayout  // Pane proportions
+	helpView      *tview.TextView // Help overlay opened with '?'
+	filename      string // Log file, read again by the hex inspector
+	blockFormat   bool   // The log consists of 512-byte blocks
 	searchTerm    string // Current search term
 	searchMatches []int  // Indices of records matching current search
 	currentSearchIndex int // Current position in search matches
 }
 
+// filterPromptLabel is the label of the filter expression input
+const filterPromptLabel = "Filter: "
+
 // TypeInfo holds information about each redo log type
 type TypeInfo struct {
 	ID          uint8
@@ -60,383 +79,30 @@ type TypeInfo struct {
 }
 
 func main() {
-	flag.Parse()
-
-	if *filename == "" {
-		fmt.Printf("Usage: %s -file <redo_log_file>\n", os.Args[0])
-		flag.PrintDefaults()
-		os.Exit(1)
-	}
-
-	// Load redo log data
-	records, header, err := loadRedoLogData(*filename)
-	if err != nil {
-		fmt.Printf("Error loading redo log: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Check if verbose mode is enabled for debug output
-	if *verbose {
-		// Search for MLOG_TABLE_DYNAMIC_META records (type 62) to show Table IDs
-		fmt.Printf("\nSearching for MLOG_TABLE_DYNAMIC_META records with Table IDs:\n")
-		count := 0
-		for i, record := range records {
-			if uint8(record.Type) == 62 { // MLOG_TABLE_DYNAMIC_META
-				fmt.Printf("Record %d: %s TableID=%d Data=%s\n", i+1, record.Type.String(), record.TableID, string(record.Data))
-				count++
-				if count >= 10 { // Limit output
-					break
-				}
-			}
-		}
-		fmt.Printf("Found %d MLOG_TABLE_DYNAMIC_META records\n\n", count)
-		
-		// Show filtering statistics
-		fmt.Printf("Filtering analysis:\n")
-		totalRecords := len(records)
-		tableID0Records := 0
-		for _, record := range records {
-			if record.TableID == 0 && record.SpaceID == 0 {
-				tableID0Records++
-			}
-		}
-		nonZeroIDRecords := totalRecords - tableID0Records
-		fmt.Printf("Total records: %d\n", totalRecords)
-		fmt.Printf("Table ID 0 records (would be hidden): %d\n", tableID0Records)
-		fmt.Printf("Non-zero ID records (would be shown): %d\n", nonZeroIDRecords)
-		fmt.Printf("Filter effectiveness: %.1f%% reduction\n\n", float64(tableID0Records)/float64(totalRecords)*100)
-		
-		// Show MLOG_REC_INSERT_8027 record analysis
-		fmt.Printf("MLOG_REC_INSERT_8027 record analysis:\n")
-		insertCount := 0
-		for i, record := range records {
-			if uint8(record.Type) == 9 { // MLOG_REC_INSERT_8027
-				fmt.Printf("Record %d: %s\n", i+1, record.Type.String())
-				fmt.Printf("  LSN: %d\n", record.LSN)
-				fmt.Printf("  Length: %d\n", record.Length)
-				fmt.Printf("  Space ID: %d\n", record.SpaceID)
-				fmt.Printf("  Page No: %d\n", record.PageNo)
-				fmt.Printf("  Data: %s\n", string(record.Data))
-				fmt.Printf("  Group: %d\n", record.MultiRecordGroup)
-				fmt.Printf("\n")
-				insertCount++
-				if insertCount >= 3 { // Limit to 3 records for readability
-					break
-				}
-			}
-		}
-		if insertCount == 0 {
-			fmt.Printf("No MLOG_REC_INSERT_8027 records found\n")
-		}
-		fmt.Printf("Found %d MLOG_REC_INSERT_8027 records\n\n", insertCount)
-		
-		// Test hex parsing with user's example
-		fmt.Printf("Hex parsing test (user example):\n")
-		testHex := "000000000503c20000"
-		hexBytes, _ := hex.DecodeString(testHex)
-		if len(hexBytes) > 0 {
-			// Test our parsing functions
-			fmt.Printf("Input: hex=%s\n", testHex)
-			
-			// Test as different field types
-			if len(hexBytes) >= 4 {
-				// Test integer parsing
-				fmt.Printf("As 4-byte integer: %d\n", binary.BigEndian.Uint32(hexBytes[:4]))
-			}
-			
-			// Test field parsing with simple heuristics
-			fieldResult := testParseFields(hexBytes)
-			fmt.Printf("Field parsing result: %s\n", fieldResult)
-		}
-		fmt.Printf("\n")
-		
-		// Show footer simulation
-		fmt.Printf("Footer display simulation:\n")
-		// Simulate what would appear in the footer
-		showTableID0 := true // Default: show all records
-		filteredRecords := make([]*types.LogRecord, 0)
-		for _, record := range records {
-			if !showTableID0 && record.TableID == 0 && record.SpaceID == 0 {
-				continue // Skip Table ID 0 records when filter is enabled
-			}
-			filteredRecords = append(filteredRecords, record)
-		}
-		
-		var filterStatus, filterColor string
-		if showTableID0 {
-			filterStatus = "OFF"
-			filterColor = "[green]"
-		} else {
-			filterStatus = "ON" 
-			filterColor = "[red]"
-		}
-		fmt.Printf("Footer: Press 's' to toggle Table ID 0 filter | Filter: %s%s | Records: %d/%d\n\n",
-			filterColor, filterStatus, len(filteredRecords), len(records))
-		
-		// Analyze multi-record groups
-		fmt.Printf("Multi-record group analysis:\n")
-		groupCount := 0
-		for i, record := range records {
-			if record.MultiRecordGroup > 0 {
-				if record.IsGroupStart {
-					groupCount++
-					fmt.Printf("Group %d starts at Record %d (%s)\n", record.MultiRecordGroup, i+1, record.Type.String())
-				}
-				if record.IsGroupEnd {
-					fmt.Printf("Group %d ends at Record %d (%s)\n", record.MultiRecordGroup, i+1, record.Type.String())
-				}
-			}
-		}
-		fmt.Printf("Found %d multi-record groups\n\n", groupCount)
-		
-		// Show MLOG_MULTI_REC_END records and their context
-		fmt.Printf("MLOG_MULTI_REC_END records (type 31) for group detection:\n")
-		multiRecEndCount := 0
-		for i, record := range records {
-			if uint8(record.Type) == 31 { // MLOG_MULTI_REC_END
-				fmt.Printf("Record %d: MLOG_MULTI_REC_END Group=%d IsGroupEnd=%v\n", i+1, record.MultiRecordGroup, record.IsGroupEnd)
-				multiRecEndCount++
-				if multiRecEndCount >= 5 { // Limit output
-					break
-				}
-			}
-		}
-		fmt.Printf("Found %d MLOG_MULTI_REC_END records total\n\n", multiRecEndCount)
-		
-		// Show visual group representation (like in TUI)
-		fmt.Printf("Visual group representation (first 20 records):\n")
-		groupColors := []string{"[white]", "[cyan]", "[yellow]", "[green]", "[magenta]", "[blue]"}
-		for i := 0; i < 20 && i < len(records); i++ {
-			record := records[i]
-			recordNum := fmt.Sprintf("%d", i+1)
-			recordType := record.Type.String()
-			
-			// Add visual grouping indicators like in TUI
-			var groupIndicator string
-			var colorPrefix string
-			
-			if record.MultiRecordGroup > 0 {
-				// Use different colors for different groups
-				colorIndex := (record.MultiRecordGroup - 1) % len(groupColors)
-				colorPrefix = groupColors[colorIndex]
-				
-				if record.IsGroupStart {
-					groupIndicator = "┌─ "
-				} else if record.IsGroupEnd {
-					groupIndicator = "└─ "
-				} else {
-					groupIndicator = "├─ "
-				}
-			} else {
-				colorPrefix = "[white]"
-				groupIndicator = "   "
-			}
-			
-			// Show SpaceID if available, otherwise TableID
-			var idInfo string
-			if record.SpaceID != 0 {
-				idInfo = fmt.Sprintf("(S:%d)", record.SpaceID)
-			} else if record.TableID != 0 {
-				idInfo = fmt.Sprintf("(T:%d)", record.TableID)
-			} else {
-				idInfo = "(0)"
-			}
-			
-			fmt.Printf("%s%s%-6s %s%s Group=%d\n", colorPrefix, groupIndicator, recordNum, recordType, idInfo, record.MultiRecordGroup)
-		}
-		return
-	}
-
-	// Test mode: parse records and show cross-block reads with strings
-	if *testMode {
-		fmt.Printf("Test mode: Searching for sakila-data.sql VARCHAR content in redo log\n\n")
-		
-		// Expected unique sakila data strings from sakila-data.sql
-		sakilaStrings := []string{
-			"sakila", "SAKILA", // Database name
-			"PENELOPE", "GUINESS", "WAHLBERG", "LOLLOBRIGIDA", 
-			"ACADEMY DINOSAUR", "AFRICAN EGG", "AGENT TRUMAN", 
-			"AIRPLANE SIERRA", "ALABAMA DEVIL", "ALADDIN CALENDAR",
-			"Epic Drama of a Feminist", "Astounding Documentary", 
-			"Fanciful Documentary", "Fast-Paced Documentary",
-			"Canadian Rockies", "Mad Scientist", "Battle a Teacher",
-			"actor", "film", "rental", "customer", "payment", // Table names
-		}
-		
-		fmt.Printf("Looking for these sakila-data.sql VARCHAR strings:\n")
-		for i, str := range sakilaStrings {
-			fmt.Printf("  %d. '%s'\n", i+1, str)
-		}
-		fmt.Printf("\n" + strings.Repeat("-", 60) + "\n")
-		
-		foundSakilaCount := 0
-		foundSystemCount := 0
-		foundAnyStrings := 0
-		
-		for i, record := range records {
-			// Search both ASCII data and raw binary data
-			recordData := string(record.Data)
-			rawData := record.Data // Raw binary data
-			recordHasSakila := false
-			recordHasSystem := false
-			
-			// Check for sakila strings in both ASCII and binary data
-			for _, sakilaStr := range sakilaStrings {
-				foundInAscii := strings.Contains(recordData, sakilaStr)
-				foundInBinary := strings.Contains(string(rawData), sakilaStr)
-				
-				if foundInAscii || foundInBinary {
-					if !recordHasSakila {
-						dataSource := "ASCII"
-						if foundInBinary && !foundInAscii {
-							dataSource = "BINARY"
-						} else if foundInBinary && foundInAscii {
-							dataSource = "ASCII+BINARY"
-						}
-						
-						fmt.Printf("🎯 Record %d: %s - FOUND SAKILA DATA! [%s]\n", i+1, record.Type.String(), dataSource)
-						fmt.Printf("   LSN: %d, TableID: %d, SpaceID: %d\n", record.LSN, record.TableID, record.SpaceID)
-						recordHasSakila = true
-						foundSakilaCount++
-					}
-					
-					// Show context around the found string (prefer binary if found there)
-					searchData := recordData
-					if foundInBinary {
-						searchData = string(rawData)
-					}
-					
-					index := strings.Index(searchData, sakilaStr)
-					start := index - 30
-					end := index + len(sakilaStr) + 30
-					if start < 0 { start = 0 }
-					if end > len(searchData) { end = len(searchData) }
-					
-					context := searchData[start:end]
-					// Replace non-printable characters for display
-					displayContext := strings.Map(func(r rune) rune {
-						if r >= 32 && r < 127 {
-							return r
-						}
-						return '.'
-					}, context)
-					
-					fmt.Printf("   Found: '%s' in context: ...%s...\n", sakilaStr, displayContext)
-					
-					// Show hex dump of the area around the found string
-					fmt.Printf("   Hex context: ")
-					hexStart := start
-					hexEnd := end
-					if hexEnd-hexStart > 60 { // Limit hex display
-						hexEnd = hexStart + 60
-					}
-					for j := hexStart; j < hexEnd && j < len(rawData); j++ {
-						fmt.Printf("%02x ", rawData[j])
-					}
-					fmt.Printf("\n")
-					
-					// Show full record details for sakila records
-					fmt.Printf("   Full record details:\n")
-					fmt.Printf("     Type: %s (ID: %d)\n", record.Type.String(), uint8(record.Type))
-					fmt.Printf("     Length: %d bytes\n", record.Length)
-					if record.SpaceID != 0 {
-						fmt.Printf("     SpaceID: %d\n", record.SpaceID)
-					}
-					if record.PageNo != 0 {
-						fmt.Printf("     PageNo: %d\n", record.PageNo)
-					}
-					if record.TableID != 0 {
-						fmt.Printf("     TableID: %d\n", record.TableID)
-					}
-					fmt.Printf("     Group: %d\n", record.MultiRecordGroup)
-					
-					// Show more data context for sakila records (first 200 bytes)
-					dataLen := len(rawData)
-					if dataLen > 200 {
-						dataLen = 200
-					}
-					fmt.Printf("   Raw data (first %d bytes): ", dataLen)
-					for k := 0; k < dataLen; k++ {
-						if k%16 == 0 {
-							fmt.Printf("\n     ")
-						}
-						fmt.Printf("%02x ", rawData[k])
-					}
-					fmt.Printf("\n")
-				}
-			}
-			
-			// Check for system strings (for comparison)
-			systemStrings := []string{"statement_analysis", "host_summary", "schema_unused", "sys_config", "setup_actors"}
-			for _, sysStr := range systemStrings {
-				foundSysAscii := strings.Contains(recordData, sysStr)
-				foundSysBinary := strings.Contains(string(rawData), sysStr)
-				
-				if (foundSysAscii || foundSysBinary) && !recordHasSystem {
-					recordHasSystem = true
-					foundSystemCount++
-					// Also show system string details
-					dataSource := "ASCII"
-					if foundSysBinary && !foundSysAscii {
-						dataSource = "BINARY"
-					}
-					fmt.Printf("📊 Record %d: %s - Found system string '%s' [%s]\n", i+1, record.Type.String(), sysStr, dataSource)
-					break
-				}
-			}
-			
-			// Check for any VARCHAR strings found
-			if strings.Contains(recordData, "found_strings=") {
-				foundAnyStrings++
-			}
-		}
-		
-		fmt.Printf("\n" + strings.Repeat("=", 60) + "\n")
-		fmt.Printf("SEARCH RESULTS:\n")
-		fmt.Printf("- Records with sakila data: %d\n", foundSakilaCount)
-		fmt.Printf("- Records with system data: %d\n", foundSystemCount)
-		fmt.Printf("- Records with any VARCHAR strings: %d\n", foundAnyStrings)
-		
-		if foundSakilaCount > 0 {
-			fmt.Printf("\n✅ SUCCESS! Found actual sakila-data.sql VARCHAR content!\n")
-		} else if foundAnyStrings > 0 {
-			fmt.Printf("\n⚠️  Found VARCHAR strings, but they are system DB data, not sakila data\n")
-			fmt.Printf("   This suggests:\n")
-			fmt.Printf("   - The redo log was captured during MySQL system operations\n")
-			fmt.Printf("   - Sakila data inserts may be in a different redo log file/timeframe\n")
-		} else {
-			fmt.Printf("\n❌ No VARCHAR strings found at all\n")
-		}
-		
-		return
-	}
-
-	// Check if export mode is requested
-	if *exportFormat != "" {
-		err := exportRecords(records, header, *exportFormat, *exportFile)
-		if err != nil {
-			fmt.Printf("Export error: %v\n", err)
-			os.Exit(1)
-		}
-		return
-	}
-
-	// Create and run TUI app
-	app := NewRedoLogApp(records, header)
-	if err := app.Run(); err != nil {
-		fmt.Printf("Error running application: %v\n", err)
-		os.Exit(1)
-	}
+	os.Exit(runCommand(os.Args[1:]))
 }
 
+// NewRedoLogApp creates the TUI with the default keys, theme and layout
 func NewRedoLogApp(records []*types.LogRecord, header *types.RedoLogHeader) *RedoLogApp {
+	return NewRedoLogAppWithConfig(records, header, defaultTUIConfig())
+}
+
+// NewRedoLogAppWithConfig creates the TUI with the given keys, theme and layout
+func NewRedoLogAppWithConfig(records []*types.LogRecord, header *types.RedoLogHeader, cfg *tuiConfig) *RedoLogApp {
 	app := &RedoLogApp{
 		records: records,
 		header:  header,
 		showTableID0: true, // Default: show all records including Table ID 0
 		operationFilter: "all", // Default: show all operation types
+		markPosition: -1,
+		theme:   cfg.theme,
+		keymap:  cfg.keymap,
+		layout:  cfg.layout,
 	}
 
+	// Widgets take their default colours from tview.Styles
+	app.theme.setStyles()
+
 	// Create main application
 	app.app = tview.NewApplication()
 
@@ -540,7 +206,7 @@ func NewRedoLogApp(records []*types.LogRecord, header *types.RedoLogHeader) *Red
 		return action, event
 	})
 
-	// Set up key bindings
+	// Set up key bindings: pane-specific keys first, then the keymap
 	app.recordList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		switch event.Key() {
 		case tcell.KeyUp:
@@ -557,103 +223,22 @@ func NewRedoLogApp(records []*types.LogRecord, header *types.RedoLogHeader) *Red
 				app.recordList.SetCurrentItem(current + 1)
 			}
 			return nil
-		case tcell.KeyTab:
-			app.app.SetFocus(app.detailsText)
-			return nil
-		case tcell.KeyEscape:
-			app.app.Stop()
-			return nil
 		case tcell.KeyEnter:
 			// Enter now just switches to details pane since selection auto-updates
 			app.app.SetFocus(app.detailsText)
 			return nil
 		}
-		// Check for character keys
-		if event.Rune() == 'q' || event.Rune() == 'Q' {
-			app.app.Stop()
-			return nil
-		}
-		if event.Rune() == 'i' || event.Rune() == 'I' {
-			app.toggleOperationFilter("insert")
-			return nil
-		}
-		if event.Rune() == 'u' || event.Rune() == 'U' {
-			app.toggleOperationFilter("update")
-			return nil
-		}
-		if event.Rune() == 'd' || event.Rune() == 'D' {
-			app.toggleOperationFilter("delete")
-			return nil
-		}
-		if event.Rune() == 'r' || event.Rune() == 'R' {
-			app.showReferenceModal()
-			return nil
-		}
-		return event
+		return app.handleActionKey(event)
 	})
 
 	app.detailsText.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		switch event.Key() {
-		case tcell.KeyUp:
-			// Allow default TextView scrolling behavior for up arrow
-			return event
-		case tcell.KeyDown:
-			// Allow default TextView scrolling behavior for down arrow
-			return event
-		case tcell.KeyPgUp:
-			// Allow default TextView scrolling behavior for Page Up
-			return event
-		case tcell.KeyPgDn:
-			// Allow default TextView scrolling behavior for Page Down
-			return event
-		case tcell.KeyHome:
-			// Allow default TextView scrolling behavior for Home
+		case tcell.KeyUp, tcell.KeyDown, tcell.KeyPgUp, tcell.KeyPgDn, tcell.KeyHome, tcell.KeyEnd:
+			// Allow default TextView scrolling behavior
 			return event
-		case tcell.KeyEnd:
-			// Allow default TextView scrolling behavior for End
-			return event
-		case tcell.KeyTab:
-			app.app.SetFocus(app.recordList)
-			return nil
-		case tcell.KeyEscape:
-			app.app.Stop()
-			return nil
-		}
-		// Check for character keys
-		if event.Rune() == 'q' || event.Rune() == 'Q' {
-			app.app.Stop()
-			return nil
-		}
-		if event.Rune() == 'i' || event.Rune() == 'I' {
-			app.toggleOperationFilter("insert")
-			return nil
-		}
-		if event.Rune() == 'u' || event.Rune() == 'U' {
-			app.toggleOperationFilter("update")
-			return nil
-		}
-		if event.Rune() == 'd' || event.Rune() == 'D' {
-			app.toggleOperationFilter("delete")
-			return nil
-		}
-		if event.Rune() == 'r' || event.Rune() == 'R' {
-			app.showReferenceModal()
-			return nil
-		}
-		if event.Rune() == '/' {
-			app.showSearchModal()
-			return nil
-		}
-		if event.Rune() == 'n' {
-			app.nextSearchResult()
-			return nil
-		}
-		if event.Rune() == 'N' {
-			app.prevSearchResult()
-			return nil
 		}
 		// For other keys (including j/k for vim-style scrolling), pass through
-		return event
+		return app.handleActionKey(event)
 	})
 
 	// Show header info initially
@@ -667,6 +252,15 @@ func NewRedoLogApp(records []*types.LogRecord, header *types.RedoLogHeader) *Red
 
 	// Initialize search components
 	app.initializeSearch()
+	app.initializeFilterPrompt()
+	app.initializeAmplificationPanel()
+	app.initializeThroughputPanel()
+	app.initializeDashboard()
+	app.initializeExportPrompt()
+	app.inspector = newHexInspector(app)
+	app.mtrTree = newMTRTree(app)
+	app.initializeNavigation()
+	app.initializeHelp()
 	
 	return app
 }
@@ -684,12 +278,14 @@ Format: %d
 Total Records: %d
 Filtered Records: %d
 Table ID 0 Filter: %s
+Filter Expression: %s
 
 [blue]Navigation:[white]
 ↑/↓: Navigate records (auto-update details)
 Tab: Switch panes  
 Enter: Focus details pane
 s: Toggle Table ID 0 filter
+f: Edit filter expression
 r: Show Type Reference
 /: Open search modal
 n: Next search result
@@ -712,6 +308,12 @@ Esc/q: Exit
 			}
 			return "[red]ON (hiding Table ID 0)"
 		}(),
+		func() string {
+			if app.whereFilter == nil {
+				return "[green]none"
+			}
+			return "[cyan]" + tview.Escape(app.whereFilter.String()) + "[white]"
+		}(),
 		func() string {
 			var filterStatus, filterColor string
 			if app.showTableID0 {
@@ -721,15 +323,19 @@ Esc/q: Exit
 				filterStatus = "ON"
 				filterColor = "[red]"
 			}
-			return fmt.Sprintf(`Press 's' to toggle filter | Filter: %s%s[white] | Records: [cyan]%d[white]/[blue]%d`,
-				filterColor, filterStatus, len(app.filteredRecords), len(app.records))
+			return fmt.Sprintf(`Statistics: '%s' | Filter: %s%s[white] | Records: [cyan]%d[white]/[blue]%d`,
+				tview.Escape(app.keymap.keys("stats")), filterColor, filterStatus, len(app.filteredRecords), len(app.records))
 		}())
 
-	app.detailsText.SetText(headerInfo)
+	app.detailsText.SetText(app.theme.colorize(headerInfo))
 }
 
 // buildBlockFormatDisplay builds the 512-byte block format display for a record
 func (app *RedoLogApp) buildBlockFormatDisplay(record *types.LogRecord, originalIndex int) string {
+	if len(record.Spans) > 0 {
+		return app.buildDecodedLayoutDisplay(record, originalIndex)
+	}
+
 	// Get type-specific format info
 	typeID := uint8(record.Type)
 	typeName := record.Type.String()
@@ -857,7 +463,7 @@ func (app *RedoLogApp) buildBlockFormatDisplay(record *types.LogRecord, original
   Remaining Space:    %d bytes available
 `,
 		record.LSN,
-		record.Timestamp.Format("2006-01-02 15:04:05"),
+		app.formatRecordTime(record),
 		record.Checksum,
 		groupInfo,
 		record.Checksum,
@@ -879,6 +485,40 @@ func (app *RedoLogApp) buildBlockFormatDisplay(record *types.LogRecord, original
 	return blockDisplay
 }
 
+// buildDecodedLayoutDisplay lists where the reader found each field of a
+// record; used instead of the estimated layout when byte spans are known
+func (app *RedoLogApp) buildDecodedLayoutDisplay(record *types.LogRecord, originalIndex int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[cyan]═══ Record %d: %s ═══[white]\n\n", originalIndex+1, record.Type.String())
+
+	start, end, _ := record.ByteRange()
+	fmt.Fprintf(&b, "[yellow]Decoded Fields[white] (%d bytes at file offset %d, press 'x' for the hex inspector):\n", end-start, start)
+	fmt.Fprintf(&b, "  %-8s %-6s %-6s %-16s %s\n", "BLOCK", "BYTE", "LENGTH", "FIELD", "KIND")
+	for _, span := range record.Spans {
+		block := reader.BlockOffset(span.Offset)
+		blockNo := fmt.Sprintf("%d", block/reader.OSFileLogBlockSize)
+		if !app.blockFormat {
+			blockNo = "-"
+		}
+		fmt.Fprintf(&b, "  %-8s %-6d %-6d %-16s %s\n", blockNo, span.Offset-block, span.Length, span.Name, span.Kind)
+	}
+
+	if record.MultiRecordGroup > 0 {
+		fmt.Fprintf(&b, "\n[green]Multi-Record Group:[white] %d\n", record.MultiRecordGroup)
+	}
+	fmt.Fprintf(&b, `
+[yellow]Record Metadata:[white]
+  LSN:            %d
+  Timestamp:      %s
+  Block Checksum: 0x%08X
+`, record.LSN, app.formatRecordTime(record), record.Checksum)
+
+	b.WriteString(`
+[cyan]═══ Record Data Details ═══[white]
+`)
+	return b.String()
+}
+
 // getCompressedSize estimates the size of a compressed integer
 func (app *RedoLogApp) getCompressedSize(value uint32) int {
 	if value < 128 {
@@ -911,7 +551,7 @@ func (app *RedoLogApp) showRecordDetails(index int) {
 		details += "(empty)"
 	}
 
-	app.detailsText.SetText(details)
+	app.detailsText.SetText(app.theme.colorize(details))
 	// Remove SetCurrentItem call to prevent infinite loop with SetChangedFunc
 }
 
@@ -1955,7 +1595,7 @@ func (app *RedoLogApp) initializeReference() {
 	app.typeDetailView.SetTitle(" Type Details ")
 	
 	// Set initial content for right pane
-	app.typeDetailView.SetText(`[cyan]═══ InnoDB Redo Log Type Reference ═══[white]
+	app.typeDetailView.SetText(app.theme.colorize(`[cyan]═══ InnoDB Redo Log Type Reference ═══[white]
 
 [yellow]Welcome to the Type Reference![white]
 
@@ -1974,7 +1614,7 @@ InnoDB redo log records are stored in 512-byte blocks with:
 • [cyan]Data Area[white] (496 bytes) - Redo log records  
 • [cyan]Block Trailer[white] (4 bytes) - Checksum
 
-Each type shows detailed byte-level formatting within this structure.`)
+Each type shows detailed byte-level formatting within this structure.`))
 	
 	// Get type information
 	typeInfoMap := getTypeInfoMap()
@@ -2002,7 +1642,7 @@ Each type shows detailed byte-level formatting within this structure.`)
 	// Add category headers and types
 	for _, category := range categories {
 		// Add category header
-		app.referenceView.AddItem(fmt.Sprintf("[yellow]▶ %s[white]", category.name), "", 0, nil)
+		app.referenceView.AddItem(app.theme.colorize(fmt.Sprintf("[yellow]▶ %s[white]", category.name)), "", 0, nil)
 		
 		// Add types in category
 		for _, typeID := range category.types {
@@ -2012,7 +1652,7 @@ Each type shows detailed byte-level formatting within this structure.`)
 				
 				// Capture typeID in closure
 				func(capturedTypeID uint8) {
-					app.referenceView.AddItem(mainText, secondaryText, 0, func() {
+					app.referenceView.AddItem(app.theme.colorize(mainText), app.theme.colorize(secondaryText), 0, func() {
 						app.updateTypeDetailPane(capturedTypeID)
 					})
 				}(typeID)
@@ -2023,7 +1663,7 @@ Each type shows detailed byte-level formatting within this structure.`)
 				secondaryText := "[gray]No detailed format available[white]"
 				
 				func(capturedTypeID uint8) {
-					app.referenceView.AddItem(mainText, secondaryText, 0, func() {
+					app.referenceView.AddItem(app.theme.colorize(mainText), app.theme.colorize(secondaryText), 0, func() {
 						app.updateTypeDetailPane(capturedTypeID)
 					})
 				}(typeID)
@@ -2042,7 +1682,8 @@ Each type shows detailed byte-level formatting within this structure.`)
 	// Add navigation instructions
 	instructions := tview.NewTextView()
 	instructions.SetDynamicColors(true)
-	instructions.SetText("[yellow]Navigation: ↑/↓=Navigate • Enter=Select • Tab=Switch Panes • ESC/q=Close • r=Toggle[white]")
+	instructions.SetText(app.theme.colorize(fmt.Sprintf("[yellow]Navigation: ↑/↓=Navigate • Enter=Select • Tab=Switch Panes • %s=Close • %s=Toggle[white]",
+		app.keymap.keys("quit"), app.keymap.keys("reference"))))
 	instructions.SetTextAlign(tview.AlignCenter)
 	
 	app.referenceModal.AddItem(instructions, 1, 0, false)
@@ -2065,7 +1706,7 @@ Each type shows detailed byte-level formatting within this structure.`)
 			return nil
 		}
 		
-		if event.Rune() == 'q' || event.Rune() == 'Q' || event.Rune() == 'r' || event.Rune() == 'R' {
+		if app.closesPanel(event, "reference") {
 			app.hideReferenceModal()
 			return nil
 		}
@@ -2083,7 +1724,7 @@ Each type shows detailed byte-level formatting within this structure.`)
 			return nil
 		}
 		
-		if event.Rune() == 'q' || event.Rune() == 'Q' || event.Rune() == 'r' || event.Rune() == 'R' {
+		if app.closesPanel(event, "reference") {
 			app.hideReferenceModal()
 			return nil
 		}
@@ -2109,7 +1750,7 @@ func (app *RedoLogApp) handleReferenceSelection(mainText string) {
 func (app *RedoLogApp) updateTypeDetailPane(typeID uint8) {
 	typeInfoMap := getTypeInfoMap()
 	if info, exists := typeInfoMap[typeID]; exists {
-		app.typeDetailView.SetText(info.Format)
+		app.typeDetailView.SetText(app.theme.colorize(info.Format))
 		app.typeDetailView.SetTitle(fmt.Sprintf(" %s - Format Details ", info.Name))
 	} else {
 		app.showBasicTypeInfoInPane(typeID)
@@ -2151,7 +1792,7 @@ All redo log records follow this basic pattern:
 			return "Non-transactional Operation"
 		}())
 	
-	app.typeDetailView.SetText(basicInfo)
+	app.typeDetailView.SetText(app.theme.colorize(basicInfo))
 	app.typeDetailView.SetTitle(fmt.Sprintf(" %s - Basic Info ", logType.String()))
 }
 
@@ -2170,192 +1811,93 @@ func (app *RedoLogApp) showReferenceModal() {
 
 // hideReferenceModal hides the reference modal and returns to main view
 func (app *RedoLogApp) hideReferenceModal() {
-	// Return to main layout
-	mainLayout := tview.NewFlex()
-	
-	// Add left pane (record list)
-	mainLayout.AddItem(app.recordList, 0, 1, true)
-	
-	// Add right pane (details)
-	rightPane := tview.NewFlex().SetDirection(tview.FlexRow)
-	rightPane.AddItem(app.detailsText, 0, 1, false)
-	rightPane.AddItem(app.footer, 3, 0, false)
-	
-	mainLayout.AddItem(rightPane, 0, 2, false)
-	
-	app.app.SetRoot(mainLayout, true)
+	app.app.SetRoot(app.mainLayout(), true)
 	app.app.SetFocus(app.recordList)
 }
 
 
 func (app *RedoLogApp) Run() error {
-	// Create main layout with footer
-	topFlex := tview.NewFlex()
-	topFlex.AddItem(app.recordList, 0, 1, true)   // Left pane (1/3)
-	topFlex.AddItem(app.detailsText, 0, 2, false) // Right pane (2/3)
-
-	mainFlex := tview.NewFlex().SetDirection(tview.FlexRow)
-	mainFlex.AddItem(topFlex, 0, 1, true)     // Top section (main content)
-	mainFlex.AddItem(app.footer, 3, 0, false) // Bottom section (footer, fixed 3 lines)
-
 	// Enable mouse support
 	app.app.EnableMouse(true)
 	
-	app.app.SetRoot(mainFlex, true)
+	app.app.SetRoot(app.mainLayout(), true)
 	app.app.SetFocus(app.recordList)
 
 	return app.app.Run()
 }
 
-func loadRedoLogData(filename string) ([]*types.LogRecord, *types.RedoLogHeader, error) {
-	// Create appropriate reader
-	readerInstance, err := createReader(filename, *verbose)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create reader: %w", err)
-	}
-	defer readerInstance.Close()
-
-	// Open the file
-	if err := readerInstance.Open(filename); err != nil {
-		return nil, nil, fmt.Errorf("failed to open file: %w", err)
-	}
-
-	// Read header
-	header, err := readerInstance.ReadHeader()
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to read header: %w", err)
-	}
+// loadOptions controls how loadRedoLogData reads a file
+type loadOptions struct {
+	MaxRecords int    // Maximum number of records to read (0 = no limit)
+	HeaderOnly bool   // Stop after reading the header
+	Verbose    bool   // Print loading progress to stderr
+	Keyring    string // Keyring file holding the master key of an encrypted log
+}
 
-	if *verbose {
-		fmt.Printf("Loading redo log file: %s\n", filename)
-		fmt.Printf("Detected format: %d\n", header.Format)
-	}
+// mainLayout builds the record list, details pane and footer layout
+func (app *RedoLogApp) mainLayout() tview.Primitive {
+	topFlex := tview.NewFlex()
+	topFlex.AddItem(app.recordList, 0, app.layout.List, true)      // Left pane (1/3 by default)
+	topFlex.AddItem(app.detailsText, 0, app.layout.Details, false) // Right pane (2/3 by default)
 
-	// Read all records
-	var records []*types.LogRecord
-	recordCount := 0
-	maxRecords := 10000 // Limit for performance
+	mainFlex := tview.NewFlex().SetDirection(tview.FlexRow)
+	mainFlex.AddItem(topFlex, 0, 1, true)                    // Top section (main content)
+	mainFlex.AddItem(app.footer, app.layout.Footer, 0, false) // Bottom section (footer, 3 lines by default)
+	return mainFlex
+}
 
-	for recordCount < maxRecords {
-		record, err := readerInstance.ReadRecord()
+// loadRedoLogData reads the header and records of a redo log file, or of
+// stdin when filename is "-". Reader warnings are printed to stderr so they
+// never mix with command output. When reading a record fails, the header
+// and the records read so far are returned together with the error.
+func loadRedoLogData(filename string, opts loadOptions) ([]*types.LogRecord, *types.RedoLogHeader, error) {
+	options := []redolog.Option{redolog.WithMaxRecords(opts.MaxRecords)}
+	if opts.Keyring != "" {
+		keyring, err := redolog.LoadKeyring(opts.Keyring)
 		if err != nil {
-			if readerInstance.IsEOF() {
-				break
-			}
-			// Check if this is a normal end-of-log condition
-			if strings.Contains(err.Error(), "end of valid log data") {
-				if *verbose {
-					fmt.Printf("Reached end of log data at record %d\n", recordCount+1)
-				}
-				break
-			}
-			return nil, nil, fmt.Errorf("failed to read record %d: %w", recordCount+1, err)
+			return nil, nil, err
 		}
-
-		records = append(records, record)
-		recordCount++
+		options = append(options, redolog.WithKeyring(keyring))
 	}
 
-	if *verbose {
-		fmt.Printf("Loaded %d records\n", len(records))
+	var log *redolog.Log
+	var err error
+	if filename == stdinFile {
+		log, err = redolog.NewReader(os.Stdin, options...)
+	} else {
+		log, err = redolog.Open(filename, options...)
 	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer log.Close()
 
-	// Post-process records to properly detect multi-record groups
-	detectMultiRecordGroups(records)
-
-	return records, header, nil
-}
-
-// detectMultiRecordGroups analyzes records to identify multi-record groups
-func detectMultiRecordGroups(records []*types.LogRecord) {
-	groupID := 0
-	groupStart := -1
-	
-	for i, record := range records {
-		// Look for MLOG_MULTI_REC_END (31) to identify group boundaries
-		if uint8(record.Type) == 31 { // MLOG_MULTI_REC_END
-			if groupStart != -1 {
-				// We found a group from groupStart to current position
-				groupID++
-				
-				// Mark all records in this group
-				for j := groupStart; j <= i; j++ {
-					records[j].MultiRecordGroup = groupID
-					if j == groupStart {
-						records[j].IsGroupStart = true
-					} else if j == i {
-						records[j].IsGroupEnd = true
-					}
-				}
-				
-				groupStart = -1 // Reset for next group
-			} else {
-				// Isolated MULTI_REC_END - shouldn't happen normally
-				record.MultiRecordGroup = 0
-			}
+	header := log.Header()
+	if opts.Verbose {
+		if log.Format() == redolog.FormatMySQL {
+			fmt.Fprintf(os.Stderr, "Detected MySQL format\n")
+		} else if log.Format() == redolog.FormatMariaDB {
+			fmt.Fprintf(os.Stderr, "Detected MariaDB format\n")
 		} else {
-			// Regular record
-			if groupStart == -1 {
-				// This could be the start of a multi-record group or a single record
-				// We'll determine this when we see MULTI_REC_END or the next group start
-				groupStart = i
-			}
+			fmt.Fprintf(os.Stderr, "Using test format reader\n")
 		}
+		fmt.Fprintf(os.Stderr, "Loading redo log file: %s\n", filename)
+		fmt.Fprintf(os.Stderr, "Detected format: %d\n", header.Format)
 	}
-	
-	// Handle any remaining records that didn't have MULTI_REC_END
-	if groupStart != -1 {
-		// These are likely single records
-		for j := groupStart; j < len(records); j++ {
-			records[j].MultiRecordGroup = 0 // Single records
-		}
-	}
-}
 
-func createReader(filename string, verbose bool) (reader.RedoLogReader, error) {
-	if info, err := os.Stat(filename); err == nil {
-		// MySQL redo logs are typically large (3MB+), test fixtures are small
-		if info.Size() > 1000000 { // > 1MB suggests MySQL format
-			if verbose {
-				fmt.Printf("Detected MySQL format (size: %d bytes)\n", info.Size())
-			}
-			return reader.NewMySQLRedoLogReader(), nil
-		}
+	if opts.HeaderOnly {
+		return nil, header, nil
 	}
 
-	if verbose {
-		fmt.Printf("Using test format reader\n")
+	records, readErr := log.ReadAll()
+	for _, warning := range log.Warnings() {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
 	}
-	return reader.NewRedoLogReader(), nil
-}
-
-// testParseFields is a simple test function for parsing hex data
-func testParseFields(data []byte) string {
-	return reader.ParseRecordDataAsFields(data)
-}
-
-// getOperationType determines if a record is INSERT, UPDATE, DELETE, or OTHER
-func getOperationType(recordType uint8) string {
-	switch recordType {
-	// INSERT operations
-	case 9, 38: // MLOG_REC_INSERT_8027, MLOG_COMP_REC_INSERT_8027
-		return "insert"
-	
-	// UPDATE operations  
-	case 13, 41: // MLOG_REC_UPDATE_IN_PLACE_8027, MLOG_COMP_REC_UPDATE_IN_PLACE_8027
-		return "update"
-	
-	// DELETE operations
-	case 10, 11, 14, 15, 16, 39, 40, 42, 43, 44:
-		// MLOG_REC_CLUST_DELETE_MARK_8027, MLOG_REC_SEC_DELETE_MARK, MLOG_REC_DELETE_8027,
-		// MLOG_LIST_END_DELETE_8027, MLOG_LIST_START_DELETE_8027, 
-		// MLOG_COMP_REC_CLUST_DELETE_MARK_8027, MLOG_COMP_REC_SEC_DELETE_MARK,
-		// MLOG_COMP_REC_DELETE_8027, MLOG_COMP_LIST_END_DELETE_8027, MLOG_COMP_LIST_START_DELETE_8027
-		return "delete"
-	
-	default:
-		return "other"
+	if opts.Verbose {
+		fmt.Fprintf(os.Stderr, "Loaded %d records\n", len(records))
 	}
+
+	return records, header, readErr
 }
 
 // updateFilteredRecords applies the current filter settings
@@ -2371,12 +1913,15 @@ func (app *RedoLogApp) updateFilteredRecords() {
 		
 		// Apply operation type filter
 		if app.operationFilter != "all" && app.operationFilter != "" {
-			recordType := uint8(record.Type)
-			opType := getOperationType(recordType)
-			if opType != app.operationFilter {
+			if record.Type.Operation() != app.operationFilter {
 				continue // Skip records that don't match the operation filter
 			}
 		}
+
+		// Apply filter expression
+		if app.whereFilter != nil && !app.whereFilter.Match(record) {
+			continue
+		}
 		
 		app.filteredRecords = append(app.filteredRecords, record)
 		app.recordIndices = append(app.recordIndices, i)
@@ -2426,7 +1971,7 @@ func (app *RedoLogApp) rebuildRecordList() {
 		
 		listItem := fmt.Sprintf("%s%s%-6s %s%s", colorPrefix, groupIndicator, recordNum, recordType, idInfo)
 		
-		app.recordList.AddItem(listItem, "", 0, nil)
+		app.recordList.AddItem(app.theme.colorize(listItem), "", 0, nil)
 	}
 }
 
@@ -2454,10 +1999,18 @@ func (app *RedoLogApp) updateFooter() {
 		opFilterText = "[white]ALL"
 	}
 
-	footerText := fmt.Sprintf(`[yellow]Keys: [bold]'i'[reset][yellow]=INSERT, [bold]'u'[reset][yellow]=UPDATE, [bold]'d'[reset][yellow]=DELETE, [bold]'r'[reset][yellow]=REFERENCE, [bold]Tab[reset][yellow]=Switch Panes [white]| Filters: Table ID 0=%s%s[white] Op=%s[white] | Records: [cyan]%d[white]/[blue]%d`,
-		filterColor, filterStatus, opFilterText, len(app.filteredRecords), len(app.records))
+	whereText := "[white]none"
+	if app.whereFilter != nil {
+		whereText = "[cyan]" + tview.Escape(app.whereFilter.String())
+	}
 
-	app.footer.SetText(footerText)
+	footerText := fmt.Sprintf(`[yellow]Keys: %s [white]| Filters: Table ID 0=%s%s[white] Op=%s[white] Where=%s[white] | Records: [cyan]%d[white]/[blue]%d`,
+		app.footerKeys(), filterColor, filterStatus, opFilterText, whereText, len(app.filteredRecords), len(app.records))
+	if app.markPosition >= 0 {
+		footerText += fmt.Sprintf(" | Mark: [magenta]#%d[white]", app.markPosition+1)
+	}
+
+	app.footer.SetText(app.theme.colorize(footerText))
 }
 
 // toggleTableID0Filter toggles the Table ID 0 filter and refreshes the display
@@ -2511,7 +2064,183 @@ func (app *RedoLogApp) toggleOperationFilter(operation string) {
 		app.showRecordDetails(0)
 	}
 }
+// initializeAmplificationPanel creates the write-amplification panel
+func (app *RedoLogApp) initializeAmplificationPanel() {
+	app.amplificationView = tview.NewTextView()
+	app.amplificationView.SetBorder(true)
+	app.amplificationView.SetTitle(" Redo Volume by Tablespace (Esc to close) ")
+	app.amplificationView.SetScrollable(true)
+	app.amplificationView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if app.closesPanel(event, "writes") {
+			app.app.SetRoot(app.mainLayout(), true)
+			app.app.SetFocus(app.recordList)
+			return nil
+		}
+		return event
+	})
+}
+
+// showAmplificationPanel ranks the tablespaces of the filtered records by
+// the redo they generate
+func (app *RedoLogApp) showAmplificationPanel() {
+	report := analyzer.AnalyzeWriteAmplification(app.filteredRecords, app.dictionary)
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "Filtered records: %d of %d\n", len(app.filteredRecords), len(app.records))
+	writeAmplificationText(&text, report)
+
+	app.amplificationView.SetText(text.String())
+	app.amplificationView.ScrollToBeginning()
+	app.app.SetRoot(app.amplificationView, true)
+	app.app.SetFocus(app.amplificationView)
+}
+
+// initializeThroughputPanel creates the throughput panel
+func (app *RedoLogApp) initializeThroughputPanel() {
+	app.throughputView = tview.NewTextView()
+	app.throughputView.SetBorder(true)
+	app.throughputView.SetTitle(" Redo Throughput per Window (Esc to close) ")
+	app.throughputView.SetScrollable(true)
+	app.throughputView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if app.closesPanel(event, "throughput") {
+			app.app.SetRoot(app.mainLayout(), true)
+			app.app.SetFocus(app.recordList)
+			return nil
+		}
+		return event
+	})
+}
+
+// showThroughputPanel shows sparklines and per-window totals of the
+// filtered records
+func (app *RedoLogApp) showThroughputPanel() {
+	var text strings.Builder
+	fmt.Fprintf(&text, "Filtered records: %d of %d\n", len(app.filteredRecords), len(app.records))
+
+	report, err := analyzer.AnalyzeThroughput(app.filteredRecords, analyzer.ThroughputConfig{Clock: app.clock, TopPages: 3})
+	if err != nil {
+		fmt.Fprintf(&text, "Error: %v\n", err)
+	} else {
+		writeThroughputText(&text, report)
+	}
+
+	app.throughputView.SetText(text.String())
+	app.throughputView.ScrollToBeginning()
+	app.app.SetRoot(app.throughputView, true)
+	app.app.SetFocus(app.throughputView)
+}
+
+// formatRecordTime returns the time a record was written, if it is known
+func (app *RedoLogApp) formatRecordTime(record *types.LogRecord) string {
+	if t, ok := app.clock.Time(record.LSN); ok {
+		return fmt.Sprintf("%s (%s)", t.Format("2006-01-02 15:04:05.000"), app.clock.Source())
+	}
+	return "unknown (pass --anchor LSN@TIME to estimate)"
+}
+
 // Search functionality methods
+// initializeFilterPrompt creates the filter expression prompt
+func (app *RedoLogApp) initializeFilterPrompt() {
+	app.filterInput = tview.NewInputField()
+	app.filterInput.SetLabel(filterPromptLabel)
+	app.filterInput.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEnter {
+			app.applyFilterExpression(app.filterInput.GetText())
+		} else if key == tcell.KeyEscape {
+			app.hideFilterPrompt()
+		}
+	})
+
+	app.filterHelp = tview.NewTextView()
+	app.filterHelp.SetBorder(true)
+	app.filterHelp.SetTitle(" Filter Expression ")
+}
+
+// showFilterPrompt opens the filter prompt with the current expression
+func (app *RedoLogApp) showFilterPrompt() {
+	app.filterInput.SetText("")
+	if app.whereFilter != nil {
+		app.filterInput.SetText(app.whereFilter.String())
+	}
+	app.showFilterHelp("")
+
+	flex := tview.NewFlex().SetDirection(tview.FlexRow)
+	flex.AddItem(app.filterInput, 1, 0, true)
+	flex.AddItem(app.filterHelp, 0, 1, false)
+
+	app.app.SetRoot(flex, true)
+	app.app.SetFocus(app.filterInput)
+}
+
+// hideFilterPrompt returns to the main layout
+func (app *RedoLogApp) hideFilterPrompt() {
+	app.app.SetRoot(app.mainLayout(), true)
+	app.app.SetFocus(app.recordList)
+}
+
+// showFilterHelp shows the expression syntax, preceded by errorText if set
+func (app *RedoLogApp) showFilterHelp(errorText string) {
+	help := fmt.Sprintf(`Enter applies the expression, an empty expression clears it, Esc cancels.
+
+Fields: %s
+Operators: == != < <= > >= in (...) ~ "regexp" !~ "regexp" && || ! ( )
+Numbers may be decimal or 0x hex; record types may be given by id or name.
+
+Examples:
+  op == insert && space == 42
+  type in (MLOG_REC_INSERT, MLOG_COMP_REC_INSERT_8027) && lsn >= 0x1A2B
+  data ~ "ACADEMY" || table != 0`, strings.Join(filter.FieldNames(), ", "))
+
+	if errorText != "" {
+		app.filterHelp.SetTextColor(app.theme.color("red"))
+		help = errorText + "\n\n" + help
+	} else {
+		app.filterHelp.SetTextColor(app.theme.color("white"))
+	}
+	app.filterHelp.SetText(help)
+}
+
+// applyFilterExpression parses expr and filters the record list with it. On
+// a syntax error the prompt stays open and points at the offending column.
+func (app *RedoLogApp) applyFilterExpression(expr string) {
+	if strings.TrimSpace(expr) == "" {
+		app.setWhereFilter(nil)
+		app.hideFilterPrompt()
+		return
+	}
+
+	f, err := filter.Parse(expr)
+	if err != nil {
+		var syntaxErr *filter.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			caret := strings.Repeat(" ", syntaxErr.Column-1) + "^"
+			app.showFilterHelp(fmt.Sprintf("Error: %v\n%s\n%s", err, expr, caret))
+		} else {
+			app.showFilterHelp(fmt.Sprintf("Error: %v", err))
+		}
+		return
+	}
+
+	app.setWhereFilter(f)
+	app.hideFilterPrompt()
+}
+
+// setWhereFilter replaces the filter expression and refreshes the record list
+func (app *RedoLogApp) setWhereFilter(f *filter.Filter) {
+	app.whereFilter = f
+
+	app.updateFilteredRecords()
+	app.rebuildRecordList()
+	app.showHeaderInfo()
+	app.updateFooter()
+
+	// Reset selection to first record if available
+	if len(app.filteredRecords) > 0 {
+		app.recordList.SetCurrentItem(0)
+		app.showRecordDetails(0)
+	}
+}
+
 func (app *RedoLogApp) initializeSearch() {
 	// Create search input field
 	app.searchInput = tview.NewInputField()
@@ -2554,20 +2283,7 @@ func (app *RedoLogApp) showSearchModal() {
 }
 
 func (app *RedoLogApp) hideSearchModal() {
-	// Return to main layout
-	mainLayout := tview.NewFlex()
-	
-	// Add left pane (record list)
-	mainLayout.AddItem(app.recordList, 0, 1, true)
-	
-	// Add right pane (details)
-	rightPane := tview.NewFlex().SetDirection(tview.FlexRow)
-	rightPane.AddItem(app.detailsText, 0, 1, false)
-	rightPane.AddItem(app.footer, 3, 0, false)
-	
-	mainLayout.AddItem(rightPane, 0, 2, false)
-	
-	app.app.SetRoot(mainLayout, true)
+	app.app.SetRoot(app.mainLayout(), true)
 	app.app.SetFocus(app.recordList)
 }
 
@@ -2675,96 +2391,6 @@ func (app *RedoLogApp) updateSearchStatus() {
 	// Get current footer text and append search status
 	currentFooter := app.footer.GetText(false)
 	if !strings.Contains(currentFooter, "Search:") {
-		app.footer.SetText(currentFooter + searchStatus)
-	}
-}
-
-// Export functionality
-func exportRecords(records []*types.LogRecord, header *types.RedoLogHeader, format, outputFile string) error {
-	var output io.Writer = os.Stdout
-	
-	if outputFile != "" {
-		file, err := os.Create(outputFile)
-		if err != nil {
-			return fmt.Errorf("failed to create output file: %v", err)
-		}
-		defer file.Close()
-		output = file
-	}
-	
-	switch strings.ToLower(format) {
-	case "json":
-		return exportJSON(output, records, header)
-	case "csv":
-		return exportCSV(output, records, header)
-	default:
-		return fmt.Errorf("unsupported export format: %s (supported: json, csv)", format)
+		app.footer.SetText(currentFooter + app.theme.colorize(searchStatus))
 	}
 }
-
-func exportJSON(w io.Writer, records []*types.LogRecord, header *types.RedoLogHeader) error {
-	data := struct {
-		Header  *types.RedoLogHeader `json:"header"`
-		Records []*types.LogRecord   `json:"records"`
-		Stats   map[string]interface{} `json:"stats"`
-	}{
-		Header:  header,
-		Records: records,
-		Stats: map[string]interface{}{
-			"total_records": len(records),
-			"export_timestamp": time.Now().Format(time.RFC3339),
-			"format_version": header.Format,
-		},
-	}
-	
-	encoder := json.NewEncoder(w)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(data)
-}
-
-func exportCSV(w io.Writer, records []*types.LogRecord, header *types.RedoLogHeader) error {
-	writer := csv.NewWriter(w)
-	defer writer.Flush()
-	
-	// Write header
-	headers := []string{
-		"Record_Number", "LSN", "Type", "Type_ID", "Length", 
-		"Space_ID", "Page_No", "Table_ID", "Group", "Data_Preview", "Data_Length",
-	}
-	if err := writer.Write(headers); err != nil {
-		return err
-	}
-	
-	// Write records
-	for i, record := range records {
-		// Limit data preview to first 100 characters
-		dataPreview := string(record.Data)
-		if len(dataPreview) > 100 {
-			dataPreview = dataPreview[:100] + "..."
-		}
-		// Replace newlines and control characters for CSV
-		dataPreview = strings.ReplaceAll(dataPreview, "\n", "\\n")
-		dataPreview = strings.ReplaceAll(dataPreview, "\r", "\\r")
-		dataPreview = strings.ReplaceAll(dataPreview, "\"", "\"\"")
-		
-		row := []string{
-			fmt.Sprintf("%d", i+1),
-			fmt.Sprintf("%d", record.LSN),
-			record.Type.String(),
-			fmt.Sprintf("%d", uint8(record.Type)),
-			fmt.Sprintf("%d", record.Length),
-			fmt.Sprintf("%d", record.SpaceID),
-			fmt.Sprintf("%d", record.PageNo),
-			fmt.Sprintf("%d", record.TableID),
-			fmt.Sprintf("%d", record.MultiRecordGroup),
-			dataPreview,
-			fmt.Sprintf("%d", len(record.Data)),
-		}
-		
-		if err := writer.Write(row); err != nil {
-			return err
-		}
-	}
-	
-	return nil
-}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/reader"
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+	"github.com/yamaru/innodb-redolog-tool/pkg/redolog"
+)
+
+// metricsContentType is the Prometheus text exposition format
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// runMetrics implements the metrics subcommand
+func runMetrics(args []string) error {
+	fs := newFlagSet("metrics", "[flags]")
+	listen := fs.String("listen", ":9180", "Address to serve /metrics on")
+	data := fs.String("data", "", "Redo log file, or a directory holding redo log files (data directory, #innodb_redo or ib_logfile files)")
+	interval := fs.Duration("interval", 10*time.Second, "How often the log files are checked for new records")
+	verbose := fs.Bool("v", false, "Log read errors to stderr")
+	var keyring string
+	addKeyringFlag(fs, &keyring)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return newUsageError("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if *data == "" {
+		return newUsageError("no data path given (see 'redolog-tool help metrics')")
+	}
+	if *interval <= 0 {
+		return newUsageError("--interval must be positive")
+	}
+
+	m := newRedoMonitor(*data)
+	if keyring != "" {
+		k, err := redolog.LoadKeyring(keyring)
+		if err != nil {
+			return err
+		}
+		m.keyring = k
+	}
+	if err := m.poll(); err != nil {
+		return err
+	}
+
+	go func() {
+		for range time.Tick(*interval) {
+			if err := m.poll(); err != nil && *verbose {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+	}()
+
+	fmt.Fprintf(os.Stderr, "Serving redo metrics of %s on %s/metrics\n", *data, *listen)
+	return http.ListenAndServe(*listen, m.handler())
+}
+
+// redoMonitor follows the redo log files of a file or directory, counting
+// each record once as the server writes it. Only the files that changed
+// since the last poll are read again, from the block holding the first
+// record not counted yet. Progress is tracked by LSN, taken from the block
+// numbers, so that records of circular ib_logfileN files are neither missed
+// nor counted twice when the server wraps around.
+type redoMonitor struct {
+	data    string
+	keyring *redolog.Keyring
+
+	mu      sync.Mutex
+	files   map[string]*monitoredFile // By path
+	metrics redoMetrics
+}
+
+// monitoredFile is how far a log file has been counted
+type monitoredFile struct {
+	size       int64
+	modified   time.Time
+	counted    uint64                  // LSN past the last record counted
+	resume     int64                   // Offset of the block the next read starts at, 0 for the first block
+	endLSN     uint64                  // LSN past the newest record of the file
+	checkpoint *reader.MySQLCheckpoint // Latest valid checkpoint of the header
+}
+
+// lsnRecord is a record read from a log file with the LSN range it covers
+// and the file offset it ends at
+type lsnRecord struct {
+	*types.LogRecord
+	start, end uint64
+	endOffset  int64
+}
+
+// redoMetrics are the values exported on /metrics
+type redoMetrics struct {
+	files         int
+	capacity      uint64 // Bytes of the log files after their headers
+	lsn           uint64 // LSN past the last record counted
+	checkpointLSN uint64
+	bytes         uint64            // Bytes of the records counted
+	mtrs          uint64            // Mini-transactions counted
+	records       map[string]uint64 // Records by type name
+	spaceBytes    map[uint32]uint64
+	spaceRecords  map[uint32]uint64
+	readErrors    uint64
+}
+
+// newRedoMonitor creates a monitor of the files of data
+func newRedoMonitor(data string) *redoMonitor {
+	return &redoMonitor{
+		data:  data,
+		files: make(map[string]*monitoredFile),
+		metrics: redoMetrics{
+			records:      make(map[string]uint64),
+			spaceBytes:   make(map[uint32]uint64),
+			spaceRecords: make(map[uint32]uint64),
+		},
+	}
+}
+
+// handler serves the metrics on /metrics
+func (m *redoMonitor) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", metricsContentType)
+		m.writeMetrics(w)
+	})
+	return mux
+}
+
+// poll counts the records written since the last poll. The file holding
+// the highest LSN is the one being written; its records are counted up to
+// the last complete mini-transaction.
+func (m *redoMonitor) poll() error {
+	paths, err := dataLogFiles(m.data)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var errs []string
+	current := make(map[string]bool, len(paths))
+	read := make(map[string][]lsnRecord)
+	m.metrics.capacity = 0
+	for _, path := range paths {
+		current[path] = true
+		info, err := os.Stat(path)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if info.Size() > reader.LogFileHdrSize {
+			m.metrics.capacity += uint64(info.Size() - reader.LogFileHdrSize)
+		}
+
+		f := m.files[path]
+		if f == nil {
+			f = &monitoredFile{}
+			m.files[path] = f
+		}
+		if f.size == info.Size() && f.modified.Equal(info.ModTime()) {
+			continue
+		}
+		records, err := m.read(path, f)
+		if err != nil {
+			m.metrics.readErrors++
+			errs = append(errs, fmt.Sprintf("%s: %v", path, err))
+			continue
+		}
+		read[path] = records
+		f.size, f.modified = info.Size(), info.ModTime()
+	}
+	for path := range m.files {
+		if !current[path] {
+			delete(m.files, path)
+		}
+	}
+
+	var active string
+	for path, f := range m.files {
+		if active == "" || f.endLSN > m.files[active].endLSN {
+			active = path
+		}
+	}
+	for path, records := range read {
+		m.countNew(m.files[path], records, path == active)
+	}
+
+	m.metrics.files = len(paths)
+	m.metrics.lsn, m.metrics.checkpointLSN = 0, 0
+	for _, f := range m.files {
+		m.metrics.lsn = max(m.metrics.lsn, f.counted)
+		if f.checkpoint != nil {
+			m.metrics.checkpointLSN = max(m.metrics.checkpointLSN, f.checkpoint.CheckpointLSN)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// read returns the records of a file with their LSNs, which are derived
+// from the block numbers near the latest checkpoint LSN known. Reading
+// resumes at f.resume, unless the server has wrapped around and written
+// over the first block or the block resumed at since.
+func (m *redoMonitor) read(path string, f *monitoredFile) ([]lsnRecord, error) {
+	src, err := reader.NewFileSource(path)
+	if err != nil {
+		return nil, err
+	}
+	r := reader.NewMySQLRedoLogReader()
+	r.SetKeyring(m.keyring)
+	if err := r.OpenSource(src); err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	header, err := r.ReadHeader()
+	if err != nil {
+		return nil, err
+	}
+	f.checkpoint = nil
+	for _, checkpoint := range r.Checkpoints() {
+		if checkpoint.IsValid && (f.checkpoint == nil || checkpoint.CheckpointNo > f.checkpoint.CheckpointNo) {
+			f.checkpoint = checkpoint
+		}
+	}
+	near := header.StartLSN
+	if f.checkpoint != nil {
+		m.metrics.checkpointLSN = max(m.metrics.checkpointLSN, f.checkpoint.CheckpointLSN)
+	}
+	if m.metrics.checkpointLSN > 0 {
+		near = m.metrics.checkpointLSN
+	}
+
+	blockLSNs := make(map[int64]uint64)
+	blockLSN := func(block int64) (uint64, error) {
+		if lsn, ok := blockLSNs[block]; ok {
+			return lsn, nil
+		}
+		header := make([]byte, reader.LogBlockHdrSize)
+		if _, err := src.ReadAt(header, block); err != nil {
+			return 0, err
+		}
+		blockLSNs[block] = reader.BlockLSN(header, near)
+		return blockLSNs[block], nil
+	}
+	if f.resume > reader.LogFileHdrSize {
+		first, err := blockLSN(reader.LogFileHdrSize)
+		if err != nil {
+			return nil, err
+		}
+		resume, err := blockLSN(f.resume)
+		if err == nil && first < resume && resume <= f.counted {
+			if err := r.Seek(f.resume); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	records, err := reader.ReadRecords(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	reader.DetectMultiRecordGroups(records)
+
+	result := make([]lsnRecord, 0, len(records))
+	for _, record := range records {
+		start, end, ok := record.ByteRange()
+		if !ok {
+			start, end = record.FileOffset, record.FileOffset+int64(record.Length)
+		}
+		block := reader.BlockOffset(start)
+		lsn, err := blockLSN(block)
+		if err != nil {
+			return nil, err
+		}
+		lsn += uint64(start - block)
+		result = append(result, lsnRecord{LogRecord: record, start: lsn, end: lsn + uint64(end-start), endOffset: end})
+		f.endLSN = max(f.endLSN, lsn+uint64(end-start))
+	}
+	return result, nil
+}
+
+// countNew counts the records past the LSN counted before. In the file
+// being written, records past the last MLOG_MULTI_REC_END may belong to a
+// mini-transaction still being written and are left for the next poll.
+func (m *redoMonitor) countNew(f *monitoredFile, records []lsnRecord, active bool) {
+	limit := uint64(math.MaxUint64)
+	if active {
+		var last uint64
+		for _, record := range records {
+			if record.IsGroupEnd {
+				last = max(last, record.end)
+			}
+		}
+		if last > 0 {
+			limit = last
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool { return records[i].start < records[j].start })
+	for _, record := range records {
+		if record.start < f.counted || record.end > limit {
+			continue
+		}
+		f.counted = record.end
+		m.count(record.LogRecord)
+
+		// A record ending in the trailer of a block is followed by a
+		// mini-transaction starting in the next block
+		f.resume = reader.BlockOffset(record.endOffset)
+		if record.endOffset-f.resume >= reader.OSFileLogBlockSize-reader.LogBlockTrlSize {
+			f.resume += reader.OSFileLogBlockSize
+		}
+	}
+}
+
+// count adds a record to the metrics
+func (m *redoMonitor) count(record *types.LogRecord) {
+	m.metrics.bytes += uint64(record.Length)
+	m.metrics.records[record.Type.String()]++
+	if record.MultiRecordGroup == 0 || record.IsGroupEnd {
+		m.metrics.mtrs++
+	}
+	if record.HasPage() {
+		m.metrics.spaceBytes[record.SpaceID] += uint64(record.Length)
+		m.metrics.spaceRecords[record.SpaceID]++
+	}
+}
+
+// metricSample is one sample of a metric, with its labels
+type metricSample struct {
+	labels string // name="value" pairs, without braces
+	value  float64
+}
+
+// writeMetrics writes the metrics in the Prometheus text format
+func (m *redoMonitor) writeMetrics(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mt := &m.metrics
+
+	var age uint64
+	if mt.lsn > mt.checkpointLSN && mt.checkpointLSN > 0 {
+		age = mt.lsn - mt.checkpointLSN
+	}
+	var used float64
+	if mt.capacity > 0 {
+		used = float64(age) / float64(mt.capacity)
+	}
+
+	writeMetric(w, "innodb_redo_files", "gauge", "Redo log files followed", metricSample{value: float64(mt.files)})
+	writeMetric(w, "innodb_redo_capacity_bytes", "gauge", "Size of the redo log files after their headers", metricSample{value: float64(mt.capacity)})
+	writeMetric(w, "innodb_redo_lsn", "gauge", "LSN past the last record read", metricSample{value: float64(mt.lsn)})
+	writeMetric(w, "innodb_redo_checkpoint_lsn", "gauge", "LSN of the latest valid checkpoint", metricSample{value: float64(mt.checkpointLSN)})
+	writeMetric(w, "innodb_redo_checkpoint_age_bytes", "gauge", "Redo written since the latest checkpoint", metricSample{value: float64(age)})
+	writeMetric(w, "innodb_redo_capacity_used_ratio", "gauge", "Checkpoint age as a fraction of the log capacity", metricSample{value: used})
+	writeMetric(w, "innodb_redo_bytes_total", "counter", "Bytes of redo records written", metricSample{value: float64(mt.bytes)})
+	writeMetric(w, "innodb_redo_mtrs_total", "counter", "Mini-transactions written", metricSample{value: float64(mt.mtrs)})
+
+	names := make([]string, 0, len(mt.records))
+	for name := range mt.records {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	samples := make([]metricSample, 0, len(names))
+	for _, name := range names {
+		samples = append(samples, metricSample{labels: "type=" + strconv.Quote(name), value: float64(mt.records[name])})
+	}
+	writeMetric(w, "innodb_redo_records_total", "counter", "Redo records written by record type", samples...)
+
+	spaces := make([]uint32, 0, len(mt.spaceBytes))
+	for spaceID := range mt.spaceBytes {
+		spaces = append(spaces, spaceID)
+	}
+	sort.Slice(spaces, func(i, j int) bool { return spaces[i] < spaces[j] })
+	bytes := make([]metricSample, 0, len(spaces))
+	records := make([]metricSample, 0, len(spaces))
+	for _, spaceID := range spaces {
+		label := fmt.Sprintf("space_id=\"%d\"", spaceID)
+		bytes = append(bytes, metricSample{labels: label, value: float64(mt.spaceBytes[spaceID])})
+		records = append(records, metricSample{labels: label, value: float64(mt.spaceRecords[spaceID])})
+	}
+	writeMetric(w, "innodb_redo_space_bytes_total", "counter", "Bytes of redo records written by tablespace", bytes...)
+	writeMetric(w, "innodb_redo_space_records_total", "counter", "Redo records written by tablespace", records...)
+	writeMetric(w, "innodb_redo_read_errors_total", "counter", "Log files that could not be read", metricSample{value: float64(mt.readErrors)})
+}
+
+// writeMetric writes the HELP and TYPE lines and the samples of a metric
+func writeMetric(w io.Writer, name, kind, help string, samples ...metricSample) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	for _, sample := range samples {
+		value := strconv.FormatFloat(sample.value, 'f', -1, 64)
+		if sample.labels == "" {
+			fmt.Fprintf(w, "%s %s\n", name, value)
+		} else {
+			fmt.Fprintf(w, "%s{%s} %s\n", name, sample.labels, value)
+		}
+	}
+}
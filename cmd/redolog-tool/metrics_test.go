@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/reader"
+	"github.com/yamaru/innodb-redolog-tool/test/fixtures"
+)
+
+func TestRedoMonitor(t *testing.T) {
+	// The reader takes up to 32 bytes after MLOG_MULTI_REC_END as its payload
+	end := append([]byte{reader.MLogMultiRecEnd}, make([]byte, 32)...)
+	dir := t.TempDir()
+	mtr := append(append(insert8028(4, "abc"), insert8028(3, "def")...), end...)
+	// The last insert has no MLOG_MULTI_REC_END yet
+	filename, err := fixtures.CreateMySQLLogFile(dir, reader.LogHeaderFormat8030, 8192, append(append([]byte{}, mtr...), insert8028(4, "ghi")...))
+	require.NoError(t, err)
+
+	m := newRedoMonitor(dir)
+	require.NoError(t, m.poll())
+	metrics := func() string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		m.handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, metricsContentType, w.Header().Get("Content-Type"))
+		return w.Body.String()
+	}
+
+	text := metrics()
+	assert.Contains(t, text, "# TYPE innodb_redo_records_total counter\n")
+	assert.Contains(t, text, "innodb_redo_files 1\n")
+	assert.Contains(t, text, "innodb_redo_capacity_bytes 512\n")
+	assert.Contains(t, text, "innodb_redo_checkpoint_lsn 8192\n")
+	assert.Contains(t, text, `innodb_redo_records_total{type="MLOG_REC_INSERT"} 2`+"\n")
+	assert.Contains(t, text, `innodb_redo_records_total{type="MLOG_MULTI_REC_END"} 1`+"\n")
+	assert.Contains(t, text, "innodb_redo_mtrs_total 1\n")
+	assert.Contains(t, text, `innodb_redo_space_records_total{space_id="5"} 2`+"\n")
+	assert.Contains(t, text, "innodb_redo_read_errors_total 0\n")
+	lsn := m.metrics.lsn
+	assert.Equal(t, uint64(8192+reader.LogBlockHdrSize+len(mtr)), lsn)
+
+	// An unchanged file is not read again
+	require.NoError(t, m.poll())
+	assert.Equal(t, text, metrics())
+
+	// The server finishes the mini-transaction and writes another one
+	grown := append(append(append([]byte{}, mtr...), insert8028(4, "ghi")...), end...)
+	grown = append(append(grown, insert8028(7, "jkl")...), end...)
+	_, err = fixtures.CreateMySQLLogFile(dir, reader.LogHeaderFormat8030, 8192, grown)
+	require.NoError(t, err)
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(filename, later, later))
+	require.NoError(t, m.poll())
+
+	text = metrics()
+	assert.Contains(t, text, `innodb_redo_records_total{type="MLOG_REC_INSERT"} 4`+"\n")
+	assert.Contains(t, text, `innodb_redo_records_total{type="MLOG_MULTI_REC_END"} 3`+"\n")
+	assert.Contains(t, text, "innodb_redo_mtrs_total 3\n")
+	assert.Contains(t, text, `innodb_redo_space_records_total{space_id="5"} 4`+"\n")
+	assert.Greater(t, m.metrics.lsn, lsn)
+	assert.Contains(t, text, "innodb_redo_checkpoint_age_bytes ")
+
+	// Files that cannot be read are counted
+	require.NoError(t, fixtures.EncryptMySQLLogFile(filename, make([]byte, 32), "3e11fa47-71ca-11e1-9e33-c80aa9429562", 1, make([]byte, 32), make([]byte, 32)))
+	require.NoError(t, os.Chtimes(filename, later.Add(time.Minute), later.Add(time.Minute)))
+	assert.Error(t, m.poll())
+	assert.Contains(t, metrics(), "innodb_redo_read_errors_total 1\n")
+}
+
+// logBlock is a log block of a circular log: the LSN it was written at and
+// its records
+type logBlock struct {
+	lsn     uint64
+	records []byte
+}
+
+// writeCircularLog writes a circular MySQL 8.0.28 ib_logfileN holding the
+// blocks in file order. A checkpointLSN of 0 writes no checkpoints, as in
+// every file but the first.
+func writeCircularLog(t *testing.T, filename string, checkpointLSN uint64, blocks ...logBlock) {
+	t.Helper()
+	data := make([]byte, reader.LogFileHdrSize+len(blocks)*reader.OSFileLogBlockSize)
+	binary.BigEndian.PutUint32(data, reader.LogHeaderFormat8028)
+	copy(data[16:48], "MySQL 8.0.29")
+	if checkpointLSN > 0 {
+		for _, offset := range []int{reader.LogCheckpoint1, reader.LogCheckpoint2} {
+			binary.BigEndian.PutUint64(data[offset:], 1)
+			binary.BigEndian.PutUint64(data[offset+8:], checkpointLSN)
+			binary.BigEndian.PutUint64(data[offset+16:], reader.LogFileHdrSize)
+		}
+	}
+	for i, b := range blocks {
+		block := data[reader.LogFileHdrSize+i*reader.OSFileLogBlockSize:]
+		binary.BigEndian.PutUint32(block, uint32(b.lsn/reader.OSFileLogBlockSize)+1)
+		binary.BigEndian.PutUint16(block[reader.LogBlockHdrDataLen:], uint16(reader.LogBlockHdrSize+len(b.records)))
+		binary.BigEndian.PutUint16(block[reader.LogBlockFirstRecGroup:], reader.LogBlockHdrSize)
+		copy(block[reader.LogBlockHdrSize:], b.records)
+	}
+	require.NoError(t, os.WriteFile(filename, data, 0644))
+}
+
+func TestRedoMonitorWrap(t *testing.T) {
+	end := append([]byte{reader.MLogMultiRecEnd}, make([]byte, 32)...)
+	mtr := func(page byte) []byte {
+		return append(insert8028(page, "abc"), end...)
+	}
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "ib_logfile0"), filepath.Join(dir, "ib_logfile1")
+	touch := func(filename string, later time.Duration) {
+		t.Helper()
+		modified := time.Now().Add(later)
+		require.NoError(t, os.Chtimes(filename, modified, modified))
+	}
+	writeCircularLog(t, first, 8192, logBlock{8192, mtr(1)}, logBlock{8704, mtr(2)})
+	writeCircularLog(t, second, 0, logBlock{9216, mtr(3)}, logBlock{9728, mtr(4)})
+	m := newRedoMonitor(dir)
+	require.NoError(t, m.poll())
+	assert.Equal(t, uint64(4), m.metrics.records["MLOG_REC_INSERT"])
+	assert.Equal(t, uint64(4), m.metrics.mtrs)
+	assert.Equal(t, uint64(9728+reader.LogBlockHdrSize+len(mtr(4))), m.metrics.lsn)
+
+	// The server wraps around to the first file, where the last insert has
+	// no MLOG_MULTI_REC_END yet; the second file is no longer the newest
+	wrapped := append(mtr(5), insert8028(6, "def")...)
+	writeCircularLog(t, first, 9216, logBlock{10240, wrapped}, logBlock{8704, mtr(2)})
+	touch(first, time.Minute)
+	require.NoError(t, m.poll())
+	assert.Equal(t, uint64(5), m.metrics.records["MLOG_REC_INSERT"])
+	assert.Equal(t, uint64(5), m.metrics.mtrs)
+	assert.Equal(t, uint64(10240+reader.LogBlockHdrSize+len(mtr(5))), m.metrics.lsn)
+	assert.Equal(t, uint64(9216), m.metrics.checkpointLSN)
+
+	// The mini-transaction completes and the next block overwrites the
+	// oldest one
+	writeCircularLog(t, first, 9216, logBlock{10240, append(wrapped, end...)}, logBlock{10752, mtr(7)})
+	touch(first, 2*time.Minute)
+	require.NoError(t, m.poll())
+	assert.Equal(t, uint64(7), m.metrics.records["MLOG_REC_INSERT"])
+	assert.Equal(t, uint64(7), m.metrics.mtrs)
+	assert.Equal(t, uint64(10752+reader.LogBlockHdrSize+len(mtr(7))), m.metrics.lsn)
+}
+
+func TestRedoMonitorResume(t *testing.T) {
+	end := append([]byte{reader.MLogMultiRecEnd}, make([]byte, 32)...)
+	mtr := func(page byte) []byte {
+		return append(insert8028(page, "abc"), end...)
+	}
+	filename := filepath.Join(t.TempDir(), "ib_logfile0")
+	writeCircularLog(t, filename, 8192, logBlock{8192, mtr(1)}, logBlock{8704, mtr(2)})
+	m := newRedoMonitor(filename)
+	require.NoError(t, m.poll())
+	assert.Equal(t, uint64(2), m.metrics.records["MLOG_REC_INSERT"])
+	assert.Equal(t, int64(reader.LogFileHdrSize+reader.OSFileLogBlockSize), m.files[filename].resume)
+
+	// The next poll starts at the second block: the first one, which now
+	// ends the log, is not read again
+	writeCircularLog(t, filename, 8192, logBlock{8192, mtr(1)}, logBlock{8704, mtr(2)}, logBlock{9216, mtr(3)})
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	binary.BigEndian.PutUint16(data[reader.LogFileHdrSize+reader.LogBlockHdrDataLen:], 0)
+	require.NoError(t, os.WriteFile(filename, data, 0644))
+	modified := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(filename, modified, modified))
+	require.NoError(t, m.poll())
+	assert.Equal(t, uint64(3), m.metrics.records["MLOG_REC_INSERT"])
+	assert.Equal(t, uint64(3), m.metrics.mtrs)
+	assert.Equal(t, int64(reader.LogFileHdrSize+2*reader.OSFileLogBlockSize), m.files[filename].resume)
+}
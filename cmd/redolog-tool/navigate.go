@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/index"
+)
+
+// gotoHelpText explains the goto prompt
+const gotoHelpText = `Enter jumps to the nearest record, Esc cancels.
+
+  123456 or 0x1E240   LSN (the record containing it)
+  @0x2000             file offset
+  #42                 record number
+  5:3                 space 5, page 3 (repeat to cycle through the page's records)`
+
+// initializeNavigation creates the goto prompt and the bookmark widgets
+func (app *RedoLogApp) initializeNavigation() {
+	app.recordIndex = index.New(app.records)
+
+	app.gotoInput = tview.NewInputField()
+	app.gotoInput.SetLabel("Go to: ")
+	app.gotoInput.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEnter {
+			app.applyGoto(app.gotoInput.GetText())
+		} else if key == tcell.KeyEscape {
+			app.hideNavigation()
+		}
+	})
+
+	app.gotoHelp = tview.NewTextView()
+	app.gotoHelp.SetBorder(true)
+	app.gotoHelp.SetTitle(" Go To ")
+
+	app.bookmarkInput = tview.NewInputField()
+	app.bookmarkInput.SetLabel("Bookmark name: ")
+	app.bookmarkInput.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEnter {
+			if err := app.addBookmark(app.bookmarkInput.GetText()); err != nil {
+				app.gotoHelp.SetTextColor(app.theme.color("red"))
+				app.gotoHelp.SetText("Error: " + err.Error())
+				return
+			}
+			app.hideNavigation()
+		} else if key == tcell.KeyEscape {
+			app.hideNavigation()
+		}
+	})
+
+	app.bookmarkList = tview.NewList()
+	app.bookmarkList.SetBorder(true)
+	app.bookmarkList.ShowSecondaryText(false)
+	app.bookmarkList.SetSelectedFunc(func(i int, _ string, _ string, _ rune) {
+		if app.bookmarks != nil && i < len(app.bookmarks.Bookmarks) {
+			app.hideNavigation()
+			app.gotoBookmark(app.bookmarks.Bookmarks[i].Name)
+		}
+	})
+	app.bookmarkList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case app.closesPanel(event, "bookmarks"):
+			app.hideNavigation()
+		case event.Rune() == 'd':
+			app.deleteBookmark(app.bookmarkList.GetCurrentItem())
+		default:
+			return event
+		}
+		return nil
+	})
+}
+
+// hideNavigation returns to the main layout
+func (app *RedoLogApp) hideNavigation() {
+	app.app.SetRoot(app.mainLayout(), true)
+	app.app.SetFocus(app.recordList)
+}
+
+// showGotoPrompt opens the goto prompt
+func (app *RedoLogApp) showGotoPrompt() {
+	app.gotoInput.SetText("")
+	app.gotoHelp.SetTextColor(app.theme.color("white"))
+	app.gotoHelp.SetText(gotoHelpText)
+
+	flex := tview.NewFlex().SetDirection(tview.FlexRow)
+	flex.AddItem(app.gotoInput, 1, 0, true)
+	flex.AddItem(app.gotoHelp, 0, 1, false)
+
+	app.app.SetRoot(flex, true)
+	app.app.SetFocus(app.gotoInput)
+}
+
+// applyGoto jumps to target, keeping the prompt open on errors
+func (app *RedoLogApp) applyGoto(text string) {
+	if err := app.gotoTarget(text); err != nil {
+		app.gotoHelp.SetTextColor(app.theme.color("red"))
+		app.gotoHelp.SetText(fmt.Sprintf("Error: %v\n\n%s", err, gotoHelpText))
+		return
+	}
+	app.hideNavigation()
+}
+
+// gotoTarget selects the filtered record nearest to the target given as text
+func (app *RedoLogApp) gotoTarget(text string) error {
+	target, err := index.ParseTarget(text)
+	if err != nil {
+		return err
+	}
+
+	from := -1
+	if current := app.recordList.GetCurrentItem(); current >= 0 && current < len(app.recordIndices) {
+		from = app.recordIndices[current]
+	}
+	position, err := app.recordIndex.Find(target, from)
+	if err != nil {
+		return err
+	}
+	return app.selectRecord(position)
+}
+
+// selectRecord selects the filtered record nearest to app.records[position]
+func (app *RedoLogApp) selectRecord(position int) error {
+	if len(app.recordIndices) == 0 {
+		return fmt.Errorf("no records match the current filters")
+	}
+
+	i := sort.SearchInts(app.recordIndices, position)
+	if i == len(app.recordIndices) || (i > 0 && position-app.recordIndices[i-1] < app.recordIndices[i]-position) {
+		i--
+	}
+	app.recordList.SetCurrentItem(i)
+	app.showRecordDetails(i)
+	return nil
+}
+
+// showBookmarkPrompt asks for the name of a bookmark on the selected record
+func (app *RedoLogApp) showBookmarkPrompt() {
+	app.bookmarkInput.SetText("")
+	app.gotoHelp.SetTextColor(app.theme.color("white"))
+	if app.bookmarks == nil {
+		app.gotoHelp.SetText("Bookmarks are not available for this log")
+	} else {
+		app.gotoHelp.SetText(fmt.Sprintf("Enter bookmarks the selected record, reusing a name moves the bookmark, Esc cancels.\n\nSaved to %s\n\n%s",
+			app.bookmarks.Path(), strings.Join(app.bookmarkLabels(), "\n")))
+	}
+
+	flex := tview.NewFlex().SetDirection(tview.FlexRow)
+	flex.AddItem(app.bookmarkInput, 1, 0, true)
+	flex.AddItem(app.gotoHelp, 0, 1, false)
+
+	app.app.SetRoot(flex, true)
+	app.app.SetFocus(app.bookmarkInput)
+}
+
+// addBookmark bookmarks the selected record as name and saves the bookmarks
+func (app *RedoLogApp) addBookmark(name string) error {
+	if app.bookmarks == nil {
+		return fmt.Errorf("bookmarks are not available for this log")
+	}
+	current := app.recordList.GetCurrentItem()
+	if current < 0 || current >= len(app.filteredRecords) {
+		return fmt.Errorf("no record selected")
+	}
+
+	err := app.bookmarks.Set(index.Bookmark{
+		Name:    name,
+		LSN:     app.filteredRecords[current].LSN,
+		Record:  app.recordIndices[current] + 1,
+		Created: time.Now().UTC().Truncate(time.Second),
+	})
+	if err != nil {
+		return err
+	}
+	return app.bookmarks.Save()
+}
+
+// gotoBookmark selects the record of the bookmark called name
+func (app *RedoLogApp) gotoBookmark(name string) error {
+	if app.bookmarks == nil {
+		return fmt.Errorf("bookmarks are not available for this log")
+	}
+	bookmark, ok := app.bookmarks.Get(name)
+	if !ok {
+		return fmt.Errorf("no bookmark %q in %s", name, app.bookmarks.Path())
+	}
+
+	position, err := app.recordIndex.FindBookmark(bookmark)
+	if err != nil {
+		return err
+	}
+	return app.selectRecord(position)
+}
+
+// deleteBookmark deletes the i-th bookmark and saves the bookmarks
+func (app *RedoLogApp) deleteBookmark(i int) {
+	if app.bookmarks == nil || i < 0 || i >= len(app.bookmarks.Bookmarks) {
+		return
+	}
+	app.bookmarks.Delete(app.bookmarks.Bookmarks[i].Name)
+	if err := app.bookmarks.Save(); err != nil {
+		app.bookmarkList.SetTitle(fmt.Sprintf(" Bookmarks - %v ", err))
+		return
+	}
+	app.refreshBookmarkList()
+}
+
+// showBookmarkList lists the bookmarks of the log
+func (app *RedoLogApp) showBookmarkList() {
+	app.refreshBookmarkList()
+	app.app.SetRoot(app.bookmarkList, true)
+	app.app.SetFocus(app.bookmarkList)
+}
+
+// refreshBookmarkList refills the bookmark list
+func (app *RedoLogApp) refreshBookmarkList() {
+	app.bookmarkList.Clear()
+	app.bookmarkList.SetTitle(" Bookmarks (Enter jumps, d deletes, Esc closes) ")
+	for _, label := range app.bookmarkLabels() {
+		app.bookmarkList.AddItem(tview.Escape(label), "", 0, nil)
+	}
+}
+
+// bookmarkLabels describes each bookmark on one line
+func (app *RedoLogApp) bookmarkLabels() []string {
+	if app.bookmarks == nil {
+		return nil
+	}
+	labels := make([]string, 0, len(app.bookmarks.Bookmarks))
+	for _, bookmark := range app.bookmarks.Bookmarks {
+		labels = append(labels, fmt.Sprintf("%-20s LSN %-12d record #%-8d %s",
+			bookmark.Name, bookmark.LSN, bookmark.Record, bookmark.Created.Format(time.RFC3339)))
+	}
+	return labels
+}
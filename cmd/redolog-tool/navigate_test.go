@@ -0,0 +1,61 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/index"
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+func TestGotoAndBookmarks(t *testing.T) {
+	records := []*types.LogRecord{
+		{Type: types.LogType(9), LSN: 100, SpaceID: 5, PageNo: 3},
+		{Type: types.LogType(1), LSN: 110},
+		{Type: types.LogType(13), LSN: 120, SpaceID: 5, PageNo: 3},
+		{Type: types.LogType(1), LSN: 130},
+	}
+	app := NewRedoLogApp(records, &types.RedoLogHeader{})
+
+	require.NoError(t, app.gotoTarget("0x73")) // LSN 115
+	assert.Equal(t, 1, app.recordList.GetCurrentItem())
+	require.NoError(t, app.gotoTarget("#4"))
+	assert.Equal(t, 3, app.recordList.GetCurrentItem())
+	require.NoError(t, app.gotoTarget("5:3"))
+	assert.Equal(t, 0, app.recordList.GetCurrentItem())
+	require.NoError(t, app.gotoTarget("5:3"))
+	assert.Equal(t, 2, app.recordList.GetCurrentItem())
+	assert.Error(t, app.gotoTarget("9:9"))
+	assert.Error(t, app.gotoTarget("lsn"))
+
+	// Targets hidden by a filter land on the nearest visible record
+	app.toggleOperationFilter("insert")
+	require.NoError(t, app.gotoTarget("#3"))
+	assert.Equal(t, 0, app.recordList.GetCurrentItem())
+	app.toggleOperationFilter("insert")
+
+	// Bookmarks persist and reopen at their record
+	logFile := filepath.Join(t.TempDir(), "ib_logfile0")
+	bookmarks, err := index.LoadBookmarks(index.BookmarksPath(logFile), logFile)
+	require.NoError(t, err)
+	app.bookmarks = bookmarks
+
+	require.NoError(t, app.gotoTarget("#3"))
+	require.NoError(t, app.addBookmark("bad-split"))
+	require.NoError(t, app.gotoTarget("#1"))
+
+	reloaded, err := index.LoadBookmarks(index.BookmarksPath(logFile), logFile)
+	require.NoError(t, err)
+	app.bookmarks = reloaded
+	require.NoError(t, app.gotoBookmark("bad-split"))
+	assert.Equal(t, 2, app.recordList.GetCurrentItem())
+	assert.Error(t, app.gotoBookmark("missing"))
+
+	app.showBookmarkList()
+	assert.Equal(t, 1, app.bookmarkList.GetItemCount())
+	app.deleteBookmark(0)
+	assert.Equal(t, 0, app.bookmarkList.GetItemCount())
+}
@@ -0,0 +1,577 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/export"
+	"github.com/yamaru/innodb-redolog-tool/internal/filter"
+	"github.com/yamaru/innodb-redolog-tool/internal/index"
+	"github.com/yamaru/innodb-redolog-tool/internal/reader"
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+	"github.com/yamaru/innodb-redolog-tool/pkg/redolog"
+)
+
+// Page sizes of the records endpoint
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+// runServe implements the serve subcommand
+func runServe(args []string) error {
+	fs := newFlagSet("serve", "[flags]")
+	listen := fs.String("listen", ":8080", "Address to listen on")
+	data := fs.String("data", "", "Redo log file, or a directory holding redo log files (data directory, #innodb_redo or ib_logfile files)")
+	maxRecords := fs.Int("max-records", 0, "Maximum number of records to read per file (0 = no limit)")
+	verbose := fs.Bool("v", false, "Log requests and file loads to stderr")
+	var keyring string
+	addKeyringFlag(fs, &keyring)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return newUsageError("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if *data == "" {
+		return newUsageError("no data path given (see 'redolog-tool help serve')")
+	}
+
+	s, err := newServer(*data, loadOptions{MaxRecords: *maxRecords, Verbose: *verbose, Keyring: keyring})
+	if err != nil {
+		return err
+	}
+	files, err := s.logFiles()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Serving %d redo log file(s) from %s on %s\n", len(files), *data, *listen)
+	return http.ListenAndServe(*listen, s.handler())
+}
+
+// server serves the redo log files of a file or directory over HTTP. Files
+// are read when first requested and read again once they change.
+type server struct {
+	data    string           // File or directory given with --data
+	options []redolog.Option // Options every file is opened with
+	verbose bool
+
+	mu     sync.Mutex
+	loaded map[string]*servedFile // By file path
+}
+
+// servedFile is a file read by the server, with the index its records are
+// paged through
+type servedFile struct {
+	name        string
+	path        string
+	size        int64
+	modified    time.Time
+	format      redolog.Format
+	redoFormat  *redolog.RedoFormat
+	header      *types.RedoLogHeader
+	checkpoints []redolog.Checkpoint
+	encryption  *redolog.EncryptionInfo
+	warnings    []string
+	readErr     error // Why reading stopped before the end of the file
+	records     []*types.LogRecord
+	index       *index.Index
+}
+
+// newServer creates a server for the files of data
+func newServer(data string, opts loadOptions) (*server, error) {
+	s := &server{
+		data:    data,
+		options: []redolog.Option{redolog.WithMaxRecords(opts.MaxRecords)},
+		verbose: opts.Verbose,
+		loaded:  make(map[string]*servedFile),
+	}
+	if opts.Keyring != "" {
+		keyring, err := redolog.LoadKeyring(opts.Keyring)
+		if err != nil {
+			return nil, err
+		}
+		s.options = append(s.options, redolog.WithKeyring(keyring))
+	}
+	return s, nil
+}
+
+// handler returns the routes of the API
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /api/files", apiHandler(s.handleFiles))
+	mux.Handle("GET /api/files/{name}/header", apiHandler(s.handleHeader))
+	mux.Handle("GET /api/files/{name}/records", apiHandler(s.handleRecords))
+	mux.Handle("GET /api/files/{name}/records/{n}", apiHandler(s.handleRecord))
+	mux.Handle("GET /api/files/{name}/stats", apiHandler(s.handleStats))
+	mux.Handle("GET /api/files/{name}/pages/{space}/{page}", apiHandler(s.handlePage))
+	mux.Handle("/", apiHandler(func(r *http.Request) (interface{}, error) {
+		return nil, newHTTPError(http.StatusNotFound, "no such endpoint: %s %s", r.Method, r.URL.Path)
+	}))
+	if !s.verbose {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(os.Stderr, "%s %s\n", r.Method, r.URL)
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// logFiles returns the paths of the files served
+func (s *server) logFiles() ([]string, error) {
+	return dataLogFiles(s.data)
+}
+
+// dataLogFiles returns the redo log files of a --data path: the file
+// itself, or the log files of a directory in log order
+func dataLogFiles(data string) ([]string, error) {
+	info, err := os.Stat(data)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{data}, nil
+	}
+	return redolog.LogFiles(data)
+}
+
+// file returns the file named by the request, reading it unless it was
+// read before and has not changed since
+func (s *server) file(r *http.Request) (*servedFile, error) {
+	name := r.PathValue("name")
+	files, err := s.logFiles()
+	if err != nil {
+		return nil, err
+	}
+	path := ""
+	for _, file := range files {
+		if filepath.Base(file) == name {
+			path = file
+		}
+	}
+	if path == "" {
+		return nil, newHTTPError(http.StatusNotFound, "no redo log file %q", name)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f := s.loaded[path]; f != nil && f.size == info.Size() && f.modified.Equal(info.ModTime()) {
+		return f, nil
+	}
+	f, err := s.load(path, info)
+	if err != nil {
+		return nil, err
+	}
+	s.loaded[path] = f
+	return f, nil
+}
+
+// load reads all records of a file and indexes them
+func (s *server) load(path string, info os.FileInfo) (*servedFile, error) {
+	log, err := redolog.Open(path, s.options...)
+	if err != nil {
+		return nil, err
+	}
+	defer log.Close()
+
+	f := &servedFile{
+		name:        filepath.Base(path),
+		path:        path,
+		size:        info.Size(),
+		modified:    info.ModTime(),
+		format:      log.Format(),
+		redoFormat:  log.RedoFormat(),
+		header:      log.Header(),
+		checkpoints: log.Checkpoints(),
+		encryption:  log.Encryption(),
+	}
+	f.records, f.readErr = log.ReadAll()
+	f.warnings = log.Warnings()
+	f.index = index.New(f.records)
+	if s.verbose {
+		fmt.Fprintf(os.Stderr, "Loaded %d records from %s\n", len(f.records), path)
+	}
+	return f, nil
+}
+
+// fileInfo describes a file of the files endpoint
+type fileInfo struct {
+	Name     string    `json:"name"` // Name used in the URLs of the file
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	Loaded   bool      `json:"loaded"` // Records are read and indexed
+}
+
+// fileList is the result of the files endpoint
+type fileList struct {
+	Data  string     `json:"data"`
+	Files []fileInfo `json:"files"`
+}
+
+// handleFiles lists the files served, in log order
+func (s *server) handleFiles(r *http.Request) (interface{}, error) {
+	files, err := s.logFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	list := &fileList{Data: s.data, Files: make([]fileInfo, 0, len(files))}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		f := s.loaded[file]
+		list.Files = append(list.Files, fileInfo{
+			Name:     filepath.Base(file),
+			Path:     file,
+			Size:     info.Size(),
+			Modified: info.ModTime(),
+			Loaded:   f != nil && f.size == info.Size() && f.modified.Equal(info.ModTime()),
+		})
+	}
+	return list, nil
+}
+
+// checkpointInfo is a checkpoint block of a header
+type checkpointInfo struct {
+	Number  uint64 `json:"number"`
+	LSN     uint64 `json:"lsn"`
+	Offset  uint64 `json:"offset"`
+	BufSize uint64 `json:"buf_size"`
+	Valid   bool   `json:"valid"`
+}
+
+// fileHeader is the result of the header endpoint
+type fileHeader struct {
+	File           string                  `json:"file"`
+	Format         string                  `json:"format"`                // mysql, mariadb or test
+	RedoFormat     string                  `json:"redo_format,omitempty"` // Server versions of a MySQL format
+	FormatVersion  uint32                  `json:"format_version"`
+	LogGroupID     uint64                  `json:"log_group_id"`
+	StartLSN       uint64                  `json:"start_lsn"`
+	FileNo         uint32                  `json:"file_no"`
+	Created        time.Time               `json:"created"`
+	LastCheckpoint uint64                  `json:"last_checkpoint"`
+	Checkpoints    []checkpointInfo        `json:"checkpoints"`
+	Encryption     *redolog.EncryptionInfo `json:"encryption,omitempty"`
+	Records        int                     `json:"records"`
+	Warnings       []string                `json:"warnings"`
+	ReadError      string                  `json:"read_error,omitempty"` // Why reading stopped early
+}
+
+// handleHeader returns the header and checkpoints of a file
+func (s *server) handleHeader(r *http.Request) (interface{}, error) {
+	f, err := s.file(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &fileHeader{
+		File:           f.name,
+		Format:         f.format.String(),
+		FormatVersion:  f.header.Format,
+		LogGroupID:     f.header.LogGroupID,
+		StartLSN:       f.header.StartLSN,
+		FileNo:         f.header.FileNo,
+		Created:        f.header.Created,
+		LastCheckpoint: f.header.LastCheckpoint,
+		Checkpoints:    make([]checkpointInfo, 0, len(f.checkpoints)),
+		Encryption:     f.encryption,
+		Records:        len(f.records),
+		Warnings:       append([]string{}, f.warnings...),
+	}
+	if f.redoFormat != nil {
+		result.RedoFormat = f.redoFormat.Name
+	}
+	for _, cp := range f.checkpoints {
+		result.Checkpoints = append(result.Checkpoints, checkpointInfo(cp))
+	}
+	if f.readErr != nil {
+		result.ReadError = f.readErr.Error()
+	}
+	return result, nil
+}
+
+// recordPage is the result of the records endpoint
+type recordPage struct {
+	File    string               `json:"file"`
+	Total   int                  `json:"total"` // Records in the file
+	Filter  string               `json:"filter,omitempty"`
+	Records []*export.RecordLine `json:"records"`        // Record is the 1-based record number in the file
+	Next    string               `json:"next,omitempty"` // from value of the next page
+}
+
+// handleRecords returns a page of the records matching the from_lsn,
+// to_lsn and where parameters. A page starts at the record given by from
+// (an LSN, @file-offset or #record) or at from_lsn, and holds up to limit
+// records.
+func (s *server) handleRecords(r *http.Request) (interface{}, error) {
+	f, err := s.file(r)
+	if err != nil {
+		return nil, err
+	}
+	query := r.URL.Query()
+	selector, err := querySelector(query)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := queryInt(query, "limit", defaultPageLimit, maxPageLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	start := 0
+	switch {
+	case query.Get("from") != "":
+		target, err := index.ParseTarget(query.Get("from"))
+		if err != nil {
+			return nil, newHTTPError(http.StatusBadRequest, "invalid from: %v", err)
+		}
+		if start, err = f.position(target); err != nil {
+			return nil, err
+		}
+	case selector.from.set:
+		if start, err = f.position(index.Target{Kind: index.TargetLSN, Value: selector.from.value}); err != nil {
+			return nil, err
+		}
+	}
+
+	page := &recordPage{File: f.name, Total: len(f.records), Filter: selector.String(), Records: make([]*export.RecordLine, 0)}
+	for i := start; i < len(f.records); i++ {
+		if len(page.Records) == limit {
+			page.Next = index.Target{Kind: index.TargetRecord, Value: uint64(i) + 1}.String()
+			break
+		}
+		if selector.matches(f.records[i]) {
+			page.Records = append(page.Records, export.NewRecordLine(i+1, f.records[i]))
+		}
+	}
+	return page, nil
+}
+
+// position returns the position of the first record at or after target
+func (f *servedFile) position(target index.Target) (int, error) {
+	if target.Kind == index.TargetPage {
+		return 0, newHTTPError(http.StatusBadRequest, "from cannot be a page; use the pages endpoint")
+	}
+	if len(f.records) == 0 {
+		return 0, nil
+	}
+	i, err := f.index.Find(target, 0)
+	if err != nil {
+		return 0, newHTTPError(http.StatusBadRequest, "%v", err)
+	}
+	switch {
+	case target.Kind == index.TargetLSN && f.records[i].LSN < target.Value,
+		target.Kind == index.TargetOffset && uint64(f.records[i].FileOffset) < target.Value,
+		target.Kind == index.TargetRecord && target.Value > uint64(len(f.records)):
+		i++
+	}
+	return i, nil
+}
+
+// spanDetail is a decoded field of a record with its bytes
+type spanDetail struct {
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Offset int64  `json:"offset"` // File offset
+	Length int    `json:"length"`
+	Hex    string `json:"hex"`
+	Value  string `json:"value"` // Decoded value
+}
+
+// recordDetail is the result of the record endpoint
+type recordDetail struct {
+	*export.RecordLine
+	Hex    string       `json:"hex,omitempty"` // Bytes the record spans in the file, block headers included
+	Fields []spanDetail `json:"decoded_fields,omitempty"`
+}
+
+// handleRecord returns a record by its 1-based number, with its decoded
+// payload and the bytes it was decoded from
+func (s *server) handleRecord(r *http.Request) (interface{}, error) {
+	f, err := s.file(r)
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(r.PathValue("n"))
+	if err != nil || n < 1 {
+		return nil, newHTTPError(http.StatusBadRequest, "invalid record number %q", r.PathValue("n"))
+	}
+	if n > len(f.records) {
+		return nil, newHTTPError(http.StatusNotFound, "record %d is past the last record %d", n, len(f.records))
+	}
+
+	record := f.records[n-1]
+	detail := &recordDetail{RecordLine: export.NewRecordLine(n, record)}
+	start, end, ok := record.ByteRange()
+	if !ok {
+		return detail, nil
+	}
+	offset, data, err := reader.ReadBlocks(f.path, start, end)
+	if err != nil {
+		return nil, err
+	}
+	bytesAt := func(at int64, length int) []byte {
+		from, to := at-offset, at-offset+int64(length)
+		if from < 0 || to > int64(len(data)) {
+			return nil
+		}
+		return data[from:to]
+	}
+	detail.Hex = hex.EncodeToString(bytesAt(start, int(end-start)))
+	for _, span := range record.Spans {
+		b := bytesAt(span.Offset, span.Length)
+		detail.Fields = append(detail.Fields, spanDetail{
+			Name:   span.Name,
+			Kind:   strings.ReplaceAll(span.Kind.String(), " ", "_"),
+			Offset: span.Offset,
+			Length: span.Length,
+			Hex:    hex.EncodeToString(b),
+			Value:  reader.DescribeSpan(span, b),
+		})
+	}
+	return detail, nil
+}
+
+// handleStats returns the stats of the records matching the from_lsn,
+// to_lsn and where parameters
+func (s *server) handleStats(r *http.Request) (interface{}, error) {
+	f, err := s.file(r)
+	if err != nil {
+		return nil, err
+	}
+	selector, err := querySelector(r.URL.Query())
+	if err != nil {
+		return nil, err
+	}
+	return newStatsReport(f.name, selector.apply(f.records), f.header)
+}
+
+// pageHistory is the result of the pages endpoint
+type pageHistory struct {
+	File    string               `json:"file"`
+	SpaceID uint32               `json:"space_id"`
+	PageNo  uint32               `json:"page_no"`
+	Records []*export.RecordLine `json:"records"`
+}
+
+// handlePage returns the records touching a page, in log order
+func (s *server) handlePage(r *http.Request) (interface{}, error) {
+	f, err := s.file(r)
+	if err != nil {
+		return nil, err
+	}
+	target, err := index.ParseTarget(r.PathValue("space") + ":" + r.PathValue("page"))
+	if err != nil {
+		return nil, newHTTPError(http.StatusBadRequest, "%v", err)
+	}
+
+	history := &pageHistory{File: f.name, SpaceID: target.SpaceID, PageNo: target.PageNo, Records: make([]*export.RecordLine, 0)}
+	for _, i := range f.index.Page(target.SpaceID, target.PageNo) {
+		history.Records = append(history.Records, export.NewRecordLine(i+1, f.records[i]))
+	}
+	return history, nil
+}
+
+// querySelector returns the record selector of the from_lsn, to_lsn and
+// where query parameters
+func querySelector(query url.Values) (*recordSelector, error) {
+	s := &recordSelector{where: query.Get("where")}
+	for _, param := range []struct {
+		name  string
+		value *lsnValue
+	}{{"from_lsn", &s.from}, {"to_lsn", &s.to}} {
+		if text := query.Get(param.name); text != "" {
+			if err := param.value.Set(text); err != nil {
+				return nil, newHTTPError(http.StatusBadRequest, "invalid %s: %v", param.name, err)
+			}
+		}
+	}
+	if s.from.set && s.to.set && s.from.value > s.to.value {
+		return nil, newHTTPError(http.StatusBadRequest, "from_lsn %d is greater than to_lsn %d", s.from.value, s.to.value)
+	}
+	if strings.TrimSpace(s.where) != "" {
+		f, err := filter.Parse(s.where)
+		if err != nil {
+			var syntaxErr *filter.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				return nil, newHTTPError(http.StatusBadRequest, "invalid where: %v\n%s", err, syntaxErr.Context())
+			}
+			return nil, newHTTPError(http.StatusBadRequest, "invalid where: %v", err)
+		}
+		s.filter = f
+	}
+	return s, nil
+}
+
+// queryInt returns a positive integer query parameter, or def when it is
+// not set
+func queryInt(query url.Values, name string, def, max int) (int, error) {
+	text := query.Get(name)
+	if text == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil || n < 1 || n > max {
+		return 0, newHTTPError(http.StatusBadRequest, "invalid %s %q: expected 1 to %d", name, text, max)
+	}
+	return n, nil
+}
+
+// httpError is an error answered with a status other than 500
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+// newHTTPError creates an error answered with status
+func newHTTPError(status int, format string, args ...interface{}) error {
+	return &httpError{status: status, err: fmt.Errorf(format, args...)}
+}
+
+// apiError is the body of an error response
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// apiHandler handles a request and returns the value sent back as JSON
+type apiHandler func(r *http.Request) (interface{}, error)
+
+func (h apiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	v, err := h(r)
+	if err != nil {
+		status := http.StatusInternalServerError
+		var httpErr *httpError
+		if errors.As(err, &httpErr) {
+			status = httpErr.status
+		}
+		w.WriteHeader(status)
+		writeJSON(w, apiError{Error: err.Error()})
+		return
+	}
+	writeJSON(w, v)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/reader"
+	"github.com/yamaru/innodb-redolog-tool/test/fixtures"
+)
+
+// insert8028 returns an MLOG_REC_INSERT record of MySQL 8.0.28 and later
+// inserting value into page 5:page
+func insert8028(page byte, value string) []byte {
+	return append([]byte{
+		reader.MLogRecInsert8028, 5, page,
+		1, reader.IndexLogCompact, 0x00, 0x02, 0x00, 0x01, 0x80, 0x04, 0x00, 0x00,
+		0x63, 0x00, 0x06,
+	}, value...)
+}
+
+func TestServe(t *testing.T) {
+	dir := t.TempDir()
+	var records []byte
+	for _, insert := range [][]byte{insert8028(4, "abc"), insert8028(3, "def"), insert8028(4, "ghi")} {
+		records = append(records, insert...)
+	}
+	filename, err := fixtures.CreateMySQLLogFile(dir, reader.LogHeaderFormat8030, 8192, append(records, reader.MLogMultiRecEnd))
+	require.NoError(t, err)
+
+	s, err := newServer(dir, loadOptions{})
+	require.NoError(t, err)
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	get := func(path string, status int, v interface{}) {
+		t.Helper()
+		resp, err := http.Get(ts.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, status, resp.StatusCode, path)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+	}
+	file := "/api/files/" + url.PathEscape("#ib_redo0")
+
+	var files fileList
+	get("/api/files", http.StatusOK, &files)
+	require.Len(t, files.Files, 1)
+	assert.Equal(t, "#ib_redo0", files.Files[0].Name)
+	assert.Equal(t, filename, files.Files[0].Path)
+	assert.False(t, files.Files[0].Loaded)
+
+	var header fileHeader
+	get(file+"/header", http.StatusOK, &header)
+	assert.Equal(t, "mysql", header.Format)
+	assert.Equal(t, "MySQL 8.0.30+", header.RedoFormat)
+	assert.Equal(t, 3, header.Records)
+	require.Len(t, header.Checkpoints, 2)
+	assert.Equal(t, uint64(8192), header.Checkpoints[0].LSN)
+	assert.Empty(t, header.ReadError)
+
+	get("/api/files", http.StatusOK, &files)
+	assert.True(t, files.Files[0].Loaded)
+
+	// Pages follow on from the next record
+	var page recordPage
+	get(file+"/records?limit=2", http.StatusOK, &page)
+	assert.Equal(t, 3, page.Total)
+	require.Len(t, page.Records, 2)
+	assert.Equal(t, 1, page.Records[0].Record)
+	assert.Equal(t, "#3", page.Next)
+	next := page.Next
+	page = recordPage{}
+	get(file+"/records?limit=2&from="+url.QueryEscape(next), http.StatusOK, &page)
+	require.Len(t, page.Records, 1)
+	assert.Equal(t, 3, page.Records[0].Record)
+	assert.Empty(t, page.Next)
+
+	// Filters and LSN ranges select records
+	get(file+"/records?where="+url.QueryEscape("page == 4"), http.StatusOK, &page)
+	require.Len(t, page.Records, 2)
+	assert.Equal(t, 3, page.Records[1].Record)
+	assert.Equal(t, "page == 4", page.Filter)
+	get(file+"/records?from_lsn="+page.Records[1].LSN, http.StatusOK, &page)
+	require.Len(t, page.Records, 1)
+	assert.Equal(t, 3, page.Records[0].Record)
+
+	var detail recordDetail
+	get(file+"/records/2", http.StatusOK, &detail)
+	assert.Equal(t, "MLOG_REC_INSERT", detail.Type)
+	assert.Equal(t, uint32(3), detail.PageNo)
+	content, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	start := detail.Span.Start
+	assert.Equal(t, hex.EncodeToString(insert8028(3, "def")), detail.Hex)
+	assert.Equal(t, hex.EncodeToString(content[start:detail.Span.End]), detail.Hex)
+	require.NotEmpty(t, detail.Fields)
+	assert.Equal(t, "type", detail.Fields[0].Name)
+	assert.Equal(t, hex.EncodeToString([]byte{reader.MLogRecInsert8028}), detail.Fields[0].Hex)
+
+	var stats statsReport
+	get(file+"/stats?where="+url.QueryEscape("page == 3"), http.StatusOK, &stats)
+	assert.Equal(t, "#ib_redo0", stats.File)
+	assert.Equal(t, uint64(1), stats.TotalRecords)
+
+	var history pageHistory
+	get(file+"/pages/5/4", http.StatusOK, &history)
+	require.Len(t, history.Records, 2)
+	assert.Equal(t, 1, history.Records[0].Record)
+	assert.Equal(t, 3, history.Records[1].Record)
+
+	// Errors are reported as JSON with a matching status
+	var apiErr apiError
+	get("/api/files/nosuch/header", http.StatusNotFound, &apiErr)
+	assert.Contains(t, apiErr.Error, "no redo log file")
+	get(file+"/records/9", http.StatusNotFound, &apiErr)
+	get(file+"/records?where="+url.QueryEscape("space =="), http.StatusBadRequest, &apiErr)
+	assert.Contains(t, apiErr.Error, "invalid where")
+	get(file+"/records?limit=0", http.StatusBadRequest, &apiErr)
+	get(file+"/records?from=5:4", http.StatusBadRequest, &apiErr)
+	get(file+"/pages/x/4", http.StatusBadRequest, &apiErr)
+	get("/api/nosuch", http.StatusNotFound, &apiErr)
+}
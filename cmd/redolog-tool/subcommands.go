@@ -0,0 +1,515 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/analyzer"
+	"github.com/yamaru/innodb-redolog-tool/internal/export"
+	"github.com/yamaru/innodb-redolog-tool/internal/index"
+	"github.com/yamaru/innodb-redolog-tool/internal/reader"
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// textPreviewLength is the number of data characters shown per record in text output
+const textPreviewLength = 60
+
+// runHeader implements the header subcommand
+func runHeader(args []string) error {
+	fs := newFlagSet("header", "[flags] <redo_log_file>")
+	in := addFileFlags(fs)
+	out := addOutputFlags(fs, "text", "json", "csv")
+	if _, err := in.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := out.validate(); err != nil {
+		return err
+	}
+
+	header, err := in.loadHeader()
+	if err != nil {
+		return err
+	}
+
+	return out.write(func(w io.Writer) error {
+		switch out.format {
+		case "json":
+			return writeJSON(w, header)
+		case "csv":
+			return writeCSVRows(w, []string{"field", "value"}, headerFields(header))
+		default:
+			for _, field := range headerFields(header) {
+				fmt.Fprintf(w, "%-16s %s\n", field[0]+":", field[1])
+			}
+			return nil
+		}
+	})
+}
+
+// headerFields returns the header as field/value pairs
+func headerFields(header *types.RedoLogHeader) [][]string {
+	return [][]string{
+		{"log_group_id", strconv.FormatUint(header.LogGroupID, 10)},
+		{"start_lsn", strconv.FormatUint(header.StartLSN, 10)},
+		{"file_no", strconv.FormatUint(uint64(header.FileNo), 10)},
+		{"created", header.Created.Format("2006-01-02 15:04:05")},
+		{"last_checkpoint", strconv.FormatUint(header.LastCheckpoint, 10)},
+		{"format", strconv.FormatUint(uint64(header.Format), 10)},
+	}
+}
+
+// runDump implements the dump subcommand
+func runDump(args []string) error {
+	fs := newFlagSet("dump", "[flags] <redo_log_file>")
+	in := addInputFlags(fs, 0)
+	selector := addSelectorFlags(fs)
+	out := addOutputFlags(fs, "text", "json", "ndjson", "csv")
+	if _, err := in.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := validateAll(out.validate, selector.validate); err != nil {
+		return err
+	}
+
+	records, header, err := in.load()
+	if err != nil {
+		return err
+	}
+
+	return writeRecords(out, selector.apply(records), header)
+}
+
+// runGrep implements the grep subcommand
+func runGrep(args []string) error {
+	fs := newFlagSet("grep", "[flags] <pattern> <redo_log_file>")
+	in := addInputFlags(fs, 0)
+	selector := addSelectorFlags(fs)
+	out := addOutputFlags(fs, "text", "json", "ndjson", "csv")
+	ignoreCase := fs.Bool("i", false, "Case insensitive match")
+	positional, err := in.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := validateAll(out.validate, selector.validate); err != nil {
+		return err
+	}
+
+	expr := positional[0]
+	if *ignoreCase {
+		expr = "(?i)" + expr
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return newUsageError("invalid pattern: %v", err)
+	}
+
+	records, header, err := in.load()
+	if err != nil {
+		return err
+	}
+
+	matches := make([]*types.LogRecord, 0)
+	for _, record := range selector.apply(records) {
+		if pattern.Match(record.Data) {
+			matches = append(matches, record)
+		}
+	}
+
+	return writeRecords(out, matches, header)
+}
+
+// runExport implements the export subcommand
+func runExport(args []string) error {
+	fs := newFlagSet("export", "[flags] <redo_log_file>")
+	in := addInputFlags(fs, 0)
+	selector := addSelectorFlags(fs)
+	out := addOutputFlags(fs, export.Formats...)
+	var parquet export.ParquetOptions
+	fs.IntVar(&parquet.RowGroupRows, "row-group-rows", export.DefaultRowGroupRows, "Records per Parquet row group")
+	fs.StringVar(&parquet.Compression, "compression", export.ParquetCompressions[0], "Parquet page compression: "+strings.Join(export.ParquetCompressions, ", "))
+	if _, err := in.parse(fs, args, 0); err != nil {
+		return err
+	}
+	validateParquet := func() error {
+		if parquet.RowGroupRows <= 0 {
+			return newUsageError("--row-group-rows must be positive")
+		}
+		for _, compression := range export.ParquetCompressions {
+			if parquet.Compression == compression {
+				return nil
+			}
+		}
+		return newUsageError("unsupported compression %q (supported: %s)", parquet.Compression, strings.Join(export.ParquetCompressions, ", "))
+	}
+	if err := validateAll(out.validate, selector.validate, validateParquet); err != nil {
+		return err
+	}
+
+	records, header, err := in.load()
+	if err != nil {
+		return err
+	}
+
+	meta := &export.Metadata{Source: in.file, Filter: selector.String(), Exported: time.Now().UTC().Truncate(time.Second)}
+	return out.write(func(w io.Writer) error {
+		if out.format == "parquet" {
+			return export.WriteParquet(w, selector.apply(records), meta, parquet)
+		}
+		return export.Write(w, out.format, selector.apply(records), header, meta)
+	})
+}
+
+// runSchema implements the schema subcommand
+func runSchema(args []string) error {
+	fs := newFlagSet("schema", "[flags]")
+	output := fs.String("output", "", "Output file (default: stdout)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return newUsageError("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	out := &outputFlags{output: *output}
+	return out.write(func(w io.Writer) error {
+		_, err := w.Write(export.NDJSONSchema)
+		return err
+	})
+}
+
+// runTUI implements the tui subcommand
+func runTUI(args []string) error {
+	fs := newFlagSet("tui", "[flags] <redo_log_file>")
+	in := addInputFlags(fs, defaultTUIMaxRecords)
+	selector := addSelectorFlags(fs)
+	dictionaryFile := fs.String("dictionary", "", "JSON file mapping space IDs to table names (used by the 'w' panel)")
+	var anchors anchorList
+	fs.Var(&anchors, "anchor", "LSN@TIME pair mapping an LSN to RFC 3339 time; repeat for at least two")
+	gotoTarget := fs.String("goto", "", "Open at an LSN, @file-offset, #record or space:page")
+	bookmark := fs.String("bookmark", "", "Open at the named bookmark")
+	bookmarksFile := fs.String("bookmarks", "", "Bookmark file (default: <redo_log_file>"+index.BookmarksSuffix+")")
+	configFile := fs.String("config", "", "JSON file with keys, theme and layout (default: "+defaultTUIConfigPath()+" if it exists)")
+	themeName := fs.String("theme", "", "Colour theme: "+strings.Join(themeNames(), ", ")+" (overrides the config file)")
+	if _, err := in.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if in.file == stdinFile {
+		return newUsageError("the TUI needs a redo log file; it cannot read stdin")
+	}
+	if err := selector.validate(); err != nil {
+		return err
+	}
+	if *gotoTarget != "" && *bookmark != "" {
+		return newUsageError("--goto and --bookmark cannot be combined")
+	}
+	if *bookmarksFile == "" {
+		*bookmarksFile = index.BookmarksPath(in.file)
+	}
+	bookmarks, err := index.LoadBookmarks(*bookmarksFile, in.file)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadTUIConfig(*configFile, *themeName)
+	if err != nil {
+		return newUsageError("%v", err)
+	}
+
+	dict, err := loadDictionary(*dictionaryFile)
+	if err != nil {
+		return err
+	}
+
+	records, header, err := in.load()
+	if err != nil {
+		return err
+	}
+
+	// The LSN range limits what is loaded; the expression becomes the TUI's
+	// editable filter so it can be changed with 'f'
+	where := selector.filter
+	selector.filter = nil
+
+	clock, err := anchors.clock()
+	if err != nil {
+		return err
+	}
+
+	blockFormat, err := reader.UsesLogBlocks(in.file)
+	if err != nil {
+		return err
+	}
+
+	app := NewRedoLogAppWithConfig(selector.apply(records), header, cfg)
+	app.dictionary = dict
+	app.clock = clock
+	app.filename = in.file
+	app.blockFormat = blockFormat
+	app.bookmarks = bookmarks
+	if where != nil {
+		app.setWhereFilter(where)
+	}
+	if *gotoTarget != "" {
+		if err := app.gotoTarget(*gotoTarget); err != nil {
+			return newUsageError("invalid --goto: %v", err)
+		}
+	}
+	if *bookmark != "" {
+		if err := app.gotoBookmark(*bookmark); err != nil {
+			return err
+		}
+	}
+	if err := app.Run(); err != nil {
+		return fmt.Errorf("error running application: %w", err)
+	}
+	return nil
+}
+
+// writeRecords writes records in the requested format
+func writeRecords(out *outputFlags, records []*types.LogRecord, header *types.RedoLogHeader) error {
+	return out.write(func(w io.Writer) error {
+		switch out.format {
+		case "json", "ndjson", "csv":
+			return export.Write(w, out.format, records, header, nil)
+		default:
+			return writeRecordsText(w, records)
+		}
+	})
+}
+
+// writeRecordsText writes one line per record
+func writeRecordsText(w io.Writer, records []*types.LogRecord) error {
+	fmt.Fprintf(w, "%-12s %-36s %8s %8s %8s %6s  %s\n", "LSN", "TYPE", "SPACE", "PAGE", "LENGTH", "GROUP", "DATA")
+	for _, record := range records {
+		preview := []rune(string(record.Data))
+		if len(preview) > textPreviewLength {
+			preview = append(preview[:textPreviewLength], []rune("...")...)
+		}
+		_, err := fmt.Fprintf(w, "%-12d %-36s %8d %8d %8d %6d  %q\n",
+			record.LSN, record.Type.String(), record.SpaceID, record.PageNo, record.Length,
+			record.MultiRecordGroup, string(preview))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statsReport is the stats of the records of a file
+type statsReport struct {
+	File string `json:"file"`
+	*analyzer.StatsReport
+}
+
+// runStats implements the stats subcommand
+func runStats(args []string) error {
+	fs := newFlagSet("stats", "[flags] <redo_log_file>")
+	in := addInputFlags(fs, 0)
+	selector := addSelectorFlags(fs)
+	out := addOutputFlags(fs, "text", "json", "csv")
+	if _, err := in.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := validateAll(out.validate, selector.validate); err != nil {
+		return err
+	}
+
+	records, header, err := in.load()
+	if err != nil {
+		return err
+	}
+
+	report, err := newStatsReport(in.file, selector.apply(records), header)
+	if err != nil {
+		return err
+	}
+
+	return out.write(func(w io.Writer) error {
+		switch out.format {
+		case "json":
+			return writeJSON(w, report)
+		case "csv":
+			rows := make([][]string, 0, len(report.Types))
+			for _, tc := range report.Types {
+				rows = append(rows, []string{strconv.Itoa(int(tc.TypeID)), tc.Type, strconv.FormatUint(tc.Count, 10)})
+			}
+			return writeCSVRows(w, []string{"type_id", "type", "count"}, rows)
+		default:
+			return writeStatsText(w, report)
+		}
+	})
+}
+
+// newStatsReport builds the stats of records
+func newStatsReport(filename string, records []*types.LogRecord, header *types.RedoLogHeader) (*statsReport, error) {
+	report, err := analyzer.BuildStatsReport(records, header, analyzer.DefaultStatsTop)
+	if err != nil {
+		return nil, err
+	}
+	return &statsReport{File: filename, StatsReport: report}, nil
+}
+
+// writeStatsText writes a stats report as text
+func writeStatsText(w io.Writer, report *statsReport) error {
+	fmt.Fprintf(w, "File:          %s\n", report.File)
+	fmt.Fprintf(w, "Records:       %d\n", report.TotalRecords)
+	fmt.Fprintf(w, "Size:          %d bytes\n", report.SizeInBytes)
+	fmt.Fprintf(w, "LSN range:     %d - %d\n", report.FirstLSN, report.LastLSN)
+	fmt.Fprintf(w, "MTR groups:    %d\n", report.MTRGroups)
+	fmt.Fprintf(w, "Operations:    insert=%d update=%d delete=%d other=%d\n",
+		report.Operations["insert"], report.Operations["update"], report.Operations["delete"], report.Operations["other"])
+	fmt.Fprintf(w, "\nRecord types:\n")
+	for _, tc := range report.Types {
+		fmt.Fprintf(w, "  %3d %-36s %8d\n", tc.TypeID, tc.Type, tc.Count)
+	}
+
+	fmt.Fprintf(w, "\nTop spaces:\n")
+	for _, space := range report.Spaces {
+		fmt.Fprintf(w, "  space %-18d %8d records %10d bytes\n", space.SpaceID, space.Records, space.Bytes)
+	}
+	fmt.Fprintf(w, "\nTop pages:\n")
+	for _, page := range report.Pages {
+		fmt.Fprintf(w, "  %-24s %8d records %10d bytes\n", fmt.Sprintf("%d:%d", page.SpaceID, page.PageNo), page.Records, page.Bytes)
+	}
+	fmt.Fprintf(w, "\nMTR sizes (records per MTR):\n")
+	for _, bucket := range report.MTRSizes {
+		fmt.Fprintf(w, "  %-6s %8d\n", bucket.Label(), bucket.MTRs)
+	}
+
+	fmt.Fprintf(w, "\n")
+	if cp := report.Checkpoint; cp != nil {
+		fmt.Fprintf(w, "Checkpoint:    LSN %d, age %d bytes\n", cp.CheckpointLSN, cp.Age)
+	} else {
+		fmt.Fprintf(w, "Checkpoint:    unknown\n")
+	}
+	cov := report.Coverage
+	fmt.Fprintf(w, "LSN coverage:  %d of %d bytes (%.1f%%), %d gaps, largest %d bytes\n",
+		cov.RecordBytes, cov.SpanBytes, cov.Percent, cov.Gaps, cov.LargestGap)
+	fmt.Fprintf(w, "Corruption:    %d issues (severity %s)\n", report.Corruption.Issues, report.Corruption.Severity)
+	for _, issue := range report.Corruption.Examples {
+		fmt.Fprintf(w, "  LSN %d: %s: %s\n", issue.LSN, issue.IssueType, issue.Description)
+	}
+	return nil
+}
+
+// verifyReport is the result of the verify subcommand
+type verifyReport struct {
+	File       string                     `json:"file"`
+	Records    int                        `json:"records"`
+	Corruption *analyzer.CorruptionReport `json:"corruption"`
+	Warnings   []string                   `json:"warnings"`
+}
+
+// runVerify implements the verify subcommand
+func runVerify(args []string) error {
+	fs := newFlagSet("verify", "[flags] <redo_log_file>")
+	in := addInputFlags(fs, 0)
+	selector := addSelectorFlags(fs)
+	out := addOutputFlags(fs, "text", "json", "csv")
+	if _, err := in.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := validateAll(out.validate, selector.validate); err != nil {
+		return err
+	}
+
+	records, header, loadErr := in.load()
+	if header == nil {
+		return loadErr
+	}
+	records = selector.apply(records)
+
+	result, err := analyzer.NewRedoLogAnalyzer().AnalyzeRecords(records)
+	if err != nil {
+		return err
+	}
+	report := &verifyReport{
+		File:       in.file,
+		Records:    len(records),
+		Corruption: result.Corruption,
+		Warnings:   result.Warnings,
+	}
+	if loadErr != nil {
+		// The record stream stopped early; everything read so far was still checked
+		analyzer.AddReadError(report.Corruption, records, loadErr)
+	}
+
+	err = out.write(func(w io.Writer) error {
+		switch out.format {
+		case "json":
+			return writeJSON(w, report)
+		case "csv":
+			rows := make([][]string, 0, len(report.Corruption.CorruptedRecords))
+			for _, issue := range report.Corruption.CorruptedRecords {
+				rows = append(rows, []string{
+					strconv.Itoa(issue.RecordIndex), strconv.FormatUint(issue.LSN, 10),
+					issue.IssueType, issue.Severity.String(), issue.Description,
+				})
+			}
+			return writeCSVRows(w, []string{"record_index", "lsn", "issue", "severity", "description"}, rows)
+		default:
+			return writeVerifyText(w, report)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if report.Corruption.HasCorruption {
+		return errVerifyFailed
+	}
+	return nil
+}
+
+// writeVerifyText writes a verify report as text
+func writeVerifyText(w io.Writer, report *verifyReport) error {
+	for _, issue := range report.Corruption.CorruptedRecords {
+		fmt.Fprintf(w, "%-6s record %d (LSN %d): %s: %s\n",
+			issue.Severity, issue.RecordIndex, issue.LSN, issue.IssueType, issue.Description)
+	}
+	for _, warning := range report.Warnings {
+		fmt.Fprintf(w, "warning: %s\n", warning)
+	}
+
+	if report.Corruption.HasCorruption {
+		fmt.Fprintf(w, "FAILED: %d issues in %d records (max severity %s)\n",
+			len(report.Corruption.CorruptedRecords), report.Records, report.Corruption.Severity)
+	} else {
+		fmt.Fprintf(w, "OK: %d records verified\n", report.Records)
+	}
+	return nil
+}
+
+// validateAll runs validators in order and returns the first error
+func validateAll(validators ...func() error) error {
+	for _, validate := range validators {
+		if err := validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// writeCSVRows writes a header line followed by rows
+func writeCSVRows(w io.Writer, header []string, rows [][]string) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
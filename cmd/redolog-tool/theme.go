@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// theme is the palette of the TUI. The views colour text with tview tags
+// such as [yellow]; Colors replaces those colour names, so a theme can make
+// them readable on any terminal background.
+type theme struct {
+	Background string            `json:"background,omitempty"` // Pane background
+	Contrast   string            `json:"contrast,omitempty"`   // Input field background
+	Text       string            `json:"text,omitempty"`
+	Border     string            `json:"border,omitempty"`
+	Title      string            `json:"title,omitempty"`
+	Colors     map[string]string `json:"colors,omitempty"` // Tag colour name -> colour
+}
+
+// themes are the built-in themes
+var themes = map[string]*theme{
+	// The colours the views were designed with, for dark terminals
+	"default": {},
+	// Dark text on a white background
+	"light": {
+		Background: "white",
+		Contrast:   "lightgray",
+		Text:       "black",
+		Border:     "dimgray",
+		Title:      "navy",
+		Colors: map[string]string{
+			"white":   "black",
+			"yellow":  "#875f00",
+			"cyan":    "teal",
+			"aqua":    "teal",
+			"green":   "darkgreen",
+			"blue":    "navy",
+			"magenta": "purple",
+			"gray":    "dimgray",
+			"red":     "darkred",
+		},
+	},
+}
+
+// themeNames returns the names of the built-in themes
+func themeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newTheme returns the built-in theme called name with colours overridden
+func newTheme(name string, colors map[string]string) (*theme, error) {
+	base, ok := themes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown theme %q (themes: %s)", name, strings.Join(themeNames(), ", "))
+	}
+
+	t := *base
+	t.Colors = make(map[string]string, len(base.Colors)+len(colors))
+	for from, to := range base.Colors {
+		t.Colors[from] = to
+	}
+	for from, to := range colors {
+		t.Colors[strings.ToLower(from)] = to
+	}
+
+	for _, color := range append([]string{t.Background, t.Contrast, t.Text, t.Border, t.Title}, mapValues(t.Colors)...) {
+		if color != "" && !validColor(color) {
+			return nil, fmt.Errorf("unknown colour %q: use a W3C colour name or #rrggbb", color)
+		}
+	}
+	return &t, nil
+}
+
+func mapValues(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for _, value := range m {
+		values = append(values, value)
+	}
+	return values
+}
+
+// validColor reports whether tcell knows color
+func validColor(color string) bool {
+	return color == "default" || tcell.GetColor(color) != tcell.ColorDefault
+}
+
+// setStyles applies the theme to tview's default styles. It must be called
+// before the widgets are created.
+func (t *theme) setStyles() {
+	set := func(style *tcell.Color, color string) {
+		if color != "" {
+			*style = tcell.GetColor(color)
+		}
+	}
+	set(&tview.Styles.PrimitiveBackgroundColor, t.Background)
+	set(&tview.Styles.ContrastBackgroundColor, t.Contrast)
+	set(&tview.Styles.MoreContrastBackgroundColor, t.Contrast)
+	set(&tview.Styles.PrimaryTextColor, t.Text)
+	set(&tview.Styles.ContrastSecondaryTextColor, t.Text)
+	set(&tview.Styles.BorderColor, t.Border)
+	set(&tview.Styles.GraphicsColor, t.Border)
+	set(&tview.Styles.TitleColor, t.Title)
+	set(&tview.Styles.SecondaryTextColor, t.Colors["yellow"])
+	set(&tview.Styles.TertiaryTextColor, t.Colors["green"])
+}
+
+// colorTag matches a tview colour tag such as [yellow], [black:aqua:b] or [-:-:-]
+var colorTag = regexp.MustCompile(`\[([a-zA-Z]+|#[0-9a-fA-F]{6}|-)?(:([a-zA-Z]+|#[0-9a-fA-F]{6}|-)?)?(:[a-zA-Z\-]*)?\]`)
+
+// colorize replaces the colour names in the tags of text
+func (t *theme) colorize(text string) string {
+	if t == nil || len(t.Colors) == 0 {
+		return text
+	}
+	return colorTag.ReplaceAllStringFunc(text, func(tag string) string {
+		m := colorTag.FindStringSubmatch(tag)
+		fg, bg := t.tagColor(m[1]), t.tagColor(m[3])
+		if m[2] != "" {
+			bg = ":" + bg
+		}
+		return "[" + fg + bg + m[4] + "]"
+	})
+}
+
+// tagColor returns the replacement of a tag colour name
+func (t *theme) tagColor(name string) string {
+	if color, ok := t.Colors[strings.ToLower(name)]; ok {
+		return color
+	}
+	return name
+}
+
+// color returns the replacement of a colour name as a tcell colour
+func (t *theme) color(name string) tcell.Color {
+	if t != nil {
+		name = t.tagColor(name)
+	}
+	return tcell.GetColor(name)
+}
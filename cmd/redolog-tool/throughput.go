@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/analyzer"
+)
+
+// sparkBars are the levels of a sparkline, lowest first
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// anchorList is a repeatable flag.Value of LSN@TIME anchors
+type anchorList []analyzer.TimeAnchor
+
+func (l *anchorList) String() string {
+	values := make([]string, 0, len(*l))
+	for _, anchor := range *l {
+		values = append(values, anchor.String())
+	}
+	return strings.Join(values, ",")
+}
+
+func (l *anchorList) Set(s string) error {
+	anchor, err := analyzer.ParseTimeAnchor(s)
+	if err != nil {
+		return err
+	}
+	*l = append(*l, anchor)
+	return nil
+}
+
+// clock returns the clock of the anchors, or nil when none are given: redo
+// records carry no wall-clock time to derive one from
+func (l anchorList) clock() (*analyzer.LSNClock, error) {
+	if len(l) == 0 {
+		return nil, nil
+	}
+	clock, err := analyzer.NewLSNClock(l)
+	if err != nil {
+		return nil, newUsageError("invalid --anchor: %v", err)
+	}
+	return clock, nil
+}
+
+// throughputReport is the result of the throughput subcommand
+type throughputReport struct {
+	File string `json:"file"`
+	*analyzer.ThroughputReport
+}
+
+// runThroughput implements the throughput subcommand
+func runThroughput(args []string) error {
+	fs := newFlagSet("throughput", "[flags] <redo_log_file>")
+	in := addInputFlags(fs, 0)
+	selector := addSelectorFlags(fs)
+	out := addOutputFlags(fs, "text", "json", "csv")
+	var windowLSN lsnValue
+	fs.Var(&windowLSN, "window-lsn", fmt.Sprintf("LSN width of a window (default: split the log into %d windows)", analyzer.DefaultThroughputWindows))
+	interval := fs.Duration("interval", 0, "Bucket by time instead of LSN, e.g. 1s or 5m (needs --anchor)")
+	var anchors anchorList
+	fs.Var(&anchors, "anchor", "LSN@TIME pair mapping an LSN to RFC 3339 time; repeat for at least two")
+	topPages := fs.Int("top-pages", 3, "Pages listed per window")
+	if _, err := in.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := validateAll(out.validate, selector.validate); err != nil {
+		return err
+	}
+	if windowLSN.set && *interval > 0 {
+		return newUsageError("--window-lsn and --interval cannot be combined")
+	}
+	if *interval < 0 || *topPages < 0 || (windowLSN.set && windowLSN.value == 0) {
+		return newUsageError("--window-lsn, --interval and --top-pages must be positive")
+	}
+
+	records, _, err := in.load()
+	if err != nil {
+		return err
+	}
+	records = selector.apply(records)
+
+	clock, err := anchors.clock()
+	if err != nil {
+		return err
+	}
+	if *interval > 0 && clock == nil {
+		return newUsageError("--interval needs LSN to time anchors: pass --anchor LSN@TIME twice")
+	}
+
+	result, err := analyzer.AnalyzeThroughput(records, analyzer.ThroughputConfig{
+		WindowLSN: windowLSN.value,
+		Interval:  *interval,
+		Clock:     clock,
+		TopPages:  *topPages,
+	})
+	if err != nil {
+		return err
+	}
+	report := &throughputReport{File: in.file, ThroughputReport: result}
+
+	return out.write(func(w io.Writer) error {
+		switch out.format {
+		case "json":
+			return writeJSON(w, report)
+		case "csv":
+			return writeCSVRows(w, throughputCSVHeader(), throughputCSVRows(result))
+		default:
+			fmt.Fprintf(w, "File: %s\n", report.File)
+			return writeThroughputText(w, result)
+		}
+	})
+}
+
+// writeThroughputText writes sparklines of the series followed by one line per window
+func writeThroughputText(w io.Writer, report *analyzer.ThroughputReport) error {
+	if report.IntervalSeconds > 0 {
+		fmt.Fprintf(w, "Window: %s, time source: %s\n\n", time.Duration(report.IntervalSeconds*float64(time.Second)), report.TimeSource)
+	} else {
+		fmt.Fprintf(w, "Window: %d LSN, time source: %s\n\n", report.WindowLSN, report.TimeSource)
+	}
+	writeThroughputSparklines(w, report)
+
+	timed := report.TimeSource != analyzer.TimeSourceNone
+	fmt.Fprintf(w, "\n%12s %12s", "START_LSN", "END_LSN")
+	if timed {
+		fmt.Fprintf(w, " %-24s %10s", "START", "BYTES/S")
+	}
+	fmt.Fprintf(w, " %10s %8s %6s %7s %7s %7s  %s\n", "BYTES", "RECORDS", "MTRS", "INSERTS", "UPDATES", "DELETES", "TOP PAGES")
+
+	for _, window := range report.Windows {
+		fmt.Fprintf(w, "%12d %12d", window.StartLSN, window.EndLSN)
+		if timed {
+			fmt.Fprintf(w, " %-24s %10.0f", window.Start.Format("2006-01-02 15:04:05.000"), window.BytesPerSecond)
+		}
+		fmt.Fprintf(w, " %10d %8d %6d %7d %7d %7d  %s\n", window.Bytes, window.Records, window.MTRs,
+			window.Inserts, window.Updates, window.Deletes, formatTopPages(window.TopPages))
+	}
+	return nil
+}
+
+// writeThroughputSparklines writes one labelled sparkline per series
+func writeThroughputSparklines(w io.Writer, report *analyzer.ThroughputReport) {
+	for _, series := range throughputSeries(report) {
+		fmt.Fprintf(w, "%-8s %s  max %d\n", series.label, sparkline(series.values), maxValue(series.values))
+	}
+}
+
+// throughputSeriesValues is a named per-window series
+type throughputSeriesValues struct {
+	label  string
+	values []uint64
+}
+
+// throughputSeries extracts the series shown as sparklines
+func throughputSeries(report *analyzer.ThroughputReport) []throughputSeriesValues {
+	series := []throughputSeriesValues{{label: "bytes"}, {label: "mtrs"}, {label: "inserts"}, {label: "updates"}, {label: "deletes"}}
+	for _, window := range report.Windows {
+		series[0].values = append(series[0].values, window.Bytes)
+		series[1].values = append(series[1].values, window.MTRs)
+		series[2].values = append(series[2].values, window.Inserts)
+		series[3].values = append(series[3].values, window.Updates)
+		series[4].values = append(series[4].values, window.Deletes)
+	}
+	return series
+}
+
+// sparkline renders values as a strip of block characters scaled to the maximum
+func sparkline(values []uint64) string {
+	peak := maxValue(values)
+	var b strings.Builder
+	for _, value := range values {
+		if value == 0 {
+			b.WriteRune(' ')
+			continue
+		}
+		b.WriteRune(sparkBars[value*uint64(len(sparkBars)-1)/peak])
+	}
+	return b.String()
+}
+
+func maxValue(values []uint64) uint64 {
+	var peak uint64
+	for _, value := range values {
+		if value > peak {
+			peak = value
+		}
+	}
+	return peak
+}
+
+// formatTopPages formats pages as space:page=records
+func formatTopPages(pages []analyzer.PageCount) string {
+	values := make([]string, 0, len(pages))
+	for _, page := range pages {
+		values = append(values, fmt.Sprintf("%d:%d=%d", page.SpaceID, page.PageNo, page.Records))
+	}
+	return strings.Join(values, " ")
+}
+
+// throughputCSVHeader returns the CSV columns
+func throughputCSVHeader() []string {
+	return []string{"start_lsn", "end_lsn", "start", "end", "bytes", "bytes_per_second", "records", "mtrs", "inserts", "updates", "deletes", "top_pages"}
+}
+
+// throughputCSVRows returns one row per window
+func throughputCSVRows(report *analyzer.ThroughputReport) [][]string {
+	u := func(v uint64) string { return strconv.FormatUint(v, 10) }
+
+	rows := make([][]string, 0, len(report.Windows))
+	for _, window := range report.Windows {
+		var start, end, rate string
+		if window.Start != nil {
+			start = window.Start.Format(time.RFC3339Nano)
+			end = window.End.Format(time.RFC3339Nano)
+			rate = strconv.FormatFloat(window.BytesPerSecond, 'f', 1, 64)
+		}
+		rows = append(rows, []string{
+			u(window.StartLSN), u(window.EndLSN), start, end,
+			u(window.Bytes), rate, u(window.Records), u(window.MTRs),
+			u(window.Inserts), u(window.Updates), u(window.Deletes),
+			formatTopPages(window.TopPages),
+		})
+	}
+	return rows
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/analyzer"
+	"github.com/yamaru/innodb-redolog-tool/internal/export"
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// treeHelp lists the keys of the MTR tree
+const treeHelp = "Enter/→ expand, ← collapse, g/G first/last record, e/E export JSON/CSV, Esc closes"
+
+// treeGroup is a transaction or mini-transaction node of the MTR tree
+type treeGroup struct {
+	name    string // Label, e.g. "MTR 12"
+	file    string // Export file name without extension, e.g. "mtr-12"
+	records []*types.LogRecord
+	split   bool // Children are MTRs rather than records
+}
+
+// mtrTree shows the filtered records grouped by transaction, when the
+// records carry transaction IDs, and by mini-transaction. Group nodes show
+// their LSN range, record count, pages touched and operation mix; their
+// children are added when they are first expanded.
+type mtrTree struct {
+	app    *RedoLogApp
+	layout *tview.Flex
+	view   *tview.TreeView
+	status *tview.TextView
+
+	index map[*types.LogRecord]int // Filtered index of each record
+}
+
+// newMTRTree creates the tree widgets
+func newMTRTree(app *RedoLogApp) *mtrTree {
+	m := &mtrTree{app: app}
+
+	m.view = tview.NewTreeView()
+	m.view.SetBorder(true)
+	m.view.SetTitle(" Transactions & Mini-Transactions ")
+	m.view.SetSelectedFunc(m.activate)
+	m.view.SetInputCapture(m.handleKey)
+
+	m.status = tview.NewTextView()
+	m.status.SetBorder(true)
+	m.status.SetDynamicColors(true)
+
+	m.layout = tview.NewFlex().SetDirection(tview.FlexRow)
+	m.layout.AddItem(m.view, 0, 1, true)
+	m.layout.AddItem(m.status, 4, 0, false)
+	return m
+}
+
+// build replaces the tree with the groups of records
+func (m *mtrTree) build(records []*types.LogRecord) {
+	m.index = make(map[*types.LogRecord]int, len(records))
+	for i, record := range records {
+		m.index[record] = i
+	}
+
+	summary := analyzer.SummarizeRecords(records)
+	root := tview.NewTreeNode(fmt.Sprintf("%d records, LSN %d-%d", summary.Records, summary.StartLSN, summary.EndLSN))
+	root.SetColor(m.app.theme.color("yellow"))
+
+	transactions, _ := analyzer.NewTransactionAnalyzer().ReconstructTransactions(records)
+	if len(transactions) == 0 {
+		m.addMTRs(root, records)
+	} else {
+		var untracked []*types.LogRecord
+		for _, record := range records {
+			if record.TransactionID == 0 {
+				untracked = append(untracked, record)
+			}
+		}
+		for _, txn := range transactions {
+			root.AddChild(m.groupNode(&treeGroup{
+				name:    fmt.Sprintf("Transaction %d (%s)", txn.ID, transactionStatusText(txn.Status)),
+				file:    fmt.Sprintf("trx-%d", txn.ID),
+				records: txn.Records,
+				split:   true,
+			}))
+		}
+		if len(untracked) > 0 {
+			root.AddChild(m.groupNode(&treeGroup{name: "No transaction ID", file: "trx-none", records: untracked, split: true}))
+		}
+	}
+
+	m.view.SetRoot(root)
+	m.view.SetCurrentNode(root)
+	if children := root.GetChildren(); len(children) > 0 {
+		m.view.SetCurrentNode(children[0])
+	}
+	m.status.SetText(m.app.theme.colorize(treeHelp))
+}
+
+// addMTRs adds a node per multi-record MTR of records to parent. Records
+// forming an MTR of their own are added as plain record nodes.
+func (m *mtrTree) addMTRs(parent *tview.TreeNode, records []*types.LogRecord) {
+	for _, mtr := range analyzer.GroupMTRs(records) {
+		if len(mtr.Records) == 1 {
+			parent.AddChild(m.recordNode(mtr.Records[0]))
+			continue
+		}
+		parent.AddChild(m.groupNode(&treeGroup{
+			name:    fmt.Sprintf("MTR %d", mtr.Group),
+			file:    fmt.Sprintf("mtr-%d", mtr.Group),
+			records: mtr.Records,
+		}))
+	}
+}
+
+// groupNode creates a collapsed node for group
+func (m *mtrTree) groupNode(group *treeGroup) *tview.TreeNode {
+	s := analyzer.SummarizeRecords(group.records)
+	text := fmt.Sprintf("%s  LSN %d-%d  %d rec  %d B  %d pages  I:%d U:%d D:%d other:%d",
+		group.name, s.StartLSN, s.EndLSN, s.Records, s.Bytes, s.Pages, s.Inserts, s.Updates, s.Deletes, s.Others)
+
+	node := tview.NewTreeNode("+ " + text)
+	node.SetReference(group)
+	node.SetColor(m.app.theme.color("aqua"))
+	node.SetExpanded(false)
+	return node
+}
+
+// recordNode creates a leaf node for record
+func (m *mtrTree) recordNode(record *types.LogRecord) *tview.TreeNode {
+	text := fmt.Sprintf("#%d %s  LSN %d  len %d", m.app.recordIndices[m.index[record]]+1, record.Type, record.LSN, record.Length)
+	if record.HasPage() {
+		text += fmt.Sprintf("  page %d:%d", record.SpaceID, record.PageNo)
+	}
+
+	node := tview.NewTreeNode(text)
+	node.SetReference(record)
+	return node
+}
+
+// expand expands a group node, adding its children the first time
+func (m *mtrTree) expand(node *tview.TreeNode) {
+	group, ok := node.GetReference().(*treeGroup)
+	if !ok {
+		return
+	}
+	if len(node.GetChildren()) == 0 {
+		if group.split {
+			m.addMTRs(node, group.records)
+		} else {
+			for _, record := range group.records {
+				node.AddChild(m.recordNode(record))
+			}
+		}
+	}
+	node.SetExpanded(true)
+	node.SetText("- " + node.GetText()[2:])
+}
+
+// collapse collapses a group node
+func (m *mtrTree) collapse(node *tview.TreeNode) {
+	if _, ok := node.GetReference().(*treeGroup); ok && node.IsExpanded() {
+		node.SetExpanded(false)
+		node.SetText("+ " + node.GetText()[2:])
+	}
+}
+
+// activate toggles a group node or jumps to the record of a record node
+func (m *mtrTree) activate(node *tview.TreeNode) {
+	switch ref := node.GetReference().(type) {
+	case *treeGroup:
+		if node.IsExpanded() {
+			m.collapse(node)
+		} else {
+			m.expand(node)
+		}
+	case *types.LogRecord:
+		m.jumpTo(ref)
+	}
+}
+
+// currentGroup returns the group under the cursor, or the group containing
+// the record under the cursor
+func (m *mtrTree) currentGroup() *treeGroup {
+	node := m.view.GetCurrentNode()
+	if node == nil {
+		return nil
+	}
+	if group, ok := node.GetReference().(*treeGroup); ok {
+		return group
+	}
+
+	path := m.view.GetPath(node)
+	for i := len(path) - 2; i >= 0; i-- {
+		if group, ok := path[i].GetReference().(*treeGroup); ok {
+			return group
+		}
+	}
+	return nil
+}
+
+// jumpTo closes the tree and selects record in the record list
+func (m *mtrTree) jumpTo(record *types.LogRecord) {
+	index, ok := m.index[record]
+	if !ok {
+		return
+	}
+	m.close()
+	m.app.recordList.SetCurrentItem(index)
+	m.app.showRecordDetails(index)
+}
+
+// export writes the records of the current group to a file in the working
+// directory
+func (m *mtrTree) export(format string) {
+	group := m.currentGroup()
+	if group == nil {
+		m.status.SetText(m.app.theme.colorize("[yellow]Select a transaction or MTR to export[white]\n" + treeHelp))
+		return
+	}
+
+	filename := group.file + "." + format
+	if err := export.WriteFile(filename, format, group.records, m.app.header, m.app.exportMetadata(group.name)); err != nil {
+		m.status.SetText(m.app.theme.colorize(fmt.Sprintf("[red]Export failed: %s[white]\n%s", tview.Escape(err.Error()), treeHelp)))
+		return
+	}
+	m.status.SetText(m.app.theme.colorize(fmt.Sprintf("[green]Exported %d records of %s to %s[white]\n%s", len(group.records), group.name, filename, treeHelp)))
+}
+
+// close returns to the main layout
+func (m *mtrTree) close() {
+	m.app.app.SetRoot(m.app.mainLayout(), true)
+	m.app.app.SetFocus(m.app.recordList)
+}
+
+// handleKey handles the keys of the tree
+func (m *mtrTree) handleKey(event *tcell.EventKey) *tcell.EventKey {
+	node := m.view.GetCurrentNode()
+	switch {
+	case event.Key() == tcell.KeyRight:
+		if node != nil {
+			m.expand(node)
+		}
+	case event.Key() == tcell.KeyLeft:
+		if node == nil {
+			return nil
+		}
+		if _, ok := node.GetReference().(*treeGroup); ok && node.IsExpanded() {
+			m.collapse(node)
+		} else if path := m.view.GetPath(node); len(path) > 1 {
+			m.view.SetCurrentNode(path[len(path)-2])
+		}
+	case event.Rune() == 'g', event.Rune() == 'G':
+		if group := m.currentGroup(); group != nil {
+			if event.Rune() == 'g' {
+				m.jumpTo(group.records[0])
+			} else {
+				m.jumpTo(group.records[len(group.records)-1])
+			}
+		}
+	case event.Rune() == 'e':
+		m.export("json")
+	case event.Rune() == 'E':
+		m.export("csv")
+	case m.app.closesPanel(event, "mtr_tree"):
+		m.close()
+	default:
+		return event
+	}
+	return nil
+}
+
+// transactionStatusText returns a short name for status
+func transactionStatusText(status analyzer.TransactionStatus) string {
+	switch status {
+	case analyzer.TransactionCommitted:
+		return "committed"
+	case analyzer.TransactionRolledBack:
+		return "rolled back"
+	case analyzer.TransactionPending:
+		return "pending"
+	default:
+		return "incomplete"
+	}
+}
+
+// showMTRTree opens the MTR tree on the filtered records
+func (app *RedoLogApp) showMTRTree() {
+	app.mtrTree.build(app.filteredRecords)
+	app.app.SetRoot(app.mtrTree.layout, true)
+	app.app.SetFocus(app.mtrTree.view)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+func TestMTRTree(t *testing.T) {
+	records := []*types.LogRecord{
+		{Type: types.LogType(9), Length: 20, LSN: 100, SpaceID: 5, PageNo: 3, MultiRecordGroup: 1, IsGroupStart: true},
+		{Type: types.LogType(13), Length: 10, LSN: 120, SpaceID: 5, PageNo: 4, MultiRecordGroup: 1},
+		{Type: types.LogType(31), Length: 1, LSN: 130, MultiRecordGroup: 1, IsGroupEnd: true},
+		{Type: types.LogType(1), Length: 4, LSN: 131},
+	}
+	app := NewRedoLogApp(records, &types.RedoLogHeader{})
+	tree := app.mtrTree
+	tree.build(app.filteredRecords)
+
+	// Without transaction IDs the MTRs are the top level
+	children := tree.view.GetRoot().GetChildren()
+	require.Len(t, children, 2)
+	assert.Equal(t, "+ MTR 1  LSN 100-130  3 rec  31 B  2 pages  I:1 U:1 D:0 other:1", children[0].GetText())
+	assert.Equal(t, "#4 MLOG_1BYTE  LSN 131  len 4", children[1].GetText())
+
+	// Children are added on expansion
+	assert.Empty(t, children[0].GetChildren())
+	tree.activate(children[0])
+	assert.True(t, children[0].IsExpanded())
+	require.Len(t, children[0].GetChildren(), 3)
+
+	// g/G jump to the first and last record of the group under the cursor
+	tree.view.SetCurrentNode(children[0].GetChildren()[1])
+	assert.Equal(t, "MTR 1", tree.currentGroup().name)
+	tree.jumpTo(tree.currentGroup().records[2])
+	assert.Equal(t, 2, app.recordList.GetCurrentItem())
+
+	// Groups are exported to the working directory
+	t.Chdir(t.TempDir())
+	tree.export("csv")
+	assert.Contains(t, tree.status.GetText(true), "Exported 3 records of MTR 1 to mtr-1.csv")
+	data, err := os.ReadFile("mtr-1.csv")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "MLOG_REC_INSERT_8027")
+
+	// Records with transaction IDs are grouped by transaction first
+	records[0].TransactionID, records[1].TransactionID = 7, 7
+	tree.build(app.filteredRecords)
+	children = tree.view.GetRoot().GetChildren()
+	require.Len(t, children, 2)
+	assert.Contains(t, children[0].GetText(), "Transaction 7 (incomplete)  LSN 100-120  2 rec")
+	assert.Contains(t, children[1].GetText(), "No transaction ID  LSN 130-131  2 rec")
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// paneLayout sizes the main screen
+type paneLayout struct {
+	List    int `json:"list"`    // Relative width of the record list
+	Details int `json:"details"` // Relative width of the details pane
+	Footer  int `json:"footer"`  // Height of the footer in lines, borders included
+}
+
+// defaultLayout gives the details pane twice the width of the record list
+var defaultLayout = paneLayout{List: 1, Details: 2, Footer: 3}
+
+// validate checks the layout sizes
+func (l paneLayout) validate() error {
+	if l.List < 1 || l.Details < 1 {
+		return fmt.Errorf("layout: list and details must be at least 1")
+	}
+	if l.Footer < 3 {
+		return fmt.Errorf("layout: footer must be at least 3 lines")
+	}
+	return nil
+}
+
+// tuiConfigFile is a TUI config file. Every field is optional:
+//
+//	{
+//	  "theme": "light",
+//	  "colors": {"yellow": "darkorange"},
+//	  "keys": {"goto": [":", "g"], "quit": ["q", "Ctrl-C"]},
+//	  "layout": {"list": 1, "details": 3, "footer": 4}
+//	}
+type tuiConfigFile struct {
+	Theme  string              `json:"theme,omitempty"`
+	Colors map[string]string   `json:"colors,omitempty"` // Overrides of the theme's colours
+	Keys   map[string][]string `json:"keys,omitempty"`   // Replaces the default keys of an action
+	Layout *paneLayout         `json:"layout,omitempty"`
+}
+
+// tuiConfig is the resolved appearance and key bindings of the TUI
+type tuiConfig struct {
+	themeName string
+	theme     *theme
+	keymap    *keymap
+	layout    paneLayout
+}
+
+// defaultTUIConfig returns the built-in configuration
+func defaultTUIConfig() *tuiConfig {
+	cfg, err := newTUIConfig(tuiConfigFile{})
+	if err != nil {
+		panic(err) // The built-in keymap and theme are valid
+	}
+	return cfg
+}
+
+// newTUIConfig resolves a config file
+func newTUIConfig(file tuiConfigFile) (*tuiConfig, error) {
+	if file.Theme == "" {
+		file.Theme = "default"
+	}
+	t, err := newTheme(file.Theme, file.Colors)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := newKeymap(file.Keys)
+	if err != nil {
+		return nil, err
+	}
+	layout := defaultLayout
+	if file.Layout != nil {
+		layout = *file.Layout
+	}
+	if err := layout.validate(); err != nil {
+		return nil, err
+	}
+	return &tuiConfig{themeName: file.Theme, theme: t, keymap: keys, layout: layout}, nil
+}
+
+// defaultTUIConfigPath returns the config file read when --config is not
+// given, or "" if there is no user config directory
+func defaultTUIConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "innodb-redolog-tool", "tui.json")
+}
+
+// loadTUIConfig reads the config file at filename; themeName, if set,
+// overrides its theme. An empty filename reads the default config file if
+// it exists.
+func loadTUIConfig(filename, themeName string) (*tuiConfig, error) {
+	var file tuiConfigFile
+
+	path, required := filename, true
+	if path == "" {
+		path, required = defaultTUIConfigPath(), false
+	}
+	read := false
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(data, &file); err != nil {
+				return nil, fmt.Errorf("invalid TUI config %s: %w", path, err)
+			}
+			read = true
+		case required || !errors.Is(err, os.ErrNotExist):
+			return nil, fmt.Errorf("failed to read TUI config: %w", err)
+		}
+	}
+
+	if themeName != "" {
+		file.Theme = themeName
+	}
+	cfg, err := newTUIConfig(file)
+	if err != nil && read {
+		return nil, fmt.Errorf("invalid TUI config %s: %w", path, err)
+	}
+	return cfg, err
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+func TestKeymap(t *testing.T) {
+	keys, err := newKeymap(map[string][]string{"insert": {"z", "Ctrl-E"}, "quit": {"esc"}})
+	require.NoError(t, err)
+
+	rune := func(ch rune) *tcell.EventKey { return tcell.NewEventKey(tcell.KeyRune, ch, tcell.ModNone) }
+	action, ok := keys.action(rune('z'))
+	require.True(t, ok)
+	assert.Equal(t, "insert", action.name)
+	assert.True(t, keys.matches(tcell.NewEventKey(tcell.KeyEscape, 0, tcell.ModNone), "quit"))
+	assert.Equal(t, "z/Ctrl-E", keys.keys("insert"))
+	assert.Equal(t, "u/U", keys.keys("update"))
+
+	// Replaced bindings are released
+	_, ok = keys.action(rune('i'))
+	assert.False(t, ok)
+	_, ok = keys.action(rune('q'))
+	assert.False(t, ok)
+
+	_, err = newKeymap(map[string][]string{"goto": {"i"}})
+	assert.ErrorContains(t, err, `key "i" is bound to both "insert" and "goto"`)
+	_, err = newKeymap(map[string][]string{"launch": {"l"}})
+	assert.ErrorContains(t, err, `unknown key action "launch"`)
+	_, err = newKeymap(map[string][]string{"goto": {"Hyper-G"}})
+	assert.ErrorContains(t, err, `unknown key "Hyper-G"`)
+}
+
+func TestTheme(t *testing.T) {
+	light, err := newTheme("light", map[string]string{"Yellow": "darkorange"})
+	require.NoError(t, err)
+	assert.Equal(t, "[darkorange]Keys [black]| [black:teal:b]x[-:-:-] [::b]bold [[white[] [#ff0000]red",
+		light.colorize("[yellow]Keys [white]| [black:aqua:b]x[-:-:-] [::b]bold [[white[] [#ff0000]red"))
+	assert.Equal(t, tcell.GetColor("teal"), light.color("cyan"))
+
+	plain, err := newTheme("default", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "[yellow]Keys", plain.colorize("[yellow]Keys"))
+
+	_, err = newTheme("solarized", nil)
+	assert.ErrorContains(t, err, `unknown theme "solarized"`)
+	_, err = newTheme("light", map[string]string{"yellow": "notacolour"})
+	assert.ErrorContains(t, err, `unknown colour "notacolour"`)
+}
+
+func TestLoadTUIConfig(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "tui.json")
+	require.NoError(t, os.WriteFile(filename, []byte(`{
+		"theme": "light",
+		"keys": {"goto": [":"]},
+		"layout": {"list": 1, "details": 3, "footer": 4}
+	}`), 0644))
+
+	cfg, err := loadTUIConfig(filename, "")
+	require.NoError(t, err)
+	assert.Equal(t, "light", cfg.themeName)
+	assert.Equal(t, ":", cfg.keymap.keys("goto"))
+	assert.Equal(t, paneLayout{List: 1, Details: 3, Footer: 4}, cfg.layout)
+
+	// --theme overrides the file
+	cfg, err = loadTUIConfig(filename, "default")
+	require.NoError(t, err)
+	assert.Equal(t, "default", cfg.themeName)
+
+	require.NoError(t, os.WriteFile(filename, []byte(`{"layout": {"list": 0, "details": 1, "footer": 3}}`), 0644))
+	_, err = loadTUIConfig(filename, "")
+	assert.ErrorContains(t, err, "list and details must be at least 1")
+	_, err = loadTUIConfig(filepath.Join(t.TempDir(), "missing.json"), "")
+	assert.Error(t, err)
+}
+
+func TestConfiguredKeys(t *testing.T) {
+	records := []*types.LogRecord{
+		{Type: types.LogType(9), LSN: 100},
+		{Type: types.LogType(1), LSN: 110},
+	}
+	keys, err := newKeymap(map[string][]string{"insert": {"z"}})
+	require.NoError(t, err)
+	cfg := defaultTUIConfig()
+	cfg.keymap = keys
+	app := NewRedoLogAppWithConfig(records, &types.RedoLogHeader{}, cfg)
+
+	// Both panes dispatch through the keymap
+	capture := app.recordList.GetInputCapture()
+	assert.Nil(t, capture(tcell.NewEventKey(tcell.KeyRune, 'z', tcell.ModNone)))
+	assert.Equal(t, "insert", app.operationFilter)
+	assert.NotNil(t, capture(tcell.NewEventKey(tcell.KeyRune, 'i', tcell.ModNone)))
+	assert.Nil(t, app.detailsText.GetInputCapture()(tcell.NewEventKey(tcell.KeyRune, 'z', tcell.ModNone)))
+	assert.Equal(t, "all", app.operationFilter)
+
+	// The footer and the help are generated from the keymap
+	assert.Contains(t, app.footer.GetText(true), "'z'=INSERT")
+	app.showHelp()
+	help := app.helpView.GetText(true)
+	assert.Contains(t, help, "z ")
+	assert.Contains(t, help, "Show only insert records")
+	assert.Contains(t, help, "?/F1")
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/yamaru/innodb-redolog-tool/pkg/redolog"
+)
+
+// xtrabackupReport is the result of the xtrabackup subcommand
+type xtrabackupReport struct {
+	File            string                         `json:"file"`
+	Log             *redolog.XtraBackupLog         `json:"log"`
+	CheckpointsFile string                         `json:"checkpoints_file,omitempty"`
+	Checkpoints     *redolog.XtraBackupCheckpoints `json:"checkpoints,omitempty"`
+	Problems        []string                       `json:"problems"`
+}
+
+// runXtraBackup implements the xtrabackup subcommand
+func runXtraBackup(args []string) error {
+	fs := newFlagSet("xtrabackup", "[flags] <xtrabackup_logfile>")
+	in := addFileFlags(fs)
+	out := addOutputFlags(fs, "text", "json")
+	checkpointsFile := fs.String("checkpoints", "", "xtrabackup_checkpoints file to check against (default: the one next to the log, if present)")
+	if _, err := in.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := out.validate(); err != nil {
+		return err
+	}
+	if in.file == stdinFile {
+		return newUsageError("xtrabackup needs a file; it cannot read stdin")
+	}
+
+	log, err := redolog.ScanXtraBackupLog(in.file)
+	if err != nil {
+		return err
+	}
+	report := &xtrabackupReport{File: in.file, Log: log, Problems: []string{}}
+
+	// A missing xtrabackup_checkpoints next to the log is not an error, one
+	// that cannot be read or parsed is
+	report.CheckpointsFile = *checkpointsFile
+	if report.CheckpointsFile == "" {
+		report.CheckpointsFile = defaultCheckpointsPath(in.file)
+	}
+	checkpoints, err := redolog.ReadXtraBackupCheckpoints(report.CheckpointsFile)
+	switch {
+	case err == nil:
+		report.Checkpoints = checkpoints
+		report.Problems = append(report.Problems, log.CrossCheck(checkpoints)...)
+	case *checkpointsFile == "" && (errors.Is(err, os.ErrNotExist) || errors.Is(err, redolog.ErrMemberNotFound)):
+		report.CheckpointsFile = ""
+	default:
+		return err
+	}
+
+	err = out.write(func(w io.Writer) error {
+		if out.format == "json" {
+			return writeJSON(w, report)
+		}
+		return writeXtraBackupText(w, report)
+	})
+	if err != nil {
+		return err
+	}
+	if len(report.Problems) > 0 {
+		return errVerifyFailed
+	}
+	return nil
+}
+
+// defaultCheckpointsPath returns the xtrabackup_checkpoints next to an
+// xtrabackup_logfile, in the same archive when the log is a member of one
+func defaultCheckpointsPath(file string) string {
+	if _, err := os.Stat(file); err != nil {
+		if i := strings.LastIndex(file, redolog.MemberSeparator); i >= 0 {
+			member := file[i+len(redolog.MemberSeparator):]
+			return file[:i+len(redolog.MemberSeparator)] + path.Join(path.Dir(member), redolog.XtraBackupCheckpointsFile)
+		}
+	}
+	return filepath.Join(filepath.Dir(file), redolog.XtraBackupCheckpointsFile)
+}
+
+// writeXtraBackupText writes an xtrabackup report as text
+func writeXtraBackupText(w io.Writer, report *xtrabackupReport) error {
+	fmt.Fprintf(w, "File:        %s\n", report.File)
+	fmt.Fprintf(w, "Creator:     %s\n", report.Log.Creator)
+	fmt.Fprintf(w, "Start LSN:   %d\n", report.Log.StartLSN)
+	fmt.Fprintf(w, "End LSN:     %d\n", report.Log.EndLSN)
+	fmt.Fprintf(w, "Blocks:      %d\n", report.Log.Blocks)
+
+	if report.Checkpoints == nil {
+		fmt.Fprintf(w, "\nNo %s found; pass --checkpoints to cross-check\n", redolog.XtraBackupCheckpointsFile)
+		return nil
+	}
+	c := report.Checkpoints
+	fmt.Fprintf(w, "\nCheckpoints: %s\n", report.CheckpointsFile)
+	fmt.Fprintf(w, "  backup_type: %s\n  from_lsn:    %d\n  to_lsn:      %d\n  last_lsn:    %d\n  flushed_lsn: %d\n",
+		c.BackupType, c.FromLSN, c.ToLSN, c.LastLSN, c.FlushedLSN)
+	if len(report.Problems) == 0 {
+		fmt.Fprintf(w, "\nThe copied redo covers to_lsn through last_lsn\n")
+		return nil
+	}
+	fmt.Fprintf(w, "\n%d problem(s):\n", len(report.Problems))
+	for _, problem := range report.Problems {
+		fmt.Fprintf(w, "  %s\n", problem)
+	}
+	return nil
+}
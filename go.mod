@@ -4,6 +4,8 @@ go 1.24.1
 
 require (
 	github.com/gdamore/tcell/v2 v2.8.1
+	github.com/klauspost/compress v1.17.11
+	github.com/pierrec/lz4/v4 v4.1.21
 	github.com/rivo/tview v0.0.0-20250625164341-a4a78f1e05cb
 	github.com/stretchr/testify v1.10.0
 )
@@ -0,0 +1,176 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// Dictionary maps tablespace IDs to table names. It is loaded from a JSON
+// file such as
+//
+//	{"spaces": {"42": "sakila/actor"}}
+//
+// which can be built from INFORMATION_SCHEMA.INNODB_TABLESPACES.
+type Dictionary struct {
+	Spaces map[uint32]string `json:"spaces"`
+}
+
+// LoadDictionary reads a dictionary file
+func LoadDictionary(filename string) (*Dictionary, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dictionary: %w", err)
+	}
+
+	dict := &Dictionary{}
+	if err := json.Unmarshal(data, dict); err != nil {
+		return nil, fmt.Errorf("failed to parse dictionary %s: %w", filename, err)
+	}
+	return dict, nil
+}
+
+// TableName returns the table name of a space, or "" if unknown
+func (d *Dictionary) TableName(spaceID uint32) string {
+	if d == nil {
+		return ""
+	}
+	return d.Spaces[spaceID]
+}
+
+// operationClasses lists the operation classes in report order
+var operationClasses = []string{types.OperationInsert, types.OperationUpdate, types.OperationDelete, types.OperationOther}
+
+// WriteVolume is the redo generated by a set of records
+type WriteVolume struct {
+	Bytes   uint64 `json:"bytes"`
+	Records uint64 `json:"records"`
+	MTRs    uint64 `json:"mtrs"`
+}
+
+// SpaceWrites is the redo generated for one tablespace
+type SpaceWrites struct {
+	SpaceID uint32 `json:"space_id"`
+	Table   string `json:"table,omitempty"`
+	WriteVolume
+	Share       float64                `json:"share"` // Fraction of all redo bytes
+	ByOperation map[string]WriteVolume `json:"by_operation"`
+}
+
+// WriteAmplificationReport ranks tablespaces by the redo they generate.
+// Records that change no page, such as MTR ends, file operations and
+// checkpoints, count towards the total and NoPage but no tablespace.
+type WriteAmplificationReport struct {
+	Total       WriteVolume            `json:"total"`
+	ByOperation map[string]WriteVolume `json:"by_operation"`
+	NoPage      WriteVolume            `json:"no_page"`
+	Spaces      []*SpaceWrites         `json:"spaces"` // Most redo bytes first
+}
+
+// volumeCounter accumulates a WriteVolume, counting each MTR once
+type volumeCounter struct {
+	volume WriteVolume
+	mtrs   map[int]struct{}
+}
+
+func (c *volumeCounter) add(record *types.LogRecord, mtrID int) {
+	if c.mtrs == nil {
+		c.mtrs = make(map[int]struct{})
+	}
+	c.volume.Bytes += uint64(record.Length)
+	c.volume.Records++
+	c.mtrs[mtrID] = struct{}{}
+	c.volume.MTRs = uint64(len(c.mtrs))
+}
+
+// AnalyzeWriteAmplification groups records by tablespace and sums the
+// encoded bytes, records and MTRs of each, split by operation class. Redo
+// records do not log the index they change, so there is no per-index
+// breakdown. dict may be nil.
+func AnalyzeWriteAmplification(records []*types.LogRecord, dict *Dictionary) *WriteAmplificationReport {
+	var total, noPage volumeCounter
+	totalByOp := make(map[string]*volumeCounter)
+	spaces := make(map[uint32]*volumeCounter)
+	spacesByOp := make(map[uint32]map[string]*volumeCounter)
+
+	for _, mtr := range GroupMTRs(records) {
+		for _, record := range mtr.Records {
+			op := record.Type.Operation()
+
+			total.add(record, mtr.ID)
+			counterFor(totalByOp, op).add(record, mtr.ID)
+			if !record.HasPage() {
+				noPage.add(record, mtr.ID)
+				continue
+			}
+
+			if spaces[record.SpaceID] == nil {
+				spaces[record.SpaceID] = &volumeCounter{}
+				spacesByOp[record.SpaceID] = make(map[string]*volumeCounter)
+			}
+			spaces[record.SpaceID].add(record, mtr.ID)
+			counterFor(spacesByOp[record.SpaceID], op).add(record, mtr.ID)
+		}
+	}
+
+	report := &WriteAmplificationReport{
+		Total:       total.volume,
+		ByOperation: volumesByOperation(totalByOp),
+		NoPage:      noPage.volume,
+		Spaces:      make([]*SpaceWrites, 0, len(spaces)),
+	}
+
+	for spaceID, counter := range spaces {
+		space := &SpaceWrites{
+			SpaceID:     spaceID,
+			Table:       dict.TableName(spaceID),
+			WriteVolume: counter.volume,
+			ByOperation: volumesByOperation(spacesByOp[spaceID]),
+		}
+		if total.volume.Bytes > 0 {
+			space.Share = float64(counter.volume.Bytes) / float64(total.volume.Bytes)
+		}
+		report.Spaces = append(report.Spaces, space)
+	}
+
+	sort.Slice(report.Spaces, func(i, j int) bool {
+		a, b := report.Spaces[i], report.Spaces[j]
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return a.SpaceID < b.SpaceID
+	})
+
+	return report
+}
+
+// counterFor returns the counter for op, creating it if needed
+func counterFor(counters map[string]*volumeCounter, op string) *volumeCounter {
+	counter, ok := counters[op]
+	if !ok {
+		counter = &volumeCounter{}
+		counters[op] = counter
+	}
+	return counter
+}
+
+// volumesByOperation returns a volume for every operation class, including empty ones
+func volumesByOperation(counters map[string]*volumeCounter) map[string]WriteVolume {
+	volumes := make(map[string]WriteVolume, len(operationClasses))
+	for _, op := range operationClasses {
+		if counter, ok := counters[op]; ok {
+			volumes[op] = counter.volume
+		} else {
+			volumes[op] = WriteVolume{}
+		}
+	}
+	return volumes
+}
+
+// OperationClasses returns the operation classes in report order
+func OperationClasses() []string {
+	return append([]string(nil), operationClasses...)
+}
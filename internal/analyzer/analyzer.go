@@ -0,0 +1,317 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/reader"
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// maxRecordType is MLOG_BIGGEST_TYPE, the highest valid mlog_id_t value
+const maxRecordType = 76
+
+// decodeFailureMarkers are the substrings the MySQL reader leaves in record
+// data when a payload could not be decoded
+var decodeFailureMarkers = []string{
+	"failed to parse",
+	"parse_failed",
+	"_insufficient_data",
+	"cross_block_read_error",
+	"cross_block_read_incomplete",
+}
+
+// Config controls how a redoLogAnalyzer reads and analyzes files
+type Config struct {
+	// MaxRecords limits how many records AnalyzeFile reads (0 means no limit)
+	MaxRecords int
+}
+
+// redoLogAnalyzer implements RedoLogAnalyzer interface
+type redoLogAnalyzer struct {
+	config       Config
+	transactions TransactionAnalyzer
+}
+
+// NewRedoLogAnalyzer creates a new RedoLogAnalyzer instance
+func NewRedoLogAnalyzer() RedoLogAnalyzer {
+	return NewRedoLogAnalyzerWithConfig(Config{})
+}
+
+// NewRedoLogAnalyzerWithConfig creates a new RedoLogAnalyzer using the given configuration
+func NewRedoLogAnalyzerWithConfig(config Config) RedoLogAnalyzer {
+	return &redoLogAnalyzer{
+		config:       config,
+		transactions: NewTransactionAnalyzer(),
+	}
+}
+
+// AnalyzeFile performs complete analysis of a redo log file. Errors are only
+// returned when the file cannot be opened or its header cannot be read;
+// problems found in the record stream are reported in the result.
+func (a *redoLogAnalyzer) AnalyzeFile(filename string) (*AnalysisResult, error) {
+	src, err := reader.OpenInput(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	logReader := reader.NewReaderForSource(src)
+	defer logReader.Close()
+
+	if err := logReader.OpenSource(src); err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	header, err := logReader.ReadHeader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	records, readErr := reader.ReadRecords(logReader, a.config.MaxRecords)
+	reader.DetectMultiRecordGroups(records)
+
+	result, err := a.AnalyzeRecords(records)
+	if err != nil {
+		return nil, err
+	}
+	result.Header = header
+
+	var warnings []string
+	if reporter, ok := logReader.(reader.WarningReporter); ok {
+		warnings = append(warnings, reporter.Warnings()...)
+	}
+	if readErr != nil {
+		// A record stream that stops before the end of the file is damaged
+		warnings = append(warnings, fmt.Sprintf("stopped reading after %d records: %v", len(records), readErr))
+		AddReadError(result.Corruption, records, readErr)
+	}
+	result.Warnings = append(warnings, result.Warnings...)
+	result.Summary = buildSummary(result)
+
+	return result, nil
+}
+
+// AnalyzeRecords analyzes a collection of log records
+func (a *redoLogAnalyzer) AnalyzeRecords(records []*types.LogRecord) (*AnalysisResult, error) {
+	stats, err := a.GenerateStats(records)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := a.transactions.ReconstructTransactions(records)
+	if err != nil {
+		return nil, err
+	}
+
+	corruption, err := a.DetectCorruption(records)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AnalysisResult{
+		Records:      records,
+		Stats:        stats,
+		Transactions: transactions,
+		Corruption:   corruption,
+		Warnings:     recordWarnings(records, transactions),
+	}
+	result.Summary = buildSummary(result)
+
+	return result, nil
+}
+
+// GenerateStats generates statistics from log records
+func (a *redoLogAnalyzer) GenerateStats(records []*types.LogRecord) (*types.RedoLogStats, error) {
+	stats := &types.RedoLogStats{
+		RecordsByType: make(map[types.LogType]uint64),
+	}
+	transactionIDs := make(map[uint64]struct{})
+
+	for _, record := range records {
+		stats.TotalRecords++
+		stats.RecordsByType[record.Type]++
+		stats.SizeInBytes += uint64(record.Length)
+
+		if record.TransactionID != 0 {
+			transactionIDs[record.TransactionID] = struct{}{}
+		}
+
+		if record.Timestamp.IsZero() {
+			continue
+		}
+		if stats.TimeRange.Start.IsZero() || record.Timestamp.Before(stats.TimeRange.Start) {
+			stats.TimeRange.Start = record.Timestamp
+		}
+		if record.Timestamp.After(stats.TimeRange.End) {
+			stats.TimeRange.End = record.Timestamp
+		}
+	}
+	stats.TransactionCount = uint64(len(transactionIDs))
+
+	return stats, nil
+}
+
+// DetectCorruption detects potential corruption in log records. It looks for
+// structural problems only: record types outside the mlog_id_t range, empty
+// records and LSNs that do not increase.
+func (a *redoLogAnalyzer) DetectCorruption(records []*types.LogRecord) (*CorruptionReport, error) {
+	report := &CorruptionReport{
+		CorruptedRecords: make([]CorruptionIssue, 0),
+		Severity:         SeverityLow,
+		Recoverable:      true,
+	}
+
+	for i, record := range records {
+		if record.Type == 0 || (uint8(record.Type) > maxRecordType && !record.Type.IsMariaDB()) {
+			addCorruptionIssue(report, CorruptionIssue{
+				LSN:         record.LSN,
+				RecordIndex: i,
+				IssueType:   "invalid_type",
+				Description: fmt.Sprintf("record type %d is outside the valid range 1-%d", uint8(record.Type), maxRecordType),
+				Severity:    SeverityMedium,
+			})
+		}
+
+		if record.Length == 0 {
+			addCorruptionIssue(report, CorruptionIssue{
+				LSN:         record.LSN,
+				RecordIndex: i,
+				IssueType:   "zero_length",
+				Description: "record has zero length",
+				Severity:    SeverityMedium,
+			})
+		}
+
+		if i > 0 && record.LSN <= records[i-1].LSN {
+			addCorruptionIssue(report, CorruptionIssue{
+				LSN:         record.LSN,
+				RecordIndex: i,
+				IssueType:   "lsn_not_increasing",
+				Description: fmt.Sprintf("LSN %d does not follow previous LSN %d", record.LSN, records[i-1].LSN),
+				Severity:    SeverityHigh,
+			})
+		}
+	}
+
+	return report, nil
+}
+
+// AddReadError reports a record stream that stopped with err after
+// records as a read_error issue of report
+func AddReadError(report *CorruptionReport, records []*types.LogRecord, err error) {
+	issue := CorruptionIssue{
+		RecordIndex: len(records),
+		IssueType:   "read_error",
+		Description: err.Error(),
+		Severity:    SeverityHigh,
+	}
+	if len(records) > 0 {
+		issue.LSN = records[len(records)-1].LSN
+	}
+	addCorruptionIssue(report, issue)
+}
+
+// addCorruptionIssue appends an issue and updates the report's summary fields
+func addCorruptionIssue(report *CorruptionReport, issue CorruptionIssue) {
+	report.CorruptedRecords = append(report.CorruptedRecords, issue)
+	report.HasCorruption = true
+	if issue.Severity > report.Severity {
+		report.Severity = issue.Severity
+	}
+	report.Recoverable = report.Severity < SeverityCritical
+}
+
+// recordWarnings collects non-corruption problems worth reporting
+func recordWarnings(records []*types.LogRecord, transactions []*Transaction) []string {
+	var warnings []string
+
+	decodeFailures := 0
+	for _, record := range records {
+		data := string(record.Data)
+		for _, marker := range decodeFailureMarkers {
+			if strings.Contains(data, marker) {
+				decodeFailures++
+				break
+			}
+		}
+	}
+	if decodeFailures > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d records could not be fully decoded", decodeFailures))
+	}
+
+	incomplete := 0
+	for _, txn := range transactions {
+		if txn.Status == TransactionIncomplete {
+			incomplete++
+		}
+	}
+	if incomplete > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d transactions have no commit or rollback record", incomplete))
+	}
+
+	return warnings
+}
+
+// buildSummary renders a one-paragraph description of an analysis result
+func buildSummary(result *AnalysisResult) string {
+	var parts []string
+
+	if result.Stats != nil {
+		parts = append(parts, fmt.Sprintf("%d records (%d bytes) across %d record types",
+			result.Stats.TotalRecords, result.Stats.SizeInBytes, len(result.Stats.RecordsByType)))
+		if result.Stats.TransactionCount > 0 {
+			parts = append(parts, fmt.Sprintf("%d transactions", result.Stats.TransactionCount))
+		}
+	}
+
+	if result.Corruption != nil && result.Corruption.HasCorruption {
+		parts = append(parts, fmt.Sprintf("%d corruption issues (max severity %s)",
+			len(result.Corruption.CorruptedRecords), result.Corruption.Severity))
+	} else {
+		parts = append(parts, "no corruption detected")
+	}
+
+	if len(result.Warnings) > 0 {
+		parts = append(parts, fmt.Sprintf("%d warnings", len(result.Warnings)))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// SortedTypeCounts returns the record type counts of stats ordered by count,
+// most frequent first
+func SortedTypeCounts(stats *types.RedoLogStats) []TypeCount {
+	counts := make([]TypeCount, 0, len(stats.RecordsByType))
+	for logType, count := range stats.RecordsByType {
+		counts = append(counts, TypeCount{Type: logType, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Type < counts[j].Type
+	})
+	return counts
+}
+
+// TypeCount pairs a record type with the number of records of that type
+type TypeCount struct {
+	Type  types.LogType
+	Count uint64
+}
+
+// String returns the string representation of CorruptionSeverity
+func (s CorruptionSeverity) String() string {
+	switch s {
+	case SeverityLow:
+		return "low"
+	case SeverityMedium:
+		return "medium"
+	case SeverityHigh:
+		return "high"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return fmt.Sprintf("severity_%d", int(s))
+	}
+}
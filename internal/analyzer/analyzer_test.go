@@ -3,8 +3,10 @@ package analyzer
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/yamaru/innodb-redolog-tool/internal/types"
@@ -23,8 +25,7 @@ func (suite *RedoLogAnalyzerTestSuite) SetupTest() {
 	suite.Require().NoError(err)
 	suite.tempDir = tempDir
 
-	// TODO: Initialize actual analyzer implementation
-	// suite.analyzer = NewRedoLogAnalyzer()
+	suite.analyzer = NewRedoLogAnalyzer()
 }
 
 func (suite *RedoLogAnalyzerTestSuite) TearDownTest() {
@@ -37,9 +38,6 @@ func (suite *RedoLogAnalyzerTestSuite) TestAnalyzeValidFile() {
 	filename, err := fixtures.CreateSampleLogFile(suite.tempDir)
 	suite.Require().NoError(err)
 
-	// This test should fail until we implement the analyzer
-	suite.T().Skip("Skipping until RedoLogAnalyzer implementation exists")
-
 	result, err := suite.analyzer.AnalyzeFile(filename)
 	suite.Assert().NoError(err)
 	suite.Assert().NotNil(result)
@@ -54,9 +52,6 @@ func (suite *RedoLogAnalyzerTestSuite) TestAnalyzeCorruptedFile() {
 	filename, err := fixtures.CreateCorruptedLogFile(suite.tempDir)
 	suite.Require().NoError(err)
 
-	// This test should fail until we implement the analyzer
-	suite.T().Skip("Skipping until RedoLogAnalyzer implementation exists")
-
 	result, err := suite.analyzer.AnalyzeFile(filename)
 	suite.Assert().NoError(err) // Should not error, but should detect corruption
 	suite.Assert().NotNil(result)
@@ -69,9 +64,6 @@ func (suite *RedoLogAnalyzerTestSuite) TestAnalyzeCorruptedFile() {
 func (suite *RedoLogAnalyzerTestSuite) TestAnalyzeRecordsFromTransaction() {
 	transaction := fixtures.SampleTransaction()
 
-	// This test should fail until we implement the analyzer
-	suite.T().Skip("Skipping until RedoLogAnalyzer implementation exists")
-
 	result, err := suite.analyzer.AnalyzeRecords(transaction)
 	suite.Assert().NoError(err)
 	suite.Assert().NotNil(result)
@@ -84,9 +76,6 @@ func (suite *RedoLogAnalyzerTestSuite) TestAnalyzeRecordsFromTransaction() {
 func (suite *RedoLogAnalyzerTestSuite) TestGenerateStatsFromRecords() {
 	transaction := fixtures.SampleTransaction()
 
-	// This test should fail until we implement the analyzer
-	suite.T().Skip("Skipping until RedoLogAnalyzer implementation exists")
-
 	stats, err := suite.analyzer.GenerateStats(transaction)
 	suite.Assert().NoError(err)
 	suite.Assert().NotNil(stats)
@@ -100,9 +89,6 @@ func (suite *RedoLogAnalyzerTestSuite) TestGenerateStatsFromRecords() {
 func (suite *RedoLogAnalyzerTestSuite) TestDetectCorruptionInValidRecords() {
 	transaction := fixtures.SampleTransaction()
 
-	// This test should fail until we implement the analyzer
-	suite.T().Skip("Skipping until RedoLogAnalyzer implementation exists")
-
 	report, err := suite.analyzer.DetectCorruption(transaction)
 	suite.Assert().NoError(err)
 	suite.Assert().NotNil(report)
@@ -143,16 +129,12 @@ type TransactionAnalyzerTestSuite struct {
 }
 
 func (suite *TransactionAnalyzerTestSuite) SetupTest() {
-	// TODO: Initialize actual transaction analyzer implementation
-	// suite.analyzer = NewTransactionAnalyzer()
+	suite.analyzer = NewTransactionAnalyzer()
 }
 
 func (suite *TransactionAnalyzerTestSuite) TestReconstructCompleteTransaction() {
 	transaction := fixtures.SampleTransaction()
 
-	// This test should fail until we implement the analyzer
-	suite.T().Skip("Skipping until TransactionAnalyzer implementation exists")
-
 	transactions, err := suite.analyzer.ReconstructTransactions(transaction)
 	suite.Assert().NoError(err)
 	suite.Assert().Len(transactions, 1)
@@ -171,8 +153,6 @@ func (suite *TransactionAnalyzerTestSuite) TestReconstructIncompleteTransaction(
 		// Missing commit record
 	}
 
-	// This test should fail until we implement the analyzer
-	suite.T().Skip("Skipping until TransactionAnalyzer implementation exists")
 
 	transactions, err := suite.analyzer.ReconstructTransactions(incompleteTransaction)
 	suite.Assert().NoError(err)
@@ -197,8 +177,6 @@ func (suite *TransactionAnalyzerTestSuite) TestFindIncompleteTransactions() {
 		},
 	}
 
-	// This test should fail until we implement the analyzer
-	suite.T().Skip("Skipping until TransactionAnalyzer implementation exists")
 
 	incompleteTransactions, err := suite.analyzer.FindIncompleteTransactions(records)
 	suite.Assert().NoError(err)
@@ -217,8 +195,6 @@ func (suite *TransactionAnalyzerTestSuite) TestAnalyzeSingleTransaction() {
 		TableAffected: []uint32{100},
 	}
 
-	// This test should fail until we implement the analyzer
-	suite.T().Skip("Skipping until TransactionAnalyzer implementation exists")
 
 	analysis, err := suite.analyzer.AnalyzeTransaction(txn)
 	suite.Assert().NoError(err)
@@ -282,23 +258,330 @@ func TestAnalyzerIntegration(t *testing.T) {
 // Error handling tests
 func TestAnalyzerErrorHandling(t *testing.T) {
 	t.Run("handle non-existent file", func(t *testing.T) {
-		// This test should fail until we implement the analyzer
-		t.Skip("Skipping until RedoLogAnalyzer implementation exists")
-
-		// TODO: Test analyzer behavior with non-existent files
+		analyzer := NewRedoLogAnalyzer()
+		_, err := analyzer.AnalyzeFile("/nonexistent/redo.log")
+		assert.Error(t, err)
 	})
 
 	t.Run("handle empty file", func(t *testing.T) {
-		// This test should fail until we implement the analyzer
-		t.Skip("Skipping until RedoLogAnalyzer implementation exists")
+		filename, err := fixtures.CreateEmptyLogFile(t.TempDir())
+		assert.NoError(t, err)
 
-		// TODO: Test analyzer behavior with empty files
+		analyzer := NewRedoLogAnalyzer()
+		_, err = analyzer.AnalyzeFile(filename)
+		assert.Error(t, err) // Header cannot be read
 	})
 
 	t.Run("handle nil records slice", func(t *testing.T) {
-		// This test should fail until we implement the analyzer
-		t.Skip("Skipping until RedoLogAnalyzer implementation exists")
-
-		// TODO: Test analyzer behavior with nil input
+		analyzer := NewRedoLogAnalyzer()
+		result, err := analyzer.AnalyzeRecords(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(0), result.Stats.TotalRecords)
+		assert.False(t, result.Corruption.HasCorruption)
 	})
-}
\ No newline at end of file
+}
+
+func TestDetectCorruptionStructuralIssues(t *testing.T) {
+	records := []*types.LogRecord{
+		{Type: types.LogType(9), Length: 10, LSN: 2000},
+		{Type: types.LogType(99), Length: 10, LSN: 2010},
+		{Type: types.LogType(9), Length: 0, LSN: 2005},
+	}
+
+	report, err := NewRedoLogAnalyzer().DetectCorruption(records)
+	assert.NoError(t, err)
+	assert.True(t, report.HasCorruption)
+	assert.Equal(t, SeverityHigh, report.Severity)
+
+	issueTypes := make([]string, 0)
+	for _, issue := range report.CorruptedRecords {
+		issueTypes = append(issueTypes, issue.IssueType)
+	}
+	assert.ElementsMatch(t, []string{"invalid_type", "zero_length", "lsn_not_increasing"}, issueTypes)
+}
+
+func TestCompareRecords(t *testing.T) {
+	recordsA := []*types.LogRecord{
+		{Type: types.LogType(9), Length: 20, LSN: 100, SpaceID: 5, PageNo: 3},
+		{Type: types.LogType(1), Length: 4, LSN: 120, SpaceID: 5, PageNo: 4},
+		{Type: types.LogType(31), Length: 1, LSN: 124},
+	}
+	recordsB := []*types.LogRecord{
+		{Type: types.LogType(9), Length: 20, LSN: 200, SpaceID: 5, PageNo: 3},
+		{Type: types.LogType(9), Length: 30, LSN: 220, SpaceID: 5, PageNo: 3},
+		{Type: types.LogType(14), Length: 8, LSN: 250, SpaceID: 7, PageNo: 9},
+	}
+
+	diff, err := NewRedoLogAnalyzer().Compare(recordsA, recordsB)
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(3), diff.StatsA.TotalRecords)
+	assert.Equal(t, []TypeDelta{
+		{Type: 1, Name: "MLOG_1BYTE", CountA: 1, CountB: 0, Delta: -1},
+		{Type: 9, Name: "MLOG_REC_INSERT_8027", CountA: 1, CountB: 2, Delta: 1},
+		{Type: 14, Name: "MLOG_REC_DELETE_8027", CountA: 0, CountB: 1, Delta: 1},
+		{Type: 31, Name: "MLOG_MULTI_REC_END", CountA: 1, CountB: 0, Delta: -1},
+	}, diff.Types)
+
+	assert.Empty(t, diff.SpacesOnlyInA)
+	assert.Equal(t, []uint32{7}, diff.SpacesOnlyInB)
+	assert.Equal(t, []PageRef{{SpaceID: 5, PageNo: 4}}, diff.PagesOnlyInA)
+	assert.Equal(t, []PageRef{{SpaceID: 7, PageNo: 9}}, diff.PagesOnlyInB)
+
+	assert.Equal(t, []TableDelta{
+		{By: TableKeySpace, ID: 5, RecordsA: 2, RecordsB: 2, BytesA: 24, BytesB: 50, ChangesA: 1, ChangesB: 2},
+		{By: TableKeySpace, ID: 7, RecordsB: 1, BytesB: 8, ChangesB: 1},
+	}, diff.Tables)
+	assert.Equal(t, "space 7", diff.Tables[1].Label())
+}
+
+func TestCompareRecordsByTable(t *testing.T) {
+	pageSpans := []types.FieldSpan{{Name: "type"}, {Name: "space_id"}, {Name: "page_no"}}
+	recordsA := []*types.LogRecord{
+		// Two tables of the system tablespace, and its header page 0
+		{Type: types.LogType(9), Length: 20, LSN: 100, TableID: 1061, Spans: pageSpans},
+		{Type: types.LogType(9), Length: 30, LSN: 120, TableID: 1062, PageNo: 7, Spans: pageSpans},
+		{Type: types.LogType(1), Length: 4, LSN: 150, Spans: pageSpans},
+		{Type: types.LogType(31), Length: 1, LSN: 154, Spans: []types.FieldSpan{{Name: "type"}}},
+	}
+	recordsB := []*types.LogRecord{
+		{Type: types.LogType(9), Length: 20, LSN: 200, TableID: 1061, Spans: pageSpans},
+	}
+
+	diff, err := NewRedoLogAnalyzer().Compare(recordsA, recordsB)
+	require.NoError(t, err)
+
+	assert.Equal(t, []TableDelta{
+		{By: TableKeyTable, ID: 1061, RecordsA: 1, RecordsB: 1, BytesA: 20, BytesB: 20, ChangesA: 1, ChangesB: 1},
+		{By: TableKeyTable, ID: 1062, RecordsA: 1, BytesA: 30, ChangesA: 1},
+		{By: TableKeySpace, ID: 0, RecordsA: 1, BytesA: 4},
+	}, diff.Tables)
+	assert.Equal(t, "table 1062", diff.Tables[1].Label())
+	assert.Empty(t, diff.SpacesOnlyInA)
+	assert.Equal(t, []PageRef{{SpaceID: 0, PageNo: 7}}, diff.PagesOnlyInA)
+	assert.Empty(t, diff.PagesOnlyInB)
+}
+
+func TestGroupMTRs(t *testing.T) {
+	records := []*types.LogRecord{
+		{Type: types.LogType(9), Length: 20, LSN: 100, MultiRecordGroup: 1},
+		{Type: types.LogType(31), Length: 1, LSN: 120, MultiRecordGroup: 1},
+		{Type: types.LogType(1), Length: 4, LSN: 121},
+		{Type: types.LogType(1), Length: 4, LSN: 125},
+		{Type: types.LogType(9), Length: 30, LSN: 129, MultiRecordGroup: 2},
+		{Type: types.LogType(31), Length: 1, LSN: 159, MultiRecordGroup: 2},
+	}
+
+	mtrs := GroupMTRs(records)
+	require.Len(t, mtrs, 4)
+
+	assert.Equal(t, 1, mtrs[0].ID)
+	assert.Equal(t, 1, mtrs[0].Group)
+	assert.Equal(t, uint64(100), mtrs[0].StartLSN)
+	assert.Equal(t, uint64(120), mtrs[0].EndLSN)
+	assert.Equal(t, uint64(21), mtrs[0].Bytes())
+	assert.Len(t, mtrs[1].Records, 1)
+	assert.Len(t, mtrs[2].Records, 1)
+	assert.Equal(t, 2, mtrs[3].Group)
+	assert.Equal(t, uint64(31), mtrs[3].Bytes())
+}
+
+func TestSummarizeRecords(t *testing.T) {
+	records := []*types.LogRecord{
+		{Type: types.LogType(9), Length: 20, LSN: 100, SpaceID: 5, PageNo: 3},
+		{Type: types.LogType(13), Length: 10, LSN: 120, SpaceID: 5, PageNo: 3},
+		{Type: types.LogType(14), Length: 8, LSN: 130, SpaceID: 5, PageNo: 4},
+		{Type: types.LogType(31), Length: 1, LSN: 138},
+		// Page 0 of the system tablespace
+		{Type: types.LogType(1), Length: 4, LSN: 139, Spans: []types.FieldSpan{{Name: "page_no", Kind: types.SpanCompressedInt, Offset: 2062, Length: 1}}},
+	}
+
+	assert.Equal(t, GroupSummary{
+		StartLSN: 100, EndLSN: 139, Records: 5, Bytes: 43, Pages: 3,
+		Inserts: 1, Updates: 1, Deletes: 1, Others: 2,
+	}, SummarizeRecords(records))
+	assert.Equal(t, GroupSummary{}, SummarizeRecords(nil))
+}
+
+func TestAnalyzeWriteAmplification(t *testing.T) {
+	records := []*types.LogRecord{
+		{Type: types.LogType(9), Length: 40, LSN: 100, SpaceID: 5, IndexID: 157, MultiRecordGroup: 1},
+		{Type: types.LogType(9), Length: 30, LSN: 140, SpaceID: 5, IndexID: 157, MultiRecordGroup: 1},
+		{Type: types.LogType(9), Length: 20, LSN: 170, SpaceID: 5, IndexID: 158, MultiRecordGroup: 1},
+		{Type: types.LogType(31), Length: 1, LSN: 190, MultiRecordGroup: 1},
+		{Type: types.LogType(14), Length: 10, LSN: 191, SpaceID: 5, IndexID: 157},
+		{Type: types.LogType(13), Length: 8, LSN: 201, SpaceID: 7, IndexID: 200},
+	}
+	dict := &Dictionary{Spaces: map[uint32]string{5: "sakila/actor"}}
+
+	report := AnalyzeWriteAmplification(records, dict)
+
+	assert.Equal(t, WriteVolume{Bytes: 109, Records: 6, MTRs: 3}, report.Total)
+	assert.Equal(t, WriteVolume{Bytes: 90, Records: 3, MTRs: 1}, report.ByOperation[types.OperationInsert])
+	assert.Equal(t, WriteVolume{Bytes: 1, Records: 1, MTRs: 1}, report.ByOperation[types.OperationOther])
+
+	// MLOG_MULTI_REC_END changes no page
+	assert.Equal(t, WriteVolume{Bytes: 1, Records: 1, MTRs: 1}, report.NoPage)
+
+	// Records of every index of a space are counted together
+	require.Len(t, report.Spaces, 2)
+	top := report.Spaces[0]
+	assert.Equal(t, uint32(5), top.SpaceID)
+	assert.Equal(t, "sakila/actor", top.Table)
+	assert.Equal(t, WriteVolume{Bytes: 100, Records: 4, MTRs: 2}, top.WriteVolume)
+	assert.InDelta(t, 100.0/109.0, top.Share, 1e-9)
+	assert.Equal(t, WriteVolume{Bytes: 90, Records: 3, MTRs: 1}, top.ByOperation[types.OperationInsert])
+	assert.Equal(t, WriteVolume{Bytes: 10, Records: 1, MTRs: 1}, top.ByOperation[types.OperationDelete])
+	assert.Equal(t, WriteVolume{}, top.ByOperation[types.OperationUpdate])
+
+	assert.Equal(t, uint32(7), report.Spaces[1].SpaceID)
+	assert.Empty(t, report.Spaces[1].Table)
+
+	// Without a dictionary spaces are reported by ID only
+	report = AnalyzeWriteAmplification(records, nil)
+	assert.Empty(t, report.Spaces[0].Table)
+}
+
+func TestLoadDictionary(t *testing.T) {
+	dir := t.TempDir()
+	path := dir + "/dict.json"
+	require.NoError(t, os.WriteFile(path, []byte(`{"spaces": {"42": "sakila/actor"}}`), 0644))
+
+	dict, err := LoadDictionary(path)
+	require.NoError(t, err)
+	assert.Equal(t, "sakila/actor", dict.TableName(42))
+	assert.Empty(t, dict.TableName(1))
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"spaces": [}`), 0644))
+	_, err = LoadDictionary(path)
+	assert.Error(t, err)
+
+	_, err = LoadDictionary(dir + "/missing.json")
+	assert.Error(t, err)
+}
+
+func TestLSNClock(t *testing.T) {
+	base := time.Date(2024, 8, 24, 12, 0, 0, 0, time.UTC)
+
+	anchor, err := ParseTimeAnchor("0x3E8@2024-08-24T12:00:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, TimeAnchor{LSN: 1000, Time: base}, anchor)
+	for _, invalid := range []string{"1000", "abc@2024-08-24T12:00:00Z", "1000@yesterday"} {
+		_, err := ParseTimeAnchor(invalid)
+		assert.Error(t, err, invalid)
+	}
+
+	clock, err := NewLSNClock([]TimeAnchor{{LSN: 3000, Time: base.Add(2 * time.Second)}, anchor})
+	require.NoError(t, err)
+	assert.Equal(t, TimeSourceAnchors, clock.Source())
+
+	at, ok := clock.Time(2000)
+	require.True(t, ok)
+	assert.Equal(t, base.Add(time.Second), at)
+	at, _ = clock.Time(4000) // Extrapolated
+	assert.Equal(t, base.Add(3*time.Second), at)
+	lsn, ok := clock.LSN(base.Add(500 * time.Millisecond))
+	require.True(t, ok)
+	assert.Equal(t, uint64(1500), lsn)
+
+	_, err = NewLSNClock([]TimeAnchor{anchor})
+	assert.Error(t, err)
+	_, err = NewLSNClock([]TimeAnchor{anchor, {LSN: 2000, Time: base}})
+	assert.Error(t, err)
+
+	var none *LSNClock
+	assert.Equal(t, TimeSourceNone, none.Source())
+	_, ok = none.Time(1000)
+	assert.False(t, ok)
+}
+
+func TestAnalyzeThroughput(t *testing.T) {
+	records := []*types.LogRecord{
+		{Type: types.LogType(9), Length: 40, LSN: 1000, SpaceID: 5, PageNo: 3, MultiRecordGroup: 1},
+		{Type: types.LogType(13), Length: 30, LSN: 1040, SpaceID: 5, PageNo: 3, MultiRecordGroup: 1},
+		{Type: types.LogType(31), Length: 1, LSN: 1070, MultiRecordGroup: 1},
+		{Type: types.LogType(14), Length: 10, LSN: 1250, SpaceID: 7, PageNo: 9},
+	}
+
+	report, err := AnalyzeThroughput(records, ThroughputConfig{WindowLSN: 100, TopPages: 1})
+	require.NoError(t, err)
+	assert.Equal(t, TimeSourceNone, report.TimeSource)
+	require.Len(t, report.Windows, 3)
+
+	first := report.Windows[0]
+	assert.Equal(t, uint64(1000), first.StartLSN)
+	assert.Equal(t, uint64(1100), first.EndLSN)
+	assert.Equal(t, uint64(71), first.Bytes)
+	assert.Equal(t, uint64(3), first.Records)
+	assert.Equal(t, uint64(1), first.MTRs)
+	assert.Equal(t, uint64(1), first.Inserts)
+	assert.Equal(t, uint64(1), first.Updates)
+	assert.Equal(t, []PageCount{{PageRef: PageRef{SpaceID: 5, PageNo: 3}, Records: 2}}, first.TopPages)
+	assert.Nil(t, first.Start)
+
+	assert.Equal(t, uint64(0), report.Windows[1].Records) // Gaps are kept
+	assert.Equal(t, uint64(1), report.Windows[2].Deletes)
+
+	// Time windows of one second with 1000 LSN per second
+	base := time.Date(2024, 8, 24, 12, 0, 0, 0, time.UTC)
+	clock, err := NewLSNClock([]TimeAnchor{{LSN: 0, Time: base}, {LSN: 1000, Time: base.Add(time.Second)}})
+	require.NoError(t, err)
+
+	report, err = AnalyzeThroughput(records, ThroughputConfig{Interval: 100 * time.Millisecond, Clock: clock})
+	require.NoError(t, err)
+	assert.Equal(t, TimeSourceAnchors, report.TimeSource)
+	assert.Equal(t, 0.1, report.IntervalSeconds)
+	require.Len(t, report.Windows, 3)
+	require.NotNil(t, report.Windows[0].Start)
+	assert.Equal(t, base.Add(time.Second), *report.Windows[0].Start)
+	assert.InDelta(t, 710.0, report.Windows[0].BytesPerSecond, 0.01)
+	assert.Empty(t, report.Windows[0].TopPages)
+
+	_, err = AnalyzeThroughput(records, ThroughputConfig{Interval: time.Second})
+	assert.Error(t, err)
+	_, err = AnalyzeThroughput(records, ThroughputConfig{WindowLSN: 1})
+	assert.NoError(t, err)
+}
+
+func TestBuildStatsReport(t *testing.T) {
+	records := []*types.LogRecord{
+		{Type: types.LogType(9), Length: 40, LSN: 1000, SpaceID: 5, PageNo: 3, MultiRecordGroup: 1},
+		{Type: types.LogType(13), Length: 30, LSN: 1040, SpaceID: 5, PageNo: 3, MultiRecordGroup: 1},
+		{Type: types.LogType(31), Length: 1, LSN: 1070, MultiRecordGroup: 1},
+		{Type: types.LogType(14), Length: 10, LSN: 1250, SpaceID: 7, PageNo: 9},
+	}
+
+	report, err := BuildStatsReport(records, &types.RedoLogHeader{LastCheckpoint: 1100}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), report.TotalRecords)
+	assert.Equal(t, uint64(1000), report.FirstLSN)
+	assert.Equal(t, uint64(1250), report.LastLSN)
+	assert.Equal(t, 1, report.MTRGroups)
+	assert.Equal(t, map[string]uint64{"insert": 1, "update": 1, "delete": 1, "other": 1}, report.Operations)
+	assert.Len(t, report.Types, 4)
+
+	// The top lists are cut to one entry
+	assert.Equal(t, []SpaceStat{{SpaceID: 5, Records: 2, Bytes: 70}}, report.Spaces)
+	assert.Equal(t, []PageStat{{PageRef: PageRef{SpaceID: 5, PageNo: 3}, Records: 2, Bytes: 70}}, report.Pages)
+
+	require.Len(t, report.MTRSizes, 8)
+	assert.Equal(t, "1", report.MTRSizes[0].Label())
+	assert.Equal(t, uint64(1), report.MTRSizes[0].MTRs)
+	assert.Equal(t, "3-4", report.MTRSizes[2].Label())
+	assert.Equal(t, uint64(1), report.MTRSizes[2].MTRs)
+	assert.Equal(t, "65+", report.MTRSizes[7].Label())
+
+	assert.Equal(t, &CheckpointAge{CheckpointLSN: 1100, LastLSN: 1250, Age: 150}, report.Checkpoint)
+	assert.Equal(t, LSNCoverage{SpanBytes: 260, RecordBytes: 81, Percent: 81.0 * 100 / 260, Gaps: 1, LargestGap: 179}, report.Coverage)
+	assert.Equal(t, "none", report.Corruption.Severity)
+
+	// Corruption findings are summarized by issue type
+	records = append(records, &types.LogRecord{Type: types.LogType(1), Length: 0, LSN: 1200})
+	report, err = BuildStatsReport(records, nil, DefaultStatsTop)
+	require.NoError(t, err)
+	assert.Nil(t, report.Checkpoint)
+	assert.Len(t, report.Spaces, 2)
+	assert.Equal(t, 2, report.Corruption.Issues)
+	assert.Equal(t, map[string]int{"zero_length": 1, "lsn_not_increasing": 1}, report.Corruption.ByType)
+	assert.Len(t, report.Corruption.Examples, 2)
+}
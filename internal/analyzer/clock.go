@@ -0,0 +1,130 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Time sources reported by LSNClock.Source
+const (
+	TimeSourceNone    = "none"    // No LSN can be mapped to time
+	TimeSourceAnchors = "anchors" // User supplied LSN@time pairs
+)
+
+// TimeAnchor pairs an LSN with the wall-clock time it was written
+type TimeAnchor struct {
+	LSN  uint64    `json:"lsn"`
+	Time time.Time `json:"time"`
+}
+
+// ParseTimeAnchor parses an anchor given as LSN@TIME, where LSN is decimal
+// or 0x hex and TIME is RFC 3339, e.g. 0x1A2B00@2024-08-24T12:00:00Z
+func ParseTimeAnchor(s string) (TimeAnchor, error) {
+	lsnText, timeText, ok := strings.Cut(s, "@")
+	if !ok {
+		return TimeAnchor{}, fmt.Errorf("invalid anchor %q: expected LSN@TIME", s)
+	}
+
+	lsn, err := strconv.ParseUint(lsnText, 0, 64)
+	if err != nil {
+		return TimeAnchor{}, fmt.Errorf("invalid anchor %q: invalid LSN %q", s, lsnText)
+	}
+	t, err := time.Parse(time.RFC3339Nano, timeText)
+	if err != nil {
+		return TimeAnchor{}, fmt.Errorf("invalid anchor %q: time must be RFC 3339, e.g. 2024-08-24T12:00:00Z", s)
+	}
+	return TimeAnchor{LSN: lsn, Time: t}, nil
+}
+
+// String formats the anchor as LSN@TIME
+func (a TimeAnchor) String() string {
+	return fmt.Sprintf("%d@%s", a.LSN, a.Time.Format(time.RFC3339Nano))
+}
+
+// LSNClock maps LSNs to wall-clock time by linear interpolation between
+// anchors, extrapolating the first and last segment beyond them. A nil
+// clock maps nothing.
+type LSNClock struct {
+	anchors []TimeAnchor // Strictly increasing in both LSN and time
+	source  string
+}
+
+// NewLSNClock creates a clock from user supplied anchors. At least two
+// anchors are needed, and later LSNs must have later times.
+func NewLSNClock(anchors []TimeAnchor) (*LSNClock, error) {
+	if len(anchors) < 2 {
+		return nil, fmt.Errorf("at least two time anchors are needed, got %d", len(anchors))
+	}
+
+	sorted := append([]TimeAnchor(nil), anchors...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].LSN < sorted[j].LSN })
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].LSN == sorted[i-1].LSN {
+			return nil, fmt.Errorf("duplicate time anchor for LSN %d", sorted[i].LSN)
+		}
+		if !sorted[i].Time.After(sorted[i-1].Time) {
+			return nil, fmt.Errorf("time anchor %s is not later than %s", sorted[i], sorted[i-1])
+		}
+	}
+
+	return &LSNClock{anchors: sorted, source: TimeSourceAnchors}, nil
+}
+
+// Source returns where the clock's anchors came from
+func (c *LSNClock) Source() string {
+	if c == nil {
+		return TimeSourceNone
+	}
+	return c.source
+}
+
+// Anchors returns the anchors of the clock, ordered by LSN
+func (c *LSNClock) Anchors() []TimeAnchor {
+	if c == nil {
+		return nil
+	}
+	return append([]TimeAnchor(nil), c.anchors...)
+}
+
+// Time returns the estimated time at which lsn was written
+func (c *LSNClock) Time(lsn uint64) (time.Time, bool) {
+	if c == nil {
+		return time.Time{}, false
+	}
+
+	a, b := c.segment(func(anchor TimeAnchor) bool { return anchor.LSN > lsn })
+	span := float64(b.LSN) - float64(a.LSN)
+	offset := (float64(lsn) - float64(a.LSN)) / span * float64(b.Time.Sub(a.Time))
+	return a.Time.Add(time.Duration(offset)), true
+}
+
+// LSN returns the estimated LSN written at t
+func (c *LSNClock) LSN(t time.Time) (uint64, bool) {
+	if c == nil {
+		return 0, false
+	}
+
+	a, b := c.segment(func(anchor TimeAnchor) bool { return anchor.Time.After(t) })
+	span := float64(b.Time.Sub(a.Time))
+	lsn := float64(a.LSN) + float64(t.Sub(a.Time))/span*(float64(b.LSN)-float64(a.LSN))
+	if lsn < 0 {
+		return 0, true
+	}
+	return uint64(lsn), true
+}
+
+// segment returns the two anchors to interpolate between; after reports
+// whether an anchor lies past the point being mapped
+func (c *LSNClock) segment(after func(TimeAnchor) bool) (TimeAnchor, TimeAnchor) {
+	i := sort.Search(len(c.anchors), func(i int) bool { return after(c.anchors[i]) })
+	switch {
+	case i == 0:
+		i = 1
+	case i == len(c.anchors):
+		i = len(c.anchors) - 1
+	}
+	return c.anchors[i-1], c.anchors[i]
+}
@@ -0,0 +1,246 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// DiffResult describes how two sets of records differ. Side A is the
+// baseline and deltas are B minus A.
+type DiffResult struct {
+	StatsA *types.RedoLogStats `json:"stats_a"`
+	StatsB *types.RedoLogStats `json:"stats_b"`
+
+	// Types is the record type distribution of both sides, by type ID
+	Types []TypeDelta `json:"types"`
+
+	// Tables holds the volume per table ID, or per tablespace for records
+	// without one
+	Tables []TableDelta `json:"tables"`
+
+	SpacesOnlyInA []uint32  `json:"spaces_only_in_a"`
+	SpacesOnlyInB []uint32  `json:"spaces_only_in_b"`
+	PagesOnlyInA  []PageRef `json:"pages_only_in_a"`
+	PagesOnlyInB  []PageRef `json:"pages_only_in_b"`
+}
+
+// TypeDelta compares the number of records of one type
+type TypeDelta struct {
+	Type   types.LogType `json:"type_id"`
+	Name   string        `json:"type"`
+	CountA uint64        `json:"count_a"`
+	CountB uint64        `json:"count_b"`
+	Delta  int64         `json:"delta"`
+}
+
+// What a TableDelta counts
+const (
+	TableKeyTable = "table" // Records carrying the table ID
+	TableKeySpace = "space" // Records of the tablespace without a table ID
+)
+
+// TableDelta compares the redo generated for one table. Records that carry
+// a table ID are counted under it; the rest under their tablespace, which
+// is one table with file-per-table but many in the system and general
+// tablespaces.
+type TableDelta struct {
+	By       string `json:"by"` // TableKeyTable or TableKeySpace
+	ID       uint32 `json:"id"` // Table ID, or space ID
+	RecordsA uint64 `json:"records_a"`
+	RecordsB uint64 `json:"records_b"`
+	BytesA   uint64 `json:"bytes_a"`
+	BytesB   uint64 `json:"bytes_b"`
+	ChangesA uint64 `json:"changes_a"` // Insert, update and delete records
+	ChangesB uint64 `json:"changes_b"`
+}
+
+// Label names the table or tablespace counted, e.g. "table 42"
+func (d TableDelta) Label() string {
+	return fmt.Sprintf("%s %d", d.By, d.ID)
+}
+
+// PageRef identifies a page within a tablespace
+type PageRef struct {
+	SpaceID uint32 `json:"space_id"`
+	PageNo  uint32 `json:"page_no"`
+}
+
+// tableKey identifies the table or tablespace a record is counted under
+type tableKey struct {
+	by string
+	id uint32
+}
+
+// tableVolume accumulates the redo generated for one table
+type tableVolume struct {
+	records uint64
+	bytes   uint64
+	changes uint64
+}
+
+// Compare compares two sets of records, for example the same workload
+// before and after a schema change, or a primary and a replica. Records
+// without a table ID or a page (file level and MTR markers) only count
+// towards the type distribution.
+func (a *redoLogAnalyzer) Compare(recordsA, recordsB []*types.LogRecord) (*DiffResult, error) {
+	statsA, err := a.GenerateStats(recordsA)
+	if err != nil {
+		return nil, err
+	}
+	statsB, err := a.GenerateStats(recordsB)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DiffResult{
+		StatsA:        statsA,
+		StatsB:        statsB,
+		Types:         diffTypes(statsA, statsB),
+		SpacesOnlyInA: make([]uint32, 0),
+		SpacesOnlyInB: make([]uint32, 0),
+		PagesOnlyInA:  make([]PageRef, 0),
+		PagesOnlyInB:  make([]PageRef, 0),
+	}
+
+	tablesA, spacesA, pagesA := collectTables(recordsA)
+	tablesB, spacesB, pagesB := collectTables(recordsB)
+
+	for spaceID := range spacesA {
+		if _, ok := spacesB[spaceID]; !ok {
+			result.SpacesOnlyInA = append(result.SpacesOnlyInA, spaceID)
+		}
+	}
+	for spaceID := range spacesB {
+		if _, ok := spacesA[spaceID]; !ok {
+			result.SpacesOnlyInB = append(result.SpacesOnlyInB, spaceID)
+		}
+	}
+	for page := range pagesA {
+		if _, ok := pagesB[page]; !ok {
+			result.PagesOnlyInA = append(result.PagesOnlyInA, page)
+		}
+	}
+	for page := range pagesB {
+		if _, ok := pagesA[page]; !ok {
+			result.PagesOnlyInB = append(result.PagesOnlyInB, page)
+		}
+	}
+	sortSpaceIDs(result.SpacesOnlyInA)
+	sortSpaceIDs(result.SpacesOnlyInB)
+	sortPageRefs(result.PagesOnlyInA)
+	sortPageRefs(result.PagesOnlyInB)
+
+	result.Tables = diffTables(tablesA, tablesB)
+
+	return result, nil
+}
+
+// diffTypes compares the record type distributions of two stats
+func diffTypes(statsA, statsB *types.RedoLogStats) []TypeDelta {
+	all := make(map[types.LogType]struct{})
+	for logType := range statsA.RecordsByType {
+		all[logType] = struct{}{}
+	}
+	for logType := range statsB.RecordsByType {
+		all[logType] = struct{}{}
+	}
+
+	deltas := make([]TypeDelta, 0, len(all))
+	for logType := range all {
+		countA := statsA.RecordsByType[logType]
+		countB := statsB.RecordsByType[logType]
+		deltas = append(deltas, TypeDelta{
+			Type:   logType,
+			Name:   logType.String(),
+			CountA: countA,
+			CountB: countB,
+			Delta:  int64(countB) - int64(countA),
+		})
+	}
+	sort.Slice(deltas, func(i, j int) bool { return deltas[i].Type < deltas[j].Type })
+	return deltas
+}
+
+// diffTables compares the per-table volume of both sides
+func diffTables(tablesA, tablesB map[tableKey]*tableVolume) []TableDelta {
+	all := make(map[tableKey]struct{})
+	for key := range tablesA {
+		all[key] = struct{}{}
+	}
+	for key := range tablesB {
+		all[key] = struct{}{}
+	}
+
+	deltas := make([]TableDelta, 0, len(all))
+	for key := range all {
+		delta := TableDelta{By: key.by, ID: key.id}
+		if volume, ok := tablesA[key]; ok {
+			delta.RecordsA, delta.BytesA, delta.ChangesA = volume.records, volume.bytes, volume.changes
+		}
+		if volume, ok := tablesB[key]; ok {
+			delta.RecordsB, delta.BytesB, delta.ChangesB = volume.records, volume.bytes, volume.changes
+		}
+		deltas = append(deltas, delta)
+	}
+	sort.Slice(deltas, func(i, j int) bool {
+		if deltas[i].By != deltas[j].By {
+			return deltas[i].By == TableKeyTable
+		}
+		return deltas[i].ID < deltas[j].ID
+	})
+	return deltas
+}
+
+// collectTables returns the volume per table, the spaces and the pages
+// touched. Page 0 of the system tablespace is a page like any other.
+func collectTables(records []*types.LogRecord) (map[tableKey]*tableVolume, map[uint32]struct{}, map[PageRef]struct{}) {
+	tables := make(map[tableKey]*tableVolume)
+	spaces := make(map[uint32]struct{})
+	pages := make(map[PageRef]struct{})
+
+	for _, record := range records {
+		hasPage := record.HasPage()
+		var key tableKey
+		switch {
+		case record.TableID != 0:
+			key = tableKey{by: TableKeyTable, id: record.TableID}
+		case hasPage:
+			key = tableKey{by: TableKeySpace, id: record.SpaceID}
+		default:
+			continue
+		}
+
+		volume, ok := tables[key]
+		if !ok {
+			volume = &tableVolume{}
+			tables[key] = volume
+		}
+		volume.records++
+		volume.bytes += uint64(record.Length)
+		if record.Type.Operation() != types.OperationOther {
+			volume.changes++
+		}
+
+		if hasPage {
+			spaces[record.SpaceID] = struct{}{}
+			pages[PageRef{SpaceID: record.SpaceID, PageNo: record.PageNo}] = struct{}{}
+		}
+	}
+
+	return tables, spaces, pages
+}
+
+func sortSpaceIDs(ids []uint32) {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+}
+
+func sortPageRefs(pages []PageRef) {
+	sort.Slice(pages, func(i, j int) bool {
+		if pages[i].SpaceID != pages[j].SpaceID {
+			return pages[i].SpaceID < pages[j].SpaceID
+		}
+		return pages[i].PageNo < pages[j].PageNo
+	})
+}
@@ -19,6 +19,9 @@ type RedoLogAnalyzer interface {
 	
 	// DetectCorruption detects potential corruption in log records
 	DetectCorruption(records []*types.LogRecord) (*CorruptionReport, error)
+
+	// Compare reports how the records of B differ from those of A
+	Compare(recordsA, recordsB []*types.LogRecord) (*DiffResult, error)
 }
 
 // TransactionAnalyzer defines the interface for transaction analysis
@@ -36,6 +39,7 @@ type TransactionAnalyzer interface {
 // AnalysisResult contains the complete analysis of a redo log
 type AnalysisResult struct {
 	Header        *types.RedoLogHeader
+	Records       []*types.LogRecord
 	Stats         *types.RedoLogStats
 	Transactions  []*Transaction
 	Corruption    *CorruptionReport
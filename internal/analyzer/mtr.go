@@ -0,0 +1,94 @@
+package analyzer
+
+import (
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// MTR is a mini-transaction: the records InnoDB applies atomically. Records
+// in a multi-record group form one MTR; every other record is an MTR of its own.
+type MTR struct {
+	ID       int // 1-based position in the log
+	Group    int // MultiRecordGroup of the records (0 for single-record MTRs)
+	StartLSN uint64
+	EndLSN   uint64
+	Records  []*types.LogRecord
+}
+
+// Bytes returns the encoded size of the MTR's records
+func (m *MTR) Bytes() uint64 {
+	var total uint64
+	for _, record := range m.Records {
+		total += uint64(record.Length)
+	}
+	return total
+}
+
+// GroupMTRs splits records into mini-transactions, in log order. Records must
+// have been processed by reader.DetectMultiRecordGroups.
+func GroupMTRs(records []*types.LogRecord) []*MTR {
+	mtrs := make([]*MTR, 0)
+	var current *MTR
+
+	for _, record := range records {
+		if current == nil || record.MultiRecordGroup == 0 || record.MultiRecordGroup != current.Group {
+			current = &MTR{
+				ID:       len(mtrs) + 1,
+				Group:    record.MultiRecordGroup,
+				StartLSN: record.LSN,
+			}
+			mtrs = append(mtrs, current)
+		}
+
+		current.Records = append(current.Records, record)
+		current.EndLSN = record.LSN
+	}
+
+	return mtrs
+}
+
+// GroupSummary describes a group of records, such as an MTR or a transaction
+type GroupSummary struct {
+	StartLSN uint64 `json:"start_lsn"`
+	EndLSN   uint64 `json:"end_lsn"`
+	Records  int    `json:"records"`
+	Bytes    uint64 `json:"bytes"`
+	Pages    int    `json:"pages"` // Distinct pages touched
+	Inserts  int    `json:"inserts"`
+	Updates  int    `json:"updates"`
+	Deletes  int    `json:"deletes"`
+	Others   int    `json:"others"`
+}
+
+// SummarizeRecords returns the LSN range, size, pages touched and operation
+// mix of records
+func SummarizeRecords(records []*types.LogRecord) GroupSummary {
+	summary := GroupSummary{Records: len(records)}
+	pages := make(map[PageRef]struct{})
+
+	for i, record := range records {
+		if i == 0 || record.LSN < summary.StartLSN {
+			summary.StartLSN = record.LSN
+		}
+		if record.LSN > summary.EndLSN {
+			summary.EndLSN = record.LSN
+		}
+		summary.Bytes += uint64(record.Length)
+		if record.HasPage() {
+			pages[PageRef{SpaceID: record.SpaceID, PageNo: record.PageNo}] = struct{}{}
+		}
+
+		switch record.Type.Operation() {
+		case types.OperationInsert:
+			summary.Inserts++
+		case types.OperationUpdate:
+			summary.Updates++
+		case types.OperationDelete:
+			summary.Deletes++
+		default:
+			summary.Others++
+		}
+	}
+	summary.Pages = len(pages)
+
+	return summary
+}
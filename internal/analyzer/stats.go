@@ -0,0 +1,293 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// DefaultStatsTop is the number of spaces and pages listed in a stats report
+const DefaultStatsTop = 10
+
+// statsCorruptionExamples is the number of corruption issues kept in a stats report
+const statsCorruptionExamples = 5
+
+// mtrSizeBuckets are the upper bounds, in records, of the MTR size histogram
+// buckets; the last bucket is unbounded
+var mtrSizeBuckets = []int{1, 2, 4, 8, 16, 32, 64}
+
+// StatsReport summarizes records: the type and operation mix, the most
+// written spaces and pages, MTR sizes, checkpoint age, corruption findings
+// and how much of the LSN range the records cover
+type StatsReport struct {
+	TotalRecords uint64            `json:"total_records"`
+	SizeInBytes  uint64            `json:"size_bytes"`
+	FirstLSN     uint64            `json:"first_lsn"`
+	LastLSN      uint64            `json:"last_lsn"`
+	MTRGroups    int               `json:"mtr_groups"`
+	Operations   map[string]uint64 `json:"operations"`
+	Types        []TypeStat        `json:"types"`
+	Spaces       []SpaceStat       `json:"top_spaces"`
+	Pages        []PageStat        `json:"top_pages"`
+	MTRSizes     []HistogramBucket `json:"mtr_sizes"`
+	Checkpoint   *CheckpointAge    `json:"checkpoint,omitempty"` // nil when the header has no checkpoint
+	Corruption   CorruptionSummary `json:"corruption"`
+	Coverage     LSNCoverage       `json:"coverage"`
+}
+
+// TypeStat is the number of records of one type
+type TypeStat struct {
+	TypeID uint8  `json:"type_id"`
+	Type   string `json:"type"`
+	Count  uint64 `json:"count"`
+}
+
+// SpaceStat is the redo written to one tablespace
+type SpaceStat struct {
+	SpaceID uint32 `json:"space_id"`
+	Records uint64 `json:"records"`
+	Bytes   uint64 `json:"bytes"`
+}
+
+// PageStat is the redo written to one page
+type PageStat struct {
+	PageRef
+	Records uint64 `json:"records"`
+	Bytes   uint64 `json:"bytes"`
+}
+
+// HistogramBucket counts the MTRs of Min to Max records (Max 0 = no limit)
+type HistogramBucket struct {
+	Min  int    `json:"min"`
+	Max  int    `json:"max"`
+	MTRs uint64 `json:"mtrs"`
+}
+
+// Label returns the record range of the bucket, e.g. "3-4" or "65+"
+func (b HistogramBucket) Label() string {
+	switch {
+	case b.Max == 0:
+		return fmt.Sprintf("%d+", b.Min)
+	case b.Min == b.Max:
+		return fmt.Sprintf("%d", b.Min)
+	default:
+		return fmt.Sprintf("%d-%d", b.Min, b.Max)
+	}
+}
+
+// CheckpointAge is how far the last record is ahead of the last checkpoint,
+// i.e. the redo that crash recovery would have to apply
+type CheckpointAge struct {
+	CheckpointLSN uint64 `json:"checkpoint_lsn"`
+	LastLSN       uint64 `json:"last_lsn"`
+	Age           uint64 `json:"age"`
+}
+
+// CorruptionSummary condenses the DetectCorruption report
+type CorruptionSummary struct {
+	Issues   int               `json:"issues"`
+	Severity string            `json:"severity"`
+	ByType   map[string]int    `json:"by_type"`
+	Examples []CorruptionIssue `json:"examples"` // The first issues found
+}
+
+// LSNCoverage compares the bytes of the records with the LSN range they
+// span. Gaps are places where a record starts beyond the end of the
+// previous one: block headers and trailers, or records that were not read.
+type LSNCoverage struct {
+	SpanBytes   uint64  `json:"span_bytes"`
+	RecordBytes uint64  `json:"record_bytes"`
+	Percent     float64 `json:"percent"`
+	Gaps        int     `json:"gaps"`
+	LargestGap  uint64  `json:"largest_gap"`
+}
+
+// BuildStatsReport builds the stats report of records, listing the top
+// spaces and pages by record count. The header may be nil.
+func BuildStatsReport(records []*types.LogRecord, header *types.RedoLogHeader, top int) (*StatsReport, error) {
+	a := NewRedoLogAnalyzer()
+	stats, err := a.GenerateStats(records)
+	if err != nil {
+		return nil, err
+	}
+	corruption, err := a.DetectCorruption(records)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &StatsReport{
+		TotalRecords: stats.TotalRecords,
+		SizeInBytes:  stats.SizeInBytes,
+		Operations:   map[string]uint64{"insert": 0, "update": 0, "delete": 0, "other": 0},
+		Types:        make([]TypeStat, 0, len(stats.RecordsByType)),
+		Corruption:   summarizeCorruption(corruption),
+	}
+	if len(records) > 0 {
+		report.FirstLSN = records[0].LSN
+		report.LastLSN = records[len(records)-1].LSN
+	}
+
+	for _, tc := range SortedTypeCounts(stats) {
+		report.Types = append(report.Types, TypeStat{TypeID: uint8(tc.Type), Type: tc.Type.String(), Count: tc.Count})
+	}
+
+	spaces := make(map[uint32]*SpaceStat)
+	pages := make(map[PageRef]*PageStat)
+	groups := make(map[int]struct{})
+	for _, record := range records {
+		report.Operations[record.Type.Operation()]++
+		if record.MultiRecordGroup > 0 {
+			groups[record.MultiRecordGroup] = struct{}{}
+		}
+		if record.SpaceID == 0 && record.PageNo == 0 {
+			continue
+		}
+
+		space, ok := spaces[record.SpaceID]
+		if !ok {
+			space = &SpaceStat{SpaceID: record.SpaceID}
+			spaces[record.SpaceID] = space
+		}
+		space.Records++
+		space.Bytes += uint64(record.Length)
+
+		ref := PageRef{SpaceID: record.SpaceID, PageNo: record.PageNo}
+		page, ok := pages[ref]
+		if !ok {
+			page = &PageStat{PageRef: ref}
+			pages[ref] = page
+		}
+		page.Records++
+		page.Bytes += uint64(record.Length)
+	}
+	report.MTRGroups = len(groups)
+	report.Spaces = topSpaceStats(spaces, top)
+	report.Pages = topPageStats(pages, top)
+	report.MTRSizes = mtrSizeHistogram(GroupMTRs(records))
+	report.Coverage = lsnCoverage(records)
+
+	if header != nil && header.LastCheckpoint > 0 && len(records) > 0 {
+		report.Checkpoint = &CheckpointAge{CheckpointLSN: header.LastCheckpoint, LastLSN: report.LastLSN}
+		if report.LastLSN > header.LastCheckpoint {
+			report.Checkpoint.Age = report.LastLSN - header.LastCheckpoint
+		}
+	}
+
+	return report, nil
+}
+
+// topSpaceStats returns the n spaces with the most records (all if n < 0)
+func topSpaceStats(spaces map[uint32]*SpaceStat, n int) []SpaceStat {
+	list := make([]SpaceStat, 0, len(spaces))
+	for _, space := range spaces {
+		list = append(list, *space)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Records != list[j].Records {
+			return list[i].Records > list[j].Records
+		}
+		if list[i].Bytes != list[j].Bytes {
+			return list[i].Bytes > list[j].Bytes
+		}
+		return list[i].SpaceID < list[j].SpaceID
+	})
+	if n >= 0 && len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
+
+// topPageStats returns the n pages with the most records (all if n < 0)
+func topPageStats(pages map[PageRef]*PageStat, n int) []PageStat {
+	list := make([]PageStat, 0, len(pages))
+	for _, page := range pages {
+		list = append(list, *page)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Records != list[j].Records {
+			return list[i].Records > list[j].Records
+		}
+		if list[i].Bytes != list[j].Bytes {
+			return list[i].Bytes > list[j].Bytes
+		}
+		if list[i].SpaceID != list[j].SpaceID {
+			return list[i].SpaceID < list[j].SpaceID
+		}
+		return list[i].PageNo < list[j].PageNo
+	})
+	if n >= 0 && len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
+
+// mtrSizeHistogram counts MTRs by number of records
+func mtrSizeHistogram(mtrs []*MTR) []HistogramBucket {
+	buckets := make([]HistogramBucket, 0, len(mtrSizeBuckets)+1)
+	low := 1
+	for _, high := range mtrSizeBuckets {
+		buckets = append(buckets, HistogramBucket{Min: low, Max: high})
+		low = high + 1
+	}
+	buckets = append(buckets, HistogramBucket{Min: low})
+
+	for _, mtr := range mtrs {
+		i := sort.SearchInts(mtrSizeBuckets, len(mtr.Records))
+		buckets[i].MTRs++
+	}
+	return buckets
+}
+
+// summarizeCorruption condenses a corruption report
+func summarizeCorruption(report *CorruptionReport) CorruptionSummary {
+	summary := CorruptionSummary{
+		Issues:   len(report.CorruptedRecords),
+		Severity: "none",
+		ByType:   make(map[string]int),
+		Examples: make([]CorruptionIssue, 0),
+	}
+	if report.HasCorruption {
+		summary.Severity = report.Severity.String()
+	}
+	for _, issue := range report.CorruptedRecords {
+		summary.ByType[issue.IssueType]++
+		if len(summary.Examples) < statsCorruptionExamples {
+			summary.Examples = append(summary.Examples, issue)
+		}
+	}
+	return summary
+}
+
+// lsnCoverage measures how much of the LSN range the records cover
+func lsnCoverage(records []*types.LogRecord) LSNCoverage {
+	var coverage LSNCoverage
+	if len(records) == 0 {
+		return coverage
+	}
+
+	first, last := lsnRange(records)
+	var lastLength uint64
+	for i, record := range records {
+		coverage.RecordBytes += uint64(record.Length)
+		if record.LSN == last {
+			lastLength = uint64(record.Length)
+		}
+		if i == 0 {
+			continue
+		}
+		prev := records[i-1]
+		if end := prev.LSN + uint64(prev.Length); record.LSN > end {
+			coverage.Gaps++
+			if gap := record.LSN - end; gap > coverage.LargestGap {
+				coverage.LargestGap = gap
+			}
+		}
+	}
+
+	coverage.SpanBytes = last + lastLength - first
+	if coverage.SpanBytes > 0 {
+		coverage.Percent = float64(coverage.RecordBytes) * 100 / float64(coverage.SpanBytes)
+	}
+	return coverage
+}
@@ -0,0 +1,219 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// DefaultThroughputWindows is the number of windows used when no window size is given
+const DefaultThroughputWindows = 60
+
+// maxThroughputWindows guards against a window size far too small for the log
+const maxThroughputWindows = 100000
+
+// ThroughputConfig selects how records are bucketed into windows
+type ThroughputConfig struct {
+	WindowLSN uint64        // LSN width of a window; 0 picks DefaultThroughputWindows windows
+	Interval  time.Duration // Bucket by wall-clock time instead; requires Clock
+	Clock     *LSNClock     // Maps LSNs to time (nil = no time)
+	TopPages  int           // Pages listed per window
+}
+
+// ThroughputReport is the redo generated per window of LSN or time
+type ThroughputReport struct {
+	TimeSource      string              `json:"time_source"`
+	WindowLSN       uint64              `json:"window_lsn,omitempty"`
+	IntervalSeconds float64             `json:"interval_seconds,omitempty"`
+	Windows         []*ThroughputWindow `json:"windows"`
+}
+
+// ThroughputWindow is the redo generated within one window. StartLSN is
+// inclusive and EndLSN exclusive.
+type ThroughputWindow struct {
+	StartLSN       uint64      `json:"start_lsn"`
+	EndLSN         uint64      `json:"end_lsn"`
+	Start          *time.Time  `json:"start,omitempty"`
+	End            *time.Time  `json:"end,omitempty"`
+	Bytes          uint64      `json:"bytes"`
+	Records        uint64      `json:"records"`
+	MTRs           uint64      `json:"mtrs"` // MTRs starting in the window
+	Inserts        uint64      `json:"inserts"`
+	Updates        uint64      `json:"updates"`
+	Deletes        uint64      `json:"deletes"`
+	BytesPerSecond float64     `json:"bytes_per_second,omitempty"`
+	TopPages       []PageCount `json:"top_pages"`
+}
+
+// PageCount is the number of records written to a page
+type PageCount struct {
+	PageRef
+	Records uint64 `json:"records"`
+}
+
+// AnalyzeThroughput buckets records into consecutive windows of LSN, or of
+// time when cfg.Interval is set, and sums the redo written in each. Windows
+// without records are kept so the series has no gaps.
+func AnalyzeThroughput(records []*types.LogRecord, cfg ThroughputConfig) (*ThroughputReport, error) {
+	report := &ThroughputReport{
+		TimeSource: cfg.Clock.Source(),
+		Windows:    make([]*ThroughputWindow, 0),
+	}
+	if len(records) == 0 {
+		return report, nil
+	}
+
+	first, last := lsnRange(records)
+
+	var bounds []uint64
+	var times []time.Time // Window boundaries in time, if known
+	var err error
+	if cfg.Interval > 0 {
+		report.IntervalSeconds = cfg.Interval.Seconds()
+		bounds, times, err = timeBounds(first, last, cfg.Interval, cfg.Clock)
+	} else {
+		report.WindowLSN = cfg.WindowLSN
+		if report.WindowLSN == 0 {
+			report.WindowLSN = (last-first)/DefaultThroughputWindows + 1
+		}
+		bounds, err = lsnBounds(first, last, report.WindowLSN)
+		if cfg.Clock != nil {
+			for _, lsn := range bounds {
+				t, _ := cfg.Clock.Time(lsn)
+				times = append(times, t)
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make([]map[PageRef]uint64, len(bounds)-1)
+	for i := range pages {
+		window := &ThroughputWindow{StartLSN: bounds[i], EndLSN: bounds[i+1], TopPages: make([]PageCount, 0)}
+		if times != nil {
+			window.Start, window.End = &times[i], &times[i+1]
+		}
+		report.Windows = append(report.Windows, window)
+		pages[i] = make(map[PageRef]uint64)
+	}
+
+	windowOf := func(lsn uint64) int {
+		return sort.Search(len(bounds)-1, func(i int) bool { return bounds[i+1] > lsn })
+	}
+
+	for _, mtr := range GroupMTRs(records) {
+		report.Windows[windowOf(mtr.Records[0].LSN)].MTRs++
+
+		for _, record := range mtr.Records {
+			i := windowOf(record.LSN)
+			window := report.Windows[i]
+			window.Bytes += uint64(record.Length)
+			window.Records++
+			switch record.Type.Operation() {
+			case types.OperationInsert:
+				window.Inserts++
+			case types.OperationUpdate:
+				window.Updates++
+			case types.OperationDelete:
+				window.Deletes++
+			}
+			if record.HasPage() {
+				pages[i][PageRef{SpaceID: record.SpaceID, PageNo: record.PageNo}]++
+			}
+		}
+	}
+
+	for i, window := range report.Windows {
+		window.TopPages = topPages(pages[i], cfg.TopPages)
+		if window.Start != nil {
+			if seconds := window.End.Sub(*window.Start).Seconds(); seconds > 0 {
+				window.BytesPerSecond = float64(window.Bytes) / seconds
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// lsnRange returns the lowest and highest LSN of records
+func lsnRange(records []*types.LogRecord) (uint64, uint64) {
+	first, last := records[0].LSN, records[0].LSN
+	for _, record := range records[1:] {
+		if record.LSN < first {
+			first = record.LSN
+		}
+		if record.LSN > last {
+			last = record.LSN
+		}
+	}
+	return first, last
+}
+
+// lsnBounds returns the window boundaries for fixed LSN widths
+func lsnBounds(first, last, width uint64) ([]uint64, error) {
+	count := (last-first)/width + 1
+	if count > maxThroughputWindows {
+		return nil, fmt.Errorf("window of %d LSN would produce %d windows (limit %d)", width, count, maxThroughputWindows)
+	}
+
+	bounds := make([]uint64, 0, count+1)
+	for i := uint64(0); i <= count; i++ {
+		bounds = append(bounds, first+i*width)
+	}
+	return bounds, nil
+}
+
+// timeBounds returns the window boundaries, in LSN and time, for fixed
+// time intervals starting at the time of the first LSN
+func timeBounds(first, last uint64, interval time.Duration, clock *LSNClock) ([]uint64, []time.Time, error) {
+	start, ok := clock.Time(first)
+	if !ok {
+		return nil, nil, fmt.Errorf("time windows need LSN to time anchors")
+	}
+	end, _ := clock.Time(last)
+
+	count := int64(end.Sub(start)/interval) + 1
+	if count > maxThroughputWindows {
+		return nil, nil, fmt.Errorf("interval of %s would produce %d windows (limit %d)", interval, count, maxThroughputWindows)
+	}
+
+	bounds := []uint64{first}
+	times := []time.Time{start}
+	for i := int64(1); i <= count; i++ {
+		t := start.Add(time.Duration(i) * interval)
+		lsn, _ := clock.LSN(t)
+		if lsn <= bounds[len(bounds)-1] {
+			lsn = bounds[len(bounds)-1] + 1
+		}
+		bounds = append(bounds, lsn)
+		times = append(times, t)
+	}
+	if bounds[len(bounds)-1] <= last {
+		bounds[len(bounds)-1] = last + 1
+	}
+	return bounds, times, nil
+}
+
+// topPages returns the n pages with the most records
+func topPages(pages map[PageRef]uint64, n int) []PageCount {
+	counts := make([]PageCount, 0, len(pages))
+	for page, records := range pages {
+		counts = append(counts, PageCount{PageRef: page, Records: records})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Records != counts[j].Records {
+			return counts[i].Records > counts[j].Records
+		}
+		if counts[i].SpaceID != counts[j].SpaceID {
+			return counts[i].SpaceID < counts[j].SpaceID
+		}
+		return counts[i].PageNo < counts[j].PageNo
+	})
+	if n >= 0 && len(counts) > n {
+		counts = counts[:n]
+	}
+	return counts
+}
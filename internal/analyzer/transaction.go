@@ -0,0 +1,184 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// Complexity thresholds by number of records in a transaction
+const (
+	moderateComplexityRecords = 5
+	highComplexityRecords     = 50
+)
+
+// transactionAnalyzer implements TransactionAnalyzer interface
+type transactionAnalyzer struct{}
+
+// NewTransactionAnalyzer creates a new TransactionAnalyzer instance
+func NewTransactionAnalyzer() TransactionAnalyzer {
+	return &transactionAnalyzer{}
+}
+
+// ReconstructTransactions reconstructs transactions from log records. Only
+// records carrying a transaction ID take part; MySQL redo records do not
+// store one, so real redo logs usually yield no transactions.
+func (a *transactionAnalyzer) ReconstructTransactions(records []*types.LogRecord) ([]*Transaction, error) {
+	byID := make(map[uint64]*Transaction)
+	var order []uint64
+
+	for _, record := range records {
+		if record.TransactionID == 0 {
+			continue
+		}
+
+		txn, exists := byID[record.TransactionID]
+		if !exists {
+			txn = &Transaction{
+				ID:       record.TransactionID,
+				StartLSN: record.LSN,
+				Status:   TransactionIncomplete,
+			}
+			byID[record.TransactionID] = txn
+			order = append(order, record.TransactionID)
+		}
+
+		txn.Records = append(txn.Records, record)
+		if record.LSN < txn.StartLSN {
+			txn.StartLSN = record.LSN
+		}
+		if record.LSN > txn.EndLSN {
+			txn.EndLSN = record.LSN
+		}
+
+		switch record.Type {
+		case types.LogTypeCommit:
+			txn.Status = TransactionCommitted
+		case types.LogTypeRollback:
+			txn.Status = TransactionRolledBack
+		}
+	}
+
+	transactions := make([]*Transaction, 0, len(order))
+	for _, id := range order {
+		txn := byID[id]
+		txn.TableAffected = tablesAffected(txn.Records)
+		txn.Duration = transactionDuration(txn.Records)
+		transactions = append(transactions, txn)
+	}
+
+	return transactions, nil
+}
+
+// FindIncompleteTransactions finds transactions without commit records
+func (a *transactionAnalyzer) FindIncompleteTransactions(records []*types.LogRecord) ([]*Transaction, error) {
+	transactions, err := a.ReconstructTransactions(records)
+	if err != nil {
+		return nil, err
+	}
+
+	incomplete := make([]*Transaction, 0)
+	for _, txn := range transactions {
+		if txn.Status == TransactionIncomplete {
+			incomplete = append(incomplete, txn)
+		}
+	}
+	return incomplete, nil
+}
+
+// AnalyzeTransaction provides detailed analysis of a single transaction
+func (a *transactionAnalyzer) AnalyzeTransaction(txn *Transaction) (*TransactionAnalysis, error) {
+	if txn == nil {
+		return nil, fmt.Errorf("transaction is nil")
+	}
+
+	analysis := &TransactionAnalysis{
+		Type:          "OTHER",
+		TablesChanged: tablesAffected(txn.Records),
+		Complexity:    ComplexitySimple,
+		Issues:        make([]string, 0),
+	}
+
+	for _, record := range txn.Records {
+		if isRowOperation(record.Type) {
+			analysis.RowsAffected++
+		}
+		if isDDLOperation(record.Type) {
+			analysis.Type = "DDL"
+		}
+	}
+	if analysis.Type != "DDL" && analysis.RowsAffected > 0 {
+		analysis.Type = "DML"
+	}
+
+	switch {
+	case len(txn.Records) > highComplexityRecords:
+		analysis.Complexity = ComplexityHigh
+	case len(txn.Records) > moderateComplexityRecords:
+		analysis.Complexity = ComplexityModerate
+	}
+
+	switch txn.Status {
+	case TransactionIncomplete:
+		analysis.Issues = append(analysis.Issues, "transaction has no commit or rollback record")
+	case TransactionRolledBack:
+		analysis.Issues = append(analysis.Issues, "transaction was rolled back")
+	}
+	if txn.EndLSN < txn.StartLSN {
+		analysis.Issues = append(analysis.Issues, fmt.Sprintf("end LSN %d precedes start LSN %d", txn.EndLSN, txn.StartLSN))
+	}
+
+	return analysis, nil
+}
+
+// isRowOperation reports whether a record type changes a row. Both the
+// simplified test format types and the MySQL record operations count.
+func isRowOperation(logType types.LogType) bool {
+	switch logType {
+	case types.LogTypeInsert, types.LogTypeUpdate, types.LogTypeDelete:
+		return true
+	}
+	return logType.IsTransactional()
+}
+
+// isDDLOperation reports whether a record type belongs to a schema change
+func isDDLOperation(logType types.LogType) bool {
+	switch uint8(logType) {
+	case 33, 34, 35, 62: // MLOG_FILE_CREATE, MLOG_FILE_RENAME, MLOG_FILE_DELETE, MLOG_TABLE_DYNAMIC_META
+		return true
+	default:
+		return false
+	}
+}
+
+// tablesAffected returns the sorted, distinct non-zero table IDs of records
+func tablesAffected(records []*types.LogRecord) []uint32 {
+	seen := make(map[uint32]struct{})
+	tables := make([]uint32, 0)
+	for _, record := range records {
+		if record.TableID == 0 {
+			continue
+		}
+		if _, exists := seen[record.TableID]; exists {
+			continue
+		}
+		seen[record.TableID] = struct{}{}
+		tables = append(tables, record.TableID)
+	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i] < tables[j] })
+	return tables
+}
+
+// transactionDuration returns the time between the first and last record in microseconds
+func transactionDuration(records []*types.LogRecord) int64 {
+	if len(records) < 2 {
+		return 0
+	}
+	first := records[0].Timestamp
+	last := records[len(records)-1].Timestamp
+	if first.IsZero() || last.IsZero() {
+		return 0
+	}
+	return last.Sub(first).Microseconds()
+}
@@ -0,0 +1,248 @@
+// Package cdc turns decoded redo log records into row-level change events
+// in the Debezium JSON envelope
+package cdc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/analyzer"
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// Connector is the source.connector of every event
+const Connector = "innodb-redo"
+
+// DefaultServerName is the logical server name used when none is given
+const DefaultServerName = "redolog"
+
+// Debezium operation codes
+const (
+	OpCreate = "c"
+	OpUpdate = "u"
+	OpDelete = "d"
+)
+
+// Event is one change event. It is the envelope the Debezium JSON converter
+// writes with schemas disabled.
+type Event struct {
+	Before      map[string]interface{} `json:"before"`
+	After       map[string]interface{} `json:"after"`
+	Source      Source                 `json:"source"`
+	Op          string                 `json:"op"`
+	TsMs        int64                  `json:"ts_ms"`
+	Transaction *Transaction           `json:"transaction"`
+}
+
+// Source describes where in the redo log a change was found
+type Source struct {
+	Connector string `json:"connector"`
+	Name      string `json:"name"`
+	TsMs      int64  `json:"ts_ms"` // Estimated write time (0 = unknown)
+	Snapshot  string `json:"snapshot"`
+	DB        string `json:"db"`
+	Table     string `json:"table"`
+	File      string `json:"file"`
+	LSN       uint64 `json:"lsn"`
+	SpaceID   uint32 `json:"space_id"`
+	PageNo    uint32 `json:"page_no"`
+	TableID   uint32 `json:"table_id"`
+	IndexID   uint32 `json:"index_id"`
+	MTR       int    `json:"mtr"`    // Multi-record group (0 = single-record MTR)
+	Record    int    `json:"record"` // 1-based position of the record in the file
+	Type      string `json:"mlog_type"`
+}
+
+// Transaction is the transaction block of an event whose record carries a
+// transaction ID
+type Transaction struct {
+	ID         string `json:"id"`
+	TotalOrder int    `json:"total_order"` // Position of the event in the transaction
+}
+
+// Options configure a Converter
+type Options struct {
+	ServerName string               // source.name (default DefaultServerName)
+	File       string               // source.file
+	Dictionary *analyzer.Dictionary // Names source.db and source.table (nil = space_<id>)
+	Clock      *analyzer.LSNClock   // Estimates source.ts_ms from the LSN (nil = 0)
+}
+
+// Converter turns records into change events, numbering the events of each
+// transaction
+type Converter struct {
+	opts   Options
+	now    func() time.Time
+	orders map[uint64]int
+}
+
+// NewConverter creates a converter
+func NewConverter(opts Options) *Converter {
+	if opts.ServerName == "" {
+		opts.ServerName = DefaultServerName
+	}
+	return &Converter{opts: opts, now: time.Now, orders: make(map[uint64]int)}
+}
+
+// parsedPrefix starts the row fields the reader decodes from an insert
+const parsedPrefix = "parsed=("
+
+// columnValue matches a decoded column after parsedPrefix, e.g.
+// field2_str='ACADEMY' or, without a position, varchar='ACADEMY'
+var columnValue = regexp.MustCompile(`(?:^|[\s\[(])(?:field(\d+)_)?(int|tinyint|timestamp|str|compressed_uint|u8|varchar)=('[^']*'|[^\s\])]+)`)
+
+// Columns returns the row columns decoded from a record's payload, named
+// field1, field2, ... by position. Integers are numbers and strings are
+// strings; the redo log does not carry column names.
+func Columns(data []byte) map[string]interface{} {
+	columns := make(map[string]interface{})
+	start := bytes.Index(data, []byte(parsedPrefix))
+	if start < 0 {
+		return columns
+	}
+	parsed := string(data[start+len(parsedPrefix):])
+	for i, m := range columnValue.FindAllStringSubmatch(parsed, -1) {
+		name := "field" + m[1]
+		if m[1] == "" {
+			name = "field" + strconv.Itoa(i+1)
+		}
+		value := m[3]
+		if strings.HasPrefix(value, "'") {
+			columns[name] = strings.Trim(value, "'")
+		} else if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			columns[name] = n
+		} else {
+			columns[name] = value
+		}
+	}
+	return columns
+}
+
+// indexFields and indexUnique match the field counts of the index logged
+// with a record
+var (
+	indexFields = regexp.MustCompile(`index_info=\([^)]*?\bn_fields=(\d+)`)
+	indexUnique = regexp.MustCompile(`index_info=\([^)]*?\bn_uniq=(\d+)`)
+)
+
+// secondaryIndex reports whether a record's payload logs a secondary index.
+// The server logs n_uniq below n_fields only for clustered indexes, whose
+// records end with DB_TRX_ID and DB_ROLL_PTR after the key.
+func secondaryIndex(data []byte) bool {
+	fields, unique := indexFields.FindSubmatch(data), indexUnique.FindSubmatch(data)
+	return fields != nil && unique != nil && bytes.Equal(fields[1], unique[1])
+}
+
+// rowOperation returns the operation code of a record changing a row of a
+// clustered index, or "" for other records. Secondary index entries repeat
+// a change of the row, page splits and reorganizations move records between
+// pages, and MLOG_REC_DELETE purges a row whose delete-mark was already
+// reported, so none of them are row changes.
+func rowOperation(record *types.LogRecord) string {
+	if record.Type.IsMariaDB() {
+		switch record.Type.Operation() {
+		case types.OperationInsert:
+			return OpCreate
+		case types.OperationDelete:
+			return OpDelete
+		}
+		return ""
+	}
+	switch uint8(record.Type) {
+	case 9, 38, 67: // MLOG_REC_INSERT_8027, MLOG_COMP_REC_INSERT_8027, MLOG_REC_INSERT
+		if !secondaryIndex(record.Data) {
+			return OpCreate
+		}
+	case 13, 41, 70: // MLOG_REC_UPDATE_IN_PLACE_8027, MLOG_COMP_REC_UPDATE_IN_PLACE_8027, MLOG_REC_UPDATE_IN_PLACE
+		if !secondaryIndex(record.Data) {
+			return OpUpdate
+		}
+	case 10, 39, 68: // MLOG_REC_CLUST_DELETE_MARK_8027, MLOG_COMP_REC_CLUST_DELETE_MARK_8027, MLOG_REC_CLUST_DELETE_MARK
+		return OpDelete
+	}
+	return ""
+}
+
+// Event converts the n-th (1-based) record of the file. It returns nil for
+// records that do not change a row (see rowOperation).
+func (c *Converter) Event(n int, record *types.LogRecord) *Event {
+	event := &Event{Source: c.source(n, record), TsMs: c.now().UnixMilli(), Op: rowOperation(record)}
+	switch event.Op {
+	case OpCreate:
+		event.After = Columns(record.Data)
+	case OpUpdate:
+		// Redo holds the new values only; the before image is in the undo log
+		event.After = Columns(record.Data)
+	case OpDelete:
+		event.Before = Columns(record.Data)
+	default:
+		return nil
+	}
+
+	if id := record.TransactionID; id != 0 {
+		c.orders[id]++
+		event.Transaction = &Transaction{ID: strconv.FormatUint(id, 10), TotalOrder: c.orders[id]}
+	}
+	return event
+}
+
+// source fills the source block of a record
+func (c *Converter) source(n int, record *types.LogRecord) Source {
+	source := Source{
+		Connector: Connector,
+		Name:      c.opts.ServerName,
+		Snapshot:  "false",
+		Table:     fmt.Sprintf("space_%d", record.SpaceID),
+		File:      c.opts.File,
+		LSN:       record.LSN,
+		SpaceID:   record.SpaceID,
+		PageNo:    record.PageNo,
+		TableID:   record.TableID,
+		IndexID:   record.IndexID,
+		MTR:       record.MultiRecordGroup,
+		Record:    n,
+		Type:      record.Type.String(),
+	}
+	if name := c.opts.Dictionary.TableName(record.SpaceID); name != "" {
+		if db, table, ok := strings.Cut(name, "/"); ok {
+			source.DB, source.Table = db, table
+		} else {
+			source.Table = name
+		}
+	}
+	if t, ok := c.opts.Clock.Time(record.LSN); ok {
+		source.TsMs = t.UnixMilli()
+	}
+	return source
+}
+
+// Encoder writes events as one JSON object per line
+type Encoder struct {
+	w       *bufio.Writer
+	encoder *json.Encoder
+}
+
+// NewEncoder creates an encoder writing to w. Call Flush when done.
+func NewEncoder(w io.Writer) *Encoder {
+	bw := bufio.NewWriter(w)
+	e := &Encoder{w: bw, encoder: json.NewEncoder(bw)}
+	e.encoder.SetEscapeHTML(false)
+	return e
+}
+
+// Encode writes an event
+func (e *Encoder) Encode(event *Event) error {
+	return e.encoder.Encode(event)
+}
+
+// Flush writes buffered events to the underlying writer
+func (e *Encoder) Flush() error {
+	return e.w.Flush()
+}
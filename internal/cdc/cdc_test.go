@@ -0,0 +1,190 @@
+package cdc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/analyzer"
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+const insertPayload = "space_id=5 | page_no=4 | index_info=(n_fields=5,n_uniq=1,fields=[field_0(len=2,NOT_NULL)]) | hex=0a0b | parsed=(innodb_record=[field_lengths=[7] header_skip=5 field1_int=42 field2_str='ACADEMY DINOSAUR' field3_tinyint=6])"
+
+func testConverter(opts Options) *Converter {
+	c := NewConverter(opts)
+	c.now = func() time.Time { return time.Date(2024, 8, 24, 12, 0, 0, 0, time.UTC) }
+	return c
+}
+
+func TestColumns(t *testing.T) {
+	assert.Equal(t, map[string]interface{}{"field1": int64(42), "field2": "ACADEMY DINOSAUR", "field3": int64(6)},
+		Columns([]byte(insertPayload)))
+	assert.Equal(t, map[string]interface{}{"field1": "PENELOPE", "field2": int64(300), "field3": "0x9a"},
+		Columns([]byte("hex=00 | parsed=(fields=[varchar='PENELOPE' compressed_uint=300 u8=0x9a])")))
+	assert.Empty(t, Columns([]byte("offset=2887 value=0x15")))
+	assert.Empty(t, Columns(nil))
+}
+
+func TestConverterEvent(t *testing.T) {
+	dict := &analyzer.Dictionary{Spaces: map[uint32]string{5: "sakila/film"}}
+	clock, err := analyzer.NewLSNClock([]analyzer.TimeAnchor{
+		{LSN: 0, Time: time.Date(2024, 8, 24, 0, 0, 0, 0, time.UTC)},
+		{LSN: 1000, Time: time.Date(2024, 8, 24, 0, 0, 1, 0, time.UTC)},
+	})
+	require.NoError(t, err)
+	c := testConverter(Options{File: "ib_logfile0", Dictionary: dict, Clock: clock})
+
+	insert := &types.LogRecord{Type: types.LogType(9), LSN: 500, SpaceID: 5, PageNo: 4, IndexID: 7, MultiRecordGroup: 3, TransactionID: 77, Data: []byte(insertPayload)}
+	event := c.Event(10, insert)
+	require.NotNil(t, event)
+	assert.Equal(t, OpCreate, event.Op)
+	assert.Nil(t, event.Before)
+	assert.Equal(t, int64(42), event.After["field1"])
+	assert.Equal(t, time.Date(2024, 8, 24, 12, 0, 0, 0, time.UTC).UnixMilli(), event.TsMs)
+	assert.Equal(t, Source{
+		Connector: Connector, Name: DefaultServerName, TsMs: time.Date(2024, 8, 24, 0, 0, 0, 500e6, time.UTC).UnixMilli(),
+		Snapshot: "false", DB: "sakila", Table: "film", File: "ib_logfile0", LSN: 500, SpaceID: 5, PageNo: 4,
+		IndexID: 7, MTR: 3, Record: 10, Type: types.LogType(9).String(),
+	}, event.Source)
+	assert.Equal(t, &Transaction{ID: "77", TotalOrder: 1}, event.Transaction)
+
+	update := &types.LogRecord{Type: types.LogType(13), LSN: 600, SpaceID: 9, TransactionID: 77}
+	event = c.Event(11, update)
+	require.NotNil(t, event)
+	assert.Equal(t, OpUpdate, event.Op)
+	assert.Nil(t, event.Before)
+	assert.Empty(t, event.After)
+	assert.Equal(t, "space_9", event.Source.Table)
+	assert.Equal(t, 2, event.Transaction.TotalOrder)
+
+	event = c.Event(12, &types.LogRecord{Type: types.LogType(10), LSN: 700})
+	require.NotNil(t, event)
+	assert.Equal(t, OpDelete, event.Op)
+	assert.NotNil(t, event.Before)
+	assert.Nil(t, event.After)
+	assert.Nil(t, event.Transaction)
+
+	assert.Nil(t, c.Event(13, &types.LogRecord{Type: types.LogType(31), LSN: 800}))
+}
+
+func TestConverterEventRowChangesOnly(t *testing.T) {
+	c := testConverter(Options{})
+	events := func(records ...*types.LogRecord) []*Event {
+		var events []*Event
+		for i, record := range records {
+			if event := c.Event(i+1, record); event != nil {
+				events = append(events, event)
+			}
+		}
+		return events
+	}
+	secondary := "space_id=5 | page_no=9 | index_info=(version=1,flags=0x01,n_fields=2,n_uniq=2,fields=[field_0(len=4,NOT_NULL),field_1(len=2,NOT_NULL)]) | parsed=(fields=[u8=7])"
+
+	// A page split moves records to a new page and deletes them from the old
+	split := events(
+		&types.LogRecord{Type: types.LogType(71), SpaceID: 5, PageNo: 12}, // MLOG_LIST_END_COPY_CREATED
+		&types.LogRecord{Type: types.LogType(75), SpaceID: 5, PageNo: 4},  // MLOG_LIST_END_DELETE
+		&types.LogRecord{Type: types.LogType(76), SpaceID: 5, PageNo: 4},  // MLOG_LIST_START_DELETE
+		&types.LogRecord{Type: types.LogType(15), SpaceID: 5, PageNo: 4},  // MLOG_LIST_END_DELETE_8027
+		&types.LogRecord{Type: types.LogType(44), SpaceID: 5, PageNo: 4},  // MLOG_COMP_LIST_START_DELETE_8027
+		&types.LogRecord{Type: types.LogType(72), SpaceID: 5, PageNo: 4},  // MLOG_PAGE_REORGANIZE
+	)
+	assert.Empty(t, split)
+
+	// Updating an indexed column delete-marks the old secondary index entry
+	// and inserts the new one
+	assert.Empty(t, events(
+		&types.LogRecord{Type: types.LogType(40), SpaceID: 5, PageNo: 9}, // MLOG_COMP_REC_SEC_DELETE_MARK
+		&types.LogRecord{Type: types.LogType(67), SpaceID: 5, PageNo: 9, Data: []byte(secondary)},
+		&types.LogRecord{Type: types.LogType(70), SpaceID: 5, PageNo: 9, Data: []byte(secondary)},
+	))
+
+	// A delete is reported once, when the row is delete-marked, not again
+	// when purge removes it
+	deleted := events(
+		&types.LogRecord{Type: types.LogType(68), SpaceID: 5, PageNo: 4}, // MLOG_REC_CLUST_DELETE_MARK
+		&types.LogRecord{Type: types.LogType(11), SpaceID: 5, PageNo: 9}, // MLOG_REC_SEC_DELETE_MARK
+		&types.LogRecord{Type: types.LogType(69), SpaceID: 5, PageNo: 4}, // MLOG_REC_DELETE
+		&types.LogRecord{Type: types.LogType(14), SpaceID: 5, PageNo: 4}, // MLOG_REC_DELETE_8027
+	)
+	require.Len(t, deleted, 1)
+	assert.Equal(t, OpDelete, deleted[0].Op)
+	assert.Equal(t, 1, deleted[0].Source.Record)
+}
+
+func TestEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	c := testConverter(Options{})
+	require.NoError(t, e.Encode(c.Event(1, &types.LogRecord{Type: types.LogType(9), Data: []byte(insertPayload)})))
+	require.NoError(t, e.Encode(c.Event(2, &types.LogRecord{Type: types.LogType(10)})))
+	require.NoError(t, e.Flush())
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	var event map[string]interface{}
+	require.NoError(t, json.Unmarshal(lines[0], &event))
+	for _, key := range []string{"before", "after", "source", "op", "ts_ms", "transaction"} {
+		assert.Contains(t, event, key)
+	}
+	assert.Nil(t, event["before"])
+	assert.Equal(t, "ACADEMY DINOSAUR", event["after"].(map[string]interface{})["field2"])
+	assert.Equal(t, "innodb-redo", event["source"].(map[string]interface{})["connector"])
+}
+
+func TestOpenSinkFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "events.json")
+	w, err := OpenSink(filename)
+	require.NoError(t, err)
+	_, err = io.WriteString(w, "{}\n")
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, "{}\n", string(data))
+
+	_, err = OpenSink(filepath.Join(t.TempDir(), "missing", "events.json"))
+	assert.Error(t, err)
+}
+
+func TestOpenSinkUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- ""
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		received <- line
+	}()
+
+	w, err := OpenSink(UnixSinkPrefix + path)
+	require.NoError(t, err)
+	_, err = io.WriteString(w, "{\"op\":\"c\"}\n")
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	assert.Equal(t, "{\"op\":\"c\"}\n", <-received)
+
+	_, err = OpenSink(UnixSinkPrefix + filepath.Join(t.TempDir(), "none.sock"))
+	assert.ErrorContains(t, err, "failed to connect to sink")
+}
@@ -0,0 +1,44 @@
+package cdc
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// UnixSinkPrefix marks a sink given as a Unix socket path
+const UnixSinkPrefix = "unix:"
+
+// OpenSink opens where events are written: "" or "-" is stdout,
+// "unix:<path>" connects to a listening Unix stream socket and anything
+// else is a file, created or truncated.
+func OpenSink(target string) (io.WriteCloser, error) {
+	switch {
+	case target == "" || target == "-":
+		return nopCloser{os.Stdout}, nil
+	case strings.HasPrefix(target, UnixSinkPrefix):
+		path := strings.TrimPrefix(target, UnixSinkPrefix)
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to sink %s: %w", path, err)
+		}
+		return conn, nil
+	default:
+		file, err := os.Create(target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create sink: %w", err)
+		}
+		return file, nil
+	}
+}
+
+// nopCloser keeps stdout open when the sink is closed
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,224 @@
+// Package export writes redo log records to files for other tools
+package export
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// Formats are the supported export formats
+var Formats = []string{"json", "ndjson", "csv", "sql", "parquet"}
+
+// SQLTable is the table the SQL export creates and fills
+const SQLTable = "redo_records"
+
+// csvPreviewLength is the number of data characters written to CSV
+const csvPreviewLength = 100
+
+// Metadata describes where exported records come from. It is written as a
+// "metadata" object in JSON, as the first line of NDJSON, as leading
+// comment lines in CSV and SQL and as key/value metadata in Parquet.
+type Metadata struct {
+	Source    string    `json:"source,omitempty"`    // Log file the records were read from
+	Filter    string    `json:"filter,omitempty"`    // Filters applied to the records
+	Selection string    `json:"selection,omitempty"` // Subset of the filtered records, e.g. "MTR 12"
+	Exported  time.Time `json:"exported"`
+}
+
+// lines returns the metadata as "key: value" lines
+func (m *Metadata) lines() []string {
+	lines := make([]string, 0, 4)
+	add := func(key, value string) {
+		if value != "" {
+			lines = append(lines, key+": "+strings.Join(strings.Fields(value), " "))
+		}
+	}
+	add("source", m.Source)
+	add("filter", m.Filter)
+	add("selection", m.Selection)
+	add("exported", m.Exported.Format(time.RFC3339))
+	return lines
+}
+
+// FormatFromName returns the export format given by the extension of filename
+func FormatFromName(filename string) (string, error) {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), "."))
+	if ext == "jsonl" {
+		ext = "ndjson"
+	}
+	for _, format := range Formats {
+		if ext == format {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("cannot tell the format of %q: use a .%s extension", filename, strings.Join(Formats, ", ."))
+}
+
+// WriteFile writes records to filename; an empty filename writes to stdout
+func WriteFile(filename, format string, records []*types.LogRecord, header *types.RedoLogHeader, meta *Metadata) error {
+	if filename == "" {
+		return Write(os.Stdout, format, records, header, meta)
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %v", err)
+	}
+	if err := Write(file, format, records, header, meta); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Write writes records in format. meta may be nil.
+func Write(w io.Writer, format string, records []*types.LogRecord, header *types.RedoLogHeader, meta *Metadata) error {
+	switch strings.ToLower(format) {
+	case "json":
+		return writeJSON(w, records, header, meta)
+	case "ndjson":
+		return writeNDJSON(w, records, meta)
+	case "csv":
+		return writeCSV(w, records, meta)
+	case "sql":
+		return writeSQL(w, records, meta)
+	case "parquet":
+		return WriteParquet(w, records, meta, ParquetOptions{})
+	default:
+		return fmt.Errorf("unsupported export format: %s (supported: %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// writeJSON writes one indented document holding the header and records
+func writeJSON(w io.Writer, records []*types.LogRecord, header *types.RedoLogHeader, meta *Metadata) error {
+	stats := map[string]interface{}{
+		"total_records":    len(records),
+		"export_timestamp": time.Now().Format(time.RFC3339),
+	}
+	if header != nil {
+		stats["format_version"] = header.Format
+	}
+	data := struct {
+		Metadata *Metadata              `json:"metadata,omitempty"`
+		Header   *types.RedoLogHeader   `json:"header"`
+		Records  []*types.LogRecord     `json:"records"`
+		Stats    map[string]interface{} `json:"stats"`
+	}{
+		Metadata: meta,
+		Header:   header,
+		Records:  records,
+		Stats:    stats,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
+
+// writeCSV writes one row per record with a preview of its data
+func writeCSV(w io.Writer, records []*types.LogRecord, meta *Metadata) error {
+	if meta != nil {
+		for _, line := range meta.lines() {
+			if _, err := fmt.Fprintf(w, "# %s\n", line); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	headers := []string{
+		"Record_Number", "LSN", "Type", "Type_ID", "Length",
+		"Space_ID", "Page_No", "Table_ID", "Group", "Data_Preview", "Data_Length",
+	}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for i, record := range records {
+		dataPreview := string(record.Data)
+		if len(dataPreview) > csvPreviewLength {
+			dataPreview = dataPreview[:csvPreviewLength] + "..."
+		}
+		// Replace newlines and control characters for CSV
+		dataPreview = strings.ReplaceAll(dataPreview, "\n", "\\n")
+		dataPreview = strings.ReplaceAll(dataPreview, "\r", "\\r")
+		dataPreview = strings.ReplaceAll(dataPreview, "\"", "\"\"")
+
+		row := []string{
+			fmt.Sprintf("%d", i+1),
+			fmt.Sprintf("%d", record.LSN),
+			record.Type.String(),
+			fmt.Sprintf("%d", uint8(record.Type)),
+			fmt.Sprintf("%d", record.Length),
+			fmt.Sprintf("%d", record.SpaceID),
+			fmt.Sprintf("%d", record.PageNo),
+			fmt.Sprintf("%d", record.TableID),
+			fmt.Sprintf("%d", record.MultiRecordGroup),
+			dataPreview,
+			fmt.Sprintf("%d", len(record.Data)),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeSQL writes a MySQL script that creates SQLTable and inserts the
+// records in one transaction
+func writeSQL(w io.Writer, records []*types.LogRecord, meta *Metadata) error {
+	var b strings.Builder
+	b.WriteString("-- InnoDB redo log records exported by redolog-tool\n")
+	if meta != nil {
+		for _, line := range meta.lines() {
+			fmt.Fprintf(&b, "-- %s\n", line)
+		}
+	}
+	fmt.Fprintf(&b, `
+CREATE TABLE IF NOT EXISTS %s (
+  record_number INT UNSIGNED NOT NULL,
+  lsn BIGINT UNSIGNED NOT NULL,
+  type VARCHAR(64) NOT NULL,
+  type_id TINYINT UNSIGNED NOT NULL,
+  length INT UNSIGNED NOT NULL,
+  space_id INT UNSIGNED NOT NULL,
+  page_no INT UNSIGNED NOT NULL,
+  table_id INT UNSIGNED NOT NULL,
+  mtr_group INT UNSIGNED NOT NULL,
+  data LONGBLOB,
+  PRIMARY KEY (record_number)
+);
+
+START TRANSACTION;
+`, SQLTable)
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return err
+	}
+
+	for i, record := range records {
+		data := "NULL"
+		if len(record.Data) > 0 {
+			data = "X'" + hex.EncodeToString(record.Data) + "'"
+		}
+		_, err := fmt.Fprintf(w, "INSERT INTO %s VALUES (%d, %d, '%s', %d, %d, %d, %d, %d, %d, %s);\n",
+			SQLTable, i+1, record.LSN, record.Type.String(), uint8(record.Type), record.Length,
+			record.SpaceID, record.PageNo, record.TableID, record.MultiRecordGroup, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, "COMMIT;\n")
+	return err
+}
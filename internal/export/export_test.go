@@ -0,0 +1,102 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+func testRecords() []*types.LogRecord {
+	return []*types.LogRecord{
+		{Type: types.LogType(9), Length: 20, LSN: 100, SpaceID: 5, PageNo: 3, MultiRecordGroup: 1, Data: []byte("ACADEMY\nDINOSAUR")},
+		{Type: types.LogType(31), Length: 1, LSN: 120, MultiRecordGroup: 1},
+	}
+}
+
+func testMetadata() *Metadata {
+	return &Metadata{
+		Source:    "ib_logfile0",
+		Filter:    "op == insert && (space == 5)",
+		Selection: "MTR group 1",
+		Exported:  time.Date(2024, 8, 24, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, "json", testRecords(), &types.RedoLogHeader{Format: 2}, testMetadata()))
+
+	var doc struct {
+		Metadata *Metadata          `json:"metadata"`
+		Records  []*types.LogRecord `json:"records"`
+		Stats    map[string]any     `json:"stats"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, testMetadata(), doc.Metadata)
+	assert.Len(t, doc.Records, 2)
+	assert.Equal(t, float64(2), doc.Stats["format_version"])
+
+	// Without metadata the document is unchanged
+	buf.Reset()
+	require.NoError(t, Write(&buf, "json", testRecords(), nil, nil))
+	assert.NotContains(t, buf.String(), "metadata")
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, "CSV", testRecords(), nil, testMetadata()))
+
+	text := buf.String()
+	assert.True(t, strings.HasPrefix(text, "# source: ib_logfile0\n# filter: op == insert && (space == 5)\n# selection: MTR group 1\n# exported: 2024-08-24T12:00:00Z\n"))
+
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comment = '#'
+	rows, err := reader.ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, "Record_Number", rows[0][0])
+	assert.Equal(t, []string{"1", "100", "MLOG_REC_INSERT_8027", "9", "20", "5", "3", "0", "1", `ACADEMY\nDINOSAUR`, "16"}, rows[1])
+}
+
+func TestWriteSQL(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, "sql", testRecords(), nil, testMetadata()))
+
+	text := buf.String()
+	assert.Contains(t, text, "-- filter: op == insert && (space == 5)\n")
+	assert.Contains(t, text, "CREATE TABLE IF NOT EXISTS redo_records (")
+	assert.Contains(t, text, "INSERT INTO redo_records VALUES (1, 100, 'MLOG_REC_INSERT_8027', 9, 20, 5, 3, 0, 1, X'41434144454d590a44494e4f53415552');\n")
+	assert.Contains(t, text, "INSERT INTO redo_records VALUES (2, 120, 'MLOG_MULTI_REC_END', 31, 1, 0, 0, 0, 1, NULL);\n")
+	assert.True(t, strings.HasSuffix(text, "COMMIT;\n"))
+
+	assert.Error(t, Write(&buf, "xml", testRecords(), nil, nil))
+}
+
+func TestWriteFile(t *testing.T) {
+	format, err := FormatFromName("out/Records.SQL")
+	require.NoError(t, err)
+	assert.Equal(t, "sql", format)
+	format, err = FormatFromName("records.jsonl")
+	require.NoError(t, err)
+	assert.Equal(t, "ndjson", format)
+	_, err = FormatFromName("records.txt")
+	assert.Error(t, err)
+
+	filename := filepath.Join(t.TempDir(), "records.csv")
+	require.NoError(t, WriteFile(filename, "csv", testRecords(), nil, nil))
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "Record_Number,"))
+
+	assert.Error(t, WriteFile(filepath.Join(t.TempDir(), "missing", "records.csv"), "csv", testRecords(), nil, nil))
+}
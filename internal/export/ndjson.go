@@ -0,0 +1,185 @@
+package export
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// SchemaVersion is the version of the NDJSON line format. It changes only
+// when a field is removed, renamed or changes type; new optional fields
+// keep the version.
+const SchemaVersion = 1
+
+// NDJSONSchema is the JSON Schema (draft 2020-12) of an NDJSON line
+//
+//go:embed schema/record-v1.json
+var NDJSONSchema []byte
+
+// Line kinds of the NDJSON export
+const (
+	KindMetadata = "metadata" // Optional first line describing the export
+	KindRecord   = "record"   // One line per record
+)
+
+// MetadataLine is the first line of an NDJSON export with metadata
+type MetadataLine struct {
+	SchemaVersion int    `json:"schema_version"`
+	Kind          string `json:"kind"`
+	*Metadata
+}
+
+// RecordLine is one record of an NDJSON export
+type RecordLine struct {
+	SchemaVersion int               `json:"schema_version"`
+	Kind          string            `json:"kind"`
+	Record        int               `json:"record"` // 1-based position in the export
+	LSN           string            `json:"lsn"`    // 0x-prefixed hexadecimal
+	Type          string            `json:"type"`
+	TypeID        uint8             `json:"type_id"`
+	Operation     string            `json:"operation"`
+	Length        uint32            `json:"length"`
+	SpaceID       uint32            `json:"space_id"`
+	PageNo        uint32            `json:"page_no"`
+	TableID       uint32            `json:"table_id"`
+	IndexID       uint32            `json:"index_id"`
+	TransactionID uint64            `json:"trx_id"`
+	MTR           MTRInfo           `json:"mtr"`
+	Fields        map[string]string `json:"fields"`  // Decoded payload fields
+	Payload       string            `json:"payload"` // Decoded payload as text
+	Span          *ByteSpan         `json:"span,omitempty"`
+}
+
+// MTRInfo places a record in its mini-transaction
+type MTRInfo struct {
+	Group int  `json:"group"` // Multi-record group (0 = single-record MTR)
+	Start bool `json:"start"`
+	End   bool `json:"end"`
+}
+
+// ByteSpan locates a record in the log file
+type ByteSpan struct {
+	Start  int64       `json:"start"` // File offset of the first byte
+	End    int64       `json:"end"`   // File offset just past the last byte
+	Fields []SpanField `json:"fields"`
+}
+
+// SpanField locates one decoded field in the log file
+type SpanField struct {
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Offset int64  `json:"offset"`
+	Length int    `json:"length"`
+}
+
+// payloadKey matches the start of a key=value pair of a decoded payload
+var payloadKey = regexp.MustCompile(`(?:^|\s)([a-z_][a-z0-9_]*)=`)
+
+// PayloadFields splits a decoded payload such as "page=3 offset=2887 value=0x15"
+// into its fields. A value runs to the next key, so it may contain spaces;
+// single quotes around it and a trailing " |" separator are removed.
+func PayloadFields(data []byte) map[string]string {
+	fields := make(map[string]string)
+	text := string(data)
+	matches := payloadKey.FindAllStringSubmatchIndex(text, -1)
+	for i, m := range matches {
+		end := len(text)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		value := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text[m[1]:end]), "|"))
+		if len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
+			value = value[1 : len(value)-1]
+		}
+		fields[text[m[2]:m[3]]] = value
+	}
+	return fields
+}
+
+// NewRecordLine converts the n-th (1-based) exported record
+func NewRecordLine(n int, record *types.LogRecord) *RecordLine {
+	line := &RecordLine{
+		SchemaVersion: SchemaVersion,
+		Kind:          KindRecord,
+		Record:        n,
+		LSN:           fmt.Sprintf("0x%x", record.LSN),
+		Type:          record.Type.String(),
+		TypeID:        uint8(record.Type),
+		Operation:     record.Type.Operation(),
+		Length:        record.Length,
+		SpaceID:       record.SpaceID,
+		PageNo:        record.PageNo,
+		TableID:       record.TableID,
+		IndexID:       record.IndexID,
+		TransactionID: record.TransactionID,
+		MTR:           MTRInfo{Group: record.MultiRecordGroup, Start: record.IsGroupStart, End: record.IsGroupEnd},
+		Fields:        PayloadFields(record.Data),
+		Payload:       strings.ToValidUTF8(string(record.Data), "�"),
+	}
+
+	if start, end, ok := record.ByteRange(); ok {
+		line.Span = &ByteSpan{Start: start, End: end, Fields: make([]SpanField, 0, len(record.Spans))}
+		for _, span := range record.Spans {
+			line.Span.Fields = append(line.Span.Fields, SpanField{
+				Name:   span.Name,
+				Kind:   strings.ReplaceAll(span.Kind.String(), " ", "_"),
+				Offset: span.Offset,
+				Length: span.Length,
+			})
+		}
+	}
+	return line
+}
+
+// RecordEncoder writes records as NDJSON lines one at a time, so an export
+// needs no more memory than a single record
+type RecordEncoder struct {
+	w       *bufio.Writer
+	encoder *json.Encoder
+	records int
+}
+
+// NewRecordEncoder creates an encoder writing to w, starting with a
+// metadata line if meta is not nil. Call Flush when done.
+func NewRecordEncoder(w io.Writer, meta *Metadata) (*RecordEncoder, error) {
+	bw := bufio.NewWriter(w)
+	e := &RecordEncoder{w: bw, encoder: json.NewEncoder(bw)}
+	e.encoder.SetEscapeHTML(false)
+	if meta != nil {
+		if err := e.encoder.Encode(MetadataLine{SchemaVersion: SchemaVersion, Kind: KindMetadata, Metadata: meta}); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// Encode writes the next record
+func (e *RecordEncoder) Encode(record *types.LogRecord) error {
+	e.records++
+	return e.encoder.Encode(NewRecordLine(e.records, record))
+}
+
+// Flush writes buffered lines to the underlying writer
+func (e *RecordEncoder) Flush() error {
+	return e.w.Flush()
+}
+
+// writeNDJSON writes a metadata line, if meta is set, and one line per record
+func writeNDJSON(w io.Writer, records []*types.LogRecord, meta *Metadata) error {
+	e, err := NewRecordEncoder(w, meta)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := e.Encode(record); err != nil {
+			return err
+		}
+	}
+	return e.Flush()
+}
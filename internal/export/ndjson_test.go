@@ -0,0 +1,113 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+func TestPayloadFields(t *testing.T) {
+	assert.Equal(t, map[string]string{"offset": "2887", "value": "0x15"}, PayloadFields([]byte("offset=2887 value=0x15")))
+	assert.Equal(t, map[string]string{"space": "5", "page": "3", "data": "ACADEMY DINOSAUR"}, PayloadFields([]byte("space=5 page=3 data=ACADEMY DINOSAUR")))
+	assert.Equal(t, map[string]string{"type_5_data": " root "}, PayloadFields([]byte("type_5_data=' root '")))
+	assert.Empty(t, PayloadFields([]byte("table_dynamic_meta_parse_failed")))
+}
+
+// schemaObject is the part of a JSON Schema object definition the tests check
+type schemaObject struct {
+	Properties map[string]json.RawMessage `json:"properties"`
+	Required   []string                   `json:"required"`
+}
+
+// checkSchemaKeys checks that line has every required property of def and
+// no other properties
+func checkSchemaKeys(t *testing.T, def schemaObject, line map[string]any) {
+	t.Helper()
+	for _, key := range def.Required {
+		assert.Contains(t, line, key)
+	}
+	for key := range line {
+		assert.Contains(t, def.Properties, key)
+	}
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	records := testRecords()
+	records[0].FileOffset = 2060
+	records[0].Spans = []types.FieldSpan{
+		{Name: "type", Kind: types.SpanRecordType, Offset: 2060, Length: 1},
+		{Name: "data", Kind: types.SpanPayload, Offset: 2061, Length: 19},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, "ndjson", records, nil, testMetadata()))
+
+	var schema struct {
+		Defs map[string]schemaObject `json:"$defs"`
+	}
+	require.NoError(t, json.Unmarshal(NDJSONSchema, &schema))
+
+	lines := make([]map[string]any, 0)
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var line map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	require.Len(t, lines, 3)
+
+	// The metadata line comes first
+	assert.Equal(t, "metadata", lines[0]["kind"])
+	assert.Equal(t, "op == insert && (space == 5)", lines[0]["filter"])
+	checkSchemaKeys(t, schema.Defs["metadata"], lines[0])
+
+	record := lines[1]
+	checkSchemaKeys(t, schema.Defs["record"], record)
+	assert.Equal(t, float64(SchemaVersion), record["schema_version"])
+	assert.Equal(t, "0x64", record["lsn"])
+	assert.Equal(t, "MLOG_REC_INSERT_8027", record["type"])
+	assert.Equal(t, "insert", record["operation"])
+	assert.Equal(t, map[string]any{"group": float64(1), "start": false, "end": false}, record["mtr"])
+	assert.Equal(t, "ACADEMY\nDINOSAUR", record["payload"])
+	span := record["span"].(map[string]any)
+	assert.Equal(t, float64(2060), span["start"])
+	assert.Equal(t, float64(2080), span["end"])
+	assert.Equal(t, "payload", span["fields"].([]any)[1].(map[string]any)["kind"])
+
+	// Records without spans leave the span out
+	checkSchemaKeys(t, schema.Defs["record"], lines[2])
+	assert.NotContains(t, lines[2], "span")
+	assert.Equal(t, float64(2), lines[2]["record"])
+}
+
+func TestNDJSONSchemaProperties(t *testing.T) {
+	// Every field of RecordLine is documented in the schema
+	var schema struct {
+		Defs map[string]schemaObject `json:"$defs"`
+	}
+	require.NoError(t, json.Unmarshal(NDJSONSchema, &schema))
+
+	data, err := json.Marshal(NewRecordLine(1, &types.LogRecord{Spans: []types.FieldSpan{{Kind: types.SpanFixedInt}}}))
+	require.NoError(t, err)
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(data, &line))
+
+	keys := make([]string, 0, len(line))
+	for key := range line {
+		keys = append(keys, key)
+	}
+	documented := make([]string, 0, len(schema.Defs["record"].Properties))
+	for key := range schema.Defs["record"].Properties {
+		documented = append(documented, key)
+	}
+	sort.Strings(keys)
+	sort.Strings(documented)
+	assert.Equal(t, documented, keys)
+}
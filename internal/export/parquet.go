@@ -0,0 +1,535 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// DefaultRowGroupRows is the number of records per Parquet row group. A
+// row group is buffered in memory until it is written.
+const DefaultRowGroupRows = 64 * 1024
+
+// ParquetCompressions are the supported Parquet page codecs
+var ParquetCompressions = []string{"gzip", "none"}
+
+// parquetMagic starts and ends a Parquet file
+const parquetMagic = "PAR1"
+
+// Parquet physical types, repetition types, converted types, encodings and
+// codecs used by the writer, as numbered in parquet.thrift
+const (
+	parquetBoolean   = 0
+	parquetInt32     = 1
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetRequired = 0
+	parquetOptional = 1
+
+	convertedNone   = -1
+	convertedUTF8   = 0
+	convertedEnum   = 4
+	convertedUint8  = 11
+	convertedUint32 = 13
+	convertedUint64 = 14
+	convertedInt32  = 17
+	convertedInt64  = 18
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecUncompressed = 0
+	codecGzip         = 2
+)
+
+// parquetFieldNames are the columns of the "fields" struct. Decoded payload
+// fields with other names are only kept in the payload column.
+var parquetFieldNames = []string{"offset", "value", "space_id", "page_no", "table_id", "version", "index_info", "record_data", "data", "hex", "parsed"}
+
+// parquetFieldAliases maps payload field names to parquetFieldNames
+var parquetFieldAliases = map[string]string{"space": "space_id", "page": "page_no"}
+
+// typedPayloadKey matches the type_<n>_data and type_<n>_hex payload fields
+// of records the reader does not decode
+var typedPayloadKey = regexp.MustCompile(`^type_\d+_(data|hex)$`)
+
+// parquetFieldName returns the "fields" column of a payload field, or ""
+func parquetFieldName(key string) string {
+	if m := typedPayloadKey.FindStringSubmatch(key); m != nil {
+		return m[1]
+	}
+	if alias, ok := parquetFieldAliases[key]; ok {
+		key = alias
+	}
+	for _, name := range parquetFieldNames {
+		if key == name {
+			return name
+		}
+	}
+	return ""
+}
+
+// ParquetOptions configure a ParquetWriter
+type ParquetOptions struct {
+	RowGroupRows int    // Records per row group (0 = DefaultRowGroupRows)
+	Compression  string // "gzip" (default) or "none"
+}
+
+// parquetLogical is a Parquet logical type annotation
+type parquetLogical struct {
+	id       int16 // Field of the LogicalType union (0 = none)
+	bitWidth int8  // INTEGER only
+	signed   bool  // INTEGER only
+}
+
+// LogicalType union fields
+const (
+	logicalStringID  = 1
+	logicalEnumID    = 4
+	logicalIntegerID = 10
+)
+
+var (
+	logicalString = parquetLogical{id: logicalStringID}
+	logicalEnum   = parquetLogical{id: logicalEnumID}
+)
+
+// logicalInt returns the INTEGER logical type
+func logicalInt(bitWidth int8, signed bool) parquetLogical {
+	return parquetLogical{id: logicalIntegerID, bitWidth: bitWidth, signed: signed}
+}
+
+// parquetRow is a record as written to Parquet
+type parquetRow struct {
+	*RecordLine
+	lsn    uint64
+	fields map[string]string // Decoded payload fields by "fields" column
+}
+
+// parquetColumn is a leaf column, buffering the values of the current row
+// group
+type parquetColumn struct {
+	path      []string
+	physical  int32
+	optional  bool
+	converted int32
+	logical   parquetLogical
+	value     func(row *parquetRow) (interface{}, bool) // false = null
+
+	values bytes.Buffer // PLAIN encoded non-null values
+	bools  []bool       // Values of a boolean column, bit-packed on flush
+	levels []uint8      // Definition levels of an optional column
+	count  int
+}
+
+// add appends the column's value of row
+func (c *parquetColumn) add(row *parquetRow) {
+	c.count++
+	v, ok := c.value(row)
+	if c.optional {
+		if !ok {
+			c.levels = append(c.levels, 0)
+			return
+		}
+		c.levels = append(c.levels, 1)
+	}
+
+	switch v := v.(type) {
+	case int32:
+		binary.Write(&c.values, binary.LittleEndian, v)
+	case int64:
+		binary.Write(&c.values, binary.LittleEndian, v)
+	case bool:
+		c.bools = append(c.bools, v)
+	case string:
+		binary.Write(&c.values, binary.LittleEndian, uint32(len(v)))
+		c.values.WriteString(v)
+	}
+}
+
+// page returns the body of a data page holding the buffered values
+func (c *parquetColumn) page() []byte {
+	var page bytes.Buffer
+	if c.optional {
+		levels := encodeLevels(c.levels)
+		binary.Write(&page, binary.LittleEndian, uint32(len(levels)))
+		page.Write(levels)
+	}
+	if c.physical == parquetBoolean {
+		packed := make([]byte, (len(c.bools)+7)/8)
+		for i, b := range c.bools {
+			if b {
+				packed[i/8] |= 1 << (i % 8)
+			}
+		}
+		page.Write(packed)
+	}
+	page.Write(c.values.Bytes())
+	return page.Bytes()
+}
+
+// reset empties the buffers for the next row group
+func (c *parquetColumn) reset() {
+	c.values.Reset()
+	c.bools = c.bools[:0]
+	c.levels = c.levels[:0]
+	c.count = 0
+}
+
+// encodeLevels encodes definition levels of bit width 1 as RLE runs of the
+// RLE/bit-packing hybrid encoding
+func encodeLevels(levels []uint8) []byte {
+	var b []byte
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		b = binary.AppendUvarint(b, uint64(j-i)<<1)
+		b = append(b, levels[i])
+		i = j
+	}
+	return b
+}
+
+// parquetColumns returns the leaf columns of an export, in schema order
+func parquetColumns() []*parquetColumn {
+	columns := []*parquetColumn{
+		{path: []string{"record"}, physical: parquetInt64, converted: convertedInt64, logical: logicalInt(64, true),
+			value: func(r *parquetRow) (interface{}, bool) { return int64(r.Record), true }},
+		{path: []string{"lsn"}, physical: parquetInt64, converted: convertedUint64, logical: logicalInt(64, false),
+			value: func(r *parquetRow) (interface{}, bool) { return int64(r.lsn), true }},
+		{path: []string{"type"}, physical: parquetByteArray, converted: convertedEnum, logical: logicalEnum,
+			value: func(r *parquetRow) (interface{}, bool) { return r.Type, true }},
+		{path: []string{"type_id"}, physical: parquetInt32, converted: convertedUint8, logical: logicalInt(8, false),
+			value: func(r *parquetRow) (interface{}, bool) { return int32(r.TypeID), true }},
+		{path: []string{"operation"}, physical: parquetByteArray, converted: convertedEnum, logical: logicalEnum,
+			value: func(r *parquetRow) (interface{}, bool) { return r.Operation, true }},
+		{path: []string{"length"}, physical: parquetInt32, converted: convertedUint32, logical: logicalInt(32, false),
+			value: func(r *parquetRow) (interface{}, bool) { return int32(r.Length), true }},
+		{path: []string{"space_id"}, physical: parquetInt32, converted: convertedUint32, logical: logicalInt(32, false),
+			value: func(r *parquetRow) (interface{}, bool) { return int32(r.SpaceID), true }},
+		{path: []string{"page_no"}, physical: parquetInt32, converted: convertedUint32, logical: logicalInt(32, false),
+			value: func(r *parquetRow) (interface{}, bool) { return int32(r.PageNo), true }},
+		{path: []string{"table_id"}, physical: parquetInt32, converted: convertedUint32, logical: logicalInt(32, false),
+			value: func(r *parquetRow) (interface{}, bool) { return int32(r.TableID), true }},
+		{path: []string{"index_id"}, physical: parquetInt32, converted: convertedUint32, logical: logicalInt(32, false),
+			value: func(r *parquetRow) (interface{}, bool) { return int32(r.IndexID), true }},
+		{path: []string{"trx_id"}, physical: parquetInt64, converted: convertedUint64, logical: logicalInt(64, false),
+			value: func(r *parquetRow) (interface{}, bool) { return int64(r.TransactionID), true }},
+		{path: []string{"mtr_group"}, physical: parquetInt32, converted: convertedInt32, logical: logicalInt(32, true),
+			value: func(r *parquetRow) (interface{}, bool) { return int32(r.MTR.Group), true }},
+		{path: []string{"mtr_start"}, physical: parquetBoolean, converted: convertedNone,
+			value: func(r *parquetRow) (interface{}, bool) { return r.MTR.Start, true }},
+		{path: []string{"mtr_end"}, physical: parquetBoolean, converted: convertedNone,
+			value: func(r *parquetRow) (interface{}, bool) { return r.MTR.End, true }},
+		{path: []string{"file_offset"}, physical: parquetInt64, optional: true, converted: convertedInt64, logical: logicalInt(64, true),
+			value: func(r *parquetRow) (interface{}, bool) {
+				if r.Span == nil {
+					return nil, false
+				}
+				return r.Span.Start, true
+			}},
+		{path: []string{"payload"}, physical: parquetByteArray, converted: convertedUTF8, logical: logicalString,
+			value: func(r *parquetRow) (interface{}, bool) { return r.Payload, true }},
+	}
+
+	for _, name := range parquetFieldNames {
+		columns = append(columns, &parquetColumn{
+			path: []string{"fields", name}, physical: parquetByteArray, optional: true, converted: convertedUTF8, logical: logicalString,
+			value: func(r *parquetRow) (interface{}, bool) {
+				v, ok := r.fields[name]
+				return v, ok
+			},
+		})
+	}
+	return columns
+}
+
+// parquetChunk is the location of a written column chunk
+type parquetChunk struct {
+	offset       int64
+	uncompressed int64
+	compressed   int64
+	values       int
+}
+
+// parquetRowGroup is the location of a written row group
+type parquetRowGroup struct {
+	rows   int
+	chunks []parquetChunk
+}
+
+// ParquetWriter writes records as a Parquet file with typed columns and a
+// "fields" struct of decoded payload fields. Records are buffered one row
+// group at a time.
+type ParquetWriter struct {
+	w         io.Writer
+	offset    int64
+	meta      *Metadata
+	opts      ParquetOptions
+	codec     int32
+	columns   []*parquetColumn
+	rows      int // Rows of the current row group
+	total     int // Rows written so far
+	rowGroups []parquetRowGroup
+}
+
+// NewParquetWriter starts a Parquet file on w. meta, if set, is stored in
+// the file's key/value metadata. Call Close to write the footer.
+func NewParquetWriter(w io.Writer, meta *Metadata, opts ParquetOptions) (*ParquetWriter, error) {
+	if opts.RowGroupRows <= 0 {
+		opts.RowGroupRows = DefaultRowGroupRows
+	}
+	p := &ParquetWriter{w: w, meta: meta, opts: opts, columns: parquetColumns()}
+	switch opts.Compression {
+	case "", "gzip":
+		p.codec = codecGzip
+	case "none":
+		p.codec = codecUncompressed
+	default:
+		return nil, fmt.Errorf("unsupported Parquet compression %q (supported: %s)", opts.Compression, strings.Join(ParquetCompressions, ", "))
+	}
+
+	if err := p.write([]byte(parquetMagic)); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// write writes b, tracking the file offset
+func (p *ParquetWriter) write(b []byte) error {
+	n, err := p.w.Write(b)
+	p.offset += int64(n)
+	return err
+}
+
+// Write adds a record, writing a row group when it is full
+func (p *ParquetWriter) Write(record *types.LogRecord) error {
+	p.total++
+	row := &parquetRow{RecordLine: NewRecordLine(p.total, record), lsn: record.LSN}
+	row.fields = make(map[string]string, len(row.Fields))
+	for key, value := range row.Fields {
+		if name := parquetFieldName(key); name != "" {
+			row.fields[name] = value
+		}
+	}
+
+	for _, c := range p.columns {
+		c.add(row)
+	}
+	p.rows++
+	if p.rows >= p.opts.RowGroupRows {
+		return p.flush()
+	}
+	return nil
+}
+
+// flush writes the buffered rows as a row group
+func (p *ParquetWriter) flush() error {
+	if p.rows == 0 {
+		return nil
+	}
+	rowGroup := parquetRowGroup{rows: p.rows}
+	for _, c := range p.columns {
+		page := c.page()
+		body, err := p.compress(page)
+		if err != nil {
+			return err
+		}
+
+		header := newThriftWriter()
+		header.i32(1, 0) // DATA_PAGE
+		header.i32(2, int32(len(page)))
+		header.i32(3, int32(len(body)))
+		header.beginStruct(5)
+		header.i32(1, int32(c.count))
+		header.i32(2, encodingPlain)
+		header.i32(3, encodingRLE)
+		header.i32(4, encodingRLE)
+		header.endStruct()
+		headerBytes := header.bytes()
+
+		chunk := parquetChunk{
+			offset:       p.offset,
+			uncompressed: int64(len(headerBytes) + len(page)),
+			compressed:   int64(len(headerBytes) + len(body)),
+			values:       c.count,
+		}
+		if err := p.write(headerBytes); err != nil {
+			return err
+		}
+		if err := p.write(body); err != nil {
+			return err
+		}
+		rowGroup.chunks = append(rowGroup.chunks, chunk)
+		c.reset()
+	}
+	p.rowGroups = append(p.rowGroups, rowGroup)
+	p.rows = 0
+	return nil
+}
+
+// compress compresses a page with the writer's codec
+func (p *ParquetWriter) compress(page []byte) ([]byte, error) {
+	if p.codec == codecUncompressed {
+		return page, nil
+	}
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	if _, err := zw.Write(page); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Close writes the remaining rows and the footer. It does not close the
+// underlying writer.
+func (p *ParquetWriter) Close() error {
+	if err := p.flush(); err != nil {
+		return err
+	}
+	footer := p.footer()
+	if err := p.write(footer); err != nil {
+		return err
+	}
+	if err := p.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer)))); err != nil {
+		return err
+	}
+	return p.write([]byte(parquetMagic))
+}
+
+// footer encodes the FileMetaData struct
+func (p *ParquetWriter) footer() []byte {
+	t := newThriftWriter()
+	t.i32(1, 1) // Format version
+
+	// Schema, flattened depth first: the root, the top-level columns, then
+	// the "fields" group followed by its children
+	top := 0
+	for _, c := range p.columns {
+		if len(c.path) == 1 {
+			top++
+		}
+	}
+	t.list(2, thriftStruct, len(p.columns)+2)
+	t.beginStructValue()
+	t.str(4, "redo_record")
+	t.i32(5, int32(top+1))
+	t.endStruct()
+	for _, c := range p.columns {
+		if c.path[0] == "fields" && c.path[1] == parquetFieldNames[0] {
+			t.beginStructValue()
+			t.i32(3, parquetRequired)
+			t.str(4, "fields")
+			t.i32(5, int32(len(parquetFieldNames)))
+			t.endStruct()
+		}
+		t.beginStructValue()
+		t.i32(1, c.physical)
+		if c.optional {
+			t.i32(3, parquetOptional)
+		} else {
+			t.i32(3, parquetRequired)
+		}
+		t.str(4, c.path[len(c.path)-1])
+		if c.converted != convertedNone {
+			t.i32(6, c.converted)
+		}
+		if c.logical.id != 0 {
+			t.beginStruct(10)
+			t.beginStruct(c.logical.id)
+			if c.logical.id == logicalIntegerID {
+				t.byteField(1, c.logical.bitWidth)
+				t.boolField(2, c.logical.signed)
+			}
+			t.endStruct()
+			t.endStruct()
+		}
+		t.endStruct()
+	}
+
+	t.i64(3, int64(p.total))
+
+	t.list(4, thriftStruct, len(p.rowGroups))
+	for _, rowGroup := range p.rowGroups {
+		var uncompressed, compressed int64
+		t.beginStructValue()
+		t.list(1, thriftStruct, len(rowGroup.chunks))
+		for i, chunk := range rowGroup.chunks {
+			c := p.columns[i]
+			uncompressed += chunk.uncompressed
+			compressed += chunk.compressed
+
+			t.beginStructValue()
+			t.i64(2, chunk.offset)
+			t.beginStruct(3)
+			t.i32(1, c.physical)
+			encodings := []int32{encodingPlain, encodingRLE}
+			t.list(2, thriftI32, len(encodings))
+			for _, e := range encodings {
+				t.i32Value(e)
+			}
+			t.list(3, thriftBinary, len(c.path))
+			for _, name := range c.path {
+				t.strValue(name)
+			}
+			t.i32(4, p.codec)
+			t.i64(5, int64(chunk.values))
+			t.i64(6, chunk.uncompressed)
+			t.i64(7, chunk.compressed)
+			t.i64(9, chunk.offset)
+			t.endStruct()
+			t.endStruct()
+		}
+		t.i64(2, uncompressed)
+		t.i64(3, int64(rowGroup.rows))
+		t.i64(5, rowGroup.chunks[0].offset)
+		t.i64(6, compressed)
+		t.endStruct()
+	}
+
+	if p.meta != nil {
+		pairs := [][2]string{{"redolog.exported", p.meta.Exported.Format(time.RFC3339)}}
+		for _, kv := range [][2]string{{"redolog.source", p.meta.Source}, {"redolog.filter", p.meta.Filter}, {"redolog.selection", p.meta.Selection}} {
+			if kv[1] != "" {
+				pairs = append(pairs, kv)
+			}
+		}
+		t.list(5, thriftStruct, len(pairs))
+		for _, kv := range pairs {
+			t.beginStructValue()
+			t.str(1, kv[0])
+			t.str(2, kv[1])
+			t.endStruct()
+		}
+	}
+
+	t.str(6, "innodb-redolog-tool")
+	return t.bytes()
+}
+
+// WriteParquet writes records as a Parquet file
+func WriteParquet(w io.Writer, records []*types.LogRecord, meta *Metadata, opts ParquetOptions) error {
+	p, err := NewParquetWriter(w, meta, opts)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := p.Write(record); err != nil {
+			return err
+		}
+	}
+	return p.Close()
+}
@@ -0,0 +1,287 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// thriftReader decodes compact protocol structs into maps of field ID to
+// value, enough to check what the writer produced
+type thriftReader struct {
+	r *bytes.Reader
+}
+
+func (t *thriftReader) varint() int64 {
+	u, err := binary.ReadUvarint(t.r)
+	if err != nil {
+		panic(err)
+	}
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+func (t *thriftReader) value(typ byte) any {
+	switch typ {
+	case thriftTrue:
+		return true
+	case thriftFalse:
+		return false
+	case thriftByte:
+		b, _ := t.r.ReadByte()
+		return int64(int8(b))
+	case thriftI32, thriftI64:
+		return t.varint()
+	case thriftBinary:
+		n, _ := binary.ReadUvarint(t.r)
+		b := make([]byte, n)
+		io.ReadFull(t.r, b)
+		return string(b)
+	case thriftList:
+		header, _ := t.r.ReadByte()
+		n := int(header >> 4)
+		if n == 15 {
+			u, _ := binary.ReadUvarint(t.r)
+			n = int(u)
+		}
+		list := make([]any, n)
+		for i := range list {
+			elem := header & 0x0f
+			if elem == thriftTrue {
+				b, _ := t.r.ReadByte()
+				list[i] = b == thriftTrue
+				continue
+			}
+			list[i] = t.value(elem)
+		}
+		return list
+	case thriftStruct:
+		return t.structValue()
+	}
+	panic("unexpected thrift type")
+}
+
+func (t *thriftReader) structValue() map[int16]any {
+	fields := make(map[int16]any)
+	var last int16
+	for {
+		header, _ := t.r.ReadByte()
+		if header == 0 {
+			return fields
+		}
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			id = int16(t.varint())
+		}
+		fields[id] = t.value(header & 0x0f)
+		last = id
+	}
+}
+
+// parquetFile is a Parquet file decoded by readParquet
+type parquetFile struct {
+	data     []byte
+	metadata map[int16]any
+}
+
+func readParquet(t *testing.T, data []byte) *parquetFile {
+	require.True(t, bytes.HasPrefix(data, []byte(parquetMagic)))
+	require.True(t, bytes.HasSuffix(data, []byte(parquetMagic)))
+	size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := data[len(data)-8-size : len(data)-8]
+	return &parquetFile{data: data, metadata: (&thriftReader{bytes.NewReader(footer)}).structValue()}
+}
+
+func (f *parquetFile) rowGroups() []any {
+	groups, _ := f.metadata[4].([]any)
+	return groups
+}
+
+// keyValues returns the key/value metadata
+func (f *parquetFile) keyValues() map[string]string {
+	kv := make(map[string]string)
+	pairs, _ := f.metadata[5].([]any)
+	for _, pair := range pairs {
+		p := pair.(map[int16]any)
+		kv[p[1].(string)] = p[2].(string)
+	}
+	return kv
+}
+
+// column decodes the values of the column at path in every row group;
+// nulls are nil
+func (f *parquetFile) column(t *testing.T, path string) []any {
+	var values []any
+	for _, group := range f.rowGroups() {
+		for _, chunk := range group.(map[int16]any)[1].([]any) {
+			meta := chunk.(map[int16]any)[3].(map[int16]any)
+			var names []string
+			for _, name := range meta[3].([]any) {
+				names = append(names, name.(string))
+			}
+			if strings.Join(names, ".") != path {
+				continue
+			}
+			values = append(values, f.page(t, meta)...)
+		}
+	}
+	return values
+}
+
+func (f *parquetFile) page(t *testing.T, meta map[int16]any) []any {
+	r := bytes.NewReader(f.data[meta[9].(int64):])
+	header := (&thriftReader{r}).structValue()
+	body := make([]byte, header[3].(int64))
+	_, err := io.ReadFull(r, body)
+	require.NoError(t, err)
+	if meta[4].(int64) == codecGzip {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		body, err = io.ReadAll(zr)
+		require.NoError(t, err)
+	}
+	require.Len(t, body, int(header[2].(int64)))
+
+	count := int(header[5].(map[int16]any)[1].(int64))
+	defined := make([]bool, count)
+	for i := range defined {
+		defined[i] = true
+	}
+	page := bytes.NewReader(body)
+	if isOptionalColumn(meta) {
+		var size uint32
+		require.NoError(t, binary.Read(page, binary.LittleEndian, &size))
+		levels := bytes.NewReader(body[4 : 4+size])
+		for i := 0; i < count; {
+			run, err := binary.ReadUvarint(levels)
+			require.NoError(t, err)
+			level, _ := levels.ReadByte()
+			for n := 0; n < int(run>>1); n++ {
+				defined[i] = level == 1
+				i++
+			}
+		}
+		page.Seek(int64(4+size), io.SeekStart)
+	}
+
+	values := make([]any, count)
+	var bits []byte
+	for i := range values {
+		if !defined[i] {
+			continue
+		}
+		switch meta[1].(int64) {
+		case parquetBoolean:
+			if bits == nil {
+				bits, _ = io.ReadAll(page)
+			}
+			values[i] = bits[i/8]&(1<<(i%8)) != 0
+		case parquetInt32:
+			var v int32
+			require.NoError(t, binary.Read(page, binary.LittleEndian, &v))
+			values[i] = v
+		case parquetInt64:
+			var v int64
+			require.NoError(t, binary.Read(page, binary.LittleEndian, &v))
+			values[i] = v
+		case parquetByteArray:
+			var n uint32
+			require.NoError(t, binary.Read(page, binary.LittleEndian, &n))
+			b := make([]byte, n)
+			_, err := io.ReadFull(page, b)
+			require.NoError(t, err)
+			values[i] = string(b)
+		}
+	}
+	return values
+}
+
+// isOptionalColumn tells optional columns apart by their path, as column
+// metadata does not carry the repetition type
+func isOptionalColumn(meta map[int16]any) bool {
+	path := meta[3].([]any)
+	return path[0] == "fields" || path[0] == "file_offset"
+}
+
+func parquetTestRecords() []*types.LogRecord {
+	return []*types.LogRecord{
+		{Type: types.LogType(1), Length: 4, LSN: 0xffffffff00000001, SpaceID: 5, PageNo: 3, MultiRecordGroup: 1, IsGroupStart: true, Data: []byte("offset=2887 value=0x15")},
+		{Type: types.LogType(9), Length: 20, LSN: 200, SpaceID: 5, PageNo: 4, MultiRecordGroup: 1, Data: []byte("space_id=5 | page_no=4 | hex=4142 | parsed=(id=1)")},
+		{Type: types.LogType(60), Length: 2, LSN: 300, MultiRecordGroup: 1, IsGroupEnd: true, Data: []byte("type_60_data='AB' type_60_hex=4142")},
+		{Type: types.LogType(31), Length: 1, LSN: 400},
+		{Type: types.LogType(2), Length: 8, LSN: 500, SpaceID: 7, PageNo: 9, Data: []byte("space=7 page=9 data=xyz")},
+	}
+}
+
+func TestWriteParquet(t *testing.T) {
+	for _, compression := range ParquetCompressions {
+		t.Run(compression, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := ParquetOptions{RowGroupRows: 2, Compression: compression}
+			require.NoError(t, WriteParquet(&buf, parquetTestRecords(), testMetadata(), opts))
+
+			f := readParquet(t, buf.Bytes())
+			assert.Equal(t, int64(5), f.metadata[3])
+			assert.Len(t, f.rowGroups(), 3)
+			assert.Equal(t, "innodb-redolog-tool", f.metadata[6])
+
+			var names []string
+			for _, element := range f.metadata[2].([]any) {
+				names = append(names, element.(map[int16]any)[4].(string))
+			}
+			assert.Equal(t, []string{"redo_record", "record", "lsn", "type", "type_id", "operation", "length",
+				"space_id", "page_no", "table_id", "index_id", "trx_id", "mtr_group", "mtr_start", "mtr_end",
+				"file_offset", "payload", "fields"}, names[:18])
+			assert.Equal(t, parquetFieldNames, names[18:])
+
+			assert.Equal(t, map[string]string{
+				"redolog.source":    "ib_logfile0",
+				"redolog.filter":    "op == insert && (space == 5)",
+				"redolog.selection": "MTR group 1",
+				"redolog.exported":  "2024-08-24T12:00:00Z",
+			}, f.keyValues())
+
+			lsns := f.column(t, "lsn")
+			require.Len(t, lsns, 5)
+			assert.Equal(t, uint64(0xffffffff00000001), uint64(lsns[0].(int64)))
+			assert.Equal(t, int64(500), lsns[4])
+
+			assert.Equal(t, []any{true, false, false, false, false}, f.column(t, "mtr_start"))
+			assert.Equal(t, []any{false, false, true, false, false}, f.column(t, "mtr_end"))
+			assert.Equal(t, []any{int32(1), int32(1), int32(1), int32(0), int32(0)}, f.column(t, "mtr_group"))
+			assert.Equal(t, []any{int32(4), int32(20), int32(2), int32(1), int32(8)}, f.column(t, "length"))
+			assert.Equal(t, []any{nil, nil, nil, nil, nil}, f.column(t, "file_offset"))
+
+			typeNames := f.column(t, "type")
+			assert.Equal(t, types.LogType(1).String(), typeNames[0])
+
+			assert.Equal(t, []any{"2887", nil, nil, nil, nil}, f.column(t, "fields.offset"))
+			assert.Equal(t, []any{nil, "5", nil, nil, "7"}, f.column(t, "fields.space_id"))
+			assert.Equal(t, []any{nil, "4142", "4142", nil, nil}, f.column(t, "fields.hex"))
+			assert.Equal(t, []any{nil, nil, "AB", nil, "xyz"}, f.column(t, "fields.data"))
+			assert.Equal(t, []any{nil, "(id=1)", nil, nil, nil}, f.column(t, "fields.parsed"))
+		})
+	}
+}
+
+func TestWriteParquetEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, "parquet", nil, nil, nil))
+
+	f := readParquet(t, buf.Bytes())
+	assert.Equal(t, int64(0), f.metadata[3])
+	assert.Empty(t, f.rowGroups())
+	assert.Empty(t, f.keyValues())
+}
+
+func TestNewParquetWriterCompression(t *testing.T) {
+	_, err := NewParquetWriter(io.Discard, nil, ParquetOptions{Compression: "snappy"})
+	assert.ErrorContains(t, err, "unsupported Parquet compression")
+}
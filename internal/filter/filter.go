@@ -0,0 +1,142 @@
+// Package filter implements a small expression language for selecting redo
+// log records, for example:
+//
+//	type in (MLOG_REC_INSERT, MLOG_COMP_REC_INSERT_8027) && space == 42 && lsn >= 0x1A2B && data ~ "ACADEMY"
+//
+// Comparisons combine with &&, || and !, and group with parentheses.
+package filter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// Filter is a parsed filter expression
+type Filter struct {
+	source string
+	match  predicate
+}
+
+// predicate reports whether a record satisfies (part of) an expression
+type predicate func(record *types.LogRecord) bool
+
+// Parse parses a filter expression. Errors are *SyntaxError values carrying
+// the column where parsing failed.
+func Parse(expr string) (*Filter, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, withSource(err, expr)
+	}
+
+	p := &parser{tokens: tokens}
+	match, err := p.parse()
+	if err != nil {
+		return nil, withSource(err, expr)
+	}
+
+	return &Filter{source: expr, match: match}, nil
+}
+
+// Match reports whether record satisfies the expression
+func (f *Filter) Match(record *types.LogRecord) bool {
+	return f.match(record)
+}
+
+// Apply returns the records that satisfy the expression
+func (f *Filter) Apply(records []*types.LogRecord) []*types.LogRecord {
+	selected := make([]*types.LogRecord, 0)
+	for _, record := range records {
+		if f.match(record) {
+			selected = append(selected, record)
+		}
+	}
+	return selected
+}
+
+// String returns the source text of the expression
+func (f *Filter) String() string {
+	return f.source
+}
+
+// SyntaxError describes an invalid filter expression
+type SyntaxError struct {
+	Expr    string // The expression being parsed
+	Column  int    // 1-based column of the offending token
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column, e.Message)
+}
+
+// Context returns the expression with a caret under the offending column
+func (e *SyntaxError) Context() string {
+	return e.Expr + "\n" + strings.Repeat(" ", e.Column-1) + "^"
+}
+
+// errorAt creates a SyntaxError at column
+func errorAt(column int, format string, args ...interface{}) error {
+	return &SyntaxError{Column: column, Message: fmt.Sprintf(format, args...)}
+}
+
+// withSource attaches the expression text to a SyntaxError
+func withSource(err error, expr string) error {
+	if syntaxErr, ok := err.(*SyntaxError); ok {
+		syntaxErr.Expr = expr
+	}
+	return err
+}
+
+// fieldKind determines which operators and values a field accepts
+type fieldKind int
+
+const (
+	numericField fieldKind = iota // Compared as unsigned integers
+	typeField                     // Record type, by number or mlog_id_t name
+	textField                     // Compared as strings or matched with regular expressions
+)
+
+// field describes a record attribute usable in expressions
+type field struct {
+	kind   fieldKind
+	number func(record *types.LogRecord) uint64
+	text   func(record *types.LogRecord) string
+	values []string // Allowed values of a text field (nil means any)
+}
+
+// fields lists the record attributes available in expressions
+var fields = map[string]field{
+	"lsn":    {kind: numericField, number: func(r *types.LogRecord) uint64 { return r.LSN }},
+	"space":  {kind: numericField, number: func(r *types.LogRecord) uint64 { return uint64(r.SpaceID) }},
+	"page":   {kind: numericField, number: func(r *types.LogRecord) uint64 { return uint64(r.PageNo) }},
+	"table":  {kind: numericField, number: func(r *types.LogRecord) uint64 { return uint64(r.TableID) }},
+	"index":  {kind: numericField, number: func(r *types.LogRecord) uint64 { return uint64(r.IndexID) }},
+	"length": {kind: numericField, number: func(r *types.LogRecord) uint64 { return uint64(r.Length) }},
+	"offset": {kind: numericField, number: func(r *types.LogRecord) uint64 { return uint64(r.Offset) }},
+	"group":  {kind: numericField, number: func(r *types.LogRecord) uint64 { return uint64(r.MultiRecordGroup) }},
+	"trx":    {kind: numericField, number: func(r *types.LogRecord) uint64 { return r.TransactionID }},
+	"type": {
+		kind:   typeField,
+		number: func(r *types.LogRecord) uint64 { return uint64(r.Type) },
+		text:   func(r *types.LogRecord) string { return r.Type.String() },
+	},
+	"op": {
+		kind:   textField,
+		text:   func(r *types.LogRecord) string { return r.Type.Operation() },
+		values: []string{types.OperationInsert, types.OperationUpdate, types.OperationDelete, types.OperationOther},
+	},
+	"data": {kind: textField, text: func(r *types.LogRecord) string { return string(r.Data) }},
+}
+
+// FieldNames returns the names of the fields usable in expressions
+func FieldNames() []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,104 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+var testRecords = []*types.LogRecord{
+	{Type: types.LogType(67), LSN: 0x1A00, SpaceID: 42, PageNo: 3, Data: []byte("title='ACADEMY DINOSAUR'")},
+	{Type: types.LogType(38), LSN: 0x1B00, SpaceID: 42, PageNo: 4, Data: []byte("title='ACE GOLDFINGER'")},
+	{Type: types.LogType(69), LSN: 0x1C00, SpaceID: 7, PageNo: 4, Data: []byte("ACADEMY")},
+	{Type: types.LogType(1), LSN: 0x1D00, SpaceID: 0, PageNo: 0, MultiRecordGroup: 2},
+}
+
+func TestFilterMatch(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected []uint64 // LSNs of matching records
+	}{
+		{`type in (MLOG_REC_INSERT, MLOG_COMP_REC_INSERT_8027) && space == 42 && lsn >= 0x1A2B && data ~ "ACADEMY"`, nil},
+		{`type in (MLOG_REC_INSERT, MLOG_COMP_REC_INSERT_8027) && space == 42 && data ~ "ACADEMY"`, []uint64{0x1A00}},
+		{`type == 38`, []uint64{0x1B00}},
+		{`type == "mlog_1byte"`, []uint64{0x1D00}},
+		{`type ~ "DELETE"`, []uint64{0x1C00}},
+		{`space != 42`, []uint64{0x1C00, 0x1D00}},
+		{`lsn > 6912 && lsn <= 0x1D00`, []uint64{0x1C00, 0x1D00}},
+		{`page in (4) || group == 2`, []uint64{0x1B00, 0x1C00, 0x1D00}},
+		{`op == insert`, []uint64{0x1A00, 0x1B00}},
+		{`op in ("delete", other)`, []uint64{0x1C00, 0x1D00}},
+		{`!(op == insert)`, []uint64{0x1C00, 0x1D00}},
+		{`data !~ "^title" && !(space == 0)`, []uint64{0x1C00}},
+		{`data == "ACADEMY"`, []uint64{0x1C00}},
+		{`space == 42 || space == 7 && page == 3`, []uint64{0x1A00, 0x1B00}},
+		{`(space == 42 || space == 7) && page == 4`, []uint64{0x1B00, 0x1C00}},
+		{`LSN == 0x1a00`, []uint64{0x1A00}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := Parse(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expr, f.String())
+
+			var lsns []uint64
+			for _, record := range f.Apply(testRecords) {
+				lsns = append(lsns, record.LSN)
+			}
+			assert.Equal(t, tt.expected, lsns)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		column  int
+		message string
+	}{
+		{``, 1, "empty expression"},
+		{`spaec == 1`, 1, `unknown field "spaec"`},
+		{`space = 1`, 7, "unexpected character '='"},
+		{`space == `, 10, "expected number for field space, found end of expression"},
+		{`space == 1 &&`, 14, "expected field name"},
+		{`space == 1 page == 2`, 12, "unexpected 'page'"},
+		{`type == MLOG_NOPE`, 9, `unknown record type "MLOG_NOPE"`},
+		{`lsn >= 0x`, 8, `invalid number "0x"`},
+		{`lsn >= 12ab`, 10, "invalid character 'a' in number"},
+		{`data ~ "("`, 8, "invalid regular expression"},
+		{`data ~ ACADEMY`, 8, "expected quoted regular expression"},
+		{`space ~ "4"`, 7, "operator ~ is not supported for numeric field space"},
+		{`op < insert`, 4, "operator < is not supported for field op"},
+		{`op == select`, 7, `invalid op value "select"`},
+		{`type in (1, 2`, 14, "expected ',' or ')'"},
+		{`(space == 1`, 12, "expected ')'"},
+		{`data == "unterminated`, 9, "unterminated string"},
+		{`space == 1 && é`, 15, "unexpected character 'é'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			require.Error(t, err)
+
+			syntaxErr, ok := err.(*SyntaxError)
+			require.True(t, ok, "expected *SyntaxError, got %T", err)
+			assert.Equal(t, tt.column, syntaxErr.Column)
+			assert.Contains(t, syntaxErr.Message, tt.message)
+			assert.Equal(t, tt.expr, syntaxErr.Expr)
+		})
+	}
+}
+
+func TestSyntaxErrorContext(t *testing.T) {
+	_, err := Parse(`space == 1 && pgae == 2`)
+	require.Error(t, err)
+
+	syntaxErr := err.(*SyntaxError)
+	assert.Equal(t, "column 15: unknown field \"pgae\" (available: data, group, index, length, lsn, offset, op, page, space, table, trx, type)", syntaxErr.Error())
+	assert.Equal(t, "space == 1 && pgae == 2\n              ^", syntaxErr.Context())
+}
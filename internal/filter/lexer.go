@@ -0,0 +1,188 @@
+package filter
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// tokenKind identifies the kind of a lexical token
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenLParen
+	tokenRParen
+	tokenComma
+	tokenAnd      // &&
+	tokenOr       // ||
+	tokenNot      // !
+	tokenEq       // ==
+	tokenNe       // !=
+	tokenLt       // <
+	tokenLe       // <=
+	tokenGt       // >
+	tokenGe       // >=
+	tokenMatch    // ~
+	tokenNotMatch // !~
+	tokenIn       // in
+)
+
+// token is a lexical token together with its position in the expression
+type token struct {
+	kind   tokenKind
+	text   string // Source text; the unquoted value for strings
+	column int    // 1-based column of the first character
+}
+
+// operators maps operator spellings to token kinds, longest first
+var operators = []struct {
+	text string
+	kind tokenKind
+}{
+	{"&&", tokenAnd},
+	{"||", tokenOr},
+	{"==", tokenEq},
+	{"!=", tokenNe},
+	{"!~", tokenNotMatch},
+	{"<=", tokenLe},
+	{">=", tokenGe},
+	{"<", tokenLt},
+	{">", tokenGt},
+	{"!", tokenNot},
+	{"~", tokenMatch},
+	{"(", tokenLParen},
+	{")", tokenRParen},
+	{",", tokenComma},
+}
+
+// lex splits an expression into tokens, ending with a tokenEOF
+func lex(expr string) ([]token, error) {
+	var tokens []token
+	pos := 0
+
+	for pos < len(expr) {
+		c := expr[pos]
+		column := columnAt(expr, pos)
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+
+		case isIdentStart(c):
+			start := pos
+			for pos < len(expr) && isIdentPart(expr[pos]) {
+				pos++
+			}
+			text := expr[start:pos]
+			kind := tokenIdent
+			if strings.EqualFold(text, "in") {
+				kind = tokenIn
+			}
+			tokens = append(tokens, token{kind: kind, text: text, column: column})
+
+		case isDigit(c):
+			start := pos
+			if c == '0' && pos+1 < len(expr) && (expr[pos+1] == 'x' || expr[pos+1] == 'X') {
+				pos += 2
+				for pos < len(expr) && isHexDigit(expr[pos]) {
+					pos++
+				}
+			} else {
+				for pos < len(expr) && isDigit(expr[pos]) {
+					pos++
+				}
+			}
+			if pos < len(expr) && isIdentPart(expr[pos]) {
+				return nil, errorAt(columnAt(expr, pos), "invalid character %q in number", expr[pos])
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: expr[start:pos], column: column})
+
+		case c == '"' || c == '\'':
+			value, end, err := lexString(expr, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, text: value, column: column})
+			pos = end
+
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(expr[pos:], op.text) {
+					tokens = append(tokens, token{kind: op.kind, text: op.text, column: column})
+					pos += len(op.text)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				r, _ := utf8.DecodeRuneInString(expr[pos:])
+				return nil, errorAt(column, "unexpected character %q", r)
+			}
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokenEOF, column: columnAt(expr, len(expr))})
+	return tokens, nil
+}
+
+// lexString reads a quoted string starting at pos and returns its value and
+// the position after the closing quote
+func lexString(expr string, pos int) (string, int, error) {
+	quote := expr[pos]
+	start := pos
+	pos++
+
+	var value strings.Builder
+	for pos < len(expr) {
+		c := expr[pos]
+		switch {
+		case c == quote:
+			return value.String(), pos + 1, nil
+		case c == '\\' && pos+1 < len(expr):
+			next := expr[pos+1]
+			switch next {
+			case 'n':
+				value.WriteByte('\n')
+			case 't':
+				value.WriteByte('\t')
+			case '\\', '"', '\'':
+				value.WriteByte(next)
+			default:
+				// Keep unknown escapes so regular expressions like "\d" work
+				value.WriteByte('\\')
+				value.WriteByte(next)
+			}
+			pos += 2
+		default:
+			value.WriteByte(c)
+			pos++
+		}
+	}
+
+	return "", 0, errorAt(columnAt(expr, start), "unterminated string")
+}
+
+// columnAt returns the 1-based column of byte offset pos
+func columnAt(expr string, pos int) int {
+	return utf8.RuneCountInString(expr[:pos]) + 1
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
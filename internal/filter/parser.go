@@ -0,0 +1,315 @@
+package filter
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// parser is a recursive descent parser over the token stream:
+//
+//	expr       = and { "||" and }
+//	and        = unary { "&&" unary }
+//	unary      = "!" unary | "(" expr ")" | comparison
+//	comparison = field ( op value | "in" "(" value { "," value } ")" )
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parse parses the whole token stream
+func (p *parser) parse() (predicate, error) {
+	if p.peek().kind == tokenEOF {
+		return nil, errorAt(p.peek().column, "empty expression")
+	}
+
+	match, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, errorAt(tok.column, "unexpected %s, expected && or ||", describe(tok))
+	}
+	return match, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// expect consumes a token of the given kind
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	tok := p.next()
+	if tok.kind != kind {
+		return tok, errorAt(tok.column, "expected %s, found %s", what, describe(tok))
+	}
+	return tok, nil
+}
+
+func (p *parser) parseOr() (predicate, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokenOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l, r := left, right
+		left = func(record *types.LogRecord) bool { return l(record) || r(record) }
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (predicate, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokenAnd {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l, r := left, right
+		left = func(record *types.LogRecord) bool { return l(record) && r(record) }
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (predicate, error) {
+	switch p.peek().kind {
+	case tokenNot:
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(record *types.LogRecord) bool { return !operand(record) }, nil
+
+	case tokenLParen:
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenRParen, "')'"); err != nil {
+			return nil, err
+		}
+		return inner, nil
+
+	default:
+		return p.parseComparison()
+	}
+}
+
+func (p *parser) parseComparison() (predicate, error) {
+	name := p.next()
+	if name.kind != tokenIdent {
+		return nil, errorAt(name.column, "expected field name, found %s", describe(name))
+	}
+	f, ok := fields[strings.ToLower(name.text)]
+	if !ok {
+		return nil, errorAt(name.column, "unknown field %q (available: %s)", name.text, strings.Join(FieldNames(), ", "))
+	}
+
+	op := p.next()
+	switch op.kind {
+	case tokenEq, tokenNe, tokenLt, tokenLe, tokenGt, tokenGe:
+		value := p.next()
+		return compare(f, name.text, op, value)
+
+	case tokenIn:
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return membership(f, name.text, values)
+
+	case tokenMatch, tokenNotMatch:
+		value := p.next()
+		return matchRegexp(f, name.text, op, value)
+
+	default:
+		return nil, errorAt(op.column, "expected comparison operator after %q, found %s", name.text, describe(op))
+	}
+}
+
+// parseList parses a parenthesized, comma separated list of values
+func (p *parser) parseList() ([]token, error) {
+	if _, err := p.expect(tokenLParen, "'(' after in"); err != nil {
+		return nil, err
+	}
+
+	var values []token
+	for {
+		value := p.next()
+		if !isValue(value) {
+			return nil, errorAt(value.column, "expected value, found %s", describe(value))
+		}
+		values = append(values, value)
+
+		sep := p.next()
+		if sep.kind == tokenRParen {
+			return values, nil
+		}
+		if sep.kind != tokenComma {
+			return nil, errorAt(sep.column, "expected ',' or ')', found %s", describe(sep))
+		}
+	}
+}
+
+// compare builds a predicate for a binary comparison
+func compare(f field, name string, op, value token) (predicate, error) {
+	if f.kind == textField {
+		if op.kind != tokenEq && op.kind != tokenNe {
+			return nil, errorAt(op.column, "operator %s is not supported for field %s", op.text, name)
+		}
+		text, err := textValue(f, name, value)
+		if err != nil {
+			return nil, err
+		}
+		equal := op.kind == tokenEq
+		return func(record *types.LogRecord) bool { return (f.text(record) == text) == equal }, nil
+	}
+
+	number, err := numericValue(f, name, value)
+	if err != nil {
+		return nil, err
+	}
+	get := f.number
+	switch op.kind {
+	case tokenEq:
+		return func(record *types.LogRecord) bool { return get(record) == number }, nil
+	case tokenNe:
+		return func(record *types.LogRecord) bool { return get(record) != number }, nil
+	case tokenLt:
+		return func(record *types.LogRecord) bool { return get(record) < number }, nil
+	case tokenLe:
+		return func(record *types.LogRecord) bool { return get(record) <= number }, nil
+	case tokenGt:
+		return func(record *types.LogRecord) bool { return get(record) > number }, nil
+	default:
+		return func(record *types.LogRecord) bool { return get(record) >= number }, nil
+	}
+}
+
+// membership builds a predicate for "field in (values)"
+func membership(f field, name string, values []token) (predicate, error) {
+	if f.kind == textField {
+		set := make(map[string]struct{}, len(values))
+		for _, value := range values {
+			text, err := textValue(f, name, value)
+			if err != nil {
+				return nil, err
+			}
+			set[text] = struct{}{}
+		}
+		return func(record *types.LogRecord) bool {
+			_, ok := set[f.text(record)]
+			return ok
+		}, nil
+	}
+
+	set := make(map[uint64]struct{}, len(values))
+	for _, value := range values {
+		number, err := numericValue(f, name, value)
+		if err != nil {
+			return nil, err
+		}
+		set[number] = struct{}{}
+	}
+	return func(record *types.LogRecord) bool {
+		_, ok := set[f.number(record)]
+		return ok
+	}, nil
+}
+
+// matchRegexp builds a predicate for the ~ and !~ operators
+func matchRegexp(f field, name string, op, value token) (predicate, error) {
+	if f.text == nil {
+		return nil, errorAt(op.column, "operator %s is not supported for numeric field %s", op.text, name)
+	}
+	if value.kind != tokenString {
+		return nil, errorAt(value.column, "expected quoted regular expression, found %s", describe(value))
+	}
+	re, err := regexp.Compile(value.text)
+	if err != nil {
+		return nil, errorAt(value.column, "invalid regular expression: %v", err)
+	}
+
+	want := op.kind == tokenMatch
+	return func(record *types.LogRecord) bool { return re.MatchString(f.text(record)) == want }, nil
+}
+
+// numericValue converts a value token for a numeric or type field
+func numericValue(f field, name string, value token) (uint64, error) {
+	switch value.kind {
+	case tokenNumber:
+		number, err := strconv.ParseUint(value.text, 0, 64)
+		if err != nil {
+			return 0, errorAt(value.column, "invalid number %q", value.text)
+		}
+		return number, nil
+	case tokenIdent, tokenString:
+		if f.kind == typeField {
+			logType, ok := types.ParseLogType(value.text)
+			if !ok {
+				return 0, errorAt(value.column, "unknown record type %q", value.text)
+			}
+			return uint64(logType), nil
+		}
+	}
+	return 0, errorAt(value.column, "expected number for field %s, found %s", name, describe(value))
+}
+
+// textValue converts a value token for a text field
+func textValue(f field, name string, value token) (string, error) {
+	if value.kind != tokenString && value.kind != tokenIdent {
+		return "", errorAt(value.column, "expected text for field %s, found %s", name, describe(value))
+	}
+	if f.values == nil {
+		return value.text, nil
+	}
+
+	text := strings.ToLower(value.text)
+	for _, allowed := range f.values {
+		if text == allowed {
+			return text, nil
+		}
+	}
+	return "", errorAt(value.column, "invalid %s value %q (expected one of: %s)", name, value.text, strings.Join(f.values, ", "))
+}
+
+// isValue reports whether tok can be used as a value
+func isValue(tok token) bool {
+	return tok.kind == tokenNumber || tok.kind == tokenString || tok.kind == tokenIdent
+}
+
+// describe returns a token description for error messages
+func describe(tok token) string {
+	switch tok.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return strconv.Quote(tok.text)
+	default:
+		return "'" + tok.text + "'"
+	}
+}
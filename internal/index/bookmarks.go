@@ -0,0 +1,115 @@
+package index
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BookmarksSuffix is appended to a log file name to form its default
+// bookmark file, so the bookmarks travel with the log
+const BookmarksSuffix = ".bookmarks.json"
+
+// Bookmark is a named record of a log file
+type Bookmark struct {
+	Name    string    `json:"name"`
+	LSN     uint64    `json:"lsn"`
+	Record  int       `json:"record"` // 1-based record number when created
+	Created time.Time `json:"created"`
+}
+
+// Bookmarks are the bookmarks of one log file, kept in a JSON state file
+type Bookmarks struct {
+	path string
+
+	LogFile   string     `json:"log_file"`
+	Bookmarks []Bookmark `json:"bookmarks"` // Ordered by LSN
+}
+
+// BookmarksPath returns the default bookmark file of logFile
+func BookmarksPath(logFile string) string {
+	return logFile + BookmarksSuffix
+}
+
+// LoadBookmarks reads the bookmark file at path. A missing file yields no
+// bookmarks; it is created by the first Save.
+func LoadBookmarks(path, logFile string) (*Bookmarks, error) {
+	b := &Bookmarks{path: path, LogFile: filepath.Base(logFile), Bookmarks: make([]Bookmark, 0)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bookmarks: %w", err)
+	}
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("invalid bookmark file %s: %w", path, err)
+	}
+	if b.Bookmarks == nil {
+		b.Bookmarks = make([]Bookmark, 0)
+	}
+	return b, nil
+}
+
+// Path returns the bookmark file
+func (b *Bookmarks) Path() string {
+	return b.path
+}
+
+// Get returns the bookmark called name
+func (b *Bookmarks) Get(name string) (Bookmark, bool) {
+	for _, bookmark := range b.Bookmarks {
+		if bookmark.Name == name {
+			return bookmark, true
+		}
+	}
+	return Bookmark{}, false
+}
+
+// Set adds bookmark, replacing any bookmark of the same name
+func (b *Bookmarks) Set(bookmark Bookmark) error {
+	bookmark.Name = strings.TrimSpace(bookmark.Name)
+	if bookmark.Name == "" {
+		return fmt.Errorf("bookmark name is empty")
+	}
+
+	b.Delete(bookmark.Name)
+	b.Bookmarks = append(b.Bookmarks, bookmark)
+	sort.SliceStable(b.Bookmarks, func(i, j int) bool { return b.Bookmarks[i].LSN < b.Bookmarks[j].LSN })
+	return nil
+}
+
+// Delete removes the bookmark called name and reports whether it existed
+func (b *Bookmarks) Delete(name string) bool {
+	for i, bookmark := range b.Bookmarks {
+		if bookmark.Name == name {
+			b.Bookmarks = append(b.Bookmarks[:i], b.Bookmarks[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Save writes the bookmarks to their file, replacing it atomically
+func (b *Bookmarks) Save() error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := b.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to save bookmarks: %w", err)
+	}
+	if err := os.Rename(tmp, b.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save bookmarks: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+// Package index locates redo log records by LSN, file offset, record number
+// or page, for example for the TUI's goto prompt:
+//
+//	123456 or 0x1E240   LSN
+//	@0x2000             file offset
+//	#42                 record number (1-based)
+//	5:3                 space 5, page 3
+package index
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// TargetKind is what a Target points at
+type TargetKind int
+
+const (
+	TargetLSN TargetKind = iota
+	TargetOffset
+	TargetRecord
+	TargetPage
+)
+
+// Target is a position to go to
+type Target struct {
+	Kind    TargetKind
+	Value   uint64 // LSN, file offset or 1-based record number
+	SpaceID uint32 // TargetPage only
+	PageNo  uint32 // TargetPage only
+}
+
+// ParseTarget parses a goto target. Numbers may be decimal or 0x hex.
+func ParseTarget(s string) (Target, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Target{}, fmt.Errorf("empty target")
+	}
+
+	switch {
+	case strings.HasPrefix(s, "#"):
+		n, err := parseNumber(s[1:], "record number")
+		if err != nil {
+			return Target{}, err
+		}
+		if n == 0 {
+			return Target{}, fmt.Errorf("record numbers start at 1")
+		}
+		return Target{Kind: TargetRecord, Value: n}, nil
+	case strings.HasPrefix(s, "@"):
+		n, err := parseNumber(s[1:], "file offset")
+		if err != nil {
+			return Target{}, err
+		}
+		return Target{Kind: TargetOffset, Value: n}, nil
+	case strings.Contains(s, ":"):
+		spaceText, pageText, _ := strings.Cut(s, ":")
+		spaceID, err := strconv.ParseUint(strings.TrimSpace(spaceText), 0, 32)
+		if err != nil {
+			return Target{}, fmt.Errorf("invalid space ID %q", spaceText)
+		}
+		pageNo, err := strconv.ParseUint(strings.TrimSpace(pageText), 0, 32)
+		if err != nil {
+			return Target{}, fmt.Errorf("invalid page number %q", pageText)
+		}
+		return Target{Kind: TargetPage, SpaceID: uint32(spaceID), PageNo: uint32(pageNo)}, nil
+	default:
+		n, err := parseNumber(s, "LSN")
+		if err != nil {
+			return Target{}, err
+		}
+		return Target{Kind: TargetLSN, Value: n}, nil
+	}
+}
+
+func parseNumber(s, what string) (uint64, error) {
+	n, err := strconv.ParseUint(strings.TrimSpace(s), 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: expected a decimal or 0x hex number", what, s)
+	}
+	return n, nil
+}
+
+// String formats the target in the syntax accepted by ParseTarget
+func (t Target) String() string {
+	switch t.Kind {
+	case TargetOffset:
+		return fmt.Sprintf("@%d", t.Value)
+	case TargetRecord:
+		return fmt.Sprintf("#%d", t.Value)
+	case TargetPage:
+		return fmt.Sprintf("%d:%d", t.SpaceID, t.PageNo)
+	default:
+		return strconv.FormatUint(t.Value, 10)
+	}
+}
+
+// pageKey identifies a page
+type pageKey struct {
+	spaceID uint32
+	pageNo  uint32
+}
+
+// Index locates records of a slice by position
+type Index struct {
+	records  []*types.LogRecord
+	byLSN    []int // Positions ordered by LSN
+	byOffset []int // Positions of records with known file offsets, ordered by offset
+	pages    map[pageKey][]int
+}
+
+// New indexes records. The records must not change while the index is used.
+func New(records []*types.LogRecord) *Index {
+	ix := &Index{
+		records: records,
+		byLSN:   make([]int, 0, len(records)),
+		pages:   make(map[pageKey][]int),
+	}
+
+	for i, record := range records {
+		ix.byLSN = append(ix.byLSN, i)
+		if _, _, ok := record.ByteRange(); ok {
+			ix.byOffset = append(ix.byOffset, i)
+		}
+		if record.HasPage() {
+			key := pageKey{record.SpaceID, record.PageNo}
+			ix.pages[key] = append(ix.pages[key], i)
+		}
+	}
+	sort.SliceStable(ix.byLSN, func(a, b int) bool { return records[ix.byLSN[a]].LSN < records[ix.byLSN[b]].LSN })
+	sort.SliceStable(ix.byOffset, func(a, b int) bool {
+		return records[ix.byOffset[a]].FileOffset < records[ix.byOffset[b]].FileOffset
+	})
+
+	return ix
+}
+
+// Find returns the position of the record nearest to target: the last
+// record starting at or before an LSN or file offset (the first record if
+// there is none), or the closest existing record number. For pages it returns
+// the next record touching the page after position from, wrapping around, so
+// that repeating the same goto cycles through the page's records.
+func (ix *Index) Find(target Target, from int) (int, error) {
+	if len(ix.records) == 0 {
+		return 0, fmt.Errorf("no records")
+	}
+
+	switch target.Kind {
+	case TargetLSN:
+		return ix.lastAtOrBefore(ix.byLSN, func(i int) bool { return ix.records[i].LSN > target.Value }), nil
+	case TargetOffset:
+		if len(ix.byOffset) == 0 {
+			return 0, fmt.Errorf("the reader did not record file offsets for these records")
+		}
+		return ix.lastAtOrBefore(ix.byOffset, func(i int) bool { return uint64(ix.records[i].FileOffset) > target.Value }), nil
+	case TargetRecord:
+		if target.Value > uint64(len(ix.records)) {
+			return len(ix.records) - 1, nil
+		}
+		return int(target.Value) - 1, nil
+	case TargetPage:
+		positions := ix.pages[pageKey{target.SpaceID, target.PageNo}]
+		if len(positions) == 0 {
+			return 0, fmt.Errorf("no record touches page %d:%d", target.SpaceID, target.PageNo)
+		}
+		next := sort.SearchInts(positions, from+1)
+		if next == len(positions) {
+			next = 0
+		}
+		return positions[next], nil
+	default:
+		return 0, fmt.Errorf("unknown target kind %d", target.Kind)
+	}
+}
+
+// Page returns the positions of the records touching a page, in order
+func (ix *Index) Page(spaceID, pageNo uint32) []int {
+	return ix.pages[pageKey{spaceID, pageNo}]
+}
+
+// lastAtOrBefore returns the last position of ordered for which after is
+// false, or the first position if after is true for all
+func (ix *Index) lastAtOrBefore(ordered []int, after func(position int) bool) int {
+	i := sort.Search(len(ordered), func(i int) bool { return after(ordered[i]) })
+	if i == 0 {
+		return ordered[0]
+	}
+	return ordered[i-1]
+}
+
+// FindBookmark returns the position of the record a bookmark points at: its
+// record number if that record still has the bookmarked LSN, otherwise the
+// record nearest to its LSN
+func (ix *Index) FindBookmark(bookmark Bookmark) (int, error) {
+	if i := bookmark.Record - 1; i >= 0 && i < len(ix.records) && ix.records[i].LSN == bookmark.LSN {
+		return i, nil
+	}
+	return ix.Find(Target{Kind: TargetLSN, Value: bookmark.LSN}, 0)
+}
@@ -0,0 +1,128 @@
+package index
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		input  string
+		target Target
+	}{
+		{"123456", Target{Kind: TargetLSN, Value: 123456}},
+		{" 0x1E240 ", Target{Kind: TargetLSN, Value: 123456}},
+		{"@0x800", Target{Kind: TargetOffset, Value: 2048}},
+		{"#42", Target{Kind: TargetRecord, Value: 42}},
+		{"5:3", Target{Kind: TargetPage, SpaceID: 5, PageNo: 3}},
+		{"0x10:0x2", Target{Kind: TargetPage, SpaceID: 16, PageNo: 2}},
+	}
+	for _, tt := range tests {
+		target, err := ParseTarget(tt.input)
+		require.NoError(t, err, tt.input)
+		assert.Equal(t, tt.target, target, tt.input)
+	}
+
+	for _, input := range []string{"", "#0", "#x", "@", "lsn", "5:", "a:1", "-1"} {
+		_, err := ParseTarget(input)
+		assert.Error(t, err, input)
+	}
+
+	assert.Equal(t, "5:3", Target{Kind: TargetPage, SpaceID: 5, PageNo: 3}.String())
+	assert.Equal(t, "@2048", Target{Kind: TargetOffset, Value: 2048}.String())
+}
+
+func TestIndexFind(t *testing.T) {
+	span := func(offset int64) []types.FieldSpan {
+		return []types.FieldSpan{{Name: "type", Kind: types.SpanRecordType, Offset: offset, Length: 1}}
+	}
+	pageSpans := func(offset int64) []types.FieldSpan {
+		return append(span(offset),
+			types.FieldSpan{Name: "space_id", Kind: types.SpanCompressedInt, Offset: offset + 1, Length: 1},
+			types.FieldSpan{Name: "page_no", Kind: types.SpanCompressedInt, Offset: offset + 2, Length: 1})
+	}
+	records := []*types.LogRecord{
+		{LSN: 100, FileOffset: 2060, Spans: pageSpans(2060), SpaceID: 5, PageNo: 3},
+		{LSN: 110, FileOffset: 2070, Spans: span(2070)},
+		{LSN: 120, FileOffset: 2080, Spans: pageSpans(2080), SpaceID: 5, PageNo: 3},
+		{LSN: 130, FileOffset: 2600, Spans: pageSpans(2600), SpaceID: 5, PageNo: 4},
+	}
+	ix := New(records)
+
+	find := func(input string, from int) int {
+		target, err := ParseTarget(input)
+		require.NoError(t, err, input)
+		i, err := ix.Find(target, from)
+		require.NoError(t, err, input)
+		return i
+	}
+
+	assert.Equal(t, 0, find("50", 0))     // Before the first record
+	assert.Equal(t, 1, find("115", 0))    // Within the second record
+	assert.Equal(t, 3, find("0xFFFF", 0)) // After the last record
+	assert.Equal(t, 2, find("@2085", 0))
+	assert.Equal(t, 3, find("#4", 0))
+	assert.Equal(t, 3, find("#99", 0))
+
+	// Pages cycle through the records touching them
+	assert.Equal(t, 2, find("5:3", 0))
+	assert.Equal(t, 0, find("5:3", 2))
+	_, err := ix.Find(Target{Kind: TargetPage, SpaceID: 9, PageNo: 9}, 0)
+	assert.Error(t, err)
+	assert.Equal(t, []int{0, 2}, ix.Page(5, 3))
+	assert.Empty(t, ix.Page(9, 9))
+
+	// Page 0 of the system tablespace is indexed, records without a page
+	// are not
+	system := New([]*types.LogRecord{{LSN: 100, Spans: pageSpans(2060)}, {LSN: 110, Spans: span(2070)}})
+	assert.Equal(t, []int{0}, system.Page(0, 0))
+
+	// File offsets need byte spans
+	_, err = New([]*types.LogRecord{{LSN: 1}}).Find(Target{Kind: TargetOffset, Value: 1}, 0)
+	assert.Error(t, err)
+
+	// Bookmarks prefer their record number while it still has their LSN
+	i, err := ix.FindBookmark(Bookmark{LSN: 120, Record: 3})
+	require.NoError(t, err)
+	assert.Equal(t, 2, i)
+	i, err = ix.FindBookmark(Bookmark{LSN: 125, Record: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, i)
+}
+
+func TestBookmarks(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "ib_logfile0")
+	path := BookmarksPath(logFile)
+
+	b, err := LoadBookmarks(path, logFile)
+	require.NoError(t, err)
+	assert.Empty(t, b.Bookmarks)
+
+	created := time.Date(2024, 8, 24, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, b.Set(Bookmark{Name: "bad-split", LSN: 200, Record: 7, Created: created}))
+	require.NoError(t, b.Set(Bookmark{Name: " start ", LSN: 100, Record: 1, Created: created}))
+	require.NoError(t, b.Set(Bookmark{Name: "bad-split", LSN: 300, Record: 9, Created: created}))
+	assert.Error(t, b.Set(Bookmark{Name: "  "}))
+	require.NoError(t, b.Save())
+
+	loaded, err := LoadBookmarks(path, logFile)
+	require.NoError(t, err)
+	assert.Equal(t, "ib_logfile0", loaded.LogFile)
+	require.Len(t, loaded.Bookmarks, 2)
+	assert.Equal(t, "start", loaded.Bookmarks[0].Name)
+
+	bookmark, ok := loaded.Get("bad-split")
+	require.True(t, ok)
+	assert.Equal(t, Bookmark{Name: "bad-split", LSN: 300, Record: 9, Created: created}, bookmark)
+
+	assert.True(t, loaded.Delete("start"))
+	assert.False(t, loaded.Delete("start"))
+	_, ok = loaded.Get("start")
+	assert.False(t, ok)
+}
@@ -0,0 +1,145 @@
+package reader
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// LogBlockHdrNoMask masks the flush bit off a block number
+const LogBlockHdrNoMask = 0x3FFFFFFF
+
+// ParseBlockHeader decodes the header and trailer of a 512-byte log block.
+// Blocks shorter than 512 bytes are reported as a DecodeError at offset 0.
+func ParseBlockHeader(block []byte) (*MySQLLogBlockHeader, error) {
+	if len(block) < OSFileLogBlockSize {
+		return nil, newDecodeError(0, 0, "log block of %d bytes, want %d", len(block), OSFileLogBlockSize)
+	}
+	header := decodeBlockHeader(block)
+	header.Checksum = binary.BigEndian.Uint32(block[OSFileLogBlockSize-LogBlockTrlSize:])
+	return header, nil
+}
+
+// decodeBlockHeader decodes the 12-byte header at the start of a log block.
+// The server writes every header and trailer field big endian
+// (mach_write_to_N).
+func decodeBlockHeader(header []byte) *MySQLLogBlockHeader {
+	return &MySQLLogBlockHeader{
+		HdrNo:         binary.BigEndian.Uint32(header[LogBlockHdrNo : LogBlockHdrNo+4]),
+		DataLen:       binary.BigEndian.Uint16(header[LogBlockHdrDataLen : LogBlockHdrDataLen+2]),
+		FirstRecGroup: binary.BigEndian.Uint16(header[LogBlockFirstRecGroup : LogBlockFirstRecGroup+2]),
+		EpochNo:       binary.BigEndian.Uint32(header[LogBlockEpochNo : LogBlockEpochNo+4]),
+	}
+}
+
+// BlockOffset returns the file offset of the log block containing offset
+func BlockOffset(offset int64) int64 {
+	return offset - offset%OSFileLogBlockSize
+}
+
+// BlockLSN returns the LSN at the start of the log block whose header
+// starts header. The server writes the block number as
+// (lsn / 512 & 0x3FFFFFFF) + 1, with the flush bit set on the first block
+// of a write, so numbers repeat every 512 GiB of log: the LSN nearest to
+// near, such as a checkpoint LSN, is returned.
+func BlockLSN(header []byte, near uint64) uint64 {
+	const period = (LogBlockHdrNoMask + 1) * OSFileLogBlockSize
+	no := decodeBlockHeader(header).HdrNo & LogBlockHdrNoMask
+	lsn := near - near%period + uint64((no-1)&LogBlockHdrNoMask)*OSFileLogBlockSize
+	switch {
+	case lsn > near+period/2 && lsn >= period:
+		lsn -= period
+	case lsn+period/2 < near:
+		lsn += period
+	}
+	return lsn
+}
+
+// BlockSpans returns the header and trailer fields of the log block
+// starting at blockOffset
+func BlockSpans(blockOffset int64) []types.FieldSpan {
+	return []types.FieldSpan{
+		{Name: "hdr_no", Kind: types.SpanBlockHeader, Offset: blockOffset + LogBlockHdrNo, Length: 4},
+		{Name: "data_len", Kind: types.SpanBlockHeader, Offset: blockOffset + LogBlockHdrDataLen, Length: 2},
+		{Name: "first_rec_group", Kind: types.SpanBlockHeader, Offset: blockOffset + LogBlockFirstRecGroup, Length: 2},
+		{Name: "epoch_no", Kind: types.SpanBlockHeader, Offset: blockOffset + LogBlockEpochNo, Length: 4},
+		{Name: "checksum", Kind: types.SpanBlockTrailer, Offset: blockOffset + OSFileLogBlockSize - LogBlockTrlSize, Length: LogBlockTrlSize},
+	}
+}
+
+// ReadBlocks reads the whole log blocks covering the file range
+// [start, end). It returns the offset of the first block and the bytes
+// read, which are shorter than whole blocks at the end of the file.
+func ReadBlocks(filename string, start, end int64) (int64, []byte, error) {
+	if end <= start {
+		return 0, nil, fmt.Errorf("invalid range %d-%d", start, end)
+	}
+
+	src, err := OpenInput(filename)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer closeSource(src)
+
+	// Compressed inputs are streams, read up to the blocks
+	first := BlockOffset(start)
+	last := BlockOffset(end-1) + OSFileLogBlockSize
+	data := make([]byte, last-first)
+	if _, err := src.Seek(first, io.SeekStart); err != nil {
+		return 0, nil, fmt.Errorf("failed to read blocks at offset %d: %w", first, err)
+	}
+	n, err := io.ReadFull(src, data)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return 0, nil, fmt.Errorf("failed to read blocks at offset %d: %w", first, err)
+	}
+	if n == 0 {
+		return 0, nil, fmt.Errorf("offset %d is past the end of %s", first, filename)
+	}
+	return first, data[:n], nil
+}
+
+// UsesLogBlocks reports whether filename is read as a MySQL log of 512-byte
+// blocks, rather than as a test fixture
+func UsesLogBlocks(filename string) (bool, error) {
+	src, err := OpenInput(filename)
+	if err != nil {
+		return false, err
+	}
+	defer closeSource(src)
+	_, ok := NewReaderForSource(src).(*MySQLRedoLogReader)
+	return ok, nil
+}
+
+// DescribeSpan decodes the bytes of a span the way the reader did. Fixed
+// size integers are big endian, as the server writes them (mach_write_to_N).
+func DescribeSpan(span types.FieldSpan, data []byte) string {
+	switch span.Kind {
+	case types.SpanRecordType:
+		if len(data) == 1 {
+			return fmt.Sprintf("%d (%s)", data[0], types.LogType(data[0]))
+		}
+	case types.SpanCompressedInt:
+		if value, n := parseCompressedUint64(data); n == len(data) {
+			return fmt.Sprintf("%d", value)
+		}
+	case types.SpanBlockHeader, types.SpanBlockTrailer, types.SpanFixedInt:
+		switch len(data) {
+		case 1:
+			return fmt.Sprintf("%d", data[0])
+		case 2:
+			return fmt.Sprintf("%d", binary.BigEndian.Uint16(data))
+		case 4:
+			return fmt.Sprintf("%d (0x%08x)", binary.BigEndian.Uint32(data), binary.BigEndian.Uint32(data))
+		case 8:
+			return fmt.Sprintf("%d", binary.BigEndian.Uint64(data))
+		}
+	case types.SpanPayload:
+		if text := extractReadableStrings(data); text != "" {
+			return fmt.Sprintf("%d bytes, text %q", len(data), text)
+		}
+		return fmt.Sprintf("%d bytes", len(data))
+	}
+	return fmt.Sprintf("% x", data)
+}
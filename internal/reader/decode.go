@@ -0,0 +1,38 @@
+package reader
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// ErrCorruptLog is wrapped by the errors of log data that cannot be
+// decoded
+var ErrCorruptLog = errors.New("corrupt redo log data")
+
+// DecodeError reports log data that cannot be decoded, such as a truncated
+// block or a record whose lengths exceed what the server can write
+type DecodeError struct {
+	Offset int64         // File offset of the block or record
+	Type   types.LogType // Record type, 0 for blocks
+	Reason string
+}
+
+// newDecodeError returns a DecodeError of the record of recordType at offset
+func newDecodeError(offset int64, recordType types.LogType, format string, args ...interface{}) *DecodeError {
+	return &DecodeError{Offset: offset, Type: recordType, Reason: fmt.Sprintf(format, args...)}
+}
+
+// Error describes the data and why it cannot be decoded
+func (e *DecodeError) Error() string {
+	if e.Type == 0 {
+		return fmt.Sprintf("%v at offset %d: %s", ErrCorruptLog, e.Offset, e.Reason)
+	}
+	return fmt.Sprintf("%v at offset %d: %s record: %s", ErrCorruptLog, e.Offset, e.Type, e.Reason)
+}
+
+// Unwrap returns ErrCorruptLog
+func (e *DecodeError) Unwrap() error {
+	return ErrCorruptLog
+}
@@ -0,0 +1,141 @@
+package reader
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+// Redo log encryption (innodb_redo_log_encrypt). The header block at
+// LogEncryptionOffset holds the key and IV of the log, encrypted with a
+// master key from the keyring; each log block is then AES-256-CBC
+// encrypted after its 12-byte header.
+const (
+	LogEncryptionOffset     = 2 * OSFileLogBlockSize // LOG_ENCRYPTION, between the two checkpoint blocks
+	LogBlockEncryptBit      = 0x8000                 // LOG_BLOCK_ENCRYPT_BIT_MASK, set in data_len
+	EncryptionKeyLen        = 32                     // ENCRYPTION_KEY_LEN
+	EncryptionServerUUIDLen = 36
+	EncryptionMasterKeyName = "INNODBKey" // ENCRYPTION_MASTER_KEY_PRIV_INFO
+)
+
+// encryptionMagics are the magic bytes starting each version of the
+// encryption info (ENCRYPTION_KEY_MAGIC_V1 to V3)
+var encryptionMagics = []string{"lCA", "lCB", "lCC"}
+
+// ErrEncryptedLog is returned for an encrypted log block when no keyring
+// was given
+var ErrEncryptedLog = errors.New("redo log is encrypted")
+
+// ErrKeyNotFound is returned when the keyring lacks the master key of the log
+var ErrKeyNotFound = errors.New("master key not found in keyring")
+
+// ErrKeyMismatch is returned when the master key does not decrypt the key
+// of the log
+var ErrKeyMismatch = errors.New("master key does not match the redo log")
+
+// EncryptionInfo is the encryption info of an encrypted redo log header
+type EncryptionInfo struct {
+	Version     int    `json:"version"`       // Version of the info layout, 1 to 3
+	MasterKeyID uint32 `json:"master_key_id"` // Sequence number of the master key
+	ServerUUID  string `json:"server_uuid"`   // Server the master key belongs to
+	encrypted   []byte // Key and IV, encrypted with the master key
+	checksum    uint32 // CRC-32C of the decrypted key and IV
+}
+
+// ParseEncryptionInfo reads the encryption info at the start of block. It
+// returns nil when the block holds none: the log is not encrypted.
+func ParseEncryptionInfo(block []byte) (*EncryptionInfo, error) {
+	version := 0
+	for i, magic := range encryptionMagics {
+		if bytes.HasPrefix(block, []byte(magic)) {
+			version = i + 1
+		}
+	}
+	if version == 0 {
+		return nil, nil
+	}
+	if version == 1 {
+		return nil, fmt.Errorf("encryption info version 1 is not supported")
+	}
+	const size = 3 + 4 + EncryptionServerUUIDLen + 2*EncryptionKeyLen + 4
+	if len(block) < size {
+		return nil, fmt.Errorf("encryption info is truncated")
+	}
+	info := &EncryptionInfo{
+		Version:     version,
+		MasterKeyID: binary.BigEndian.Uint32(block[3:]),
+		ServerUUID:  string(block[7 : 7+EncryptionServerUUIDLen]),
+	}
+	keys := block[7+EncryptionServerUUIDLen:]
+	info.encrypted = append([]byte(nil), keys[:2*EncryptionKeyLen]...)
+	info.checksum = binary.BigEndian.Uint32(keys[2*EncryptionKeyLen:])
+	return info, nil
+}
+
+// MasterKeyName returns the keyring ID of the master key, as in
+// INNODBKey-<server uuid>-<master key id>
+func (info *EncryptionInfo) MasterKeyName() string {
+	return fmt.Sprintf("%s-%s-%d", EncryptionMasterKeyName, info.ServerUUID, info.MasterKeyID)
+}
+
+// logDecrypter decrypts the log blocks of an encrypted redo log
+type logDecrypter struct {
+	block cipher.Block
+	iv    []byte
+}
+
+// decrypter returns the block decrypter of the log, using the master key
+// from keyring to decrypt the key and IV of the log
+func (info *EncryptionInfo) decrypter(keyring *Keyring) (*logDecrypter, error) {
+	name := info.MasterKeyName()
+	masterKey, ok := keyring.Key(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not in %s", ErrKeyNotFound, name, keyring.Source)
+	}
+	if len(masterKey) != EncryptionKeyLen {
+		return nil, fmt.Errorf("%w: %s is %d bytes, not %d", ErrKeyMismatch, name, len(masterKey), EncryptionKeyLen)
+	}
+
+	// The key and IV are AES-256-ECB encrypted with the master key
+	master, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(info.encrypted))
+	for i := 0; i < len(plain); i += aes.BlockSize {
+		master.Decrypt(plain[i:i+aes.BlockSize], info.encrypted[i:i+aes.BlockSize])
+	}
+	if crc32.Checksum(plain, castagnoli) != info.checksum {
+		return nil, fmt.Errorf("%w: %s does not decrypt the log key (checksum mismatch)", ErrKeyMismatch, name)
+	}
+	block, err := aes.NewCipher(plain[:EncryptionKeyLen])
+	if err != nil {
+		return nil, err
+	}
+	return &logDecrypter{block: block, iv: plain[EncryptionKeyLen : EncryptionKeyLen+aes.BlockSize]}, nil
+}
+
+// decryptBlock decrypts a 512-byte log block in place when its encrypt bit
+// is set, and clears the bit. The 500 bytes after the header are
+// encrypted as 496 bytes, with the last 32 bytes encrypted once more to
+// cover the 4 bytes left over.
+func (d *logDecrypter) decryptBlock(block []byte) {
+	dataLen := binary.BigEndian.Uint16(block[LogBlockHdrDataLen:])
+	if dataLen&LogBlockEncryptBit == 0 {
+		return
+	}
+	const tail = 2 * aes.BlockSize
+	cipher.NewCBCDecrypter(d.block, d.iv).CryptBlocks(block[OSFileLogBlockSize-tail:], block[OSFileLogBlockSize-tail:])
+	body := block[LogBlockHdrSize : LogBlockHdrSize+(OSFileLogBlockSize-LogBlockHdrSize)/aes.BlockSize*aes.BlockSize]
+	cipher.NewCBCDecrypter(d.block, d.iv).CryptBlocks(body, body)
+	binary.BigEndian.PutUint16(block[LogBlockHdrDataLen:], dataLen&^LogBlockEncryptBit)
+}
+
+// isEncryptedBlock reports whether the encrypt bit of a log block is set
+func isEncryptedBlock(block []byte) bool {
+	return binary.BigEndian.Uint16(block[LogBlockHdrDataLen:])&LogBlockEncryptBit != 0
+}
@@ -0,0 +1,110 @@
+package reader
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+	"github.com/yamaru/innodb-redolog-tool/test/fixtures"
+)
+
+const testServerUUID = "3e11fa47-71ca-11e1-9e33-c80aa9429562"
+
+func TestEncryptedRedoLog(t *testing.T) {
+	dir := t.TempDir()
+	records := append(append([]byte{}, insert8028...), MLogMultiRecEnd, 0)
+	filename, err := fixtures.CreateMySQLLogFile(dir, LogHeaderFormat8030, 29480960, records)
+	require.NoError(t, err)
+	plain, err := os.ReadFile(filename)
+	require.NoError(t, err)
+
+	masterKey := bytes.Repeat([]byte{0x5A}, EncryptionKeyLen)
+	key, iv := bytes.Repeat([]byte{1}, EncryptionKeyLen), bytes.Repeat([]byte{2}, EncryptionKeyLen)
+	require.NoError(t, fixtures.EncryptMySQLLogFile(filename, masterKey, testServerUUID, 7, key, iv))
+	encrypted, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	assert.NotEqual(t, plain[LogFileHdrSize+LogBlockHdrSize:], encrypted[LogFileHdrSize+LogBlockHdrSize:])
+
+	name := "INNODBKey-" + testServerUUID + "-7"
+	pluginKeyring, err := fixtures.CreateKeyringFile(dir, map[string][]byte{name: masterKey, "other": {1, 2, 3}})
+	require.NoError(t, err)
+	componentKeyring, err := fixtures.CreateComponentKeyringFile(dir, map[string][]byte{name: masterKey})
+	require.NoError(t, err)
+
+	for _, keyringFile := range []string{pluginKeyring, componentKeyring} {
+		keyring, err := LoadKeyring(keyringFile)
+		require.NoError(t, err)
+
+		r := NewMySQLRedoLogReader()
+		r.SetKeyring(keyring)
+		require.NoError(t, r.Open(filename))
+		_, err = r.ReadHeader()
+		require.NoError(t, err)
+		require.NotNil(t, r.Encryption())
+		assert.Equal(t, 3, r.Encryption().Version)
+		assert.Equal(t, name, r.Encryption().MasterKeyName())
+
+		got, err := ReadRecords(r, 0)
+		require.NoError(t, err)
+		require.Len(t, got, 2, keyringFile)
+		assert.Equal(t, types.LogType(MLogRecInsert8028), got[0].Type)
+		assert.Contains(t, string(got[0].Data), "found_strings='abc'")
+		r.Close()
+	}
+}
+
+func TestEncryptedRedoLogKeyErrors(t *testing.T) {
+	dir := t.TempDir()
+	filename, err := fixtures.CreateMySQLLogFile(dir, LogHeaderFormat8030, 8192, []byte{1, 5, 3, 0x00, 0x10, 0x2B, MLogMultiRecEnd, 0})
+	require.NoError(t, err)
+	masterKey := bytes.Repeat([]byte{0x5A}, EncryptionKeyLen)
+	require.NoError(t, fixtures.EncryptMySQLLogFile(filename, masterKey, testServerUUID, 1, make([]byte, 32), make([]byte, 32)))
+	name := "INNODBKey-" + testServerUUID + "-1"
+
+	// Without a keyring the header is readable but the records are not
+	r := NewMySQLRedoLogReader()
+	require.NoError(t, r.Open(filename))
+	_, err = r.ReadHeader()
+	require.NoError(t, err)
+	_, err = ReadRecords(r, 0)
+	assert.ErrorIs(t, err, ErrEncryptedLog)
+	assert.ErrorContains(t, err, name)
+	r.Close()
+
+	tests := []struct {
+		name string
+		keys map[string][]byte
+		want error
+	}{
+		{"missing key", map[string][]byte{"INNODBKey-" + testServerUUID + "-2": masterKey}, ErrKeyNotFound},
+		{"wrong key", map[string][]byte{name: bytes.Repeat([]byte{0x5B}, EncryptionKeyLen)}, ErrKeyMismatch},
+		{"short key", map[string][]byte{name: masterKey[:16]}, ErrKeyMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewMySQLRedoLogReader()
+			r.SetKeyring(NewKeyring("test", tt.keys))
+			require.NoError(t, r.Open(filename))
+			defer r.Close()
+			_, err := r.ReadHeader()
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestParseKeyring(t *testing.T) {
+	_, err := ParseKeyring([]byte("not a keyring"))
+	assert.ErrorContains(t, err, "not a keyring file")
+	_, err = ParseKeyring([]byte(KeyringFileVersion1 + "\x08"))
+	assert.ErrorContains(t, err, "no EOF marker")
+	_, err = ParseKeyring([]byte(`{"elements":[{"data_id":"k","data":"zz"}]}`))
+	assert.ErrorContains(t, err, "invalid hex data")
+
+	keys, err := ParseKeyring([]byte(KeyringFileVersion1 + keyringFileEOF))
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
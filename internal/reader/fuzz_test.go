@@ -0,0 +1,272 @@
+package reader
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+	"github.com/yamaru/innodb-redolog-tool/test/fixtures"
+)
+
+// fuzzMaxRecords bounds the records read from one fuzzed log
+const fuzzMaxRecords = 10000
+
+// mysqlLogBytes returns a redo log of the given header format version
+// holding records, split over as many log blocks as they need. Like the
+// server, it writes every field big endian and checksums the blocks with
+// CRC-32C.
+func mysqlLogBytes(format uint32, records []byte) []byte {
+	data := make([]byte, LogFileHdrSize)
+	binary.BigEndian.PutUint32(data[LogHeaderFormat:], format)
+	binary.BigEndian.PutUint64(data[8:], 8192)
+	copy(data[16:48], MySQLCreator)
+	for _, offset := range []int{LogCheckpoint1, LogCheckpoint2} {
+		binary.BigEndian.PutUint64(data[offset+LogCheckpointNo:], 1)
+		binary.BigEndian.PutUint64(data[offset+LogCheckpointLSN:], 8192)
+		binary.BigEndian.PutUint64(data[offset+LogCheckpointOffset:], LogFileHdrSize)
+	}
+	for i := 0; i == 0 || len(records) > 0; i++ {
+		n := min(len(records), LogBlockDataSize)
+		block := make([]byte, OSFileLogBlockSize)
+		binary.BigEndian.PutUint32(block[LogBlockHdrNo:], uint32(17+i))
+		binary.BigEndian.PutUint16(block[LogBlockHdrDataLen:], uint16(LogBlockHdrSize+n))
+		if i == 0 {
+			block[LogBlockHdrNo] |= 0x80
+			binary.BigEndian.PutUint16(block[LogBlockFirstRecGroup:], LogBlockHdrSize)
+		}
+		binary.BigEndian.PutUint32(block[LogBlockEpochNo:], 1)
+		copy(block[LogBlockHdrSize:], records[:n])
+		checksum := crc32.Checksum(block[:OSFileLogBlockSize-LogBlockTrlSize], crc32.MakeTable(crc32.Castagnoli))
+		binary.BigEndian.PutUint32(block[OSFileLogBlockSize-LogBlockTrlSize:], checksum)
+		data = append(data, block...)
+		records = records[n:]
+	}
+	return data
+}
+
+// writeCompressedUint64 encodes value the way mach_u64_write_much_compressed
+// does
+func writeCompressedUint64(value uint64) []byte {
+	if high := uint32(value >> 32); high != 0 {
+		return append(append([]byte{0xFF}, writeCompressedUint32(high)...), writeCompressedUint32(uint32(value))...)
+	}
+	return writeCompressedUint32(uint32(value))
+}
+
+// writeCompressedUint32 encodes value the way mach_write_compressed does
+func writeCompressedUint32(value uint32) []byte {
+	switch {
+	case value < 0x80:
+		return []byte{byte(value)}
+	case value < 0x4000:
+		return []byte{byte(value>>8) | 0x80, byte(value)}
+	case value < 0x200000:
+		return []byte{byte(value>>16) | 0xC0, byte(value >> 8), byte(value)}
+	case value < 0x10000000:
+		return []byte{byte(value>>24) | 0xE0, byte(value >> 16), byte(value >> 8), byte(value)}
+	}
+	return []byte{0xF0, byte(value >> 24), byte(value >> 16), byte(value >> 8), byte(value)}
+}
+
+// checkRecords reads the records of a log and checks that they lie within
+// it. Decode errors are fine; panics are not.
+func checkRecords(t *testing.T, r RedoLogReader, size int) {
+	t.Helper()
+	if _, err := r.ReadHeader(); err != nil {
+		return
+	}
+	for i := 0; i < fuzzMaxRecords; i++ {
+		record, err := r.ReadRecord()
+		if err != nil {
+			return
+		}
+		require.NotNil(t, record)
+		for _, span := range record.Spans {
+			assert.True(t, span.Offset >= 0 && span.Length > 0 && span.Offset+int64(span.Length) <= int64(size), "span %+v outside the log", span)
+		}
+	}
+}
+
+func FuzzParseBlockHeader(f *testing.F) {
+	f.Add(mysqlLogBytes(LogHeaderFormat8030, []byte{MLogMultiRecEnd})[LogFileHdrSize:])
+	f.Add(make([]byte, LogBlockHdrSize))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, block []byte) {
+		header, err := ParseBlockHeader(block)
+		if len(block) < OSFileLogBlockSize {
+			assert.ErrorIs(t, err, ErrCorruptLog)
+			return
+		}
+		require.NoError(t, err)
+		assert.Equal(t, binary.BigEndian.Uint32(block[LogBlockHdrNo:]), header.HdrNo)
+		assert.Equal(t, binary.BigEndian.Uint16(block[LogBlockHdrDataLen:]), header.DataLen)
+		assert.Equal(t, binary.BigEndian.Uint16(block[LogBlockFirstRecGroup:]), header.FirstRecGroup)
+		assert.Equal(t, binary.BigEndian.Uint32(block[LogBlockEpochNo:]), header.EpochNo)
+		assert.Equal(t, binary.BigEndian.Uint32(block[OSFileLogBlockSize-LogBlockTrlSize:]), header.Checksum)
+	})
+}
+
+func FuzzParseCompressedUint64(f *testing.F) {
+	for _, value := range []uint64{0, 0x7F, 0x80, 0x3FFF, 0x4000, 0x1FFFFF, 0x200000, 0xFFFFFFF, 0x10000000, 0xFFFFFFFF, 1 << 32, 1<<64 - 1} {
+		f.Add(writeCompressedUint64(value))
+	}
+	for b := 0xF1; b <= 0xFE; b++ {
+		f.Add([]byte{byte(b), 1, 2, 3, 4, 5, 6, 7, 8})
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		value, n := parseCompressedUint64(data)
+		require.LessOrEqual(t, n, len(data))
+		if n == 0 {
+			assert.Zero(t, value)
+			return
+		}
+		// What was parsed encodes the value again, possibly shorter
+		encoded := writeCompressedUint64(value)
+		assert.LessOrEqual(t, len(encoded), n)
+		again, m := parseCompressedUint64(encoded)
+		assert.Equal(t, len(encoded), m)
+		assert.Equal(t, value, again)
+	})
+}
+
+func TestParseCompressedUint64(t *testing.T) {
+	tests := []struct {
+		data  []byte
+		value uint64
+		n     int
+	}{
+		{[]byte{0x7F, 0xFF}, 0x7F, 1},
+		{[]byte{0x81, 0x02}, 0x102, 2},
+		{[]byte{0xC1, 0x02, 0x03}, 0x10203, 3},
+		{[]byte{0xE1, 0x02, 0x03, 0x04}, 0x1020304, 4},
+		{[]byte{0xF0, 0xF1, 0x02, 0x03, 0x04}, 0xF1020304, 5},
+		{[]byte{0xFF, 0x01, 0x81, 0x02}, 1<<32 | 0x102, 4},
+		{[]byte{0xE1, 0x02, 0x03}, 0, 0},
+		{[]byte{0xFF, 0x01}, 0, 0},
+		{[]byte{0xF8, 0x01, 0x02, 0x03, 0x04, 0x05}, 0, 0},
+		{[]byte{0xFE, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}, 0, 0},
+		{[]byte{0xFF, 0xFB, 0x01}, 0, 0},
+	}
+	for _, tt := range tests {
+		value, n := parseCompressedUint64(tt.data)
+		assert.Equal(t, tt.value, value, "% x", tt.data)
+		assert.Equal(t, tt.n, n, "% x", tt.data)
+	}
+}
+
+func FuzzRecordDecoders(f *testing.F) {
+	payloads := [][]byte{
+		{},
+		{0x00, 0x10, 0xAB, 0xCD, 0xEF, 0x01, 0x23, 0x45, 0x67},
+		{0x05, 0x04, 0x01, IndexLogCompact, 0x00, 0x02, 0x00, 0x01, 0x80, 0x04, 0x00, 0x00, 0x00, 0x63, 0x06, 'a', 'b', 'c'},
+		{0x05, 0x04, 0x01, IndexLogVersioned | IndexLogInstant, 0x00, 0x01, 0x00, 0x01, 0x00, 0x01, 0x80, 0x04, 0x01, 0x00, 0x00, 0x01, 0x00},
+		{0x05, 0x04, 0x00, 0x02, 0x00, 0x01, 0x80, 0x04, 0x00, 0x00, 0x00, 0x0B, 'x', 'y', 'z', 'w', 'v'},
+		{0x05, 0x04, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x00, 0x00, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF},
+		{0xFF, 0xF0, 0xFF, 0xFF, 0xFF, 0xFF, 0xF0, 0xFF, 0xFF, 0xFF, 0xFF, 0x00},
+	}
+	for _, format := range RedoFormats {
+		recordTypes := make([]int, 0, len(format.decoders))
+		for recordType := range format.decoders {
+			recordTypes = append(recordTypes, int(recordType))
+		}
+		sort.Ints(recordTypes)
+		for _, recordType := range recordTypes {
+			for _, payload := range payloads {
+				f.Add(format.Versions[0], append([]byte{byte(recordType)}, payload...))
+			}
+		}
+		f.Add(format.Versions[0], []byte{MLogMultiRecEnd, 0, 0, 0})
+	}
+
+	f.Fuzz(func(t *testing.T, version uint32, records []byte) {
+		if LookupRedoFormat(version) == nil || len(records) > 8*LogBlockDataSize {
+			t.Skip()
+		}
+		data := mysqlLogBytes(version, records)
+		r := NewMySQLRedoLogReader()
+		require.NoError(t, r.OpenSource(NewBytesSource(data)))
+		defer r.Close()
+		checkRecords(t, r, len(data))
+	})
+}
+
+func FuzzReadLog(f *testing.F) {
+	f.Add(mysqlLogBytes(LogHeaderFormat8030, []byte{MLogRecInsert8028, 5, 4, 1, IndexLogCompact, 0, 1, 0, 1, 0x80, 4, 0, 0, 0, 0x63, 2, 'a', MLogMultiRecEnd}))
+	f.Add(mysqlLogBytes(LogHeaderFormat57, []byte{MLogRecUpdateInPlace, 1, 0, 0, 0, 3, 0, 0, 0, 'a', 'b', MLogMultiRecEnd}))
+	f.Add(mysqlLogBytes(LogHeaderFormat8028, make([]byte, 2*LogBlockDataSize)))
+	for _, format := range []uint32{fixtures.MariaDBFormat105, fixtures.MariaDBFormat108} {
+		filename, err := fixtures.CreateMariaDBLogFile(f.TempDir(), format, 8192, mariadbMTRs)
+		require.NoError(f, err)
+		content, err := os.ReadFile(filename)
+		require.NoError(f, err)
+		f.Add(content)
+	}
+	f.Add(make([]byte, LogFileHdrSize+100))
+	// A fixture record shorter than its fixed fields
+	f.Add(append(make([]byte, 64), 1, 10, 0, 0, 0, 0, 0, 0, 0, 0))
+	f.Add([]byte("Phys"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) > 1<<20 {
+			t.Skip()
+		}
+		for _, r := range []RedoLogReader{NewMySQLRedoLogReader(), NewMariaDBRedoLogReader(), NewRedoLogReader()} {
+			if r.OpenSource(NewBytesSource(data)) == nil {
+				checkRecords(t, r, len(data))
+			}
+			r.Close()
+		}
+	})
+}
+
+func FuzzParseRecordDataAsFields(f *testing.F) {
+	f.Add([]byte{0x03, 0x00, 0x00, 0x00, 0x10, 0x00, 0x1F, 0x00, 0x00, 0x00, 0x01, 'A', 'C', 'E'})
+	f.Add([]byte{0x05, 'h', 'e', 'l', 'l', 'o', 0x81, 0x02, 0xC1, 0x02, 0x03})
+	f.Add([]byte{0x00, 0x02, 'h', 'i', 0x00, 0x00, 0x00, 0x00, 0x5F, 0x5E, 0x10, 0x00})
+	f.Add([]byte{0xFF, 0xF8, 0xF0})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		assert.NotEmpty(t, ParseRecordDataAsFields(data))
+		assert.NotEmpty(t, parseRecordDataAsFields(data, 3))
+	})
+}
+
+// TestDecodeErrors checks that corrupted data is reported as a DecodeError
+func TestDecodeErrors(t *testing.T) {
+	_, err := ParseBlockHeader(make([]byte, LogBlockHdrSize))
+	var decodeErr *DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+	assert.ErrorIs(t, err, ErrCorruptLog)
+
+	// end_seg_len of a record larger than any page: space, page, n_fields,
+	// n_uniq, one field and the cursor offset come first
+	insert := append([]byte{MLogRecInsert, 0x05, 0x04, 0x00, 0x01, 0x00, 0x01, 0x80, 0x04, 0x00, 0x63}, writeCompressedUint64(1<<40)...)
+	data := mysqlLogBytes(LogHeaderFormat57, insert)
+	r := NewMySQLRedoLogReader()
+	require.NoError(t, r.OpenSource(NewBytesSource(data)))
+	defer r.Close()
+	_, err = r.ReadHeader()
+	require.NoError(t, err)
+	_, err = ReadRecords(r, 0)
+	require.ErrorAs(t, err, &decodeErr)
+	assert.Equal(t, int64(LogFileHdrSize+LogBlockHdrSize), decodeErr.Offset)
+	assert.Equal(t, types.LogType(MLogRecInsert), decodeErr.Type)
+
+	// MLOG_ZIP_PAGE_COMPRESS_NO_DATA with a compression level past 9
+	r = NewMySQLRedoLogReader()
+	require.NoError(t, r.OpenSource(NewBytesSource(mysqlLogBytes(LogHeaderFormat8030, []byte{74, 5, 4, 1, IndexLogCompact, 0x00, 0x01, 0x00, 0x01, 0x80, 0x04, 10}))))
+	defer r.Close()
+	_, err = r.ReadHeader()
+	require.NoError(t, err)
+	_, err = ReadRecords(r, 0)
+	require.ErrorAs(t, err, &decodeErr)
+	assert.Equal(t, types.LogType(74), decodeErr.Type)
+}
@@ -0,0 +1,378 @@
+package reader
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+// MemberSeparator separates an archive from the member to read in an input
+// name such as backup.tar!ib_logfile0
+const MemberSeparator = "!"
+
+// ErrMemberNotFound is returned when an archive has no member of the
+// requested name
+var ErrMemberNotFound = errors.New("archive member not found")
+
+// Compression and archive formats recognized by their magic bytes
+var (
+	gzipMagic     = []byte{0x1f, 0x8b}
+	zstdMagic     = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	lz4Magic      = []byte{0x04, 0x22, 0x4d, 0x18}
+	xbstreamMagic = []byte("XBSTCK01")
+	tarMagic      = []byte("ustar")
+)
+
+// tarMagicOffset is where the ustar magic sits in a tar header
+const tarMagicOffset = 257
+
+// inputMagicSize is how much of an input is inspected to detect its format
+const inputMagicSize = tarMagicOffset + 8
+
+// OpenInput opens a redo log given by name: a file, optionally compressed
+// with gzip, zstd or lz4, or a member of a tar or xbstream archive written
+// as archive!member. Archives may be compressed and nested, as in
+// backup.tar.gz!backup.xbstream!ib_logfile0. Formats are detected by their
+// magic bytes. A plain file is returned as a seekable file source; anything
+// decoded is a stream source. Close the source to close the file.
+func OpenInput(name string) (Source, error) {
+	parts := []string{name}
+	if _, err := os.Stat(name); err != nil && strings.Contains(name, MemberSeparator) {
+		parts = strings.Split(name, MemberSeparator)
+	}
+
+	file, err := os.Open(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	magic := make([]byte, inputMagicSize)
+	n, _ := file.ReadAt(magic, 0)
+	if len(parts) == 1 && inputFormat(magic[:n]) == "" {
+		file.Close()
+		return NewFileSource(name)
+	}
+
+	closers := inputClosers{file}
+	var r io.Reader = file
+	for i, member := range parts[1:] {
+		if r, err = decompress(r, &closers); err != nil {
+			closers.Close()
+			return nil, fmt.Errorf("%s: %w", strings.Join(parts[:i+1], MemberSeparator), err)
+		}
+		if r, err = openMember(r, member); err != nil {
+			closers.Close()
+			return nil, fmt.Errorf("%s: %w", strings.Join(parts[:i+1], MemberSeparator), err)
+		}
+	}
+	if r, err = decompress(r, &closers); err != nil {
+		closers.Close()
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if format := inputFormat(peek(r, inputMagicSize)); format == "tar" || format == "xbstream" {
+		closers.Close()
+		return nil, fmt.Errorf("%s is a %s archive; name the redo log in it as %s%sib_logfile0", name, format, name, MemberSeparator)
+	}
+
+	src, err := NewStreamSource(r)
+	if err != nil {
+		closers.Close()
+		return nil, err
+	}
+	src.(*streamSource).closer = closers
+	return src, nil
+}
+
+// inputFormat names the compression or archive format that data starts
+// with, or returns "" for anything else
+func inputFormat(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		return "gzip"
+	case bytes.HasPrefix(data, zstdMagic):
+		return "zstd"
+	case bytes.HasPrefix(data, lz4Magic):
+		return "lz4"
+	case bytes.HasPrefix(data, xbstreamMagic):
+		return "xbstream"
+	case len(data) >= tarMagicOffset+len(tarMagic) && bytes.Equal(data[tarMagicOffset:tarMagicOffset+len(tarMagic)], tarMagic):
+		return "tar"
+	}
+	return ""
+}
+
+// peek returns the first n bytes of r without consuming them; r must be a
+// *bufio.Reader, as returned by decompress
+func peek(r io.Reader, n int) []byte {
+	data, _ := r.(*bufio.Reader).Peek(n)
+	return data
+}
+
+// decompress returns r decompressed, repeatedly, until its content is not
+// a compressed stream. The result is always a *bufio.Reader.
+func decompress(r io.Reader, closers *inputClosers) (io.Reader, error) {
+	for {
+		buffered := bufio.NewReader(r)
+		var err error
+		switch inputFormat(peek(buffered, inputMagicSize)) {
+		case "gzip":
+			var gz *gzip.Reader
+			if gz, err = gzip.NewReader(buffered); err == nil {
+				*closers = append(*closers, gz)
+				r = gz
+			}
+		case "zstd":
+			var zr *zstd.Decoder
+			if zr, err = zstd.NewReader(buffered); err == nil {
+				*closers = append(*closers, zstdCloser{zr})
+				r = zr
+			}
+		case "lz4":
+			r = lz4.NewReader(buffered)
+		default:
+			return buffered, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress: %w", err)
+		}
+	}
+}
+
+// openMember returns the content of the named member of the tar or
+// xbstream archive r
+func openMember(r io.Reader, member string) (io.Reader, error) {
+	switch format := inputFormat(peek(r, inputMagicSize)); format {
+	case "tar":
+		return openTarMember(tar.NewReader(r), member)
+	case "xbstream":
+		return openXbstreamMember(r, member)
+	default:
+		return nil, fmt.Errorf("cannot read member %s: not a tar or xbstream archive", member)
+	}
+}
+
+// memberMatches reports whether an archive entry is the requested member:
+// the same path, or a path ending in it
+func memberMatches(entry, member string) bool {
+	entry = path.Clean(strings.TrimPrefix(entry, "./"))
+	member = path.Clean(strings.TrimPrefix(member, "./"))
+	return entry == member || strings.HasSuffix(entry, "/"+member)
+}
+
+// openTarMember returns the content of the first regular file in tr
+// matching member
+func openTarMember(tr *tar.Reader, member string) (io.Reader, error) {
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: %s", ErrMemberNotFound, member)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar archive: %w", err)
+		}
+		if header.Typeflag == tar.TypeReg && memberMatches(header.Name, member) {
+			return tr, nil
+		}
+	}
+}
+
+// xbstream chunk types
+const (
+	xbstreamChunkPayload = 'P'
+	xbstreamChunkEOF     = 'E'
+	xbstreamChunkSparse  = 'S'
+)
+
+// xbstreamReader reads the payload chunks of one file of an xbstream
+// archive, the format Percona XtraBackup streams backups in
+type xbstreamReader struct {
+	r       *bufio.Reader
+	member  string // Path of the file being read
+	payload io.Reader
+	offset  uint64 // File offset the next payload byte belongs at
+	done    bool
+}
+
+// openXbstreamMember returns the content of the first file in the
+// xbstream archive r matching member
+func openXbstreamMember(r io.Reader, member string) (io.Reader, error) {
+	x := &xbstreamReader{r: bufio.NewReader(r)}
+	for {
+		chunk, err := x.readChunk()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: %s", ErrMemberNotFound, member)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !memberMatches(chunk.path, member) {
+			if _, err := io.Copy(io.Discard, chunk.payload); err != nil {
+				return nil, fmt.Errorf("failed to read xbstream archive: %w", err)
+			}
+			continue
+		}
+		x.member = chunk.path
+		if chunk.chunkType == xbstreamChunkEOF {
+			x.done = true
+			return x, nil
+		}
+		if chunk.offset != 0 {
+			return nil, fmt.Errorf("xbstream file %s starts at offset %d", chunk.path, chunk.offset)
+		}
+		x.payload = chunk.payload
+		x.offset = chunk.length
+		return x, nil
+	}
+}
+
+// xbstreamChunk is the header of an xbstream chunk; payload reads its data
+// and checks its CRC32 at the end
+type xbstreamChunk struct {
+	chunkType byte
+	path      string
+	length    uint64
+	offset    uint64
+	payload   io.Reader
+}
+
+// readChunk reads the next chunk header. It returns io.EOF at the end of
+// the archive.
+func (x *xbstreamReader) readChunk() (*xbstreamChunk, error) {
+	fixed := make([]byte, len(xbstreamMagic)+2+4)
+	if _, err := io.ReadFull(x.r, fixed); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to read xbstream chunk: %w", err)
+	}
+	if !bytes.HasPrefix(fixed, xbstreamMagic) {
+		return nil, fmt.Errorf("invalid xbstream chunk magic %q", fixed[:len(xbstreamMagic)])
+	}
+	chunk := &xbstreamChunk{chunkType: fixed[len(xbstreamMagic)+1]}
+	name := make([]byte, binary.LittleEndian.Uint32(fixed[len(xbstreamMagic)+2:]))
+	if _, err := io.ReadFull(x.r, name); err != nil {
+		return nil, fmt.Errorf("failed to read xbstream chunk: %w", err)
+	}
+	chunk.path = string(name)
+
+	switch chunk.chunkType {
+	case xbstreamChunkEOF:
+		chunk.payload = bytes.NewReader(nil)
+		return chunk, nil
+	case xbstreamChunkPayload:
+	case xbstreamChunkSparse:
+		return nil, fmt.Errorf("xbstream file %s: sparse chunks are not supported", chunk.path)
+	default:
+		return nil, fmt.Errorf("xbstream file %s: unknown chunk type %q", chunk.path, chunk.chunkType)
+	}
+
+	sizes := make([]byte, 8+8+4)
+	if _, err := io.ReadFull(x.r, sizes); err != nil {
+		return nil, fmt.Errorf("failed to read xbstream chunk: %w", err)
+	}
+	chunk.length = binary.LittleEndian.Uint64(sizes[0:8])
+	chunk.offset = binary.LittleEndian.Uint64(sizes[8:16])
+	chunk.payload = &checkedReader{
+		r:    io.LimitReader(x.r, int64(chunk.length)),
+		want: binary.LittleEndian.Uint32(sizes[16:20]),
+		what: chunk.path,
+	}
+	return chunk, nil
+}
+
+// Read reads the file's payload chunks in order, skipping the chunks of
+// other files interleaved with them
+func (x *xbstreamReader) Read(p []byte) (int, error) {
+	for !x.done {
+		if x.payload != nil {
+			n, err := x.payload.Read(p)
+			if err == io.EOF {
+				x.payload = nil
+				if n > 0 {
+					return n, nil
+				}
+				continue
+			}
+			return n, err
+		}
+
+		chunk, err := x.readChunk()
+		if err == io.EOF {
+			x.done = true
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		if chunk.path != x.member {
+			if _, err := io.Copy(io.Discard, chunk.payload); err != nil {
+				return 0, fmt.Errorf("failed to read xbstream archive: %w", err)
+			}
+			continue
+		}
+		if chunk.chunkType == xbstreamChunkEOF {
+			x.done = true
+			break
+		}
+		if chunk.offset != x.offset {
+			return 0, fmt.Errorf("xbstream file %s: chunk at offset %d, expected %d", x.member, chunk.offset, x.offset)
+		}
+		x.payload = chunk.payload
+		x.offset += chunk.length
+	}
+	return 0, io.EOF
+}
+
+// checkedReader reads r and checks its CRC32 against want at the end
+type checkedReader struct {
+	r    io.Reader
+	crc  uint32
+	want uint32
+	what string
+}
+
+// Read reads from r, failing at the end when the checksum does not match
+func (c *checkedReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.crc = crc32.Update(c.crc, crc32.IEEETable, p[:n])
+	if err == io.EOF && c.crc != c.want {
+		return n, fmt.Errorf("xbstream file %s: chunk checksum 0x%08x, expected 0x%08x", c.what, c.crc, c.want)
+	}
+	return n, err
+}
+
+// inputClosers closes the file and decoders of an input, last opened first
+type inputClosers []io.Closer
+
+// Close closes all of them and returns the first error
+func (c inputClosers) Close() error {
+	var first error
+	for i := len(c) - 1; i >= 0; i-- {
+		if err := c[i].Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// zstdCloser adapts a zstd decoder, whose Close returns nothing
+type zstdCloser struct {
+	d *zstd.Decoder
+}
+
+// Close releases the decoder
+func (z zstdCloser) Close() error {
+	z.d.Close()
+	return nil
+}
@@ -0,0 +1,201 @@
+package reader
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yamaru/innodb-redolog-tool/test/fixtures"
+)
+
+// sampleLogData returns the bytes of the fixture sample log
+func sampleLogData(t *testing.T) []byte {
+	filename, err := fixtures.CreateSampleLogFile(t.TempDir())
+	require.NoError(t, err)
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	return data
+}
+
+// compress returns data compressed with gzip, zstd or lz4
+func compress(t *testing.T, format string, data []byte) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch format {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "zstd":
+		zw, err := zstd.NewWriter(&buf)
+		require.NoError(t, err)
+		w = zw
+	case "lz4":
+		w = lz4.NewWriter(&buf)
+	}
+	_, err := w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+// archiveFile is a file to put in a test archive
+type archiveFile struct {
+	name string
+	data []byte
+}
+
+// tarArchive returns a tar archive of the given files, in order
+func tarArchive(t *testing.T, files ...archiveFile) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, file := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: file.name, Mode: 0644, Size: int64(len(file.data)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write(file.data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+// xbstreamChunkBytes encodes an xbstream payload chunk, or an EOF chunk when
+// data is nil
+func xbstreamChunkBytes(name string, offset uint64, data []byte) []byte {
+	var buf bytes.Buffer
+	buf.Write(xbstreamMagic)
+	buf.WriteByte(0)
+	if data == nil {
+		buf.WriteByte(xbstreamChunkEOF)
+	} else {
+		buf.WriteByte(xbstreamChunkPayload)
+	}
+	binary.Write(&buf, binary.LittleEndian, uint32(len(name)))
+	buf.WriteString(name)
+	if data != nil {
+		binary.Write(&buf, binary.LittleEndian, uint64(len(data)))
+		binary.Write(&buf, binary.LittleEndian, offset)
+		binary.Write(&buf, binary.LittleEndian, crc32.ChecksumIEEE(data))
+		buf.Write(data)
+	}
+	return buf.Bytes()
+}
+
+// readInput opens name with OpenInput and reads all of it
+func readInput(t *testing.T, name string) []byte {
+	src, err := OpenInput(name)
+	require.NoError(t, err)
+	defer closeSource(src)
+	data, err := io.ReadAll(src)
+	require.NoError(t, err)
+	return data
+}
+
+func TestOpenInputCompressed(t *testing.T) {
+	data := sampleLogData(t)
+	dir := t.TempDir()
+
+	for _, format := range []string{"gzip", "zstd", "lz4"} {
+		t.Run(format, func(t *testing.T) {
+			filename := filepath.Join(dir, "ib_logfile0."+format)
+			require.NoError(t, os.WriteFile(filename, compress(t, format, data), 0644))
+			assert.Equal(t, data, readInput(t, filename))
+		})
+	}
+
+	// Plain files stay seekable file sources
+	plain := filepath.Join(dir, "ib_logfile0")
+	require.NoError(t, os.WriteFile(plain, data, 0644))
+	src, err := OpenInput(plain)
+	require.NoError(t, err)
+	defer closeSource(src)
+	assert.IsType(t, &fileSource{}, src)
+}
+
+func TestOpenInputTar(t *testing.T) {
+	data := sampleLogData(t)
+	dir := t.TempDir()
+	archive := tarArchive(t,
+		archiveFile{"./backup/xtrabackup_info", []byte("tool_name = xtrabackup\n")},
+		archiveFile{"./backup/ib_logfile0", data},
+	)
+	filename := filepath.Join(dir, "backup.tar.gz")
+	require.NoError(t, os.WriteFile(filename, compress(t, "gzip", archive), 0644))
+
+	assert.Equal(t, data, readInput(t, filename+"!ib_logfile0"))
+	assert.Equal(t, data, readInput(t, filename+"!backup/ib_logfile0"))
+
+	_, err := OpenInput(filename + "!ib_logfile1")
+	assert.True(t, errors.Is(err, ErrMemberNotFound))
+	_, err = OpenInput(filename)
+	assert.ErrorContains(t, err, "is a tar archive")
+	_, err = OpenInput(filename + "!ib_logfile0!ib_logfile0")
+	assert.ErrorContains(t, err, "not a tar or xbstream archive")
+}
+
+func TestOpenInputXbstream(t *testing.T) {
+	data := sampleLogData(t)
+	dir := t.TempDir()
+
+	// The log is split in two chunks with another file's chunk in between
+	var stream bytes.Buffer
+	stream.Write(xbstreamChunkBytes("ibdata1", 0, []byte("system tablespace")))
+	stream.Write(xbstreamChunkBytes("xtrabackup_logfile", 0, data[:100]))
+	stream.Write(xbstreamChunkBytes("ibdata1", 17, []byte("more")))
+	stream.Write(xbstreamChunkBytes("xtrabackup_logfile", 100, data[100:]))
+	stream.Write(xbstreamChunkBytes("ibdata1", 0, nil))
+	stream.Write(xbstreamChunkBytes("xtrabackup_logfile", 0, nil))
+	filename := filepath.Join(dir, "backup.xbstream")
+	require.NoError(t, os.WriteFile(filename, stream.Bytes(), 0644))
+
+	assert.Equal(t, data, readInput(t, filename+"!xtrabackup_logfile"))
+	assert.Equal(t, []byte("system tablespacemore"), readInput(t, filename+"!ibdata1"))
+
+	// Nested in a compressed tar
+	archive := tarArchive(t, archiveFile{"backup.xbstream", stream.Bytes()})
+	nested := filepath.Join(dir, "backup.tar.zst")
+	require.NoError(t, os.WriteFile(nested, compress(t, "zstd", archive), 0644))
+	assert.Equal(t, data, readInput(t, nested+"!backup.xbstream!xtrabackup_logfile"))
+
+	// A corrupted payload fails its chunk checksum
+	corrupted := bytes.Clone(stream.Bytes())
+	corrupted[bytes.Index(corrupted, data[:100])] ^= 0xff
+	require.NoError(t, os.WriteFile(filename, corrupted, 0644))
+	src, err := OpenInput(filename + "!xtrabackup_logfile")
+	if err == nil {
+		defer closeSource(src)
+		_, err = io.ReadAll(src)
+	}
+	assert.ErrorContains(t, err, "checksum")
+}
+
+func TestOpenInputReadsLog(t *testing.T) {
+	data := sampleLogData(t)
+	filename := filepath.Join(t.TempDir(), "ib_logfile0.gz")
+	require.NoError(t, os.WriteFile(filename, compress(t, "gzip", data), 0644))
+
+	src, err := OpenInput(filename)
+	require.NoError(t, err)
+	r := NewReaderForSource(src)
+	defer r.Close()
+	require.NoError(t, r.OpenSource(src))
+	header, err := r.ReadHeader()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1000), header.StartLSN)
+	records, err := ReadRecords(r, 0)
+	require.NoError(t, err)
+	assert.Len(t, records, 3)
+
+	ok, err := UsesLogBlocks(filename)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
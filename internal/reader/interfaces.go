@@ -7,6 +7,10 @@ type RedoLogReader interface {
 	// Open opens a redo log file
 	Open(filename string) error
 	
+	// OpenSource reads the redo log from src instead of a named file. Close
+	// closes src if it is an io.Closer, even after OpenSource fails.
+	OpenSource(src Source) error
+	
 	// ReadHeader reads the header from the redo log file
 	ReadHeader() (*types.RedoLogHeader, error)
 	
@@ -0,0 +1,143 @@
+package reader
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Keyring file formats read by LoadKeyring
+const (
+	// KeyringFileVersion1 and KeyringFileVersion2 start the data file of
+	// the keyring_file plugin; version 2 ends with a SHA-256 digest
+	KeyringFileVersion1 = "Keyring file version:1.0"
+	KeyringFileVersion2 = "Keyring file version:2.0"
+	// keyringFileEOF follows the last key of a keyring_file data file
+	keyringFileEOF = "EOF"
+	// keyringObfuscation is XORed over the key data the keyring_file
+	// plugin stores (Key::xor_data)
+	keyringObfuscation = "*305=Ljt0*!@$Hnm(*-9-w;:"
+)
+
+// Keyring holds the keys of a keyring file by their key ID
+type Keyring struct {
+	Source string // File the keys were read from
+	keys   map[string][]byte
+}
+
+// NewKeyring returns a keyring holding the given keys
+func NewKeyring(source string, keys map[string][]byte) *Keyring {
+	return &Keyring{Source: source, keys: keys}
+}
+
+// Key returns the key with the given ID
+func (k *Keyring) Key(id string) ([]byte, bool) {
+	key, ok := k.keys[id]
+	return key, ok
+}
+
+// Len returns the number of keys
+func (k *Keyring) Len() int {
+	return len(k.keys)
+}
+
+// LoadKeyring reads the data file of the keyring_file plugin or the JSON
+// file of component_keyring_file
+func LoadKeyring(filename string) (*Keyring, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %w", err)
+	}
+	keys, err := ParseKeyring(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return NewKeyring(filename, keys), nil
+}
+
+// ParseKeyring returns the keys of a keyring file by their key ID
+func ParseKeyring(data []byte) (map[string][]byte, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return parseComponentKeyring(trimmed)
+	}
+	return parsePluginKeyring(data)
+}
+
+// componentKeyring is the JSON file of component_keyring_file
+type componentKeyring struct {
+	Version  string `json:"version"`
+	Elements []struct {
+		User     string `json:"user"`
+		DataID   string `json:"data_id"`
+		DataType string `json:"data_type"`
+		Data     string `json:"data"` // Hex encoded
+	} `json:"elements"`
+}
+
+// parseComponentKeyring reads the JSON file of component_keyring_file
+func parseComponentKeyring(data []byte) (map[string][]byte, error) {
+	var file componentKeyring
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid component keyring: %w", err)
+	}
+	keys := make(map[string][]byte, len(file.Elements))
+	for _, element := range file.Elements {
+		key, err := hex.DecodeString(element.Data)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: invalid hex data: %w", element.DataID, err)
+		}
+		keys[element.DataID] = key
+	}
+	return keys, nil
+}
+
+// parsePluginKeyring reads the data file of the keyring_file plugin. Each
+// key is stored as five 8-byte little endian lengths (the padded size of
+// the entry, then the lengths of the key ID, key type, user ID and key)
+// followed by those fields and padding to 8 bytes.
+func parsePluginKeyring(data []byte) (map[string][]byte, error) {
+	var rest []byte
+	switch {
+	case bytes.HasPrefix(data, []byte(KeyringFileVersion2)):
+		rest = data[len(KeyringFileVersion2):]
+		if len(rest) < len(keyringFileEOF)+32 {
+			return nil, fmt.Errorf("keyring file is truncated")
+		}
+		rest = rest[:len(rest)-32] // SHA-256 digest of the file
+	case bytes.HasPrefix(data, []byte(KeyringFileVersion1)):
+		rest = data[len(KeyringFileVersion1):]
+	default:
+		return nil, fmt.Errorf("not a keyring file")
+	}
+	if !bytes.HasSuffix(rest, []byte(keyringFileEOF)) {
+		return nil, fmt.Errorf("keyring file is truncated: no EOF marker")
+	}
+	rest = rest[:len(rest)-len(keyringFileEOF)]
+
+	keys := make(map[string][]byte)
+	for len(rest) > 0 {
+		if len(rest) < 40 {
+			return nil, fmt.Errorf("keyring entry %d is truncated", len(keys)+1)
+		}
+		var lengths [5]uint64
+		for i := range lengths {
+			lengths[i] = binary.LittleEndian.Uint64(rest[8*i:])
+		}
+		size, idLen, typeLen, userLen, keyLen := lengths[0], lengths[1], lengths[2], lengths[3], lengths[4]
+		if size > uint64(len(rest)) || 40+idLen+typeLen+userLen+keyLen > size {
+			return nil, fmt.Errorf("keyring entry %d has invalid lengths", len(keys)+1)
+		}
+		fields := rest[40:size]
+		id := string(fields[:idLen])
+		key := append([]byte(nil), fields[idLen+typeLen+userLen:idLen+typeLen+userLen+keyLen]...)
+		for i := range key {
+			key[i] ^= keyringObfuscation[i%len(keyringObfuscation)]
+		}
+		keys[id] = key
+		rest = rest[size:]
+	}
+	return keys, nil
+}
@@ -0,0 +1,157 @@
+package reader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// ErrEndOfLog is returned when a block with data_len=0 marks the end of the
+// valid part of the log
+var ErrEndOfLog = errors.New("end of valid log data")
+
+// mysqlFormatMinSize is the size above which a file is assumed to be a real
+// MySQL redo log rather than a test fixture
+const mysqlFormatMinSize = 1000000
+
+// WarningReporter is implemented by readers that collect non-fatal problems
+// while reading instead of failing
+type WarningReporter interface {
+	// Warnings returns the problems noticed so far
+	Warnings() []string
+}
+
+// CheckpointReporter is implemented by readers that parse the checkpoint
+// blocks of the file header
+type CheckpointReporter interface {
+	// Checkpoints returns the checkpoint blocks read by ReadHeader
+	Checkpoints() []*MySQLCheckpoint
+}
+
+// NewReaderForFile returns the reader implementation suited to the given file
+func NewReaderForFile(filename string) (RedoLogReader, error) {
+	src, err := NewFileSource(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer closeSource(src)
+	return NewReaderForSource(src), nil
+}
+
+// NewReaderForSource returns the reader implementation suited to src.
+// Files whose header names MariaDB 10.5 or later as their creator are
+// read as MariaDB redo logs. Streams whose length is not known yet, and
+// files of any size whose header names MySQL or XtraBackup as their
+// creator, are read as MySQL redo logs.
+func NewReaderForSource(src Source) RedoLogReader {
+	if isMariaDBLog(src) {
+		return NewMariaDBRedoLogReader()
+	}
+	// MySQL redo logs are typically large (3MB+), test fixtures are small
+	if size := src.Size(); size < 0 || size > mysqlFormatMinSize || isMySQLCreator(headerCreator(src)) {
+		return NewMySQLRedoLogReader()
+	}
+	return NewRedoLogReader()
+}
+
+// isMySQLCreator reports whether a header creator field names the server
+// or XtraBackup
+func isMySQLCreator(creator string) bool {
+	return strings.HasPrefix(creator, MySQLCreator) || strings.HasPrefix(creator, XtraBackupCreator)
+}
+
+// IsEndOfLog reports whether err marks the normal end of the log stream
+func IsEndOfLog(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, ErrEndOfLog)
+}
+
+// EndsReading reports whether err from r.ReadRecord ends the log normally:
+// the end of the log, or a failure at the end of the file, which is
+// usually a truncated last record. Encrypted blocks that cannot be
+// decrypted and corrupt records are always reported.
+func EndsReading(r RedoLogReader, err error) bool {
+	if errors.Is(err, ErrEncryptedLog) || errors.Is(err, ErrCorruptLog) {
+		return false
+	}
+	return IsEndOfLog(err) || r.IsEOF()
+}
+
+// ReadRecords reads records until the end of the log or until maxRecords
+// records have been read (0 means no limit). Records read before a failure
+// are returned together with the error.
+func ReadRecords(r RedoLogReader, maxRecords int) ([]*types.LogRecord, error) {
+	var records []*types.LogRecord
+
+	for maxRecords <= 0 || len(records) < maxRecords {
+		record, err := r.ReadRecord()
+		if err != nil {
+			if EndsReading(r, err) {
+				break
+			}
+			return records, fmt.Errorf("failed to read record %d: %w", len(records)+1, err)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// DetectMultiRecordGroups analyzes records to identify multi-record groups
+func DetectMultiRecordGroups(records []*types.LogRecord) {
+	var grouper MultiRecordGrouper
+	for _, record := range records {
+		grouper.Add(record)
+	}
+	grouper.Flush()
+}
+
+// MultiRecordGrouper assigns multi-record groups to records read one at a
+// time, with the same result as DetectMultiRecordGroups. Records are held
+// back until the MLOG_MULTI_REC_END (31) or MariaDB END_OF_MTR that ends
+// their group, or Flush.
+type MultiRecordGrouper struct {
+	groupID int
+	pending []*types.LogRecord
+}
+
+// Add takes the next record and returns the records whose group is now
+// known, in order
+func (g *MultiRecordGrouper) Add(record *types.LogRecord) []*types.LogRecord {
+	if uint8(record.Type) != MLogMultiRecEnd && record.Type != types.MariaDBEndOfMTR {
+		g.pending = append(g.pending, record)
+		return nil
+	}
+	if len(g.pending) == 0 {
+		// Isolated MULTI_REC_END - shouldn't happen normally
+		record.MultiRecordGroup = 0
+		return []*types.LogRecord{record}
+	}
+
+	// The group runs from the first pending record to this one
+	g.groupID++
+	group := append(g.pending, record)
+	for i, r := range group {
+		r.MultiRecordGroup = g.groupID
+		if i == 0 {
+			r.IsGroupStart = true
+		} else if i == len(group)-1 {
+			r.IsGroupEnd = true
+		}
+	}
+	g.pending = nil
+	return group
+}
+
+// Flush returns the held back records as single records; they were not
+// followed by MULTI_REC_END
+func (g *MultiRecordGrouper) Flush() []*types.LogRecord {
+	records := g.pending
+	for _, record := range records {
+		record.MultiRecordGroup = 0
+	}
+	g.pending = nil
+	return records
+}
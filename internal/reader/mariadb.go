@@ -0,0 +1,845 @@
+package reader
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// MariaDBCreator starts the creator field of redo logs written by MariaDB
+const MariaDBCreator = "MariaDB"
+
+// MariaDB 10.5 and later redo log layout (log0log.h). Both formats keep
+// the whole log in a single circular ib_logfile0 whose header is big
+// endian and protected by CRC-32C.
+const (
+	MariaDBFormat105       = 0x50485953 // log_t::FORMAT_10_5, "PHYS": 512-byte blocks
+	MariaDBFormat108       = 0x50687973 // log_t::FORMAT_10_8, "Phys": no blocks, CRC-32C per mini-transaction
+	MariaDBFormatEncrypted = 1 << 31    // log_t::FORMAT_ENCRYPTED, set on top of the format
+
+	MariaDBStartOffset105 = LogFileHdrSize // First log block
+	MariaDBStartOffset108 = 12288          // log_t::START_OFFSET
+	MariaDBCheckpoint108a = 4096           // Checkpoint blocks of 10.8
+	MariaDBCheckpoint108b = 8192
+	MariaDBCheckpointSum  = 60  // CRC-32C of a 10.8 checkpoint block
+	MariaDBBlockChecksum  = 508 // CRC-32C of a header, checkpoint or log block
+)
+
+// castagnoli is the CRC-32C table MariaDB checksums its log with
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// errMariaDBVarint is returned for a first byte no varint starts with
+var errMariaDBVarint = errors.New("invalid varint")
+
+// IsMariaDBFormat reports whether a header format version is one of the
+// MariaDB 10.5 and later formats, encrypted or not
+func IsMariaDBFormat(version uint32) bool {
+	version &^= MariaDBFormatEncrypted
+	return version == MariaDBFormat105 || version == MariaDBFormat108
+}
+
+// isMariaDBLog reports whether src is a redo log of MariaDB 10.5 or later
+func isMariaDBLog(src Source) bool {
+	if !strings.HasPrefix(headerCreator(src), MariaDBCreator) {
+		return false
+	}
+	version, err := ReadLogFormat(src)
+	return err == nil && IsMariaDBFormat(version)
+}
+
+// parseMariaDBVarint decodes the variable length integer MariaDB writes
+// page numbers, offsets and lengths with (mlog_decode_varint). It returns
+// the value and its length, or a length of 0 when data is too short.
+func parseMariaDBVarint(data []byte) (uint32, int, error) {
+	if len(data) == 0 {
+		return 0, 0, nil
+	}
+	b := data[0]
+	switch {
+	case b < 0x80:
+		return uint32(b), 1, nil
+	case b < 0xC0:
+		if len(data) < 2 {
+			return 0, 0, nil
+		}
+		return (uint32(b&0x3F)<<8 | uint32(data[1])) + 0x80, 2, nil
+	case b < 0xE0:
+		if len(data) < 3 {
+			return 0, 0, nil
+		}
+		return (uint32(b&0x1F)<<16 | uint32(data[1])<<8 | uint32(data[2])) + 0x4080, 3, nil
+	case b < 0xF0:
+		if len(data) < 4 {
+			return 0, 0, nil
+		}
+		return (uint32(b&0x0F)<<24 | uint32(data[1])<<16 | uint32(data[2])<<8 | uint32(data[3])) + 0x204080, 4, nil
+	case b == 0xF0:
+		if len(data) < 5 {
+			return 0, 0, nil
+		}
+		return binary.BigEndian.Uint32(data[1:5]) + 0x10204080, 5, nil
+	default:
+		return 0, 0, fmt.Errorf("%w: first byte 0x%02x", errMariaDBVarint, b)
+	}
+}
+
+// mariadbVarintLen returns the length of the varint starting with b
+func mariadbVarintLen(b byte) int {
+	switch {
+	case b < 0x80:
+		return 1
+	case b < 0xC0:
+		return 2
+	case b < 0xE0:
+		return 3
+	case b < 0xF0:
+		return 4
+	default:
+		return 5
+	}
+}
+
+// mariadbChunk is a run of log payload bytes that are contiguous in the file
+type mariadbChunk struct {
+	data   []byte
+	pos    int64  // Position of data[0] in the payload stream
+	offset int64  // File offset of data[0]
+	lsn    uint64 // LSN of data[0]
+}
+
+// mariadbStream reads the payload bytes of a MariaDB log in LSN order,
+// leaving out the block headers and trailers of 10.5 and wrapping around
+// the end of the circular file. Bytes are addressed by their position in
+// the stream and kept until discard, so that a mini-transaction can be
+// checksummed once its end has been found.
+type mariadbStream struct {
+	src    Source
+	blocks bool   // Payload is in 512-byte blocks (10.5)
+	start  int64  // File offset of the log area
+	size   int64  // File size, or -1 when unknown
+	offset int64  // File offset of the next read
+	lsn    uint64 // LSN of the next read; the block LSN in 10.5
+	left   int64  // Bytes to read before the log comes back to where it started, or -1
+	skip   int    // Bytes of the next 10.5 block before the first record to read
+	resync bool   // Skip to the first_rec_group of the next 10.5 block
+	chunks []mariadbChunk
+	end    int64 // Stream position after the last read byte
+	err    error // Set once no more bytes can be read
+}
+
+// fill reads until the stream holds the bytes before position to
+func (s *mariadbStream) fill(to int64) error {
+	for s.end < to {
+		if s.err != nil {
+			return s.err
+		}
+		if err := s.next(); err != nil {
+			s.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+// next reads the next chunk of payload
+func (s *mariadbStream) next() error {
+	if s.left == 0 {
+		return fmt.Errorf("%w (the log has been read around once)", ErrEndOfLog)
+	}
+	if s.size >= 0 && s.offset >= s.size {
+		if _, err := s.src.Seek(s.start, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to wrap around to the start of the log: %w", err)
+		}
+		s.offset = s.start
+	}
+	n := int64(4096)
+	if s.blocks {
+		n = OSFileLogBlockSize
+	}
+	if s.size >= 0 && s.offset+n > s.size {
+		n = s.size - s.offset
+	}
+	if s.left > 0 && n > s.left {
+		n = s.left
+	}
+	buf := make([]byte, n)
+	m, err := io.ReadFull(s.src, buf)
+	if err == io.ErrUnexpectedEOF && !s.blocks {
+		err = nil
+	}
+	if err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return io.EOF
+		}
+		return err
+	}
+	offset := s.offset
+	s.offset += int64(m)
+	if s.left > 0 {
+		s.left -= int64(m)
+	}
+	if !s.blocks {
+		s.add(buf[:m], offset, s.lsn)
+		s.lsn += uint64(m)
+		return nil
+	}
+	return s.addBlock(buf, offset)
+}
+
+// addBlock validates a 10.5 log block and adds its payload
+func (s *mariadbStream) addBlock(block []byte, offset int64) error {
+	lsn := s.lsn
+	s.lsn += OSFileLogBlockSize
+	if crc32.Checksum(block[:MariaDBBlockChecksum], castagnoli) != binary.BigEndian.Uint32(block[MariaDBBlockChecksum:]) {
+		return fmt.Errorf("%w (block at offset %d has a bad checksum)", ErrEndOfLog, offset)
+	}
+	// A block left over from the previous round of the circular log
+	// carries an older block number
+	if no := binary.BigEndian.Uint32(block[LogBlockHdrNo:]) & 0x3FFFFFFF; no != uint32(lsn/OSFileLogBlockSize)&0x3FFFFFFF+1 {
+		return fmt.Errorf("%w (block at offset %d is numbered %d, not for LSN %d)", ErrEndOfLog, offset, no, lsn)
+	}
+	dataLen := int(binary.BigEndian.Uint16(block[LogBlockHdrDataLen:]))
+	if dataLen <= LogBlockHdrSize {
+		return fmt.Errorf("%w (data_len=%d)", ErrEndOfLog, dataLen)
+	}
+	end := min(dataLen, OSFileLogBlockSize-LogBlockTrlSize)
+	if dataLen < OSFileLogBlockSize {
+		// The last block written so far
+		s.left = 0
+	}
+
+	from := max(LogBlockHdrSize, s.skip)
+	s.skip = 0
+	if s.resync {
+		first := int(binary.BigEndian.Uint16(block[LogBlockFirstRecGroup:]))
+		if first < LogBlockHdrSize || first >= end {
+			// No mini-transaction starts in this block
+			return nil
+		}
+		from, s.resync = first, false
+	}
+	if from < end {
+		s.add(block[from:end], offset+int64(from), lsn+uint64(from))
+	}
+	return nil
+}
+
+// add appends a chunk of payload
+func (s *mariadbStream) add(data []byte, offset int64, lsn uint64) {
+	s.chunks = append(s.chunks, mariadbChunk{data: data, pos: s.end, offset: offset, lsn: lsn})
+	s.end += int64(len(data))
+}
+
+// chunk returns the chunk holding the byte at position pos, which has been
+// read and not discarded
+func (s *mariadbStream) chunk(pos int64) *mariadbChunk {
+	for i := range s.chunks {
+		if c := &s.chunks[i]; pos < c.pos+int64(len(c.data)) {
+			return c
+		}
+	}
+	return &s.chunks[len(s.chunks)-1]
+}
+
+// read returns n bytes from position pos
+func (s *mariadbStream) read(pos int64, n int) ([]byte, error) {
+	if err := s.fill(pos + int64(n)); err != nil {
+		return nil, err
+	}
+	data := make([]byte, 0, n)
+	for len(data) < n {
+		c := s.chunk(pos + int64(len(data)))
+		from := pos + int64(len(data)) - c.pos
+		data = append(data, c.data[from:min(int64(len(c.data)), from+int64(n-len(data)))]...)
+	}
+	return data, nil
+}
+
+// lsnAt returns the LSN of the byte at position pos
+func (s *mariadbStream) lsnAt(pos int64) uint64 {
+	c := s.chunk(pos)
+	return c.lsn + uint64(pos-c.pos)
+}
+
+// fileOffset returns the file offset of the byte at position pos
+func (s *mariadbStream) fileOffset(pos int64) int64 {
+	c := s.chunk(pos)
+	return c.offset + pos - c.pos
+}
+
+// spans returns the file ranges of the bytes from position from up to to,
+// split where the bytes are not contiguous in the file
+func (s *mariadbStream) spans(name string, kind types.SpanKind, from, to int64) []types.FieldSpan {
+	var spans []types.FieldSpan
+	for from < to {
+		c := s.chunk(from)
+		n := min(to, c.pos+int64(len(c.data))) - from
+		spans = append(spans, types.FieldSpan{Name: name, Kind: kind, Offset: c.offset + from - c.pos, Length: int(n)})
+		from += n
+	}
+	return spans
+}
+
+// discard drops the chunks before position pos
+func (s *mariadbStream) discard(pos int64) {
+	i := 0
+	for i < len(s.chunks) && s.chunks[i].pos+int64(len(s.chunks[i].data)) <= pos {
+		i++
+	}
+	s.chunks = s.chunks[i:]
+}
+
+// MariaDBRedoLogReader implements RedoLogReader for the physical redo log
+// of MariaDB 10.5 and later. Records are read from the latest checkpoint
+// one mini-transaction at a time; a mini-transaction that fails its
+// checks ends the log.
+type MariaDBRedoLogReader struct {
+	src            Source
+	version        uint32             // Header format, without the encryption flag
+	firstLSN       uint64             // LSN at the start of the log area
+	logStart       int64              // File offset of the log area
+	baseTimestamp  time.Time          // File modification time, reported as the header creation time
+	checkpoints    []*MySQLCheckpoint // Both checkpoint blocks of the header, valid or not
+	lastCheckpoint *MySQLCheckpoint   // Latest valid checkpoint
+	stream         *mariadbStream
+	pos            int64              // Stream position of the next mini-transaction
+	sequenceBit    int                // Terminator byte of 10.8 mini-transactions, or -1 while unknown
+	pending        []*types.LogRecord // Records of the last mini-transaction not returned yet
+	eof            bool
+	warnings       []string
+}
+
+// NewMariaDBRedoLogReader creates a new MariaDB format redo log reader
+func NewMariaDBRedoLogReader() *MariaDBRedoLogReader {
+	return &MariaDBRedoLogReader{sequenceBit: -1}
+}
+
+// Open opens the MariaDB redo log file
+func (r *MariaDBRedoLogReader) Open(filename string) error {
+	src, err := NewFileSource(filename)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	return r.OpenSource(src)
+}
+
+// OpenSource reads the MariaDB redo log from src
+func (r *MariaDBRedoLogReader) OpenSource(src Source) error {
+	r.src = src
+	version, err := ReadLogFormat(src)
+	if err != nil {
+		return fmt.Errorf("failed to read log format: %w", err)
+	}
+	if !IsMariaDBFormat(version) {
+		return fmt.Errorf("log format 0x%08x is not a MariaDB 10.5 or later format", version)
+	}
+	if version&MariaDBFormatEncrypted != 0 {
+		return fmt.Errorf("encrypted MariaDB redo logs are not supported")
+	}
+	r.version = version
+	r.logStart = MariaDBStartOffset105
+	if version == MariaDBFormat108 {
+		r.logStart = MariaDBStartOffset108
+	}
+	return nil
+}
+
+// ReadHeader reads the file header and checkpoints, and positions the
+// reader at the latest checkpoint
+func (r *MariaDBRedoLogReader) ReadHeader() (*types.RedoLogHeader, error) {
+	r.baseTimestamp = sourceModTime(r.src)
+
+	// The header is read forward so that a stream can be read from its
+	// start when there is no checkpoint to go to
+	if _, err := r.src.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek to header: %w", err)
+	}
+	header := make([]byte, r.logStart)
+	if _, err := io.ReadFull(r.src, header); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	if crc32.Checksum(header[:MariaDBBlockChecksum], castagnoli) != binary.BigEndian.Uint32(header[MariaDBBlockChecksum:]) {
+		r.warn("header block checksum mismatch")
+	}
+	r.firstLSN = binary.BigEndian.Uint64(header[LogHeaderStartLSN:])
+	r.parseCheckpoints(header)
+
+	r.stream = &mariadbStream{
+		src:    r.src,
+		blocks: r.version == MariaDBFormat105,
+		start:  r.logStart,
+		size:   r.src.Size(),
+		offset: r.logStart,
+		lsn:    r.firstLSN,
+		left:   -1,
+	}
+	startLSN := r.firstLSN
+	capacity := r.stream.size - r.logStart
+	switch {
+	case r.lastCheckpoint == nil:
+		r.warn("no valid checkpoint found, reading from the start of the log")
+		r.stream.resync = true
+	case r.stream.size < 0:
+		r.warn(fmt.Sprintf("log size unknown, reading from the start of the log instead of checkpoint LSN %d", r.lastCheckpoint.CheckpointLSN))
+		r.stream.resync = true
+	case capacity <= 0 || r.lastCheckpoint.CheckpointLSN < r.firstLSN:
+		return nil, fmt.Errorf("checkpoint LSN %d is outside the log starting at LSN %d", r.lastCheckpoint.CheckpointLSN, r.firstLSN)
+	default:
+		// The log is circular: the checkpoint is at its LSN's distance
+		// from the first LSN, modulo the size of the log area
+		startLSN = r.lastCheckpoint.CheckpointLSN
+		lsn := startLSN
+		if r.stream.blocks {
+			r.stream.skip = int(lsn % OSFileLogBlockSize)
+			lsn -= lsn % OSFileLogBlockSize
+		}
+		r.stream.lsn = lsn
+		r.stream.offset = r.logStart + int64((lsn-r.firstLSN)%uint64(capacity))
+		if _, err := r.src.Seek(r.stream.offset, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to seek to checkpoint: %w", err)
+		}
+	}
+	if capacity > 0 {
+		r.stream.left = capacity
+	}
+
+	h := &types.RedoLogHeader{
+		LogGroupID: 0,
+		StartLSN:   startLSN,
+		FileNo:     1,
+		Created:    r.baseTimestamp,
+		Format:     r.version,
+	}
+	if r.lastCheckpoint != nil {
+		h.LogGroupID = r.lastCheckpoint.CheckpointNo
+		h.LastCheckpoint = r.lastCheckpoint.CheckpointLSN
+	}
+	return h, nil
+}
+
+// parseCheckpoints reads both checkpoint blocks of the header and picks
+// the latest valid one. 10.5 numbers its checkpoints; 10.8 does not, so
+// the one with the higher LSN is the latest.
+func (r *MariaDBRedoLogReader) parseCheckpoints(header []byte) {
+	r.checkpoints = nil
+	r.lastCheckpoint = nil
+	offsets := []int{LogCheckpoint1, LogCheckpoint2}
+	if r.version == MariaDBFormat108 {
+		offsets = []int{MariaDBCheckpoint108a, MariaDBCheckpoint108b}
+	}
+	for _, offset := range offsets {
+		block := header[offset : offset+OSFileLogBlockSize]
+		checkpoint := &MySQLCheckpoint{}
+		if r.version == MariaDBFormat108 {
+			checkpoint.CheckpointLSN = binary.BigEndian.Uint64(block[0:8])
+			checkpoint.Checksum = binary.BigEndian.Uint32(block[MariaDBCheckpointSum:])
+			checkpoint.IsValid = crc32.Checksum(block[:MariaDBCheckpointSum], castagnoli) == checkpoint.Checksum
+		} else {
+			checkpoint.CheckpointNo = binary.BigEndian.Uint64(block[LogCheckpointNo:])
+			checkpoint.CheckpointLSN = binary.BigEndian.Uint64(block[LogCheckpointLSN:])
+			checkpoint.Offset = binary.BigEndian.Uint64(block[LogCheckpointOffset:])
+			checkpoint.BufSize = binary.BigEndian.Uint64(block[LogCheckpointBufSize:])
+			checkpoint.Checksum = binary.BigEndian.Uint32(block[MariaDBBlockChecksum:])
+			checkpoint.IsValid = crc32.Checksum(block[:MariaDBBlockChecksum], castagnoli) == checkpoint.Checksum
+		}
+		checkpoint.IsValid = checkpoint.IsValid && checkpoint.CheckpointLSN != 0
+		r.checkpoints = append(r.checkpoints, checkpoint)
+
+		if !checkpoint.IsValid {
+			continue
+		}
+		if latest := r.lastCheckpoint; latest == nil || checkpoint.CheckpointNo > latest.CheckpointNo ||
+			(checkpoint.CheckpointNo == latest.CheckpointNo && checkpoint.CheckpointLSN > latest.CheckpointLSN) {
+			r.lastCheckpoint = checkpoint
+		}
+	}
+}
+
+// ReadRecord returns the next record. The records of a mini-transaction
+// are returned once all of it has been read and checked, followed by an
+// END_OF_MTR record.
+func (r *MariaDBRedoLogReader) ReadRecord() (*types.LogRecord, error) {
+	if r.stream == nil {
+		return nil, fmt.Errorf("ReadHeader must be called before ReadRecord")
+	}
+	for len(r.pending) == 0 {
+		if r.eof {
+			return nil, ErrEndOfLog
+		}
+		if err := r.readMTR(); err != nil {
+			if errors.Is(err, errMariaDBEndOfMTRs) || IsEndOfLog(err) {
+				r.eof = true
+				continue
+			}
+			return nil, err
+		}
+	}
+	record := r.pending[0]
+	r.pending = r.pending[1:]
+	return record, nil
+}
+
+// errMariaDBEndOfMTRs is returned by readMTR when the bytes at the current
+// position are not a complete mini-transaction
+var errMariaDBEndOfMTRs = errors.New("no further mini-transaction")
+
+// mariadbMTR is the state of the mini-transaction being parsed
+type mariadbMTR struct {
+	gotPage    bool   // A page record has set the page of same_page records
+	spaceID    uint32 // Page of the previous page record
+	pageNo     uint32
+	lastOffset uint32 // End of the previous WRITE, MEMSET or MEMMOVE on the page
+}
+
+// readMTR parses the mini-transaction at the current position into
+// r.pending
+func (r *MariaDBRedoLogReader) readMTR() error {
+	s := r.stream
+	start := r.pos
+	pos := start
+	var mtr mariadbMTR
+	var records []*types.LogRecord
+	for {
+		first, err := s.read(pos, 1)
+		if err != nil {
+			return r.endOfMTRs(start, pos, err)
+		}
+		if first[0] <= 1 {
+			break
+		}
+		record, next, err := r.parseRecord(&mtr, pos)
+		if err != nil {
+			return r.endOfMTRs(start, pos, err)
+		}
+		records = append(records, record)
+		pos = next
+	}
+	if len(records) == 0 {
+		return errMariaDBEndOfMTRs
+	}
+
+	// 10.5 ends a mini-transaction with a NUL byte. 10.8 ends it with the
+	// sequence bit of the current round of the circular log and the
+	// CRC-32C of its records.
+	terminator, _ := s.read(pos, 1)
+	end := &types.LogRecord{
+		Type:       types.MariaDBEndOfMTR,
+		LSN:        s.lsnAt(pos),
+		Length:     1,
+		Data:       []byte("end_of_mtr"),
+		FileOffset: s.fileOffset(pos),
+		Spans:      s.spans("terminator", types.SpanRecordType, pos, pos+1),
+	}
+	if r.version == MariaDBFormat105 {
+		if terminator[0] != 0 {
+			return errMariaDBEndOfMTRs
+		}
+	} else {
+		trailer, err := s.read(pos+1, 4)
+		if err != nil {
+			return r.endOfMTRs(start, pos, err)
+		}
+		body, _ := s.read(start, int(pos-start))
+		checksum := binary.BigEndian.Uint32(trailer)
+		if crc32.Checksum(body, castagnoli) != checksum || !r.sequenceBitMatches(int(terminator[0]), end.LSN) {
+			return errMariaDBEndOfMTRs
+		}
+		end.Length = 5
+		end.Checksum = checksum
+		end.Data = []byte(fmt.Sprintf("end_of_mtr sequence_bit=%d crc=0x%08x", terminator[0], checksum))
+		end.Spans = append(end.Spans, s.spans("checksum", types.SpanFixedInt, pos+1, pos+5)...)
+		for _, record := range records {
+			record.Checksum = checksum
+		}
+	}
+
+	r.pos = pos + int64(end.Length)
+	s.discard(r.pos)
+	r.pending = append(records, end)
+	return nil
+}
+
+// sequenceBitMatches checks the terminator of a 10.8 mini-transaction
+// ending at lsn. The bit is 1 in the first round of the circular log and
+// flips every time the log wraps around.
+func (r *MariaDBRedoLogReader) sequenceBitMatches(bit int, lsn uint64) bool {
+	if capacity := r.stream.size - r.logStart; r.stream.size >= 0 && capacity > 0 {
+		return bit == 1-int((lsn-r.firstLSN)/uint64(capacity))&1
+	}
+	// Without the size the round is not known: the bit must not change
+	if r.sequenceBit < 0 {
+		r.sequenceBit = bit
+	}
+	return bit == r.sequenceBit
+}
+
+// endOfMTRs handles an error met while parsing the mini-transaction that
+// started at position start. The end of the bytes or a malformed record
+// ends the log; only read errors are returned.
+func (r *MariaDBRedoLogReader) endOfMTRs(start, pos int64, err error) error {
+	if !IsEndOfLog(err) && !errors.Is(err, errMariaDBVarint) && !errors.Is(err, errMariaDBRecord) {
+		return err
+	}
+	if pos > start {
+		r.warn(fmt.Sprintf("incomplete mini-transaction at LSN %d: %v", r.stream.lsnAt(start), err))
+	}
+	return errMariaDBEndOfMTRs
+}
+
+// errMariaDBRecord is returned for a record that cannot be parsed
+var errMariaDBRecord = errors.New("malformed record")
+
+// mariadbCursor reads the body of a record, recording the spans of the
+// fields it reads
+type mariadbCursor struct {
+	s     *mariadbStream
+	body  []byte
+	at    int
+	base  int64 // Stream position of body[0]
+	spans []types.FieldSpan
+}
+
+// varint reads a varint field
+func (c *mariadbCursor) varint(name string) (uint32, error) {
+	v, n, err := parseMariaDBVarint(c.body[c.at:])
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s: %v", errMariaDBRecord, name, err)
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("%w: %s past the end of the record", errMariaDBRecord, name)
+	}
+	c.mark(name, types.SpanCompressedInt, n)
+	return v, nil
+}
+
+// bytes reads a field of n bytes, or of the rest of the record when n < 0
+func (c *mariadbCursor) bytes(name string, kind types.SpanKind, n int) ([]byte, error) {
+	if n < 0 {
+		n = len(c.body) - c.at
+	}
+	if c.at+n > len(c.body) {
+		return nil, fmt.Errorf("%w: %s past the end of the record", errMariaDBRecord, name)
+	}
+	data := c.body[c.at : c.at+n]
+	c.mark(name, kind, n)
+	return data, nil
+}
+
+// mark records the next n bytes as a field and moves past them
+func (c *mariadbCursor) mark(name string, kind types.SpanKind, n int) {
+	from := c.base + int64(c.at)
+	c.spans = append(c.spans, c.s.spans(name, kind, from, from+int64(n))...)
+	c.at += n
+}
+
+// parseRecord parses the record at position pos. The first byte holds the
+// same_page flag in bit 7, the record type in bits 4-6 and the length of
+// the rest of the record in bits 0-3, or 0 when a varint length follows.
+func (r *MariaDBRedoLogReader) parseRecord(mtr *mariadbMTR, pos int64) (*types.LogRecord, int64, error) {
+	s := r.stream
+	first, err := s.read(pos, 1)
+	if err != nil {
+		return nil, 0, err
+	}
+	b := first[0]
+	spans := s.spans("type", types.SpanRecordType, pos, pos+1)
+	bodyStart := pos + 1
+	length := int(b & 0x0F)
+	if length == 0 {
+		lead, err := s.read(bodyStart, 1)
+		if err != nil {
+			return nil, 0, err
+		}
+		n := mariadbVarintLen(lead[0])
+		encoded, err := s.read(bodyStart, n)
+		if err != nil {
+			return nil, 0, err
+		}
+		added, _, err := parseMariaDBVarint(encoded)
+		if err != nil {
+			return nil, 0, err
+		}
+		length = int(added) + 15 - n
+		spans = append(spans, s.spans("length", types.SpanCompressedInt, bodyStart, bodyStart+int64(n))...)
+		bodyStart += int64(n)
+	}
+	body, err := s.read(bodyStart, length)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	c := &mariadbCursor{s: s, body: body, base: bodyStart, spans: spans}
+	record := &types.LogRecord{
+		LSN:        s.lsnAt(pos),
+		Length:     uint32(bodyStart - pos + int64(length)),
+		FileOffset: s.fileOffset(pos),
+	}
+	if b&0x80 != 0 && mtr.gotPage {
+		// Same page as the previous record
+		record.Type = types.MariaDBFreePage + types.LogType(b>>4&7)
+	} else {
+		if mtr.spaceID, err = c.varint("space_id"); err != nil {
+			return nil, 0, err
+		}
+		if mtr.pageNo, err = c.varint("page_no"); err != nil {
+			return nil, 0, err
+		}
+		mtr.lastOffset = 0
+		mtr.gotPage = b&0x80 == 0
+		if mtr.gotPage {
+			record.Type = types.MariaDBFreePage + types.LogType(b>>4&7)
+		} else {
+			record.Type = types.MariaDBFileCreate + types.LogType(b>>4&7)
+		}
+	}
+	record.SpaceID, record.PageNo = mtr.spaceID, mtr.pageNo
+
+	var data string
+	if mtr.gotPage {
+		data, err = r.decodePageRecord(mtr, record, c)
+	} else {
+		data, err = decodeFileRecord(record, c)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	record.Data = []byte(data)
+	record.Spans = c.spans
+	return record, bodyStart + int64(length), nil
+}
+
+// decodePageRecord decodes the body of a page record. The offsets of
+// WRITE, MEMSET and MEMMOVE are relative to the end of the previous one
+// on the same page.
+func (r *MariaDBRedoLogReader) decodePageRecord(mtr *mariadbMTR, record *types.LogRecord, c *mariadbCursor) (string, error) {
+	page := fmt.Sprintf("space=%d page=%d", record.SpaceID, record.PageNo)
+	switch record.Type {
+	case types.MariaDBFreePage, types.MariaDBInitPage:
+		return page, nil
+	case types.MariaDBExtended:
+		subtype, err := c.bytes("subtype", types.SpanFixedInt, 1)
+		if err != nil {
+			return "", err
+		}
+		if ext := types.MariaDBInitRowFormatRedundant + types.LogType(subtype[0]); subtype[0] <= uint8(types.MariaDBTrimPages-types.MariaDBInitRowFormatRedundant) {
+			record.Type = ext
+		}
+		rest, _ := c.bytes("record", types.SpanPayload, -1)
+		return fmt.Sprintf("%s subtype=%d%s", page, subtype[0], describeBytes(rest)), nil
+	case types.MariaDBWrite, types.MariaDBMemset, types.MariaDBMemmove:
+	default:
+		rest, _ := c.bytes("record", types.SpanPayload, -1)
+		return page + describeBytes(rest), nil
+	}
+
+	relative, err := c.varint("offset")
+	if err != nil {
+		return "", err
+	}
+	offset := mtr.lastOffset + relative
+	record.Offset = uint16(offset)
+	var result string
+	switch record.Type {
+	case types.MariaDBWrite:
+		data, _ := c.bytes("data", types.SpanPayload, -1)
+		mtr.lastOffset = offset + uint32(len(data))
+		result = fmt.Sprintf("%s offset=%d len=%d%s", page, offset, len(data), describeBytes(data))
+	case types.MariaDBMemset:
+		n, err := c.varint("len")
+		if err != nil {
+			return "", err
+		}
+		fill, _ := c.bytes("fill", types.SpanPayload, -1)
+		mtr.lastOffset = offset + n
+		result = fmt.Sprintf("%s offset=%d len=%d fill=%x", page, offset, n, fill)
+	default:
+		n, err := c.varint("len")
+		if err != nil {
+			return "", err
+		}
+		// The source is a signed distance from the target, its sign in
+		// the lowest bit
+		distance, err := c.varint("source")
+		if err != nil {
+			return "", err
+		}
+		source := int64(offset) + int64(distance>>1)
+		if distance&1 != 0 {
+			source = int64(offset) - int64(distance>>1)
+		}
+		mtr.lastOffset = offset + n
+		result = fmt.Sprintf("%s offset=%d len=%d source=%d", page, offset, n, source)
+	}
+	return result, nil
+}
+
+// decodeFileRecord decodes the body of a FILE_ record: the file name, the
+// old and new names of FILE_RENAME, or the LSN of FILE_CHECKPOINT
+func decodeFileRecord(record *types.LogRecord, c *mariadbCursor) (string, error) {
+	if record.Type == types.MariaDBFileCheckpoint {
+		lsn, err := c.bytes("checkpoint_lsn", types.SpanFixedInt, 8)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("checkpoint_lsn=%d", binary.BigEndian.Uint64(lsn)), nil
+	}
+	name, _ := c.bytes("name", types.SpanPayload, -1)
+	if record.Type == types.MariaDBFileRename {
+		if old, renamed, ok := strings.Cut(string(name), "\x00"); ok {
+			return fmt.Sprintf("space=%d name='%s' new_name='%s'", record.SpaceID, old, renamed), nil
+		}
+	}
+	return fmt.Sprintf("space=%d name='%s'", record.SpaceID, name), nil
+}
+
+// describeBytes formats record bytes as hex, with their readable strings
+func describeBytes(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	result := fmt.Sprintf(" hex=%x", data)
+	if readable := extractReadableStrings(data); readable != "" {
+		result += fmt.Sprintf(" data='%s'", readable)
+	}
+	return result
+}
+
+// Seek is not supported: records are read in LSN order from the checkpoint
+func (r *MariaDBRedoLogReader) Seek(offset int64) error {
+	return fmt.Errorf("seeking is not supported by the MariaDB reader")
+}
+
+// IsEOF reports whether the end of the log has been reached
+func (r *MariaDBRedoLogReader) IsEOF() bool {
+	return r.src == nil || (r.eof && len(r.pending) == 0)
+}
+
+// Checkpoints returns the checkpoint blocks read by ReadHeader, in file
+// order. Only 10.5 numbers its checkpoints and records their offset.
+func (r *MariaDBRedoLogReader) Checkpoints() []*MySQLCheckpoint {
+	return r.checkpoints
+}
+
+// Warnings returns the non-fatal problems noticed while reading so far
+func (r *MariaDBRedoLogReader) Warnings() []string {
+	return r.warnings
+}
+
+// warn records a non-fatal problem
+func (r *MariaDBRedoLogReader) warn(message string) {
+	r.warnings = append(r.warnings, message)
+}
+
+// Close closes the source
+func (r *MariaDBRedoLogReader) Close() error {
+	if r.src != nil {
+		return closeSource(r.src)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package reader
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+	"github.com/yamaru/innodb-redolog-tool/test/fixtures"
+)
+
+// mariadbMTRs are three mini-transactions: page records of space 5, file
+// records, and a WRITE long enough for a varint length
+var mariadbMTRs = [][]byte{
+	{
+		0x12, 5, 3, // INIT_PAGE
+		0xB4, 0x26, 'a', 'b', 'c', // WRITE to the same page at 38
+		0xC3, 2, 10, 0, // MEMSET of the same page at 43
+		0x25, 5, 4, 6, 0xAA, 0xBB, // EXTENDED INSERT_HEAP_DYNAMIC of page 4
+	},
+	{
+		0xBD, 5, 0, 't', 'e', 's', 't', '/', 't', '1', '.', 'i', 'b', 'd', // FILE_MODIFY
+		0xFA, 0, 0, 0, 0, 0, 0, 0, 0, 0x20, 0x00, // FILE_CHECKPOINT
+	},
+	append([]byte{0x30, 9, 1, 2, 0x10}, bytes.Repeat([]byte{'x'}, 20)...),
+}
+
+func TestMariaDBRedoLog(t *testing.T) {
+	const startLSN = 8192
+	tests := []struct {
+		name     string
+		format   uint32
+		firstLSN uint64
+		endLen   uint32
+	}{
+		{"10.5", fixtures.MariaDBFormat105, startLSN + LogBlockHdrSize, 1},
+		{"10.8", fixtures.MariaDBFormat108, startLSN, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename, err := fixtures.CreateMariaDBLogFile(t.TempDir(), tt.format, startLSN, mariadbMTRs)
+			require.NoError(t, err)
+
+			r, err := NewReaderForFile(filename)
+			require.NoError(t, err)
+			mariadb, ok := r.(*MariaDBRedoLogReader)
+			require.True(t, ok)
+			require.NoError(t, mariadb.Open(filename))
+			defer mariadb.Close()
+
+			header, err := mariadb.ReadHeader()
+			require.NoError(t, err)
+			assert.Equal(t, tt.format, header.Format)
+			assert.Equal(t, tt.firstLSN, header.StartLSN)
+			assert.Equal(t, tt.firstLSN, header.LastCheckpoint)
+			require.Len(t, mariadb.Checkpoints(), 2)
+			assert.True(t, mariadb.Checkpoints()[0].IsValid)
+
+			records, err := ReadRecords(mariadb, 0)
+			require.NoError(t, err)
+			assert.Empty(t, mariadb.Warnings())
+			assert.True(t, mariadb.IsEOF())
+
+			var got []types.LogType
+			for _, record := range records {
+				got = append(got, record.Type)
+			}
+			assert.Equal(t, []types.LogType{
+				types.MariaDBInitPage, types.MariaDBWrite, types.MariaDBMemset, types.MariaDBInsertHeapDynamic, types.MariaDBEndOfMTR,
+				types.MariaDBFileModify, types.MariaDBFileCheckpoint, types.MariaDBEndOfMTR,
+				types.MariaDBWrite, types.MariaDBEndOfMTR,
+			}, got)
+			require.Len(t, records, 10)
+
+			assert.Equal(t, tt.firstLSN, records[0].LSN)
+			assert.Equal(t, "space=5 page=3", string(records[0].Data))
+			assert.Equal(t, "space=5 page=3 offset=38 len=3 hex=616263 data='abc'", string(records[1].Data))
+			assert.Equal(t, uint16(38), records[1].Offset)
+			assert.Equal(t, "space=5 page=3 offset=43 len=10 fill=00", string(records[2].Data))
+			assert.Equal(t, uint32(4), records[3].PageNo)
+			assert.Equal(t, "space=5 page=4 subtype=6 hex=aabb", string(records[3].Data))
+			assert.Equal(t, types.OperationInsert, records[3].Type.Operation())
+			assert.Equal(t, tt.firstLSN+uint64(len(mariadbMTRs[0])), records[4].LSN)
+			assert.Equal(t, tt.endLen, records[4].Length)
+			assert.Equal(t, "space=5 name='test/t1.ibd'", string(records[5].Data))
+			assert.Equal(t, "checkpoint_lsn=8192", string(records[6].Data))
+			assert.Equal(t, uint32(len(mariadbMTRs[2])), records[8].Length)
+			assert.Equal(t, uint32(1), records[8].SpaceID)
+			assert.Contains(t, string(records[8].Data), "offset=16 len=20")
+
+			// The spans of a record cover its bytes in the file
+			content, err := os.ReadFile(filename)
+			require.NoError(t, err)
+			start, end, ok := records[1].ByteRange()
+			require.True(t, ok)
+			assert.Equal(t, records[1].FileOffset, start)
+			assert.Equal(t, mariadbMTRs[0][3:8], content[start:end])
+
+			// END_OF_MTR closes a multi-record group
+			DetectMultiRecordGroups(records)
+			assert.True(t, records[0].IsGroupStart)
+			assert.True(t, records[4].IsGroupEnd)
+			assert.Equal(t, 3, records[9].MultiRecordGroup)
+		})
+	}
+}
+
+func TestMariaDBChecksumEndsLog(t *testing.T) {
+	filename, err := fixtures.CreateMariaDBLogFile(t.TempDir(), fixtures.MariaDBFormat108, 8192, mariadbMTRs)
+	require.NoError(t, err)
+	content, err := os.ReadFile(filename)
+	require.NoError(t, err)
+
+	// A changed byte in the second mini-transaction fails its CRC-32C
+	content[MariaDBStartOffset108+len(mariadbMTRs[0])+5+4] ^= 0xFF
+	r := NewMariaDBRedoLogReader()
+	require.NoError(t, r.OpenSource(NewBytesSource(content)))
+	_, err = r.ReadHeader()
+	require.NoError(t, err)
+	records, err := ReadRecords(r, 0)
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+	assert.Equal(t, types.MariaDBEndOfMTR, records[4].Type)
+}
+
+func TestMariaDBStream(t *testing.T) {
+	filename, err := fixtures.CreateMariaDBLogFile(t.TempDir(), fixtures.MariaDBFormat105, 8192, mariadbMTRs)
+	require.NoError(t, err)
+	content, err := os.ReadFile(filename)
+	require.NoError(t, err)
+
+	// Without the size the circular log is read from its start
+	src, err := NewStreamSource(bytes.NewReader(content))
+	require.NoError(t, err)
+	r := NewReaderForSource(src)
+	require.IsType(t, &MariaDBRedoLogReader{}, r)
+	require.NoError(t, r.OpenSource(src))
+	_, err = r.ReadHeader()
+	require.NoError(t, err)
+	records, err := ReadRecords(r, 0)
+	require.NoError(t, err)
+	assert.Len(t, records, 10)
+	require.Len(t, r.(*MariaDBRedoLogReader).Warnings(), 1)
+	assert.Contains(t, r.(*MariaDBRedoLogReader).Warnings()[0], "log size unknown")
+}
+
+func TestMariaDBEncrypted(t *testing.T) {
+	filename, err := fixtures.CreateMariaDBLogFile(t.TempDir(), fixtures.MariaDBFormat108|MariaDBFormatEncrypted, 8192, nil)
+	require.NoError(t, err)
+	r := NewMariaDBRedoLogReader()
+	assert.ErrorContains(t, r.Open(filename), "encrypted MariaDB redo logs are not supported")
+	r.Close()
+}
+
+func TestParseMariaDBVarint(t *testing.T) {
+	tests := []struct {
+		data  []byte
+		value uint32
+		n     int
+	}{
+		{[]byte{0x7F}, 0x7F, 1},
+		{[]byte{0x80, 0x00}, 0x80, 2},
+		{[]byte{0xBF, 0xFF}, 0x407F, 2},
+		{[]byte{0xC0, 0x00, 0x00}, 0x4080, 3},
+		{[]byte{0xE0, 0x00, 0x00, 0x00}, 0x204080, 4},
+		{[]byte{0xF0, 0x00, 0x00, 0x00, 0x01}, 0x10204081, 5},
+		{[]byte{0xC0, 0x00}, 0, 0},
+	}
+	for _, tt := range tests {
+		value, n, err := parseMariaDBVarint(tt.data)
+		require.NoError(t, err)
+		assert.Equal(t, tt.value, value, "%x", tt.data)
+		assert.Equal(t, tt.n, n, "%x", tt.data)
+	}
+	_, _, err := parseMariaDBVarint([]byte{0xF8})
+	assert.ErrorIs(t, err, errMariaDBVarint)
+}
@@ -4,8 +4,6 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 	
@@ -48,6 +46,13 @@ const (
 	LogCheckpointOffset  = 16 // Checkpoint offset (8 bytes)
 	LogCheckpointBufSize = 24 // Log buffer size (8 bytes)
 	LogCheckpointSum     = 60 // Checksum offset (4 bytes, at end of block)
+
+	// UnivPageSizeMax bounds the length of a record in a page
+	UnivPageSizeMax = 1 << 16 // UNIV_PAGE_SIZE_MAX
+
+	// File header block
+	LogHeaderFormat     = 0 // Log format version (4 bytes, big endian)
+	LogHeaderFormat8030 = 6 // LOG_HEADER_FORMAT_8_0_30, first version of #innodb_redo files
 )
 
 // MySQL Log Record Types (mlog_id_t from mtr0types.h)
@@ -102,40 +107,41 @@ type MySQLLogRecord struct {
 
 // MySQLRedoLogReader implements RedoLogReader for actual MySQL format
 type MySQLRedoLogReader struct {
-	file          *os.File
+	src           Source
 	currentBlock  MySQLLogBlockHeader
 	blockData     []byte
 	dataOffset    int
 	position      int64
-	baseTimestamp time.Time       // File modification time for realistic timestamp calculation
-	baseLSN       uint64          // First LSN encountered for relative timestamp calculation
+	baseTimestamp time.Time       // File modification time, reported as the header creation time; zero for other sources
+	baseLSN       uint64          // LSN where record parsing starts
 	currentLSN    uint64          // Current LSN position in log stream
 	formatType    MySQLFormatType // Detected MySQL format (classic vs modern)
+	version       uint32          // Header format version
+	format        *RedoFormat     // Record types and decoders of the version
 	lastCheckpoint *MySQLCheckpoint // Latest valid checkpoint found
+	checkpoints   []*MySQLCheckpoint // Both checkpoint blocks of the header, valid or not
+	warnings      []string        // Non-fatal problems noticed while reading
+	blockStart    int64             // File offset of the current block
+	recordStart   int64             // File offset of the record being parsed
+	spans         []types.FieldSpan // Fields of the record being parsed
+	xtrabackup    *XtraBackupLog    // Set when the file is an xtrabackup_logfile
+	keyring       *Keyring          // Master keys of encrypted logs, if given
+	encryption    *EncryptionInfo   // Set when the header holds encryption info
+	decrypter     *logDecrypter     // Decrypts encrypted blocks once the master key is found
 }
 
-// DetectMySQLFormat detects whether we're dealing with MySQL classic or modern format
-func DetectMySQLFormat(filename string) (MySQLFormatType, error) {
-	// Get the directory containing the log file
-	dir := filepath.Dir(filename)
-	
-	// Check for modern format: #innodb_redo directory
-	innodbRedoDir := filepath.Join(dir, "#innodb_redo")
-	if info, err := os.Stat(innodbRedoDir); err == nil && info.IsDir() {
-		return MySQLFormatModern, nil
-	}
-	
-	// Check for classic format: ib_logfile* files
-	matches, err := filepath.Glob(filepath.Join(dir, "ib_logfile*"))
+// DetectMySQLFormat detects whether we're dealing with MySQL classic or
+// modern format from the format version in the file header
+func DetectMySQLFormat(src Source) (MySQLFormatType, error) {
+	version, err := ReadLogFormat(src)
 	if err != nil {
-		return MySQLFormatClassic, fmt.Errorf("error checking for ib_logfile*: %w", err)
+		return MySQLFormatClassic, err
 	}
 	
-	if len(matches) > 0 {
-		return MySQLFormatClassic, nil
+	// #innodb_redo files start with LOG_HEADER_FORMAT_8_0_30 or later
+	if version >= LogHeaderFormat8030 {
+		return MySQLFormatModern, nil
 	}
-	
-	// If neither found, assume we're dealing with a standalone file (classic format)
 	return MySQLFormatClassic, nil
 }
 
@@ -143,23 +149,38 @@ func DetectMySQLFormat(filename string) (MySQLFormatType, error) {
 func NewMySQLRedoLogReader() *MySQLRedoLogReader {
 	return &MySQLRedoLogReader{
 		blockData: make([]byte, LogBlockDataSize),
+		format:    RedoFormat8030,
 	}
 }
 
 // Open opens the MySQL redo log file
 func (r *MySQLRedoLogReader) Open(filename string) error {
-	// Detect MySQL format first
-	formatType, err := DetectMySQLFormat(filename)
+	src, err := NewFileSource(filename)
 	if err != nil {
-		return fmt.Errorf("failed to detect MySQL format: %w", err)
+		return fmt.Errorf("failed to open file: %w", err)
 	}
-	r.formatType = formatType
+	return r.OpenSource(src)
+}
+
+// OpenSource reads the MySQL redo log from src
+func (r *MySQLRedoLogReader) OpenSource(src Source) error {
+	r.src = src
 	
-	file, err := os.Open(filename)
+	// Detect MySQL format first; unknown versions are decoded like the
+	// newest format
+	version, err := ReadLogFormat(src)
 	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
+		return fmt.Errorf("failed to detect MySQL format: %w", err)
+	}
+	r.version = version
+	if r.format = LookupRedoFormat(version); r.format == nil {
+		r.format = RedoFormat8030
+		r.warn(fmt.Sprintf("unknown log format %d, decoding as %s", version, r.format))
+	}
+	r.formatType = MySQLFormatClassic
+	if r.format.FilePerRange {
+		r.formatType = MySQLFormatModern
 	}
-	r.file = file
 	return nil
 }
 
@@ -167,17 +188,17 @@ func (r *MySQLRedoLogReader) Open(filename string) error {
 func (r *MySQLRedoLogReader) parseCheckpointBlock(offset int64) (*MySQLCheckpoint, error) {
 	// Read checkpoint block (512 bytes)
 	checkpointData := make([]byte, OSFileLogBlockSize)
-	_, err := r.file.ReadAt(checkpointData, offset)
+	_, err := r.src.ReadAt(checkpointData, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read checkpoint block at offset %d: %w", offset, err)
 	}
 	
 	checkpoint := &MySQLCheckpoint{
-		CheckpointNo:  binary.LittleEndian.Uint64(checkpointData[LogCheckpointNo:LogCheckpointNo+8]),
-		CheckpointLSN: binary.LittleEndian.Uint64(checkpointData[LogCheckpointLSN:LogCheckpointLSN+8]),
-		Offset:        binary.LittleEndian.Uint64(checkpointData[LogCheckpointOffset:LogCheckpointOffset+8]),
-		BufSize:       binary.LittleEndian.Uint64(checkpointData[LogCheckpointBufSize:LogCheckpointBufSize+8]),
-		Checksum:      binary.LittleEndian.Uint32(checkpointData[LogCheckpointSum:LogCheckpointSum+4]),
+		CheckpointNo:  binary.BigEndian.Uint64(checkpointData[LogCheckpointNo:LogCheckpointNo+8]),
+		CheckpointLSN: binary.BigEndian.Uint64(checkpointData[LogCheckpointLSN:LogCheckpointLSN+8]),
+		Offset:        binary.BigEndian.Uint64(checkpointData[LogCheckpointOffset:LogCheckpointOffset+8]),
+		BufSize:       binary.BigEndian.Uint64(checkpointData[LogCheckpointBufSize:LogCheckpointBufSize+8]),
+		Checksum:      binary.BigEndian.Uint32(checkpointData[LogCheckpointSum:LogCheckpointSum+4]),
 	}
 	
 	// Basic validation: checkpoint_no should not be 0
@@ -192,6 +213,13 @@ func (r *MySQLRedoLogReader) findLatestCheckpoint() error {
 	checkpoint1, err1 := r.parseCheckpointBlock(LogCheckpoint1)
 	checkpoint2, err2 := r.parseCheckpointBlock(LogCheckpoint2)
 	
+	r.checkpoints = nil
+	for _, checkpoint := range []*MySQLCheckpoint{checkpoint1, checkpoint2} {
+		if checkpoint != nil {
+			r.checkpoints = append(r.checkpoints, checkpoint)
+		}
+	}
+	
 	// Find the checkpoint with the highest checkpoint_no
 	var latestCheckpoint *MySQLCheckpoint
 	
@@ -218,45 +246,26 @@ func (r *MySQLRedoLogReader) findLatestCheckpoint() error {
 // ReadHeader reads the MySQL redo log file header
 func (r *MySQLRedoLogReader) ReadHeader() (*types.RedoLogHeader, error) {
 	// Get actual file modification time for realistic timestamp calculation
-	fileInfo, err := r.file.Stat()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get file info: %w", err)
+	r.baseTimestamp = sourceModTime(r.src)
+	r.xtrabackup = parseXtraBackupHeader(r.src)
+	if err := r.readEncryptionInfo(); err != nil {
+		return nil, err
 	}
-	r.baseTimestamp = fileInfo.ModTime()
 
 	// Try to find the latest valid checkpoint from file header
-	err = r.findLatestCheckpoint()
+	err := r.findLatestCheckpoint()
 	if err != nil {
 		// If no valid checkpoint found, this might be a test file or corrupted header
 		// Fall back to starting from the beginning of log blocks
-		fmt.Printf("Warning: No valid checkpoint found, starting from beginning of log blocks\n")
+		r.warn("no valid checkpoint found, starting from beginning of log blocks")
 		
 		// Initialize with default values
 		r.baseLSN = uint64(LogFileHdrSize)
 		r.currentLSN = uint64(LogFileHdrSize)
-		
-		// Skip file header and start from first log block
-		_, err = r.file.Seek(LogFileHdrSize, io.SeekStart)
-		if err != nil {
-			return nil, fmt.Errorf("failed to seek to log blocks: %w", err)
-		}
-		r.position = LogFileHdrSize
 	} else {
 		// Use checkpoint LSN as the starting point for log analysis
 		r.baseLSN = r.lastCheckpoint.CheckpointLSN
 		r.currentLSN = r.lastCheckpoint.CheckpointLSN
-
-		// Position to start reading from checkpoint offset
-		startOffset := r.lastCheckpoint.Offset
-		if startOffset < LogFileHdrSize {
-			startOffset = LogFileHdrSize // Ensure we don't read before log blocks start
-		}
-
-		_, err = r.file.Seek(int64(startOffset), io.SeekStart)
-		if err != nil {
-			return nil, fmt.Errorf("failed to seek to checkpoint offset %d: %w", startOffset, err)
-		}
-		r.position = int64(startOffset)
 	}
 
 	// Create header with checkpoint or fallback information
@@ -267,8 +276,8 @@ func (r *MySQLRedoLogReader) ReadHeader() (*types.RedoLogHeader, error) {
 			StartLSN:       r.lastCheckpoint.CheckpointLSN,
 			FileNo:         1,
 			Created:        r.baseTimestamp,
-			LastCheckpoint: 0,
-			Format:         2, // Indicate MySQL format
+			LastCheckpoint: r.lastCheckpoint.CheckpointLSN,
+			Format:         r.version,
 		}
 	} else {
 		// Fallback header for test files without valid checkpoints
@@ -278,12 +287,26 @@ func (r *MySQLRedoLogReader) ReadHeader() (*types.RedoLogHeader, error) {
 			FileNo:         1,
 			Created:        r.baseTimestamp,
 			LastCheckpoint: 0,
-			Format:         2, // Indicate MySQL format
+			Format:         r.version,
+		}
+	}
+
+	// An xtrabackup_logfile starts at the block XtraBackup began copying,
+	// and each file of a file-per-range log at the LSN in its header
+	if r.xtrabackup != nil {
+		header.StartLSN = r.xtrabackup.StartLSN
+	} else if r.format.FilePerRange {
+		startLSN := make([]byte, 8)
+		if _, err := r.src.ReadAt(startLSN, LogHeaderStartLSN); err == nil && binary.BigEndian.Uint64(startLSN) != 0 {
+			header.StartLSN = binary.BigEndian.Uint64(startLSN)
 		}
 	}
 
-	// Reset to beginning of log data for record reading
-	r.file.Seek(LogFileHdrSize, io.SeekStart)
+	// Records are read from the first log block after the file header, so
+	// that a forward-only source never has to seek back
+	if _, err := r.src.Seek(LogFileHdrSize, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek to log blocks: %w", err)
+	}
 	r.position = LogFileHdrSize
 	
 	return header, nil
@@ -321,7 +344,7 @@ func (r *MySQLRedoLogReader) validateBlockChecksum(blockData []byte) error {
 	}
 	
 	// Extract stored checksum from trailer
-	storedChecksum := binary.LittleEndian.Uint32(blockData[OSFileLogBlockSize-LogBlockTrlSize:])
+	storedChecksum := binary.BigEndian.Uint32(blockData[OSFileLogBlockSize-LogBlockTrlSize:])
 	
 	// For real MySQL redo logs, checksum validation is complex and our simplified
 	// algorithm doesn't match MySQL's exactly. For now, we'll log mismatches but continue
@@ -341,7 +364,7 @@ func (r *MySQLRedoLogReader) validateBlockChecksum(blockData []byte) error {
 // readBlockHeader reads a 12-byte MySQL log block header
 func (r *MySQLRedoLogReader) readBlockHeader() (*MySQLLogBlockHeader, error) {
 	headerBytes := make([]byte, LogBlockHdrSize)
-	n, err := r.file.Read(headerBytes)
+	n, err := r.src.Read(headerBytes)
 	if err != nil {
 		return nil, err
 	}
@@ -350,14 +373,7 @@ func (r *MySQLRedoLogReader) readBlockHeader() (*MySQLLogBlockHeader, error) {
 	}
 	r.position += LogBlockHdrSize
 
-	header := &MySQLLogBlockHeader{
-		HdrNo:         binary.LittleEndian.Uint32(headerBytes[LogBlockHdrNo:LogBlockHdrNo+4]),
-		DataLen:       binary.BigEndian.Uint16(headerBytes[LogBlockHdrDataLen:LogBlockHdrDataLen+2]),    // Big endian!
-		FirstRecGroup: binary.BigEndian.Uint16(headerBytes[LogBlockFirstRecGroup:LogBlockFirstRecGroup+2]), // Big endian!
-		EpochNo:       binary.LittleEndian.Uint32(headerBytes[LogBlockEpochNo:LogBlockEpochNo+4]),
-	}
-
-	return header, nil
+	return decodeBlockHeader(headerBytes), nil
 }
 
 // ReadRecord reads the next log record
@@ -391,15 +407,18 @@ func (r *MySQLRedoLogReader) ReadRecord() (*types.LogRecord, error) {
 			// Read potential record type (first byte)
 			recordType := r.blockData[r.dataOffset]
 			
-			// Validate that this is a valid MySQL mlog_id_t value (1-76, excluding 0)
-			if recordType == 0 || recordType > 76 {
+			// Validate that this is an mlog_id_t value of the log's format
+			if !r.format.ValidType(recordType) {
 				// Skip this byte and continue searching for valid record type
 				r.dataOffset++
 				continue
 			}
 
 			// Found a valid record type, advance offset and continue parsing
+			r.recordStart = r.fileOffset(r.dataOffset)
+			r.spans = nil
 			r.dataOffset++
+			r.markSpan("type", types.SpanRecordType, r.dataOffset-1)
 			return r.parseValidRecord(recordType)
 		}
 		
@@ -408,93 +427,85 @@ func (r *MySQLRedoLogReader) ReadRecord() (*types.LogRecord, error) {
 	}
 }
 
-// parseCompressedUint64 parses MySQL's compressed integer format (mach_parse_u64_much_compressed)
-// Based on MySQL ut0rnd.h and mach0data.cc implementation
+// parseCompressedUint64 parses an integer written by
+// mach_u64_write_much_compressed: 0xFF followed by the high and the low 32
+// bits in the mach_write_compressed format, or the low 32 bits alone. It
+// reads 0 bytes when data is truncated or starts with 0xF8 to 0xFE, which
+// the server never writes (mach_u64_parse_much_compressed in mach0data.cc).
 func parseCompressedUint64(data []byte) (value uint64, bytesRead int) {
-	if len(data) == 0 {
+	if len(data) == 0 || data[0] != 0xFF {
+		low, n := parseCompressedUint32(data)
+		return uint64(low), n
+	}
+	high, n := parseCompressedUint32(data[1:])
+	if n == 0 {
 		return 0, 0
 	}
-	
-	firstByte := data[0]
-	
-	// MySQL compressed integer format analysis:
-	// If first byte < 0x80 (128), value is stored in 1 byte
-	if firstByte < 0x80 {
-		return uint64(firstByte), 1
+	low, m := parseCompressedUint32(data[1+n:])
+	if m == 0 {
+		return 0, 0
 	}
-	
-	// If first byte < 0xC0 (192), value uses 2 bytes
-	if firstByte < 0xC0 {
+	return uint64(high)<<32 | uint64(low), 1 + n + m
+}
+
+// parseCompressedUint32 parses an integer written by mach_write_compressed:
+// one to four big endian bytes, the leading one bits of the first counting
+// the bytes after it, or 0xF0 followed by the four bytes of the value. It
+// reads 0 bytes when data is truncated or starts with 0xF8 or more.
+func parseCompressedUint32(data []byte) (value uint32, bytesRead int) {
+	if len(data) == 0 {
+		return 0, 0
+	}
+	switch first := data[0]; {
+	case first < 0x80:
+		return uint32(first), 1
+	case first < 0xC0:
 		if len(data) < 2 {
 			return 0, 0
 		}
-		// Remove the 2-byte marker bits (0x80) and combine
-		value = uint64(firstByte&0x3F)<<8 | uint64(data[1])
-		return value, 2
-	}
-	
-	// If first byte < 0xE0 (224), value uses 3 bytes  
-	if firstByte < 0xE0 {
+		return uint32(binary.BigEndian.Uint16(data)) & 0x3FFF, 2
+	case first < 0xE0:
 		if len(data) < 3 {
 			return 0, 0
 		}
-		value = uint64(firstByte&0x1F)<<16 | uint64(data[1])<<8 | uint64(data[2])
-		return value, 3
-	}
-	
-	// If first byte < 0xF0 (240), value uses 4 bytes
-	if firstByte < 0xF0 {
+		return uint32(first&0x1F)<<16 | uint32(binary.BigEndian.Uint16(data[1:])), 3
+	case first < 0xF0:
 		if len(data) < 4 {
 			return 0, 0
 		}
-		value = uint64(firstByte&0x0F)<<24 | uint64(data[1])<<16 | uint64(data[2])<<8 | uint64(data[3])
-		return value, 4
-	}
-	
-	// If first byte < 0xF8 (248), value uses 5 bytes
-	if firstByte < 0xF8 {
+		return binary.BigEndian.Uint32(data) & 0x0FFFFFFF, 4
+	case first < 0xF8:
+		// The server only writes 0xF0 but does not check the low bits
 		if len(data) < 5 {
 			return 0, 0
 		}
-		value = uint64(firstByte&0x07)<<32 | uint64(data[1])<<24 | uint64(data[2])<<16 | uint64(data[3])<<8 | uint64(data[4])
-		return value, 5
-	}
-	
-	// For larger values, MySQL uses more complex encoding
-	// For now, handle up to 8-byte values
-	if firstByte == 0xFF {
-		if len(data) < 9 {
-			return 0, 0
-		}
-		// 8-byte value follows
-		value = binary.BigEndian.Uint64(data[1:9])
-		return value, 9
+		return binary.BigEndian.Uint32(data[1:]), 5
 	}
-	
-	// Fallback for other cases
-	return uint64(firstByte), 1
+	return 0, 0
 }
 
 // parseMLOG_REC_INSERT_8027 parses MLOG_REC_INSERT_8027 record based on MySQL source analysis
 // Structure: Space ID (compressed) + Page Number (compressed) + Index Info + Record Data
-func (r *MySQLRedoLogReader) parseMLOG_REC_INSERT_8027() []byte {
+func (r *MySQLRedoLogReader) parseMLOG_REC_INSERT_8027() ([]byte, error) {
 	startOffset := r.dataOffset
 	result := make([]string, 0)
 	
 	// Parse Space ID (compressed integer)
 	spaceID, spaceIDBytes := parseCompressedUint64(r.blockData[r.dataOffset:])
 	if spaceIDBytes == 0 {
-		return []byte("MLOG_REC_INSERT_8027: failed to parse space ID")
+		return []byte("MLOG_REC_INSERT_8027: failed to parse space ID"), nil
 	}
 	r.dataOffset += spaceIDBytes
+	r.markSpan("space_id", types.SpanCompressedInt, r.dataOffset-spaceIDBytes)
 	result = append(result, fmt.Sprintf("space_id=%d", spaceID))
 	
 	// Parse Page Number (compressed integer)
 	pageNo, pageNoBytes := parseCompressedUint64(r.blockData[r.dataOffset:])
 	if pageNoBytes == 0 {
-		return []byte("MLOG_REC_INSERT_8027: failed to parse page number")
+		return []byte("MLOG_REC_INSERT_8027: failed to parse page number"), nil
 	}
 	r.dataOffset += pageNoBytes
+	r.markSpan("page_no", types.SpanCompressedInt, r.dataOffset-pageNoBytes)
 	result = append(result, fmt.Sprintf("page_no=%d", pageNo))
 	
 	// Parse Index Information (mlog_parse_index_8027 format)
@@ -504,7 +515,10 @@ func (r *MySQLRedoLogReader) parseMLOG_REC_INSERT_8027() []byte {
 	}
 	
 	// Parse Record Data portion
-	recordInfo := r.parseRecordData8027()
+	recordInfo, err := r.parseRecordData8027()
+	if err != nil {
+		return nil, err
+	}
 	if len(recordInfo) > 0 {
 		result = append(result, recordInfo)
 	}
@@ -523,7 +537,7 @@ func (r *MySQLRedoLogReader) parseMLOG_REC_INSERT_8027() []byte {
 		result = append(result, fmt.Sprintf("parsed=(%s)", fieldParseResult))
 	}
 	
-	return []byte(strings.Join(result, " | "))
+	return []byte(strings.Join(result, " | ")), nil
 }
 
 // parseIndexInfo8027 parses the index information part of MLOG_REC_INSERT_8027
@@ -534,8 +548,9 @@ func (r *MySQLRedoLogReader) parseIndexInfo8027() string {
 	}
 	
 	// Parse n_fields (2 bytes) - may contain instant columns flag
-	nFields := binary.LittleEndian.Uint16(r.blockData[r.dataOffset:r.dataOffset+2])
+	nFields := binary.BigEndian.Uint16(r.blockData[r.dataOffset:r.dataOffset+2])
 	r.dataOffset += 2
+	r.markSpan("n_fields", types.SpanFixedInt, r.dataOffset-2)
 	
 	hasInstantCols := (nFields & 0x8000) != 0
 	actualNFields := nFields & 0x7FFF
@@ -547,14 +562,16 @@ func (r *MySQLRedoLogReader) parseIndexInfo8027() string {
 		result = append(result, "instant_cols=true")
 		// Parse additional instant column info if present
 		if r.dataOffset+2 <= len(r.blockData) {
-			nInstantCols := binary.LittleEndian.Uint16(r.blockData[r.dataOffset:r.dataOffset+2])
+			nInstantCols := binary.BigEndian.Uint16(r.blockData[r.dataOffset:r.dataOffset+2])
 			r.dataOffset += 2
+			r.markSpan("n_instant_cols", types.SpanFixedInt, r.dataOffset-2)
 			result = append(result, fmt.Sprintf("n_instant_cols=%d", nInstantCols))
 			
 			// Parse actual n_fields if different
 			if r.dataOffset+2 <= len(r.blockData) {
-				actualNFields = binary.LittleEndian.Uint16(r.blockData[r.dataOffset:r.dataOffset+2])
+				actualNFields = binary.BigEndian.Uint16(r.blockData[r.dataOffset:r.dataOffset+2])
 				r.dataOffset += 2
+				r.markSpan("actual_n_fields", types.SpanFixedInt, r.dataOffset-2)
 				result = append(result, fmt.Sprintf("actual_n_fields=%d", actualNFields))
 			}
 		}
@@ -562,8 +579,9 @@ func (r *MySQLRedoLogReader) parseIndexInfo8027() string {
 	
 	// Parse n_uniq (2 bytes)
 	if r.dataOffset+2 <= len(r.blockData) {
-		nUniq := binary.LittleEndian.Uint16(r.blockData[r.dataOffset:r.dataOffset+2])
+		nUniq := binary.BigEndian.Uint16(r.blockData[r.dataOffset:r.dataOffset+2])
 		r.dataOffset += 2
+		r.markSpan("n_uniq", types.SpanFixedInt, r.dataOffset-2)
 		result = append(result, fmt.Sprintf("n_uniq=%d", nUniq))
 	}
 	
@@ -575,8 +593,9 @@ func (r *MySQLRedoLogReader) parseIndexInfo8027() string {
 	
 	fields := make([]string, 0)
 	for i := 0; i < fieldCount && r.dataOffset+2 <= len(r.blockData); i++ {
-		fieldDesc := binary.LittleEndian.Uint16(r.blockData[r.dataOffset:r.dataOffset+2])
+		fieldDesc := binary.BigEndian.Uint16(r.blockData[r.dataOffset:r.dataOffset+2])
 		r.dataOffset += 2
+		r.markSpan(fmt.Sprintf("field_%d", i), types.SpanFixedInt, r.dataOffset-2)
 		
 		fieldLen := fieldDesc & 0x7FFF
 		notNull := (fieldDesc & 0x8000) != 0
@@ -597,23 +616,29 @@ func (r *MySQLRedoLogReader) parseIndexInfo8027() string {
 }
 
 // parseRecordData8027 parses the record data part of MLOG_REC_INSERT_8027
-// Based on page_cur_parse_insert_rec function from MySQL source
-func (r *MySQLRedoLogReader) parseRecordData8027() string {
+// Based on page_cur_parse_insert_rec function from MySQL source. Like the
+// server, it fails on records longer than a page.
+func (r *MySQLRedoLogReader) parseRecordData8027() (string, error) {
 	result := make([]string, 0)
 	
 	// Parse cursor_offset (2 bytes) - may not always be present
 	if r.dataOffset+2 <= len(r.blockData) {
-		cursorOffset := binary.LittleEndian.Uint16(r.blockData[r.dataOffset:r.dataOffset+2])
+		cursorOffset := binary.BigEndian.Uint16(r.blockData[r.dataOffset:r.dataOffset+2])
 		r.dataOffset += 2
+		r.markSpan("cursor_offset", types.SpanFixedInt, r.dataOffset-2)
 		result = append(result, fmt.Sprintf("cursor_offset=%d", cursorOffset))
 	}
 	
 	// Parse end_seg_len (compressed integer)
 	endSegLen, endSegLenBytes := parseCompressedUint64(r.blockData[r.dataOffset:])
 	if endSegLenBytes == 0 {
-		return "record_data=parse_failed"
+		return "record_data=parse_failed", nil
+	}
+	if endSegLen >= 2*UnivPageSizeMax {
+		return "", fmt.Errorf("end_seg_len %d exceeds the page size", endSegLen)
 	}
 	r.dataOffset += endSegLenBytes
+	r.markSpan("end_seg_len", types.SpanCompressedInt, r.dataOffset-endSegLenBytes)
 	result = append(result, fmt.Sprintf("end_seg_len=%d", endSegLen))
 	
 	// Check if there are info and status bits
@@ -622,6 +647,7 @@ func (r *MySQLRedoLogReader) parseRecordData8027() string {
 		if r.dataOffset+1 <= len(r.blockData) {
 			infoBits := r.blockData[r.dataOffset]
 			r.dataOffset += 1
+			r.markSpan("info_bits", types.SpanFixedInt, r.dataOffset-1)
 			result = append(result, fmt.Sprintf("info_bits=0x%02x", infoBits))
 		}
 		
@@ -629,6 +655,7 @@ func (r *MySQLRedoLogReader) parseRecordData8027() string {
 		originOffset, originOffsetBytes := parseCompressedUint64(r.blockData[r.dataOffset:])
 		if originOffsetBytes > 0 {
 			r.dataOffset += originOffsetBytes
+			r.markSpan("origin_offset", types.SpanCompressedInt, r.dataOffset-originOffsetBytes)
 			result = append(result, fmt.Sprintf("origin_offset=%d", originOffset))
 		}
 		
@@ -636,6 +663,7 @@ func (r *MySQLRedoLogReader) parseRecordData8027() string {
 		mismatchIndex, mismatchIndexBytes := parseCompressedUint64(r.blockData[r.dataOffset:])
 		if mismatchIndexBytes > 0 {
 			r.dataOffset += mismatchIndexBytes
+			r.markSpan("mismatch_index", types.SpanCompressedInt, r.dataOffset-mismatchIndexBytes)
 			result = append(result, fmt.Sprintf("mismatch_index=%d", mismatchIndex))
 		}
 	}
@@ -674,7 +702,7 @@ func (r *MySQLRedoLogReader) parseRecordData8027() string {
 		}
 	}
 	
-	return fmt.Sprintf("record_data=(%s)", strings.Join(result, ","))
+	return fmt.Sprintf("record_data=(%s)", strings.Join(result, ",")), nil
 }
 
 // InnoDB Record Data Type Constants (from data0type.h)
@@ -933,214 +961,62 @@ func sanitizeString(data []byte) string {
 	return string(result)
 }
 
-// parseValidRecord parses a record with a validated record type
+// parseValidRecord parses a record with a validated record type using the
+// decoder the log's format has for it
 func (r *MySQLRedoLogReader) parseValidRecord(recordType uint8) (*types.LogRecord, error) {
-	// Parse record structure based on actual MySQL format discovered from source
-	var recordLength uint32 = 1
-	var spaceID uint32 = 0
-	var pageNo uint32 = 0
-	var recordData []byte
-	
-	// Calculate realistic timestamp based on LSN progression
-	currentLSN := uint64(r.position + int64(r.dataOffset))
-	lsnDiff := currentLSN - r.baseLSN
-	relativeTimeMs := lsnDiff / 1000
-	recordTimestamp := r.baseTimestamp.Add(time.Duration(relativeTimeMs) * time.Millisecond)
-	
-	// Parse based on actual MySQL mlog record structure
-	remainingData := len(r.blockData) - r.dataOffset
-	
-	if remainingData >= 4 {
-		switch recordType {
-		case 1: // MLOG_1BYTE
-			// Format: offset(2) + value(1)
-			if remainingData >= 3 && r.dataOffset+3 <= len(r.blockData) {
-				offset := binary.LittleEndian.Uint16(r.blockData[r.dataOffset:r.dataOffset+2])
-				r.dataOffset += 2
-				value := r.blockData[r.dataOffset]
-				r.dataOffset += 1
-				recordLength += 3
-				recordData = []byte(fmt.Sprintf("offset=%d value=0x%02x", offset, value))
-			}
-			
-		case 2: // MLOG_2BYTES
-			// Format: offset(2) + value(2)
-			if remainingData >= 4 && r.dataOffset+4 <= len(r.blockData) {
-				offset := binary.LittleEndian.Uint16(r.blockData[r.dataOffset:r.dataOffset+2])
-				r.dataOffset += 2
-				value := binary.LittleEndian.Uint16(r.blockData[r.dataOffset:r.dataOffset+2])
-				r.dataOffset += 2
-				recordLength += 4
-				recordData = []byte(fmt.Sprintf("offset=%d value=0x%04x", offset, value))
-			}
-			
-		case 4: // MLOG_4BYTES
-			// Format: offset(2) + value(4)
-			if remainingData >= 6 && r.dataOffset+6 <= len(r.blockData) {
-				offset := binary.LittleEndian.Uint16(r.blockData[r.dataOffset:r.dataOffset+2])
-				r.dataOffset += 2
-				value := binary.LittleEndian.Uint32(r.blockData[r.dataOffset:r.dataOffset+4])
-				r.dataOffset += 4
-				recordLength += 6
-				recordData = []byte(fmt.Sprintf("offset=%d value=0x%08x", offset, value))
-			}
-			
-		case 8: // MLOG_8BYTES
-			// Format: offset(2) + value(8)
-			if remainingData >= 10 && r.dataOffset+10 <= len(r.blockData) {
-				offset := binary.LittleEndian.Uint16(r.blockData[r.dataOffset:r.dataOffset+2])
-				r.dataOffset += 2
-				value := binary.LittleEndian.Uint64(r.blockData[r.dataOffset:r.dataOffset+8])
-				r.dataOffset += 8
-				recordLength += 10
-				recordData = []byte(fmt.Sprintf("offset=%d value=0x%016x", offset, value))
-			}
-			
-		case 62: // MLOG_TABLE_DYNAMIC_META - contains actual Table ID
-			// Format: type + compressed_table_id + compressed_version + metadata
-			if remainingData >= 2 && r.dataOffset+2 <= len(r.blockData) {
-				// Parse compressed table ID
-				tableID, bytesRead := parseCompressedUint64(r.blockData[r.dataOffset:])
-				if bytesRead > 0 {
-					r.dataOffset += bytesRead
-					recordLength += uint32(bytesRead)
-					
-					// Parse compressed version
-					version, versionBytesRead := parseCompressedUint64(r.blockData[r.dataOffset:])
-					if versionBytesRead > 0 {
-						r.dataOffset += versionBytesRead
-						recordLength += uint32(versionBytesRead)
-						
-						// Try to read remaining metadata
-						remaining := len(r.blockData) - r.dataOffset
-						if remaining > 0 {
-							maxMetadata := remaining
-							if maxMetadata > 64 {
-								maxMetadata = 64
-							}
-							metadata := r.blockData[r.dataOffset:r.dataOffset+maxMetadata]
-							r.dataOffset += maxMetadata
-							recordLength += uint32(maxMetadata)
-							
-							recordData = []byte(fmt.Sprintf("table_id=%d version=%d metadata_len=%d", tableID, version, len(metadata)))
-						} else {
-							recordData = []byte(fmt.Sprintf("table_id=%d version=%d", tableID, version))
-						}
-						
-						// Set the actual table ID for this record
-						return &types.LogRecord{
-							Type:          types.LogType(recordType),
-							Length:        recordLength,
-							LSN:          uint64(r.position + int64(r.dataOffset)),
-							Timestamp:    recordTimestamp,
-							TransactionID: uint64(r.position),
-							TableID:      uint32(tableID), // Use extracted table ID
-							IndexID:      0,
-							Data:         recordData,
-							Checksum:     0,
-							SpaceID:      spaceID,
-							PageNo:       pageNo,
-							Offset:       0,
-						}, nil
-					}
-				}
-				// Fallback if parsing fails
-				recordData = []byte("table_dynamic_meta_parse_failed")
-			}
-			
-		case 9: // MLOG_REC_INSERT_8027 - detailed parsing based on MySQL source analysis
-			recordData = r.parseMLOG_REC_INSERT_8027()
-			recordLength = uint32(len(recordData))
-			
-		case 13, 14: // UPDATE, DELETE records
-			// These often contain space_id and page_no
-			if remainingData >= 8 && r.dataOffset+8 <= len(r.blockData) {
-				spaceID = binary.LittleEndian.Uint32(r.blockData[r.dataOffset:r.dataOffset+4])
-				r.dataOffset += 4
-				pageNo = binary.LittleEndian.Uint32(r.blockData[r.dataOffset:r.dataOffset+4])
-				r.dataOffset += 4
-				recordLength += 8
-				
-				// Try to parse additional data as potential string/row data
-				remainingAfterSpacePage := len(r.blockData) - r.dataOffset
-				if remainingAfterSpacePage > 0 {
-					extraDataLen := remainingAfterSpacePage
-					if extraDataLen > 128 { // Limit to reasonable size
-						extraDataLen = 128
-					}
-					
-					// Ensure we don't read beyond blockData bounds
-					if r.dataOffset+extraDataLen <= len(r.blockData) {
-						extraData := r.blockData[r.dataOffset:r.dataOffset+extraDataLen]
-						r.dataOffset += extraDataLen
-						recordLength += uint32(extraDataLen)
-						
-						// Try to extract readable strings from the data
-						readableData := extractReadableStrings(extraData)
-						if len(readableData) > 0 {
-							recordData = []byte(fmt.Sprintf("space=%d page=%d data=%s", spaceID, pageNo, readableData))
-						} else {
-							recordData = []byte(fmt.Sprintf("space=%d page=%d hex=%x", spaceID, pageNo, extraData))
-						}
-					} else {
-						recordData = []byte(fmt.Sprintf("space=%d page=%d", spaceID, pageNo))
-					}
-				} else {
-					recordData = []byte(fmt.Sprintf("space=%d page=%d", spaceID, pageNo))
-				}
-			}
-			
-		default:
-			// For unknown record types, read available data without assuming structure
-			// Don't assume offset+length format since many MLOG types have different structures
-			maxRead := remainingData
-			if maxRead > 32 { // Limit to reasonable size for unknown types
-				maxRead = 32
-			}
-			if maxRead > 0 && r.dataOffset+maxRead <= len(r.blockData) {
-				someData := r.blockData[r.dataOffset:r.dataOffset+maxRead]
-				r.dataOffset += maxRead
-				recordLength += uint32(maxRead)
-				
-				// Try to extract readable strings from the data
-				readableStr := extractReadableStrings(someData)
-				if len(readableStr) > 0 {
-					recordData = []byte(fmt.Sprintf("type_%d_data='%s'", recordType, readableStr))
-				} else {
-					recordData = []byte(fmt.Sprintf("type_%d_hex=%x", recordType, someData))
-				}
-			} else {
-				// No data available
-				recordData = []byte(fmt.Sprintf("type_%d_empty", recordType))
-			}
+	// Redo records carry no wall-clock time; Timestamp is left zero and
+	// analyzer.LSNClock maps LSNs to time from anchors instead
+	decoded := decodedRecord{length: 1}
+	if len(r.blockData)-r.dataOffset >= 4 {
+		decode, ok := r.format.decoders[recordType]
+		if !ok {
+			decode = decodeRaw
+		}
+		decode(r, recordType, &decoded)
+		if decoded.err != nil {
+			return nil, newDecodeError(r.recordStart, types.LogType(recordType), "%v", decoded.err)
 		}
 	} else {
 		// Not enough data for any structured parsing
-		recordData = []byte(fmt.Sprintf("type_%d_insufficient_data", recordType))
+		decoded.data = []byte(fmt.Sprintf("type_%d_insufficient_data", recordType))
 	}
 
 	record := &types.LogRecord{
-		Type:             types.LogType(recordType), // Store raw type for now
+		Type:             types.LogType(recordType),
 		LSN:              uint64(r.position + int64(r.dataOffset)),
-		Length:           recordLength,
+		Length:           decoded.length,
 		TransactionID:    0, // Not directly available in redo log records
-		Timestamp:        recordTimestamp, // Calculated based on LSN progression
-		TableID:          0, // Would need complex parsing to extract
-		SpaceID:          spaceID,
-		PageNo:           pageNo,
-		Data:             recordData,
+		TableID:          decoded.tableID,
+		SpaceID:          decoded.spaceID,
+		PageNo:           decoded.pageNo,
+		Data:             decoded.data,
 		Checksum:         r.currentBlock.Checksum,
+		FileOffset:       r.recordStart,
+		Spans:            r.spans,
 		MultiRecordGroup: 0,    // Will be set by post-processing
 		IsGroupStart:     false, // Will be set by post-processing
 		IsGroupEnd:       false, // Will be set by post-processing
 	}
 
-	// Note: r.dataOffset has already been advanced by the parsing logic above
+	// Note: r.dataOffset has already been advanced by the decoder
 	// Don't skip to end of block - continue parsing from current position
 	
 	return record, nil
 }
 
+// fileOffset returns the file offset of a byte in the current block's payload
+func (r *MySQLRedoLogReader) fileOffset(dataOffset int) int64 {
+	return r.blockStart + LogBlockHdrSize + int64(dataOffset)
+}
+
+// markSpan records the payload bytes from start up to the current data
+// offset as a field of the record being parsed
+func (r *MySQLRedoLogReader) markSpan(name string, kind types.SpanKind, start int) {
+	if r.dataOffset > start {
+		r.spans = append(r.spans, types.FieldSpan{Name: name, Kind: kind, Offset: r.fileOffset(start), Length: r.dataOffset - start})
+	}
+}
+
 // readDataAcrossBlocks reads data that may span across multiple blocks
 func (r *MySQLRedoLogReader) readDataAcrossBlocks(length int) ([]byte, error) {
 	if length <= 0 {
@@ -1171,6 +1047,7 @@ func (r *MySQLRedoLogReader) readDataAcrossBlocks(length int) ([]byte, error) {
 		// Copy data from current block
 		result = append(result, r.blockData[r.dataOffset:r.dataOffset+toRead]...)
 		r.dataOffset += toRead
+		r.markSpan("record", types.SpanPayload, r.dataOffset-toRead)
 		remaining -= toRead
 	}
 	
@@ -1181,14 +1058,21 @@ func (r *MySQLRedoLogReader) readDataAcrossBlocks(length int) ([]byte, error) {
 func (r *MySQLRedoLogReader) readNextBlock() error {
 	// Read entire block (512 bytes) at once for validation
 	blockBytes := make([]byte, OSFileLogBlockSize)
-	n, err := r.file.Read(blockBytes)
+	n, err := r.src.Read(blockBytes)
 	if err != nil {
 		return err
 	}
 	if n < OSFileLogBlockSize {
 		return io.EOF
 	}
+	r.blockStart = r.position
 	r.position += OSFileLogBlockSize
+	if isEncryptedBlock(blockBytes) {
+		if r.decrypter == nil {
+			return r.encryptedBlockError()
+		}
+		r.decrypter.decryptBlock(blockBytes)
+	}
 
 	// Validate block checksum first
 	err = r.validateBlockChecksum(blockBytes)
@@ -1199,13 +1083,9 @@ func (r *MySQLRedoLogReader) readNextBlock() error {
 	}
 
 	// Parse block header
-	// Note: MySQL redo log uses mixed endianness - some fields are big endian
-	header := &MySQLLogBlockHeader{
-		HdrNo:         binary.LittleEndian.Uint32(blockBytes[LogBlockHdrNo:LogBlockHdrNo+4]),
-		DataLen:       binary.BigEndian.Uint16(blockBytes[LogBlockHdrDataLen:LogBlockHdrDataLen+2]),    // Big endian!
-		FirstRecGroup: binary.BigEndian.Uint16(blockBytes[LogBlockFirstRecGroup:LogBlockFirstRecGroup+2]), // Big endian!
-		EpochNo:       binary.LittleEndian.Uint32(blockBytes[LogBlockEpochNo:LogBlockEpochNo+4]),
-		Checksum:      binary.LittleEndian.Uint32(blockBytes[OSFileLogBlockSize-LogBlockTrlSize:]),
+	header, err := ParseBlockHeader(blockBytes)
+	if err != nil {
+		return err
 	}
 	r.currentBlock = *header
 
@@ -1213,14 +1093,17 @@ func (r *MySQLRedoLogReader) readNextBlock() error {
 	// Real MySQL redo logs can have very small data_len values, especially early blocks
 	// Only treat data_len=0 as true end-of-log
 	if header.DataLen == 0 {
-		return fmt.Errorf("end of valid log data (data_len=%d)", header.DataLen)
+		return fmt.Errorf("%w (data_len=%d)", ErrEndOfLog, header.DataLen)
+	}
+	if r.xtrabackup != nil {
+		r.xtrabackup.blockEnd(r.blockStart, header.DataLen)
 	}
 	
 	// For very small data_len, we still process but may not have much payload
 	if header.DataLen < LogBlockHdrSize {
 		// This is unusual but can happen - the header reports less than header size
 		// Proceed with caution, there might be no actual payload
-		fmt.Printf("Warning: data_len (%d) is smaller than header size (%d)\n", header.DataLen, LogBlockHdrSize)
+		r.warn(fmt.Sprintf("block %d: data_len (%d) is smaller than header size (%d)", header.HdrNo, header.DataLen, LogBlockHdrSize))
 	}
 
 	// Extract data payload (skip header, before trailer)
@@ -1328,7 +1211,7 @@ func getRecordTypeName(recordType uint8) string {
 
 // Remaining methods for interface compatibility
 func (r *MySQLRedoLogReader) Seek(offset int64) error {
-	_, err := r.file.Seek(offset, io.SeekStart)
+	_, err := r.src.Seek(offset, io.SeekStart)
 	if err != nil {
 		return err
 	}
@@ -1337,23 +1220,88 @@ func (r *MySQLRedoLogReader) Seek(offset int64) error {
 }
 
 func (r *MySQLRedoLogReader) IsEOF() bool {
-	if r.file == nil {
+	if r.src == nil {
 		return true
 	}
-	// Try to read one byte ahead to check EOF
-	currentPos, _ := r.file.Seek(0, io.SeekCurrent)
-	_, err := r.file.Read(make([]byte, 1))
-	if err == io.EOF {
-		return true
+	// A stream's size is known once it has been read to the end
+	currentPos, err := r.src.Seek(0, io.SeekCurrent)
+	size := r.src.Size()
+	return err == nil && size >= 0 && currentPos >= size
+}
+
+// Checkpoints returns the checkpoint blocks read by ReadHeader, in file order
+func (r *MySQLRedoLogReader) Checkpoints() []*MySQLCheckpoint {
+	return r.checkpoints
+}
+
+// RedoFormat returns the format the log is decoded with
+func (r *MySQLRedoLogReader) RedoFormat() *RedoFormat {
+	return r.format
+}
+
+// XtraBackup describes an xtrabackup_logfile, or returns nil for redo logs
+// written by the server. Blocks and EndLSN cover the blocks read so far.
+func (r *MySQLRedoLogReader) XtraBackup() *XtraBackupLog {
+	return r.xtrabackup
+}
+
+// SetKeyring sets the keyring holding the master key of an encrypted log.
+// It must be called before ReadHeader.
+func (r *MySQLRedoLogReader) SetKeyring(keyring *Keyring) {
+	r.keyring = keyring
+}
+
+// Encryption returns the encryption info of the header, or nil when the
+// log is not encrypted
+func (r *MySQLRedoLogReader) Encryption() *EncryptionInfo {
+	return r.encryption
+}
+
+// readEncryptionInfo reads the encryption info of the header and, when a
+// keyring was given, decrypts the key of the log with its master key
+func (r *MySQLRedoLogReader) readEncryptionInfo() error {
+	block := make([]byte, OSFileLogBlockSize)
+	if _, err := r.src.ReadAt(block, LogEncryptionOffset); err != nil {
+		// Too short for a header block; reading the records will tell
+		return nil
+	}
+	info, err := ParseEncryptionInfo(block)
+	if err != nil {
+		return fmt.Errorf("failed to read encryption info: %w", err)
+	}
+	r.encryption = info
+	if info == nil || r.keyring == nil {
+		return nil
+	}
+	if r.decrypter, err = info.decrypter(r.keyring); err != nil {
+		return err
 	}
-	// Seek back
-	r.file.Seek(currentPos, io.SeekStart)
-	return false
+	return nil
+}
+
+// encryptedBlockError returns the error for an encrypted block that
+// cannot be decrypted
+func (r *MySQLRedoLogReader) encryptedBlockError() error {
+	if r.encryption == nil {
+		return fmt.Errorf("%w: block at offset %d is encrypted but the header has no encryption info", ErrEncryptedLog, r.blockStart)
+	}
+	return fmt.Errorf("%w: a keyring holding master key %s is needed", ErrEncryptedLog, r.encryption.MasterKeyName())
+}
+
+// Warnings returns the non-fatal problems noticed while reading so far
+func (r *MySQLRedoLogReader) Warnings() []string {
+	return r.warnings
+}
+
+// warn records a non-fatal problem instead of printing it, so that callers
+// producing machine-readable output keep stdout clean
+func (r *MySQLRedoLogReader) warn(message string) {
+	r.warnings = append(r.warnings, message)
 }
 
 func (r *MySQLRedoLogReader) Close() error {
-	if r.file != nil {
-		return r.file.Close()
+	if r.src != nil {
+		return closeSource(r.src)
 	}
 	return nil
 }
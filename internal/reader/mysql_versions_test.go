@@ -0,0 +1,239 @@
+package reader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+	"github.com/yamaru/innodb-redolog-tool/test/fixtures"
+)
+
+// insert8028 is an MLOG_REC_INSERT of MySQL 8.0.28 into space 5 page 4:
+// compact index of two fields, then the inserted record "abc"
+var insert8028 = []byte{
+	MLogRecInsert8028, 5, 4,
+	1, IndexLogCompact, 0x00, 0x02, 0x00, 0x01, 0x80, 0x04, 0x00, 0x00,
+	0x00, 0x63, 0x06, 'a', 'b', 'c',
+}
+
+func TestRedoFormatVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  uint32
+		records []byte
+		want    *RedoFormat
+		types   []types.LogType
+		check   func(t *testing.T, first *types.LogRecord)
+	}{
+		{
+			// MLOG_REC_INSERT (67) is past MLOG_BIGGEST_TYPE of 5.7 and skipped
+			name:    "5.7",
+			format:  LogHeaderFormat57,
+			records: []byte{MLogRecInsert8028, 1, 5, 3, 0x00, 0x10, 0x2B, MLogMultiRecEnd, 0},
+			want:    RedoFormat57,
+			types:   []types.LogType{1, MLogMultiRecEnd},
+			check: func(t *testing.T, first *types.LogRecord) {
+				assert.Equal(t, "space=5 page=3 offset=16 value=0x2b", string(first.Data))
+			},
+		},
+		{
+			name:    "8.0.27",
+			format:  LogHeaderFormat8019,
+			records: []byte{MLogTableDynamicMeta, 42, 1, 0xEE, 0xFF},
+			want:    RedoFormat80,
+			types:   []types.LogType{MLogTableDynamicMeta},
+			check: func(t *testing.T, first *types.LogRecord) {
+				assert.Equal(t, uint32(42), first.TableID)
+				assert.Equal(t, "table_id=42 version=1 metadata_len=2", string(first.Data))
+			},
+		},
+		{
+			name:    "8.0.28",
+			format:  LogHeaderFormat8028,
+			records: append(append([]byte{}, insert8028...), MLogMultiRecEnd, 0),
+			want:    RedoFormat8028,
+			types:   []types.LogType{MLogRecInsert8028, MLogMultiRecEnd},
+			check: func(t *testing.T, first *types.LogRecord) {
+				assert.Equal(t, uint32(5), first.SpaceID)
+				assert.Equal(t, uint32(4), first.PageNo)
+				assert.Equal(t, uint32(len(insert8028)), first.Length)
+				assert.Contains(t, string(first.Data), "index_info=(version=1,flags=0x01,n_fields=2,n_uniq=1,fields=[field_0(len=4,NOT_NULL),field_1(len=0,NULLABLE)])")
+				assert.Contains(t, string(first.Data), "cursor_offset=99")
+				assert.Contains(t, string(first.Data), "found_strings='abc'")
+			},
+		},
+		{
+			name:    "8.0.30 insert",
+			format:  LogHeaderFormat8030,
+			records: insert8028,
+			want:    RedoFormat8030,
+			types:   []types.LogType{MLogRecInsert8028},
+		},
+		{
+			name:   "8.0.30 versioned delete",
+			format: LogHeaderFormat8030,
+			records: []byte{
+				69, 5, 4,
+				1, IndexLogVersioned, 0x00, 0x01, 0x00, 0x01, 0x80, 0x04,
+				1, 0, 2, 1, 0,
+			},
+			want:  RedoFormat8030,
+			types: []types.LogType{69},
+			check: func(t *testing.T, first *types.LogRecord) {
+				assert.Equal(t, "space_id=5 | page_no=4 | index_info=(version=1,flags=0x02,n_fields=1,n_uniq=1,fields=[field_0(len=4,NOT_NULL)],versioned=[field_0(pos=2,added=1,dropped=0)])", string(first.Data))
+			},
+		},
+		{
+			// The next record starts right after the copied records
+			name:   "8.0.30 list end copy created",
+			format: LogHeaderFormat8030,
+			records: []byte{
+				71, 5, 4,
+				1, IndexLogCompact, 0x00, 0x01, 0x00, 0x01, 0x80, 0x04,
+				0x00, 0x00, 0x00, 0x03, 'a', 'b', 'c',
+				1, 5, 3, 0x00, 0x10, 0x2B,
+			},
+			want:  RedoFormat8030,
+			types: []types.LogType{71, 1},
+			check: func(t *testing.T, first *types.LogRecord) {
+				assert.Equal(t, "space_id=5 | page_no=4 | index_info=(version=1,flags=0x01,n_fields=1,n_uniq=1,fields=[field_0(len=4,NOT_NULL)]) | log_data_len=3", string(first.Data))
+				assert.Equal(t, uint32(18), first.Length)
+			},
+		},
+		{
+			name:   "8.0.30 page reorganize",
+			format: LogHeaderFormat8030,
+			records: []byte{
+				72, 5, 4, 1, IndexLogCompact, 0x00, 0x01, 0x00, 0x01, 0x80, 0x04,
+				73, 5, 4, 1, IndexLogCompact, 0x00, 0x01, 0x00, 0x01, 0x80, 0x04, 6,
+				74, 5, 4, 1, IndexLogCompact, 0x00, 0x01, 0x00, 0x01, 0x80, 0x04, 6,
+				1, 5, 3, 0x00, 0x10, 0x2B,
+			},
+			want:  RedoFormat8030,
+			types: []types.LogType{72, 73, 74, 1},
+			check: func(t *testing.T, first *types.LogRecord) {
+				assert.Equal(t, uint32(11), first.Length)
+			},
+		},
+	}
+
+	const startLSN = 29480960
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename, err := fixtures.CreateMySQLLogFile(t.TempDir(), tt.format, startLSN, tt.records)
+			require.NoError(t, err)
+
+			// The creator marks the small file as a MySQL redo log
+			r, err := NewReaderForFile(filename)
+			require.NoError(t, err)
+			mysql, ok := r.(*MySQLRedoLogReader)
+			require.True(t, ok)
+			require.NoError(t, mysql.Open(filename))
+			defer mysql.Close()
+			assert.Same(t, tt.want, mysql.RedoFormat())
+
+			header, err := mysql.ReadHeader()
+			require.NoError(t, err)
+			assert.Equal(t, tt.format, header.Format)
+			assert.Equal(t, uint64(startLSN), header.StartLSN)
+
+			records, err := ReadRecords(mysql, 0)
+			require.NoError(t, err)
+			var got []types.LogType
+			for _, record := range records {
+				got = append(got, record.Type)
+			}
+			assert.Equal(t, tt.types, got)
+			assert.Empty(t, mysql.Warnings())
+			if tt.check != nil && len(records) > 0 {
+				tt.check(t, records[0])
+			}
+		})
+	}
+}
+
+func TestUnknownRedoFormat(t *testing.T) {
+	filename, err := fixtures.CreateMySQLLogFile(t.TempDir(), 0, 8192, []byte{1, 5, 3, 0x00, 0x10, 0x2B, MLogMultiRecEnd})
+	require.NoError(t, err)
+
+	r := NewMySQLRedoLogReader()
+	require.NoError(t, r.Open(filename))
+	defer r.Close()
+	assert.Same(t, RedoFormat8030, r.RedoFormat())
+	require.NotEmpty(t, r.Warnings())
+	assert.Contains(t, r.Warnings()[0], "unknown log format 0")
+}
+
+func TestRedoFormatTypeName(t *testing.T) {
+	assert.Same(t, RedoFormat80, LookupRedoFormat(LogHeaderFormat801))
+	assert.Nil(t, LookupRedoFormat(0))
+	assert.Nil(t, LookupRedoFormat(LogHeaderFormat8030+1))
+
+	tests := []struct {
+		format *RedoFormat
+		lt     types.LogType
+		want   string
+	}{
+		{RedoFormat57, 9, "MLOG_REC_INSERT"},
+		{RedoFormat57, 55, "MLOG_FILE_NAME"},
+		{RedoFormat57, 62, "INVALID_MLOG_62 (exceeds MLOG_BIGGEST_TYPE=61)"},
+		{RedoFormat80, 38, "MLOG_COMP_REC_INSERT"},
+		{RedoFormat80, 62, "MLOG_TABLE_DYNAMIC_META"},
+		{RedoFormat80, 67, "INVALID_MLOG_67 (exceeds MLOG_BIGGEST_TYPE=66)"},
+		{RedoFormat8028, 9, "MLOG_REC_INSERT_8027"},
+		{RedoFormat8030, 67, "MLOG_REC_INSERT"},
+		{RedoFormat8030, 0, "INVALID_MLOG_0 (should not exist)"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.format.TypeName(tt.lt), "%s type %d", tt.format, uint8(tt.lt))
+	}
+}
+
+func TestIndexLogVersionMismatch(t *testing.T) {
+	// Version 77 is no index layout: the bytes are read raw, not as an insert
+	records := append([]byte{MLogRecInsert8028, 5, 4, 77, IndexLogCompact}, make([]byte, 8)...)
+	filename, err := fixtures.CreateMySQLLogFile(t.TempDir(), LogHeaderFormat8030, 8192, records)
+	require.NoError(t, err)
+
+	r := NewMySQLRedoLogReader()
+	require.NoError(t, r.Open(filename))
+	defer r.Close()
+	_, err = r.ReadHeader()
+	require.NoError(t, err)
+	record, err := r.ReadRecord()
+	require.NoError(t, err)
+	assert.Equal(t, types.LogType(MLogRecInsert8028), record.Type)
+	assert.Equal(t, uint32(0), record.SpaceID)
+	assert.Equal(t, "type_67_hex=05044d010000000000000000", string(record.Data))
+}
+
+func TestFixedWriteRecords(t *testing.T) {
+	// Page IDs and values are compressed, the page offset is big endian
+	records := []byte{
+		2, 0x81, 0x2C, 3, 0x00, 0x40, 0x92, 0x34, // MLOG_2BYTES space 300 page 3
+		8, 5, 3, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, // MLOG_8BYTES space 5 page 3
+		MLogRecUpdateInPlace, 0x81, 0x2C, 0x84, 0x00, 'a', 'b', 'c', // space 300 page 1024
+	}
+	filename, err := fixtures.CreateMySQLLogFile(t.TempDir(), LogHeaderFormat801, 8192, records)
+	require.NoError(t, err)
+
+	r := NewMySQLRedoLogReader()
+	require.NoError(t, r.Open(filename))
+	defer r.Close()
+	_, err = r.ReadHeader()
+	require.NoError(t, err)
+	got, err := ReadRecords(r, 0)
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+
+	assert.Equal(t, "space=300 page=3 offset=64 value=0x1234", string(got[0].Data))
+	assert.Equal(t, uint32(8), got[0].Length)
+	assert.Equal(t, uint32(300), got[0].SpaceID)
+	assert.Equal(t, "space=5 page=3 offset=256 value=0x0000000100000002", string(got[1].Data))
+	assert.Equal(t, uint32(10), got[1].Length)
+	assert.Equal(t, uint32(300), got[2].SpaceID)
+	assert.Equal(t, uint32(1024), got[2].PageNo)
+	assert.Contains(t, string(got[2].Data), "space=300 page=1024 data=abc")
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
-	"os"
 	"time"
 	
 	"github.com/yamaru/innodb-redolog-tool/internal/types"
@@ -12,8 +11,8 @@ import (
 
 // redoLogReader implements RedoLogReader interface
 type redoLogReader struct {
-	file *os.File
-	eof  bool
+	src Source
+	eof bool
 }
 
 // NewRedoLogReader creates a new RedoLogReader instance
@@ -23,29 +22,35 @@ func NewRedoLogReader() RedoLogReader {
 
 // Open opens a redo log file
 func (r *redoLogReader) Open(filename string) error {
-	file, err := os.Open(filename)
+	src, err := NewFileSource(filename)
 	if err != nil {
 		return err
 	}
-	r.file = file
+	return r.OpenSource(src)
+}
+
+// OpenSource reads the redo log from src
+func (r *redoLogReader) OpenSource(src Source) error {
+	r.src = src
+	r.eof = false
 	return nil
 }
 
 // ReadHeader reads the header from the redo log file
 func (r *redoLogReader) ReadHeader() (*types.RedoLogHeader, error) {
-	if r.file == nil {
+	if r.src == nil {
 		return nil, fmt.Errorf("file not opened")
 	}
 	
 	// Seek to beginning of file
-	_, err := r.file.Seek(0, 0)
+	_, err := r.src.Seek(0, 0)
 	if err != nil {
 		return nil, fmt.Errorf("failed to seek to file beginning: %w", err)
 	}
 	
 	// Read header (64 bytes)
 	headerBytes := make([]byte, 64)
-	n, err := r.file.Read(headerBytes)
+	n, err := r.src.Read(headerBytes)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read header: %w", err)
 	}
@@ -68,13 +73,18 @@ func (r *redoLogReader) ReadHeader() (*types.RedoLogHeader, error) {
 
 // ReadRecord reads the next record from the redo log file
 func (r *redoLogReader) ReadRecord() (*types.LogRecord, error) {
-	if r.file == nil {
+	if r.src == nil {
 		return nil, fmt.Errorf("file not opened")
 	}
 	
+	recordStart, err := r.src.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get record position: %w", err)
+	}
+	
 	// Read record type (1 byte)
 	typeBytes := make([]byte, 1)
-	n, err := r.file.Read(typeBytes)
+	n, err := r.src.Read(typeBytes)
 	if err != nil {
 		if err == io.EOF {
 			r.eof = true
@@ -88,7 +98,7 @@ func (r *redoLogReader) ReadRecord() (*types.LogRecord, error) {
 	
 	// Read record length (4 bytes)
 	lengthBytes := make([]byte, 4)
-	n, err = r.file.Read(lengthBytes)
+	n, err = r.src.Read(lengthBytes)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read record length: %w", err)
 	}
@@ -101,12 +111,16 @@ func (r *redoLogReader) ReadRecord() (*types.LogRecord, error) {
 	
 	// Read remaining record data (total length - type(1) - length(4))
 	remainingSize := int(recordLength) - 5
-	if remainingSize <= 0 {
-		return nil, fmt.Errorf("invalid record length: %d", recordLength)
+	if remainingSize < 42 {
+		// Shorter than the fixed fields following the length
+		return nil, newDecodeError(recordStart, recordType, "invalid record length: %d", recordLength)
+	}
+	if size := r.src.Size(); size >= 0 && int64(remainingSize) > size-recordStart-5 {
+		return nil, newDecodeError(recordStart, recordType, "record length %d runs past the end of the file", recordLength)
 	}
 	
 	remainingBytes := make([]byte, remainingSize)
-	n, err = r.file.Read(remainingBytes)
+	n, err = r.src.Read(remainingBytes)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read record data: %w", err)
 	}
@@ -142,13 +156,48 @@ func (r *redoLogReader) ReadRecord() (*types.LogRecord, error) {
 		record.Checksum = binary.LittleEndian.Uint32(remainingBytes[checksumStart : checksumStart+4])
 	}
 	
+	record.FileOffset = recordStart
+	record.Spans = fixtureRecordSpans(recordStart, len(record.Data))
+	
 	return record, nil
 }
 
+// fixtureRecordLayout lists the fixed fields of a record in this format
+var fixtureRecordLayout = []struct {
+	name   string
+	kind   types.SpanKind
+	length int
+}{
+	{"type", types.SpanRecordType, 1},
+	{"length", types.SpanFixedInt, 4},
+	{"lsn", types.SpanFixedInt, 8},
+	{"timestamp", types.SpanFixedInt, 8},
+	{"trx_id", types.SpanFixedInt, 8},
+	{"table_id", types.SpanFixedInt, 4},
+	{"index_id", types.SpanFixedInt, 4},
+	{"space_id", types.SpanFixedInt, 4},
+	{"page_no", types.SpanFixedInt, 4},
+	{"offset", types.SpanFixedInt, 2},
+}
+
+// fixtureRecordSpans returns the field spans of a record starting at offset
+func fixtureRecordSpans(offset int64, dataLength int) []types.FieldSpan {
+	spans := make([]types.FieldSpan, 0, len(fixtureRecordLayout)+2)
+	for _, field := range fixtureRecordLayout {
+		spans = append(spans, types.FieldSpan{Name: field.name, Kind: field.kind, Offset: offset, Length: field.length})
+		offset += int64(field.length)
+	}
+	if dataLength > 0 {
+		spans = append(spans, types.FieldSpan{Name: "data", Kind: types.SpanPayload, Offset: offset, Length: dataLength})
+		offset += int64(dataLength)
+	}
+	return append(spans, types.FieldSpan{Name: "checksum", Kind: types.SpanFixedInt, Offset: offset, Length: 4})
+}
+
 // Seek sets the file position for the next read operation
 func (r *redoLogReader) Seek(offset int64) error {
 	// TODO: Implement actual seek operation
-	_, err := r.file.Seek(offset, 0)
+	_, err := r.src.Seek(offset, 0)
 	return err
 }
 
@@ -159,8 +208,8 @@ func (r *redoLogReader) IsEOF() bool {
 
 // Close closes the redo log file
 func (r *redoLogReader) Close() error {
-	if r.file != nil {
-		return r.file.Close()
+	if r.src != nil {
+		return closeSource(r.src)
 	}
 	return nil
 }
\ No newline at end of file
@@ -1,8 +1,10 @@
 package reader
 
 import (
+	"encoding/binary"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
@@ -256,4 +258,172 @@ func TestEdgeCases(t *testing.T) {
 		// err := reader.Seek(1000)
 		// assert.Error(t, err)
 	})
-}
\ No newline at end of file
+}
+func TestReadRecords(t *testing.T) {
+	filename, err := fixtures.CreateSampleLogFile(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := NewReaderForFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	if err := reader.Open(filename); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reader.ReadHeader(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := ReadRecords(reader, 0)
+	if err != nil {
+		t.Fatalf("ReadRecords() returned error: %v", err)
+	}
+	if len(records) != 3 {
+		t.Errorf("ReadRecords() returned %d records, want 3", len(records))
+	}
+}
+
+func TestDetectMultiRecordGroups(t *testing.T) {
+	records := []*types.LogRecord{
+		{Type: types.LogType(9)},
+		{Type: types.LogType(1)},
+		{Type: types.LogType(MLogMultiRecEnd)},
+		{Type: types.LogType(2)},
+	}
+
+	DetectMultiRecordGroups(records)
+
+	if records[0].MultiRecordGroup != 1 || !records[0].IsGroupStart {
+		t.Errorf("first record should start group 1, got group=%d start=%v", records[0].MultiRecordGroup, records[0].IsGroupStart)
+	}
+	if records[2].MultiRecordGroup != 1 || !records[2].IsGroupEnd {
+		t.Errorf("MLOG_MULTI_REC_END should end group 1, got group=%d end=%v", records[2].MultiRecordGroup, records[2].IsGroupEnd)
+	}
+	if records[3].MultiRecordGroup != 0 {
+		t.Errorf("trailing record should be ungrouped, got group=%d", records[3].MultiRecordGroup)
+	}
+}
+
+func TestRecordSpansFixtureFormat(t *testing.T) {
+	filename, err := fixtures.CreateSampleLogFile(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewRedoLogReader()
+	defer reader.Close()
+	if err := reader.Open(filename); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reader.ReadHeader(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := ReadRecords(reader, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, record := range records {
+		start, end, ok := record.ByteRange()
+		if !ok || start != record.FileOffset {
+			t.Errorf("record %d: range starts at %d, want %d", i, start, record.FileOffset)
+		}
+		if end-start != int64(record.Length) {
+			t.Errorf("record %d: spans cover %d bytes, want %d", i, end-start, record.Length)
+		}
+		if i > 0 {
+			_, prevEnd, _ := records[i-1].ByteRange()
+			if start != prevEnd {
+				t.Errorf("record %d starts at %d, previous record ends at %d", i, start, prevEnd)
+			}
+		}
+	}
+}
+
+func TestRecordSpansBlockFormat(t *testing.T) {
+	block := make([]byte, OSFileLogBlockSize)
+	block[LogBlockHdrNo+3] = 1
+	binary.BigEndian.PutUint16(block[LogBlockHdrDataLen:], 20)
+	binary.BigEndian.PutUint16(block[LogBlockFirstRecGroup:], LogBlockHdrSize)
+	copy(block[LogBlockHdrSize:], []byte{1, 5, 3, 0x00, 0x10, 0x2B, MLogMultiRecEnd, 0, 0, 0})
+
+	filename := filepath.Join(t.TempDir(), "ib_logfile0")
+	if err := os.WriteFile(filename, append(make([]byte, LogFileHdrSize), block...), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewMySQLRedoLogReader()
+	defer reader.Close()
+	if err := reader.Open(filename); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reader.ReadHeader(); err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := reader.ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordStart := int64(LogFileHdrSize + LogBlockHdrSize)
+	want := []types.FieldSpan{
+		{Name: "type", Kind: types.SpanRecordType, Offset: recordStart, Length: 1},
+		{Name: "space_id", Kind: types.SpanCompressedInt, Offset: recordStart + 1, Length: 1},
+		{Name: "page_no", Kind: types.SpanCompressedInt, Offset: recordStart + 2, Length: 1},
+		{Name: "offset", Kind: types.SpanFixedInt, Offset: recordStart + 3, Length: 2},
+		{Name: "value", Kind: types.SpanCompressedInt, Offset: recordStart + 5, Length: 1},
+	}
+	if record.FileOffset != recordStart || !reflect.DeepEqual(record.Spans, want) {
+		t.Errorf("MLOG_1BYTE at %d has spans %+v, want %+v", record.FileOffset, record.Spans, want)
+	}
+
+	offset, data, err := ReadBlocks(filename, record.FileOffset, record.FileOffset+4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset != LogFileHdrSize || len(data) != OSFileLogBlockSize {
+		t.Fatalf("ReadBlocks() returned offset %d and %d bytes", offset, len(data))
+	}
+	header, err := ParseBlockHeader(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.HdrNo != 1 || header.DataLen != 20 || header.FirstRecGroup != LogBlockHdrSize {
+		t.Errorf("ParseBlockHeader() = %+v", header)
+	}
+	spans := BlockSpans(offset)
+	if spans[1].Name != "data_len" || spans[1].Offset != LogFileHdrSize+LogBlockHdrDataLen || spans[4].Offset != LogFileHdrSize+508 {
+		t.Errorf("BlockSpans() = %+v", spans)
+	}
+
+	if _, _, err := ReadBlocks(filename, 1<<20, 1<<20+1); err == nil {
+		t.Error("ReadBlocks() past the end of the file should fail")
+	}
+}
+
+func TestBlockLSN(t *testing.T) {
+	const period = (LogBlockHdrNoMask + 1) * OSFileLogBlockSize
+	header := make([]byte, LogBlockHdrSize)
+	tests := []struct {
+		hdrNo uint32
+		near  uint64
+		want  uint64
+	}{
+		{17, 8192, 8192},
+		{17 | 0x80000000, 1 << 20, 8192}, // Flush bit
+		{21, 9216, 10240},
+		{1, period - 512, period},                     // Numbers wrap after 512 GiB
+		{LogBlockHdrNoMask + 1, period, period - 512}, // Block 0x40000000 precedes block 1
+		{17, 3*period + 8192, 3*period + 8192},
+	}
+	for _, tt := range tests {
+		binary.BigEndian.PutUint32(header, tt.hdrNo)
+		if got := BlockLSN(header, tt.near); got != tt.want {
+			t.Errorf("BlockLSN(%#x, %d) = %d, want %d", tt.hdrNo, tt.near, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package reader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// ErrNotSeekable is returned when a stream source is asked to go back to
+// bytes it no longer holds
+var ErrNotSeekable = errors.New("source cannot seek backwards")
+
+// StreamHeadSize is how much of the start of a stream source is kept, so
+// that the file header and checkpoint blocks can be read again
+const StreamHeadSize = LogFileHdrSize
+
+// Source is the content of one redo log file. Readers read it sequentially
+// and use ReadAt and Seek only for the file header, so a forward-only
+// stream will do.
+type Source interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+
+	// Size returns the length of the source, or -1 while it is not known
+	Size() int64
+}
+
+// fileSource is a Source reading an open file
+type fileSource struct {
+	*os.File
+	size int64
+}
+
+// NewFileSource opens filename as a Source. Close the source to close the
+// file.
+func NewFileSource(filename string) (Source, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &fileSource{File: file, size: info.Size()}, nil
+}
+
+// Size returns the size of the file when it was opened
+func (s *fileSource) Size() int64 {
+	return s.size
+}
+
+// NewReaderAtSource returns a Source reading the first size bytes of r
+func NewReaderAtSource(r io.ReaderAt, size int64) Source {
+	return io.NewSectionReader(r, 0, size)
+}
+
+// NewBytesSource returns a Source reading a redo log held in memory
+func NewBytesSource(data []byte) Source {
+	return bytes.NewReader(data)
+}
+
+// streamSource is a Source reading a forward-only stream. The first
+// StreamHeadSize bytes are kept; past them it can only seek forward.
+type streamSource struct {
+	r      io.Reader
+	head   []byte
+	whole  bool // The stream ended within head
+	pos    int64
+	eof    bool      // r has returned io.EOF
+	closer io.Closer // Closes what r reads from, if set by OpenInput
+}
+
+// NewStreamSource returns a Source reading r forward-only, such as stdin or
+// a pipe. The start of the stream is read before it returns.
+func NewStreamSource(r io.Reader) (Source, error) {
+	s := &streamSource{r: r, head: make([]byte, StreamHeadSize)}
+	n, err := io.ReadFull(r, s.head)
+	s.head = s.head[:n]
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		s.whole, s.eof = true, true
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+	return s, nil
+}
+
+// Read reads like a file does: it returns fewer bytes than asked for only
+// at the end of the stream
+func (s *streamSource) Read(p []byte) (int, error) {
+	n := 0
+	if s.pos < int64(len(s.head)) {
+		n = copy(p, s.head[s.pos:])
+		s.pos += int64(n)
+	}
+	for n < len(p) && !s.eof {
+		m, err := s.r.Read(p[n:])
+		n += m
+		s.pos += int64(m)
+		if err == io.EOF {
+			s.eof = true
+		} else if err != nil {
+			return n, err
+		}
+	}
+	if n == 0 && len(p) > 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// ReadAt reads from the kept start of the stream
+func (s *streamSource) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset %d", off)
+	}
+	if off+int64(len(p)) > int64(len(s.head)) && !s.whole {
+		return 0, fmt.Errorf("%w: offset %d is past the first %d bytes", ErrNotSeekable, off, len(s.head))
+	}
+	if off >= int64(len(s.head)) {
+		return 0, io.EOF
+	}
+	n := copy(p, s.head[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Seek moves to an offset in the kept start of the stream or forward from
+// the current position, discarding the bytes in between
+func (s *streamSource) Seek(offset int64, whence int) (int64, error) {
+	target := offset
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		target += s.pos
+	case io.SeekEnd:
+		size := s.Size()
+		if size < 0 {
+			return s.pos, fmt.Errorf("%w: stream length is not known", ErrNotSeekable)
+		}
+		target += size
+	default:
+		return s.pos, fmt.Errorf("invalid whence %d", whence)
+	}
+	if target < 0 {
+		return s.pos, fmt.Errorf("negative offset %d", target)
+	}
+
+	if target < s.pos && s.pos > int64(len(s.head)) {
+		return s.pos, fmt.Errorf("%w: offset %d is before %d", ErrNotSeekable, target, s.pos)
+	}
+	if target <= int64(len(s.head)) || target <= s.pos {
+		s.pos = target
+		return s.pos, nil
+	}
+	if s.pos < int64(len(s.head)) {
+		s.pos = int64(len(s.head))
+	}
+	// A stream ending before target leaves the position at its end
+	if _, err := io.CopyN(io.Discard, s, target-s.pos); err != nil && err != io.EOF {
+		return s.pos, err
+	}
+	return s.pos, nil
+}
+
+// Size returns the length of the stream once it has been read to the end
+func (s *streamSource) Size() int64 {
+	if !s.eof {
+		return -1
+	}
+	if s.pos > int64(len(s.head)) {
+		return s.pos
+	}
+	return int64(len(s.head))
+}
+
+// Close closes the file and decoders a stream opened by OpenInput reads
+// from; streams from NewStreamSource are left open
+func (s *streamSource) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// closeSource closes src if it holds a resource
+func closeSource(src Source) error {
+	if closer, ok := src.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// sourceModTime returns the modification time of a file source, or the
+// zero time for other sources
+func sourceModTime(src Source) time.Time {
+	if file, ok := src.(interface{ Stat() (fs.FileInfo, error) }); ok {
+		if info, err := file.Stat(); err == nil {
+			return info.ModTime()
+		}
+	}
+	return time.Time{}
+}
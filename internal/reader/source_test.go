@@ -0,0 +1,120 @@
+package reader
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+func TestStreamSource(t *testing.T) {
+	data := make([]byte, StreamHeadSize+1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	src, err := NewStreamSource(iotest.HalfReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := src.Size(); size != -1 {
+		t.Errorf("Size() before the end = %d, want -1", size)
+	}
+
+	// The head can be read again and at any offset
+	buf := make([]byte, 4)
+	if n, err := src.ReadAt(buf, 512); n != 4 || err != nil || !bytes.Equal(buf, data[512:516]) {
+		t.Errorf("ReadAt(512) = %d, %v, % x", n, err, buf)
+	}
+	if _, err := src.ReadAt(buf, StreamHeadSize-2); !errors.Is(err, ErrNotSeekable) {
+		t.Errorf("ReadAt() past the head returned %v, want ErrNotSeekable", err)
+	}
+
+	// Reads cross from the head into the stream without short reads
+	if pos, err := src.Seek(StreamHeadSize-10, io.SeekStart); pos != StreamHeadSize-10 || err != nil {
+		t.Fatalf("Seek() = %d, %v", pos, err)
+	}
+	buf = make([]byte, 100)
+	if n, err := src.Read(buf); n != 100 || err != nil || !bytes.Equal(buf, data[StreamHeadSize-10:StreamHeadSize+90]) {
+		t.Errorf("Read() across the head = %d, %v", n, err)
+	}
+	if _, err := src.Seek(0, io.SeekStart); !errors.Is(err, ErrNotSeekable) {
+		t.Errorf("Seek() back past the head returned %v, want ErrNotSeekable", err)
+	}
+
+	// Seeking forward discards, and the size is known at the end
+	if pos, err := src.Seek(int64(len(data))-1, io.SeekStart); pos != int64(len(data))-1 || err != nil {
+		t.Fatalf("Seek() forward = %d, %v", pos, err)
+	}
+	buf = make([]byte, 10)
+	if n, err := src.Read(buf); n != 1 || err != nil || buf[0] != data[len(data)-1] {
+		t.Errorf("Read() at the end = %d, %v", n, err)
+	}
+	if n, err := src.Read(buf); n != 0 || err != io.EOF {
+		t.Errorf("Read() past the end = %d, %v, want io.EOF", n, err)
+	}
+	if size := src.Size(); size != int64(len(data)) {
+		t.Errorf("Size() at the end = %d, want %d", size, len(data))
+	}
+}
+
+func TestStreamSourceShort(t *testing.T) {
+	src, err := NewStreamSource(bytes.NewReader([]byte("short")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := src.Size(); size != 5 {
+		t.Errorf("Size() = %d, want 5", size)
+	}
+	buf := make([]byte, 10)
+	if n, err := src.ReadAt(buf, 2); n != 3 || err != io.EOF {
+		t.Errorf("ReadAt() = %d, %v, want 3, io.EOF", n, err)
+	}
+}
+
+func TestMySQLReaderSources(t *testing.T) {
+	block := make([]byte, OSFileLogBlockSize)
+	binary.BigEndian.PutUint16(block[LogBlockHdrDataLen:], 20)
+	binary.BigEndian.PutUint16(block[LogBlockFirstRecGroup:], LogBlockHdrSize)
+	copy(block[LogBlockHdrSize:], []byte{1, 5, 3, 0x00, 0x10, 0x2B, MLogMultiRecEnd, 0, 0, 0})
+	data := append(make([]byte, LogFileHdrSize), block...)
+	binary.BigEndian.PutUint32(data[LogHeaderFormat:], LogHeaderFormat8030)
+
+	stream, err := NewStreamSource(io.MultiReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := map[string]Source{
+		"bytes":    NewBytesSource(data),
+		"readerAt": NewReaderAtSource(bytes.NewReader(data), int64(len(data))),
+		"stream":   stream,
+	}
+	if _, ok := NewReaderForSource(stream).(*MySQLRedoLogReader); !ok {
+		t.Error("a stream of unknown length is not read as a MySQL log")
+	}
+	for name, src := range sources {
+		reader := NewMySQLRedoLogReader()
+		if err := reader.OpenSource(src); err != nil {
+			t.Fatalf("%s: OpenSource() = %v", name, err)
+		}
+		if reader.formatType != MySQLFormatModern {
+			t.Errorf("%s: format %d, want MySQLFormatModern", name, reader.formatType)
+		}
+		if _, err := reader.ReadHeader(); err != nil {
+			t.Fatalf("%s: ReadHeader() = %v", name, err)
+		}
+		records, err := ReadRecords(reader, 0)
+		if err != nil {
+			t.Fatalf("%s: ReadRecords() = %v", name, err)
+		}
+		if len(records) != 2 || records[0].Type != types.LogType(1) || records[0].FileOffset != LogFileHdrSize+LogBlockHdrSize {
+			t.Errorf("%s: read %d records, first %+v", name, len(records), records[0])
+		}
+		if !reader.IsEOF() {
+			t.Errorf("%s: IsEOF() = false after the last block", name)
+		}
+	}
+}
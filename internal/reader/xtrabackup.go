@@ -0,0 +1,188 @@
+package reader
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Header block fields of a redo log file, written by MySQL and by XtraBackup
+const (
+	LogHeaderStartLSN   = 8  // LSN of the first log block (8 bytes, big endian)
+	LogHeaderCreator    = 16 // Creator string, NUL padded
+	LogHeaderCreatorLen = 32
+)
+
+// XtraBackupCreator starts the creator field of an xtrabackup_logfile,
+// followed by the backup time
+const XtraBackupCreator = "xtrabkup"
+
+// XtraBackupCheckpointsFile is the metadata file XtraBackup writes next to
+// xtrabackup_logfile
+const XtraBackupCheckpointsFile = "xtrabackup_checkpoints"
+
+// XtraBackupLog describes the redo XtraBackup copied into an
+// xtrabackup_logfile. The copied blocks follow the 2048-byte file header
+// in LSN order, starting with the block holding StartLSN.
+type XtraBackupLog struct {
+	Creator  string `json:"creator"`   // Creator field, "xtrabkup" and the backup time
+	StartLSN uint64 `json:"start_lsn"` // LSN of the first copied block
+	EndLSN   uint64 `json:"end_lsn"`   // LSN just after the last copied byte
+	Blocks   int    `json:"blocks"`    // Copied blocks holding log data
+}
+
+// blockEnd records a copied block starting at file offset blockStart
+// holding dataLen bytes, header included
+func (l *XtraBackupLog) blockEnd(blockStart int64, dataLen uint16) {
+	if dataLen > OSFileLogBlockSize {
+		dataLen = OSFileLogBlockSize
+	}
+	l.Blocks++
+	l.EndLSN = l.StartLSN + uint64(blockStart-LogFileHdrSize) + uint64(dataLen)
+}
+
+// headerCreator returns the creator field of the header block of src, or
+// "" when it cannot be read
+func headerCreator(src Source) string {
+	creator := make([]byte, LogHeaderCreatorLen)
+	if _, err := src.ReadAt(creator, LogHeaderCreator); err != nil {
+		return ""
+	}
+	return strings.TrimRight(string(creator), "\x00 ")
+}
+
+// parseXtraBackupHeader returns the XtraBackupLog described by the header
+// block of src, or nil when src was not written by XtraBackup
+func parseXtraBackupHeader(src Source) *XtraBackupLog {
+	creator := headerCreator(src)
+	if !strings.HasPrefix(creator, XtraBackupCreator) {
+		return nil
+	}
+	lsn := make([]byte, 8)
+	if _, err := src.ReadAt(lsn, LogHeaderStartLSN); err != nil {
+		return nil
+	}
+	startLSN := binary.BigEndian.Uint64(lsn)
+	return &XtraBackupLog{
+		Creator:  creator,
+		StartLSN: startLSN - startLSN%OSFileLogBlockSize,
+		EndLSN:   startLSN - startLSN%OSFileLogBlockSize,
+	}
+}
+
+// ScanXtraBackupLog reads the header and every copied block of an
+// xtrabackup_logfile. It returns nil when src was not written by
+// XtraBackup.
+func ScanXtraBackupLog(src Source) (*XtraBackupLog, error) {
+	r := NewMySQLRedoLogReader()
+	if err := r.OpenSource(src); err != nil {
+		return nil, err
+	}
+	if _, err := r.ReadHeader(); err != nil {
+		return nil, err
+	}
+	if r.xtrabackup == nil {
+		return nil, nil
+	}
+	for {
+		if err := r.readNextBlock(); err != nil {
+			if IsEndOfLog(err) {
+				return r.xtrabackup, nil
+			}
+			return nil, fmt.Errorf("failed to read block at offset %d: %w", r.position, err)
+		}
+	}
+}
+
+// XtraBackupCheckpoints is the content of xtrabackup_checkpoints
+type XtraBackupCheckpoints struct {
+	BackupType string `json:"backup_type"`
+	FromLSN    uint64 `json:"from_lsn"`    // Start of an incremental backup, 0 for a full one
+	ToLSN      uint64 `json:"to_lsn"`      // Checkpoint LSN when the copy started; prepare recovers from it
+	LastLSN    uint64 `json:"last_lsn"`    // LSN the redo copy reached
+	FlushedLSN uint64 `json:"flushed_lsn"` // LSN flushed to disk when the backup ended
+}
+
+// ParseXtraBackupCheckpoints reads the "key = value" lines of
+// xtrabackup_checkpoints. Unknown keys are ignored.
+func ParseXtraBackupCheckpoints(r io.Reader) (*XtraBackupCheckpoints, error) {
+	checkpoints := &XtraBackupCheckpoints{}
+	lsns := map[string]*uint64{
+		"from_lsn":    &checkpoints.FromLSN,
+		"to_lsn":      &checkpoints.ToLSN,
+		"last_lsn":    &checkpoints.LastLSN,
+		"flushed_lsn": &checkpoints.FlushedLSN,
+	}
+	found := false
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value, got %q", line, text)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if key == "backup_type" {
+			checkpoints.BackupType = value
+			continue
+		}
+		if lsn, ok := lsns[key]; ok {
+			n, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid %s %q", line, key, value)
+			}
+			*lsn = n
+			found = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("no LSNs found")
+	}
+	return checkpoints, nil
+}
+
+// ReadXtraBackupCheckpoints reads an xtrabackup_checkpoints file, which may
+// be an archive member like any input of OpenInput
+func ReadXtraBackupCheckpoints(filename string) (*XtraBackupCheckpoints, error) {
+	src, err := OpenInput(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer closeSource(src)
+	checkpoints, err := ParseXtraBackupCheckpoints(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return checkpoints, nil
+}
+
+// CrossCheck compares the copied redo with xtrabackup_checkpoints and
+// returns the problems that would make a prepare fail or lose changes
+func (l *XtraBackupLog) CrossCheck(c *XtraBackupCheckpoints) []string {
+	var problems []string
+	if c.ToLSN < l.StartLSN {
+		problems = append(problems, fmt.Sprintf("to_lsn %d is before the first copied LSN %d: the redo prepare recovers from is missing", c.ToLSN, l.StartLSN))
+	}
+	if c.ToLSN > l.EndLSN {
+		problems = append(problems, fmt.Sprintf("to_lsn %d is after the last copied LSN %d", c.ToLSN, l.EndLSN))
+	}
+	if c.LastLSN > l.EndLSN {
+		problems = append(problems, fmt.Sprintf("last_lsn %d is after the last copied LSN %d: the redo copy is truncated", c.LastLSN, l.EndLSN))
+	}
+	if c.LastLSN < c.ToLSN {
+		problems = append(problems, fmt.Sprintf("last_lsn %d is before to_lsn %d", c.LastLSN, c.ToLSN))
+	}
+	if c.FromLSN > c.ToLSN {
+		problems = append(problems, fmt.Sprintf("from_lsn %d is after to_lsn %d", c.FromLSN, c.ToLSN))
+	}
+	return problems
+}
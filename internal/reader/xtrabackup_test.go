@@ -0,0 +1,77 @@
+package reader
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yamaru/innodb-redolog-tool/test/fixtures"
+)
+
+func TestXtraBackupLog(t *testing.T) {
+	dir := t.TempDir()
+	filename, err := fixtures.CreateXtraBackupLogFile(dir, 8804, 3, 112)
+	require.NoError(t, err)
+
+	src, err := OpenInput(filename)
+	require.NoError(t, err)
+	log, err := ScanXtraBackupLog(src)
+	closeSource(src)
+	require.NoError(t, err)
+	assert.Equal(t, &XtraBackupLog{Creator: "xtrabkup 240824 12:00:00", StartLSN: 8704, EndLSN: 8704 + 2*512 + 112, Blocks: 3}, log)
+
+	// The layout is recognised although the file is small
+	r, err := NewReaderForFile(filename)
+	require.NoError(t, err)
+	mysql, ok := r.(*MySQLRedoLogReader)
+	require.True(t, ok)
+	require.NoError(t, mysql.Open(filename))
+	defer mysql.Close()
+	header, err := mysql.ReadHeader()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(8704), header.StartLSN)
+	assert.Equal(t, uint64(8804), header.LastCheckpoint)
+	records, err := ReadRecords(mysql, 0)
+	require.NoError(t, err)
+	assert.NotEmpty(t, records)
+	assert.Equal(t, log, mysql.XtraBackup())
+
+	// Server redo logs are not xtrabackup logs
+	sample, err := fixtures.CreateSampleLogFile(dir)
+	require.NoError(t, err)
+	src, err = OpenInput(sample)
+	require.NoError(t, err)
+	defer closeSource(src)
+	log, err = ScanXtraBackupLog(src)
+	require.NoError(t, err)
+	assert.Nil(t, log)
+}
+
+func TestParseXtraBackupCheckpoints(t *testing.T) {
+	checkpoints, err := ParseXtraBackupCheckpoints(strings.NewReader("backup_type = full-backuped\nfrom_lsn = 0\nto_lsn = 18421920\nlast_lsn = 18421936\n\ncompact = 0\n"))
+	require.NoError(t, err)
+	assert.Equal(t, &XtraBackupCheckpoints{BackupType: "full-backuped", ToLSN: 18421920, LastLSN: 18421936}, checkpoints)
+
+	_, err = ParseXtraBackupCheckpoints(strings.NewReader("to_lsn = x\n"))
+	assert.ErrorContains(t, err, "invalid to_lsn")
+	_, err = ParseXtraBackupCheckpoints(strings.NewReader("to_lsn 5\n"))
+	assert.ErrorContains(t, err, "expected key = value")
+	_, err = ParseXtraBackupCheckpoints(strings.NewReader("backup_type = full-backuped\n"))
+	assert.ErrorContains(t, err, "no LSNs found")
+}
+
+func TestXtraBackupCrossCheck(t *testing.T) {
+	log := &XtraBackupLog{StartLSN: 8704, EndLSN: 9840, Blocks: 3}
+
+	assert.Empty(t, log.CrossCheck(&XtraBackupCheckpoints{ToLSN: 8804, LastLSN: 9840}))
+
+	problems := log.CrossCheck(&XtraBackupCheckpoints{ToLSN: 8804, LastLSN: 10240})
+	require.Len(t, problems, 1)
+	assert.Contains(t, problems[0], "the redo copy is truncated")
+
+	problems = log.CrossCheck(&XtraBackupCheckpoints{ToLSN: 8000, LastLSN: 9000})
+	require.Len(t, problems, 1)
+	assert.Contains(t, problems[0], "the redo prepare recovers from is missing")
+}
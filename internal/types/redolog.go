@@ -2,6 +2,7 @@ package types
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -41,6 +42,10 @@ type LogRecord struct {
 	PageNo  uint32
 	Offset  uint16
 	
+	// Location in the file; only set by readers that track byte positions
+	FileOffset int64       // Position of the record's type byte
+	Spans      []FieldSpan `json:"-"` // Bytes of each decoded field, in file order
+	
 	// Multi-record grouping
 	MultiRecordGroup int  // Group ID for multi-record transactions (0 = single record)
 	IsGroupStart     bool // True if this record starts a multi-record group
@@ -69,7 +74,9 @@ type RedoLogStats struct {
 	}
 }
 
-// String returns the string representation of LogType using MySQL 8.0 mlog_id_t definitions
+// String returns the string representation of LogType using the mlog_id_t
+// names of MySQL 8.0.28 and later, which suffix types replaced in 8.0.28
+// with _8027. reader.RedoFormat.TypeName gives the names of older formats.
 func (lt LogType) String() string {
 	recordType := uint8(lt)
 	switch recordType {
@@ -229,6 +236,9 @@ func (lt LogType) String() string {
 	case 0:
 		return "INVALID_MLOG_0 (should not exist)"
 	default:
+		if lt.IsMariaDB() {
+			return lt.mariadbString()
+		}
 		if recordType > 76 {
 			return fmt.Sprintf("INVALID_MLOG_%d (exceeds MLOG_BIGGEST_TYPE=76)", recordType)
 		}
@@ -248,6 +258,51 @@ func (lt LogType) IsTransactional() bool {
 	case 67, 68, 69, 70: // MLOG_REC_INSERT, MLOG_REC_CLUST_DELETE_MARK, MLOG_REC_DELETE, MLOG_REC_UPDATE_IN_PLACE
 		return true
 	default:
-		return false
+		// MariaDB row inserts and deletes
+		return lt.IsMariaDB() && lt.mariadbOperation() != OperationOther
+	}
+}
+// Row operation classes returned by LogType.Operation
+const (
+	OperationInsert = "insert"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+	OperationOther  = "other"
+)
+
+// Operation classifies the log type as an insert, update, delete or other operation
+func (lt LogType) Operation() string {
+	switch uint8(lt) {
+	// INSERT operations
+	case 9, 38, 67: // MLOG_REC_INSERT_8027, MLOG_COMP_REC_INSERT_8027, MLOG_REC_INSERT
+		return OperationInsert
+
+	// UPDATE operations
+	case 13, 41, 70: // MLOG_REC_UPDATE_IN_PLACE_8027, MLOG_COMP_REC_UPDATE_IN_PLACE_8027, MLOG_REC_UPDATE_IN_PLACE
+		return OperationUpdate
+
+	// DELETE operations
+	case 10, 11, 14, 15, 16, 39, 40, 42, 43, 44, 68, 69, 75, 76:
+		// MLOG_REC_CLUST_DELETE_MARK_8027, MLOG_REC_SEC_DELETE_MARK, MLOG_REC_DELETE_8027,
+		// MLOG_LIST_END_DELETE_8027, MLOG_LIST_START_DELETE_8027,
+		// MLOG_COMP_REC_CLUST_DELETE_MARK_8027, MLOG_COMP_REC_SEC_DELETE_MARK,
+		// MLOG_COMP_REC_DELETE_8027, MLOG_COMP_LIST_END_DELETE_8027, MLOG_COMP_LIST_START_DELETE_8027,
+		// MLOG_REC_CLUST_DELETE_MARK, MLOG_REC_DELETE, MLOG_LIST_END_DELETE, MLOG_LIST_START_DELETE
+		return OperationDelete
+
+	default:
+		return lt.mariadbOperation()
+	}
+}
+
+// ParseLogType returns the log type with the given mlog_id_t or MariaDB
+// record name, ignoring case
+func ParseLogType(name string) (LogType, bool) {
+	for id := 1; id <= 0xFF; id++ {
+		lt := LogType(id)
+		if strings.EqualFold(lt.String(), name) {
+			return lt, true
+		}
 	}
-}
\ No newline at end of file
+	return 0, false
+}
@@ -69,6 +69,56 @@ func TestLogType_IsTransactional(t *testing.T) {
 	}
 }
 
+func TestLogType_Operation(t *testing.T) {
+	tests := []struct {
+		logType   LogType
+		operation string
+	}{
+		{LogType(9), OperationInsert},  // MLOG_REC_INSERT_8027
+		{LogType(67), OperationInsert}, // MLOG_REC_INSERT
+		{LogType(41), OperationUpdate}, // MLOG_COMP_REC_UPDATE_IN_PLACE_8027
+		{LogType(70), OperationUpdate}, // MLOG_REC_UPDATE_IN_PLACE
+		{LogType(14), OperationDelete}, // MLOG_REC_DELETE_8027
+		{LogType(69), OperationDelete}, // MLOG_REC_DELETE
+		{LogType(1), OperationOther},   // MLOG_1BYTE
+		{LogType(31), OperationOther},  // MLOG_MULTI_REC_END
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.logType.String(), func(t *testing.T) {
+			assert.Equal(t, tt.operation, tt.logType.Operation())
+		})
+	}
+}
+
+func TestParseLogType(t *testing.T) {
+	lt, ok := ParseLogType("MLOG_COMP_REC_INSERT_8027")
+	assert.True(t, ok)
+	assert.Equal(t, LogType(38), lt)
+
+	lt, ok = ParseLogType("mlog_rec_insert")
+	assert.True(t, ok)
+	assert.Equal(t, LogType(67), lt)
+
+	lt, ok = ParseLogType("write")
+	assert.True(t, ok)
+	assert.Equal(t, MariaDBWrite, lt)
+
+	_, ok = ParseLogType("MLOG_NOT_A_TYPE")
+	assert.False(t, ok)
+}
+
+func TestMariaDBLogTypes(t *testing.T) {
+	assert.Equal(t, "FILE_CHECKPOINT", MariaDBFileCheckpoint.String())
+	assert.Equal(t, "TRIM_PAGES", MariaDBTrimPages.String())
+	assert.Equal(t, "INVALID_MLOG_140 (exceeds MLOG_BIGGEST_TYPE=76)", LogType(0x8C).String())
+	assert.Equal(t, OperationInsert, MariaDBInsertReuseDynamic.Operation())
+	assert.Equal(t, OperationDelete, MariaDBDeleteRowFormatRedundant.Operation())
+	assert.Equal(t, OperationOther, MariaDBWrite.Operation())
+	assert.True(t, MariaDBDeleteRowFormatDynamic.IsTransactional())
+	assert.False(t, MariaDBEndOfMTR.IsTransactional())
+}
+
 func TestLogRecord_Creation(t *testing.T) {
 	timestamp := time.Now()
 	record := &LogRecord{
@@ -150,4 +200,39 @@ func TestRedoLogStats_Creation(t *testing.T) {
 	assert.Equal(t, uint64(100), stats.RecordsByType[LogType(1)])   // 1BYTE
 	assert.Equal(t, startTime, stats.TimeRange.Start)
 	assert.Equal(t, endTime, stats.TimeRange.End)
-}
\ No newline at end of file
+}
+func TestRecordByteRange(t *testing.T) {
+	record := &LogRecord{}
+	_, _, ok := record.ByteRange()
+	assert.False(t, ok)
+
+	record.Spans = []FieldSpan{
+		{Name: "type", Kind: SpanRecordType, Offset: 2060, Length: 1},
+		{Name: "record", Kind: SpanPayload, Offset: 2061, Length: 435},
+		{Name: "record", Kind: SpanPayload, Offset: 2572, Length: 20},
+	}
+	start, end, ok := record.ByteRange()
+	assert.True(t, ok)
+	assert.Equal(t, int64(2060), start)
+	assert.Equal(t, int64(2592), end)
+
+	assert.True(t, record.Spans[1].Contains(2495))
+	assert.False(t, record.Spans[1].Contains(2496))
+	assert.Equal(t, "compressed int", SpanCompressedInt.String())
+}
+
+func TestRecordHasPage(t *testing.T) {
+	// Page 0 of the system tablespace is a page
+	assert.True(t, (&LogRecord{Type: 1, Spans: []FieldSpan{{Name: "type"}, {Name: "space_id"}, {Name: "page_no"}}}).HasPage())
+	assert.False(t, (&LogRecord{Type: 31, Spans: []FieldSpan{{Name: "type"}}}).HasPage())
+
+	// Without byte positions only a non-zero space or page is known
+	assert.True(t, (&LogRecord{Type: 9, SpaceID: 5}).HasPage())
+	assert.False(t, (&LogRecord{Type: 9}).HasPage())
+
+	// MariaDB records repeating the page of the previous one have no page_no
+	assert.True(t, (&LogRecord{Type: MariaDBWrite}).HasPage())
+	assert.True(t, (&LogRecord{Type: MariaDBInsertHeapDynamic}).HasPage())
+	assert.False(t, (&LogRecord{Type: MariaDBFileCreate, Spans: []FieldSpan{{Name: "page_no"}}}).HasPage())
+	assert.False(t, (&LogRecord{Type: MariaDBEndOfMTR}).HasPage())
+}
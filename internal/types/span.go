@@ -0,0 +1,86 @@
+package types
+
+// SpanKind classifies the bytes covered by a FieldSpan
+type SpanKind uint8
+
+const (
+	SpanBlockHeader   SpanKind = iota + 1 // Log block header field
+	SpanBlockTrailer                      // Log block checksum
+	SpanRecordType                        // Record type byte
+	SpanCompressedInt                     // mach_parse_compressed integer
+	SpanFixedInt                          // Fixed width integer
+	SpanPayload                           // Record body bytes
+)
+
+// String returns the name of the span kind
+func (k SpanKind) String() string {
+	switch k {
+	case SpanBlockHeader:
+		return "block header"
+	case SpanBlockTrailer:
+		return "block trailer"
+	case SpanRecordType:
+		return "type"
+	case SpanCompressedInt:
+		return "compressed int"
+	case SpanFixedInt:
+		return "fixed int"
+	case SpanPayload:
+		return "payload"
+	default:
+		return "unknown"
+	}
+}
+
+// FieldSpan locates a decoded field in the log file. A field that crosses
+// a block boundary is split into one span per block.
+type FieldSpan struct {
+	Name   string   `json:"name"`
+	Kind   SpanKind `json:"kind"`
+	Offset int64    `json:"offset"` // File offset of the first byte
+	Length int      `json:"length"`
+}
+
+// End returns the file offset just past the span
+func (s FieldSpan) End() int64 {
+	return s.Offset + int64(s.Length)
+}
+
+// Contains reports whether the byte at offset belongs to the span
+func (s FieldSpan) Contains(offset int64) bool {
+	return offset >= s.Offset && offset < s.End()
+}
+
+// ByteRange returns the file range [start, end) covered by the record's
+// spans; ok is false when the reader did not record any
+func (r *LogRecord) ByteRange() (start, end int64, ok bool) {
+	if len(r.Spans) == 0 {
+		return 0, 0, false
+	}
+	start, end = r.Spans[0].Offset, r.Spans[0].End()
+	for _, span := range r.Spans[1:] {
+		if span.Offset < start {
+			start = span.Offset
+		}
+		if span.End() > end {
+			end = span.End()
+		}
+	}
+	return start, end, true
+}
+
+// HasPage reports whether the record changes a page, which may be page 0
+// of the system tablespace. MariaDB page records are known by type; other
+// records name a page when they have a page_no field, or, without byte
+// positions, a non-zero space or page.
+func (r *LogRecord) HasPage() bool {
+	if r.Type.IsMariaDB() {
+		return r.Type < MariaDBFileCreate || (r.Type >= MariaDBInitRowFormatRedundant && r.Type < MariaDBEndOfMTR)
+	}
+	for _, span := range r.Spans {
+		if span.Name == "page_no" {
+			return true
+		}
+	}
+	return len(r.Spans) == 0 && (r.SpaceID != 0 || r.PageNo != 0)
+}
@@ -0,0 +1,33 @@
+package redolog
+
+import (
+	"github.com/yamaru/innodb-redolog-tool/internal/cdc"
+	"github.com/yamaru/innodb-redolog-tool/internal/export"
+	"github.com/yamaru/innodb-redolog-tool/internal/reader"
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// PayloadFields splits the decoded payload of a record, such as
+// "page=3 offset=2887 value=0x15", into its key/value fields
+func PayloadFields(record *Record) map[string]string {
+	return export.PayloadFields(record.Data)
+}
+
+// RowColumns returns the row columns decoded from an insert, update or
+// delete record, named field1, field2, ... by position. Integers are int64
+// and strings are string. It is empty when no column could be decoded.
+func RowColumns(record *Record) map[string]interface{} {
+	return cdc.Columns(record.Data)
+}
+
+// DecodeRow describes the fields of raw InnoDB COMPACT record bytes, as
+// found in the "parsed" payload field of insert records
+func DecodeRow(data []byte) string {
+	return reader.ParseRecordDataAsFields(data)
+}
+
+// ParseLogType returns the log type with the given mlog_id_t name, such as
+// "MLOG_REC_INSERT_8027", ignoring case
+func ParseLogType(name string) (LogType, bool) {
+	return types.ParseLogType(name)
+}
@@ -0,0 +1,31 @@
+// Package redolog reads InnoDB redo logs.
+//
+// Open a redo log file or a data directory, read one from an io.ReaderAt
+// with NewReaderAt, or from any io.Reader, including stdin, with NewReader.
+// Then iterate its records:
+//
+//	log, err := redolog.Open("/var/lib/mysql/#innodb_redo")
+//	if err != nil {
+//		return err
+//	}
+//	defer log.Close()
+//
+//	for record, err := range log.Records() {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(record.LSN, record.Type, redolog.PayloadFields(record))
+//	}
+//
+// Header and Checkpoints describe the log itself. PayloadFields and
+// RowColumns decode the payload text the reader produces for each record.
+//
+// # Compatibility
+//
+// The package follows semantic versioning from v1.0.0 of the module: within
+// a major version, exported names are only added, never removed or changed
+// in a way that breaks callers. This covers the fields of Record, Header
+// and FieldSpan. The text of Record.Data and of error messages may change
+// as decoding improves; match errors with errors.Is against the exported
+// error values instead.
+package redolog
@@ -0,0 +1,44 @@
+package redolog
+
+import (
+	"github.com/yamaru/innodb-redolog-tool/internal/reader"
+)
+
+// Keyring holds the master keys of a keyring file by their key ID
+type Keyring = reader.Keyring
+
+// EncryptionInfo is the encryption info of an encrypted redo log header:
+// which master key encrypts the key of the log
+type EncryptionInfo = reader.EncryptionInfo
+
+// Errors reading an encrypted redo log
+var (
+	// ErrEncryptedLog is returned for an encrypted log block when no
+	// keyring was given
+	ErrEncryptedLog = reader.ErrEncryptedLog
+	// ErrKeyNotFound is returned when the keyring lacks the master key
+	ErrKeyNotFound = reader.ErrKeyNotFound
+	// ErrKeyMismatch is returned when the master key in the keyring does
+	// not decrypt the key of the log
+	ErrKeyMismatch = reader.ErrKeyMismatch
+)
+
+// LoadKeyring reads the data file of the keyring_file plugin
+// (keyring_file_data) or the JSON file of component_keyring_file
+func LoadKeyring(filename string) (*Keyring, error) {
+	return reader.LoadKeyring(filename)
+}
+
+// WithKeyring decrypts redo logs written with innodb_redo_log_encrypt=ON
+// using the master key held in keyring
+func WithKeyring(keyring *Keyring) Option {
+	return func(c *config) {
+		c.keyring = keyring
+	}
+}
+
+// Encryption returns the encryption info of the first file's header, or
+// nil when the log is not encrypted
+func (l *Log) Encryption() *EncryptionInfo {
+	return l.encryption
+}
@@ -0,0 +1,101 @@
+package redolog_test
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/yamaru/innodb-redolog-tool/pkg/redolog"
+	"github.com/yamaru/innodb-redolog-tool/test/fixtures"
+)
+
+// sampleLog writes a small redo log and returns its name
+func sampleLog() string {
+	dir, err := os.MkdirTemp("", "redolog-example")
+	if err != nil {
+		log.Fatal(err)
+	}
+	filename, err := fixtures.CreateSampleLogFile(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return filename
+}
+
+func Example() {
+	filename := sampleLog()
+	defer os.RemoveAll(filepath.Dir(filename))
+
+	l, err := redolog.Open(filename)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer l.Close()
+
+	fmt.Println("start LSN:", l.Header().StartLSN)
+	for record, err := range l.Records() {
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(record.LSN, record.Type)
+	}
+	// Output:
+	// start LSN: 1000
+	// 1001 MLOG_1BYTE
+	// 1002 MLOG_2BYTES
+	// 1003 MLOG_4BYTES
+}
+
+func ExampleNewReader() {
+	filename := sampleLog()
+	defer os.RemoveAll(filepath.Dir(filename))
+	file, err := os.Open(filename)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	l, err := redolog.NewReader(file, redolog.WithMaxRecords(2))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer l.Close()
+
+	records, err := l.ReadAll()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(len(records), "records")
+	// Output:
+	// 2 records
+}
+
+func ExamplePayloadFields() {
+	record := &redolog.Record{Data: []byte("space=5 page=3 offset=2887 value=0x15")}
+
+	fields := redolog.PayloadFields(record)
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Printf("%s=%s\n", key, fields[key])
+	}
+	// Output:
+	// offset=2887
+	// page=3
+	// space=5
+	// value=0x15
+}
+
+func ExampleRowColumns() {
+	record := &redolog.Record{Data: []byte("space_id=5 | page_no=4 | parsed=(innodb_record=[header_skip=5 field1_int=42 field2_str='ACADEMY DINOSAUR'])")}
+
+	columns := redolog.RowColumns(record)
+	fmt.Println(columns["field1"], columns["field2"])
+	// Output:
+	// 42 ACADEMY DINOSAUR
+}
@@ -0,0 +1,494 @@
+package redolog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/reader"
+	"github.com/yamaru/innodb-redolog-tool/internal/types"
+)
+
+// Record is one redo log record
+type Record = types.LogRecord
+
+// Header describes a redo log file
+type Header = types.RedoLogHeader
+
+// LogType is the mlog_id_t type of a record
+type LogType = types.LogType
+
+// FieldSpan locates one decoded field of a record in the file
+type FieldSpan = types.FieldSpan
+
+// SpanKind classifies the bytes covered by a FieldSpan
+type SpanKind = types.SpanKind
+
+// Operation classes returned by LogType.Operation
+const (
+	OperationInsert = types.OperationInsert
+	OperationUpdate = types.OperationUpdate
+	OperationDelete = types.OperationDelete
+	OperationOther  = types.OperationOther
+)
+
+// MemberSeparator separates an archive from the member Open reads, as in
+// backup.tar.gz!ib_logfile0
+const MemberSeparator = reader.MemberSeparator
+
+// ErrMemberNotFound is returned when an archive has no member of the name
+// given after MemberSeparator
+var ErrMemberNotFound = reader.ErrMemberNotFound
+
+// ErrNoLogFiles is returned by Open for a directory without redo log files
+var ErrNoLogFiles = errors.New("no redo log files found")
+
+// Format selects how a file is decoded
+type Format int
+
+const (
+	// FormatAuto picks FormatMariaDB for files whose header names MariaDB
+	// 10.5 or later as their creator, FormatMySQL for files larger than
+	// 1 MB or whose header names MySQL or XtraBackup, and FormatTest
+	// otherwise
+	FormatAuto Format = iota
+	// FormatMySQL is the MySQL redo log layout of 512-byte blocks; the
+	// header's format version selects the RedoFormat records are decoded in
+	FormatMySQL
+	// FormatTest is the 64-byte header and fixed record layout written by
+	// this project's test fixtures
+	FormatTest
+	// FormatMariaDB is the physical redo log of MariaDB 10.5 and later, in
+	// the 512-byte blocks of 10.5 or the unblocked layout of 10.8
+	FormatMariaDB
+)
+
+// String returns the name of the format
+func (f Format) String() string {
+	switch f {
+	case FormatMySQL:
+		return "mysql"
+	case FormatTest:
+		return "test"
+	case FormatMariaDB:
+		return "mariadb"
+	default:
+		return "auto"
+	}
+}
+
+// RedoFormat is a version of the MySQL redo format: the record types it
+// defines and how their payloads are decoded
+type RedoFormat = reader.RedoFormat
+
+// LookupRedoFormat returns the RedoFormat of a header format version, or
+// nil when the version is not supported
+func LookupRedoFormat(version uint32) *RedoFormat {
+	return reader.LookupRedoFormat(version)
+}
+
+// Checkpoint is a checkpoint block of a redo log header
+type Checkpoint struct {
+	Number  uint64 // Checkpoint sequence number; the highest valid one is current
+	LSN     uint64 // Recovery starts at this LSN
+	Offset  uint64 // Offset in the log of LSN
+	BufSize uint64 // Log buffer size when the checkpoint was written
+	Valid   bool
+}
+
+// Option configures Open and NewReader
+type Option func(*config)
+
+// config holds the options of a Log
+type config struct {
+	maxRecords int
+	format     Format
+	keyring    *Keyring
+}
+
+// WithMaxRecords stops reading after n records; 0 (the default) reads to
+// the end of the log
+func WithMaxRecords(n int) Option {
+	return func(c *config) {
+		c.maxRecords = n
+	}
+}
+
+// WithFormat decodes the log in format f instead of guessing it
+func WithFormat(f Format) Option {
+	return func(c *config) {
+		c.format = f
+	}
+}
+
+// Log is an open redo log: one file, or the log files of a directory read
+// in order as one stream. It is not safe for concurrent use.
+type Log struct {
+	cfg         config
+	files       []string
+	src         reader.Source // Source of a log read by NewReader, instead of files
+	next        int           // Index of the next file to open
+	current     reader.RedoLogReader
+	format      Format
+	header      *Header
+	checkpoints []Checkpoint
+	xtrabackup  *XtraBackupLog
+	redoFormat  *RedoFormat
+	encryption  *EncryptionInfo
+	warnings    []string
+	read        int // Records read from the files, before grouping
+	grouper     reader.MultiRecordGrouper
+	ready       []*Record // Grouped records not yet returned
+	done        bool
+}
+
+// Open opens a redo log file, or a directory holding redo log files: a
+// MySQL data directory, its #innodb_redo directory or a directory of
+// ib_logfile files. Files compressed with gzip, zstd or lz4 are decompressed
+// as they are read, and a member of a tar or xbstream archive is opened as
+// archive!member. The header of the first file is read before Open returns.
+func Open(path string, opts ...Option) (*Log, error) {
+	files := []string{path}
+	info, err := os.Stat(path)
+	switch {
+	case err == nil && info.IsDir():
+		if files, err = LogFiles(path); err != nil {
+			return nil, err
+		}
+	case err != nil && !strings.Contains(path, MemberSeparator):
+		return nil, fmt.Errorf("failed to open redo log: %w", err)
+	}
+
+	l := &Log{files: files}
+	for _, opt := range opts {
+		opt(&l.cfg)
+	}
+	if err := l.openNext(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// NewReader reads a redo log from r. Readers that also implement
+// io.ReaderAt and Size, such as bytes.Reader, and regular files are read
+// like NewReaderAt reads them; anything else, such as stdin or a pipe, is
+// read once from start to end. NewReader does not close r.
+func NewReader(r io.Reader, opts ...Option) (*Log, error) {
+	if file, ok := r.(*os.File); ok {
+		if info, err := file.Stat(); err == nil && info.Mode().IsRegular() {
+			return NewReaderAt(file, info.Size(), opts...)
+		}
+	}
+	if sized, ok := r.(interface {
+		io.ReaderAt
+		Size() int64
+	}); ok {
+		return NewReaderAt(sized, sized.Size(), opts...)
+	}
+
+	src, err := reader.NewStreamSource(r)
+	if err != nil {
+		return nil, err
+	}
+	return newSourceLog(src, opts)
+}
+
+// NewReaderAt reads a redo log from the first size bytes of r, such as a
+// file inside an archive or a buffer in memory
+func NewReaderAt(r io.ReaderAt, size int64, opts ...Option) (*Log, error) {
+	return newSourceLog(reader.NewReaderAtSource(r, size), opts)
+}
+
+// newSourceLog returns a log reading src as its only file
+func newSourceLog(src reader.Source, opts []Option) (*Log, error) {
+	l := &Log{src: src}
+	for _, opt := range opts {
+		opt(&l.cfg)
+	}
+	if err := l.openNext(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// logFilePatterns match redo log file names; the number orders them
+var logFilePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^#ib_redo(\d+)$`),
+	regexp.MustCompile(`^ib_logfile(\d+)$`),
+}
+
+// LogFiles returns the redo log files of a directory in log order. It looks
+// in dir and in its #innodb_redo subdirectory; files being created or
+// resized (#ib_redoN_tmp) are skipped.
+func LogFiles(dir string) ([]string, error) {
+	for _, d := range []string{dir, filepath.Join(dir, "#innodb_redo")} {
+		entries, err := os.ReadDir(d)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) && d != dir {
+				continue
+			}
+			return nil, fmt.Errorf("failed to list %s: %w", d, err)
+		}
+		for _, pattern := range logFilePatterns {
+			type logFile struct {
+				name   string
+				number int
+			}
+			var found []logFile
+			for _, entry := range entries {
+				m := pattern.FindStringSubmatch(entry.Name())
+				if m == nil || entry.IsDir() {
+					continue
+				}
+				number, _ := strconv.Atoi(m[1])
+				found = append(found, logFile{filepath.Join(d, entry.Name()), number})
+			}
+			if len(found) == 0 {
+				continue
+			}
+			sort.Slice(found, func(i, j int) bool { return found[i].number < found[j].number })
+			files := make([]string, len(found))
+			for i, f := range found {
+				files[i] = f.name
+			}
+			return files, nil
+		}
+	}
+	return nil, fmt.Errorf("%w in %s", ErrNoLogFiles, dir)
+}
+
+// openNext opens the next file and reads its header. The first file's
+// header and checkpoints are those of the log.
+func (l *Log) openNext() error {
+	src := l.src
+	if src == nil {
+		var err error
+		if src, err = reader.OpenInput(l.files[l.next]); err != nil {
+			return fmt.Errorf("failed to open redo log: %w", err)
+		}
+	}
+	var r reader.RedoLogReader
+	switch l.cfg.format {
+	case FormatMySQL:
+		r = reader.NewMySQLRedoLogReader()
+	case FormatTest:
+		r = reader.NewRedoLogReader()
+	case FormatMariaDB:
+		r = reader.NewMariaDBRedoLogReader()
+	default:
+		r = reader.NewReaderForSource(src)
+	}
+	if mysql, ok := r.(*reader.MySQLRedoLogReader); ok && l.cfg.keyring != nil {
+		mysql.SetKeyring(l.cfg.keyring)
+	}
+	if err := r.OpenSource(src); err != nil {
+		r.Close()
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	header, err := r.ReadHeader()
+	if err != nil {
+		r.Close()
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+
+	if l.next == 0 {
+		l.format = FormatTest
+		l.header = header
+		switch r := r.(type) {
+		case *reader.MySQLRedoLogReader:
+			l.format = FormatMySQL
+			l.xtrabackup = r.XtraBackup()
+			l.redoFormat = r.RedoFormat()
+			l.encryption = r.Encryption()
+		case *reader.MariaDBRedoLogReader:
+			l.format = FormatMariaDB
+		}
+		if reporter, ok := r.(reader.CheckpointReporter); ok {
+			for _, c := range reporter.Checkpoints() {
+				l.checkpoints = append(l.checkpoints, Checkpoint{
+					Number: c.CheckpointNo, LSN: c.CheckpointLSN, Offset: c.Offset, BufSize: c.BufSize, Valid: c.IsValid,
+				})
+			}
+		}
+	}
+	l.current = r
+	l.next++
+	return nil
+}
+
+// closeCurrent closes the open file, keeping its warnings
+func (l *Log) closeCurrent() error {
+	if l.current == nil {
+		return nil
+	}
+	l.warnings = append(l.warnings, l.currentWarnings()...)
+	err := l.current.Close()
+	l.current = nil
+	return err
+}
+
+// currentWarnings returns the warnings of the open file, prefixed with its
+// name when the log has several files
+func (l *Log) currentWarnings() []string {
+	reporter, ok := l.current.(reader.WarningReporter)
+	if !ok {
+		return nil
+	}
+	warnings := reporter.Warnings()
+	if len(l.files) <= 1 {
+		return warnings
+	}
+	prefixed := make([]string, len(warnings))
+	for i, warning := range warnings {
+		prefixed[i] = filepath.Base(l.files[l.next-1]) + ": " + warning
+	}
+	return prefixed
+}
+
+// Files returns the files of the log, in the order they are read. A log
+// read by NewReader or NewReaderAt has none.
+func (l *Log) Files() []string {
+	return l.files
+}
+
+// fileCount returns the number of files or sources the log reads
+func (l *Log) fileCount() int {
+	if l.src != nil {
+		return 1
+	}
+	return len(l.files)
+}
+
+// Format returns the format the first file is decoded in
+func (l *Log) Format() Format {
+	return l.format
+}
+
+// RedoFormat returns the MySQL redo format version the first file is
+// decoded in, or nil for files in the test and MariaDB formats. Unknown
+// versions are decoded like the newest one, with a warning.
+func (l *Log) RedoFormat() *RedoFormat {
+	return l.redoFormat
+}
+
+// Header returns the header of the first file
+func (l *Log) Header() *Header {
+	return l.header
+}
+
+// Checkpoints returns the checkpoint blocks of the first file's header.
+// Files in the test format have none.
+func (l *Log) Checkpoints() []Checkpoint {
+	return l.checkpoints
+}
+
+// XtraBackup describes the first file when it is an xtrabackup_logfile,
+// or returns nil. Its Blocks and EndLSN cover the blocks read so far; use
+// ScanXtraBackupLog for the whole file without decoding records.
+func (l *Log) XtraBackup() *XtraBackupLog {
+	return l.xtrabackup
+}
+
+// Warnings returns the non-fatal problems noticed in the files read so far
+func (l *Log) Warnings() []string {
+	return append(append([]string(nil), l.warnings...), l.currentWarnings()...)
+}
+
+// Next returns the next record, or io.EOF after the last one. Records are
+// returned once their multi-record group is known, so Next may read ahead
+// to the end of the group.
+func (l *Log) Next() (*Record, error) {
+	for len(l.ready) == 0 {
+		if l.done {
+			return nil, io.EOF
+		}
+		record, err := l.read1()
+		if err == io.EOF {
+			l.ready = l.grouper.Flush()
+			l.done = true
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		l.ready = l.grouper.Add(record)
+	}
+
+	record := l.ready[0]
+	l.ready = l.ready[1:]
+	return record, nil
+}
+
+// read1 reads the next ungrouped record, moving on to the next file at the
+// end of one
+func (l *Log) read1() (*Record, error) {
+	for {
+		if l.cfg.maxRecords > 0 && l.read >= l.cfg.maxRecords {
+			return nil, io.EOF
+		}
+		if l.current == nil {
+			if l.next >= l.fileCount() {
+				return nil, io.EOF
+			}
+			if err := l.openNext(); err != nil {
+				return nil, err
+			}
+		}
+
+		record, err := l.current.ReadRecord()
+		if err == nil {
+			l.read++
+			return record, nil
+		}
+		if !reader.EndsReading(l.current, err) {
+			return nil, fmt.Errorf("failed to read record %d: %w", l.read+1, err)
+		}
+		if err := l.closeCurrent(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Records iterates over the remaining records. Iteration stops after the
+// first error.
+func (l *Log) Records() iter.Seq2[*Record, error] {
+	return func(yield func(*Record, error) bool) {
+		for {
+			record, err := l.Next()
+			if err == io.EOF {
+				return
+			}
+			if !yield(record, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// ReadAll reads the remaining records. On a read error it returns the
+// records read before it together with the error.
+func (l *Log) ReadAll() ([]*Record, error) {
+	var records []*Record
+	for {
+		record, err := l.Next()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return append(records, l.grouper.Flush()...), err
+		}
+		records = append(records, record)
+	}
+}
+
+// Close closes the log
+func (l *Log) Close() error {
+	return l.closeCurrent()
+}
@@ -0,0 +1,317 @@
+package redolog
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/reader"
+	"github.com/yamaru/innodb-redolog-tool/test/fixtures"
+)
+
+// writeMySQLLog writes a log in the MySQL block format with checkpoint 2
+// newer than checkpoint 1 and one block holding an MLOG_1BYTE record and
+// MLOG_MULTI_REC_END
+func writeMySQLLog(t *testing.T, filename string) {
+	data := make([]byte, reader.LogFileHdrSize+reader.OSFileLogBlockSize)
+	for i, checkpoint := range []struct{ no, lsn uint64 }{{1, 8704}, {2, 9216}} {
+		block := data[reader.LogCheckpoint1+i*2*reader.OSFileLogBlockSize:]
+		binary.BigEndian.PutUint64(block[reader.LogCheckpointNo:], checkpoint.no)
+		binary.BigEndian.PutUint64(block[reader.LogCheckpointLSN:], checkpoint.lsn)
+		binary.BigEndian.PutUint64(block[reader.LogCheckpointOffset:], reader.LogFileHdrSize)
+	}
+	block := data[reader.LogFileHdrSize:]
+	binary.BigEndian.PutUint16(block[reader.LogBlockHdrDataLen:], 20)
+	binary.BigEndian.PutUint16(block[reader.LogBlockFirstRecGroup:], reader.LogBlockHdrSize)
+	copy(block[reader.LogBlockHdrSize:], []byte{1, 5, 3, 0x00, 0x10, 0x2B, reader.MLogMultiRecEnd, 0, 0, 0})
+	require.NoError(t, os.WriteFile(filename, data, 0644))
+}
+
+func TestOpenMySQL(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "ib_logfile0")
+	writeMySQLLog(t, filename)
+
+	l, err := Open(filename, WithFormat(FormatMySQL))
+	require.NoError(t, err)
+	defer l.Close()
+
+	assert.Equal(t, FormatMySQL, l.Format())
+	assert.Equal(t, uint64(9216), l.Header().LastCheckpoint)
+	assert.Equal(t, []Checkpoint{
+		{Number: 1, LSN: 8704, Offset: reader.LogFileHdrSize, Valid: true},
+		{Number: 2, LSN: 9216, Offset: reader.LogFileHdrSize, Valid: true},
+	}, l.Checkpoints())
+
+	records, err := l.ReadAll()
+	require.NoError(t, err)
+	require.NotEmpty(t, records)
+	assert.Equal(t, "MLOG_1BYTE", records[0].Type.String())
+	assert.Equal(t, map[string]string{"space": "5", "page": "3", "offset": "16", "value": "0x2b"}, PayloadFields(records[0]))
+	assert.Equal(t, 1, records[0].MultiRecordGroup)
+	assert.True(t, records[0].IsGroupStart)
+}
+
+func TestOpenDirectory(t *testing.T) {
+	dir := t.TempDir()
+	sample, err := fixtures.CreateSampleLogFile(dir)
+	require.NoError(t, err)
+	data, err := os.ReadFile(sample)
+	require.NoError(t, err)
+	redoDir := filepath.Join(dir, "#innodb_redo")
+	require.NoError(t, os.Mkdir(redoDir, 0755))
+	for _, name := range []string{"#ib_redo10", "#ib_redo9", "#ib_redo11_tmp"} {
+		require.NoError(t, os.WriteFile(filepath.Join(redoDir, name), data, 0644))
+	}
+
+	files, err := LogFiles(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(redoDir, "#ib_redo9"), filepath.Join(redoDir, "#ib_redo10")}, files)
+
+	l, err := Open(dir)
+	require.NoError(t, err)
+	defer l.Close()
+	assert.Equal(t, files, l.Files())
+	assert.Equal(t, FormatTest, l.Format())
+	assert.Empty(t, l.Checkpoints())
+
+	records, err := l.ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, records, 6)
+
+	_, err = Open(t.TempDir())
+	assert.True(t, errors.Is(err, ErrNoLogFiles))
+}
+
+func TestOpenArchiveMember(t *testing.T) {
+	dir := t.TempDir()
+	sample, err := fixtures.CreateSampleLogFile(dir)
+	require.NoError(t, err)
+	data, err := os.ReadFile(sample)
+	require.NoError(t, err)
+
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "backup/ib_logfile0", Mode: 0644, Size: int64(len(data))}))
+	_, err = tw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	filename := filepath.Join(dir, "backup.tar.gz")
+	require.NoError(t, os.WriteFile(filename, archive.Bytes(), 0644))
+
+	l, err := Open(filename + MemberSeparator + "ib_logfile0")
+	require.NoError(t, err)
+	defer l.Close()
+	records, err := l.ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, records, 3)
+
+	_, err = Open(filename + MemberSeparator + "ib_logfile1")
+	assert.ErrorContains(t, err, "archive member not found")
+}
+
+func TestOpenXtraBackup(t *testing.T) {
+	dir := t.TempDir()
+	logfile, err := fixtures.CreateXtraBackupLogFile(dir, 8704, 2, 100)
+	require.NoError(t, err)
+
+	l, err := Open(logfile)
+	require.NoError(t, err)
+	defer l.Close()
+	assert.Equal(t, FormatMySQL, l.Format())
+	require.NotNil(t, l.XtraBackup())
+	assert.Equal(t, uint64(8704), l.Header().StartLSN)
+
+	log, err := ScanXtraBackupLog(logfile)
+	require.NoError(t, err)
+	assert.Equal(t, 2, log.Blocks)
+	assert.Equal(t, uint64(8704+512+100), log.EndLSN)
+
+	sample, err := fixtures.CreateSampleLogFile(dir)
+	require.NoError(t, err)
+	_, err = ScanXtraBackupLog(sample)
+	assert.ErrorContains(t, err, "is not an xtrabackup_logfile")
+}
+
+func TestOpenRedoFormat(t *testing.T) {
+	dir := t.TempDir()
+	for _, version := range []uint32{1, 6} {
+		filename, err := fixtures.CreateMySQLLogFile(dir, version, 1<<20, []byte{1, 5, 3, 0x00, 0x10, 0x2B, 31, 0})
+		require.NoError(t, err)
+
+		l, err := Open(filename)
+		require.NoError(t, err)
+		assert.Equal(t, FormatMySQL, l.Format())
+		assert.Same(t, LookupRedoFormat(version), l.RedoFormat())
+		assert.Equal(t, version, l.Header().Format)
+		records, err := l.ReadAll()
+		require.NoError(t, err)
+		assert.Len(t, records, 2)
+		require.NoError(t, l.Close())
+	}
+	assert.Equal(t, "MySQL 5.7", LookupRedoFormat(1).Name)
+	assert.True(t, LookupRedoFormat(6).FilePerRange)
+
+	sample, err := fixtures.CreateSampleLogFile(dir)
+	require.NoError(t, err)
+	l, err := Open(sample)
+	require.NoError(t, err)
+	defer l.Close()
+	assert.Nil(t, l.RedoFormat())
+}
+
+func TestOpenMariaDB(t *testing.T) {
+	mtrs := [][]byte{{0x12, 5, 3, 0xB4, 0x26, 'a', 'b', 'c'}, {0x33, 5, 4, 0x10}}
+	filename, err := fixtures.CreateMariaDBLogFile(t.TempDir(), fixtures.MariaDBFormat108, 1<<20, mtrs)
+	require.NoError(t, err)
+
+	l, err := Open(filename)
+	require.NoError(t, err)
+	defer l.Close()
+	assert.Equal(t, FormatMariaDB, l.Format())
+	assert.Equal(t, "mariadb", l.Format().String())
+	assert.Nil(t, l.RedoFormat())
+	require.Len(t, l.Checkpoints(), 2)
+	assert.Equal(t, uint64(1<<20), l.Checkpoints()[0].LSN)
+
+	records, err := l.ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+	assert.Equal(t, "WRITE", records[1].Type.String())
+	assert.Equal(t, 1, records[2].MultiRecordGroup)
+	assert.True(t, records[2].IsGroupEnd)
+	assert.Equal(t, "END_OF_MTR", records[4].Type.String())
+	assert.Empty(t, l.Warnings())
+}
+
+func TestOpenEncrypted(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "ib_logfile0")
+	writeMySQLLog(t, filename)
+	masterKey := bytes.Repeat([]byte{0x5A}, reader.EncryptionKeyLen)
+	const uuid = "3e11fa47-71ca-11e1-9e33-c80aa9429562"
+	require.NoError(t, fixtures.EncryptMySQLLogFile(filename, masterKey, uuid, 3, make([]byte, 32), make([]byte, 32)))
+
+	// Without a keyring the header opens but the records fail
+	l, err := Open(filename, WithFormat(FormatMySQL))
+	require.NoError(t, err)
+	require.NotNil(t, l.Encryption())
+	assert.Equal(t, "INNODBKey-"+uuid+"-3", l.Encryption().MasterKeyName())
+	_, err = l.ReadAll()
+	assert.ErrorIs(t, err, ErrEncryptedLog)
+	l.Close()
+
+	keyringFile, err := fixtures.CreateKeyringFile(dir, map[string][]byte{"INNODBKey-" + uuid + "-3": masterKey})
+	require.NoError(t, err)
+	keyring, err := LoadKeyring(keyringFile)
+	require.NoError(t, err)
+	l, err = Open(filename, WithFormat(FormatMySQL), WithKeyring(keyring))
+	require.NoError(t, err)
+	defer l.Close()
+	records, err := l.ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "MLOG_1BYTE", records[0].Type.String())
+}
+
+func TestLogNextAndMaxRecords(t *testing.T) {
+	filename, err := fixtures.CreateSampleLogFile(t.TempDir())
+	require.NoError(t, err)
+
+	l, err := Open(filename, WithMaxRecords(2))
+	require.NoError(t, err)
+	defer l.Close()
+
+	var lsns []uint64
+	for {
+		record, err := l.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		lsns = append(lsns, record.LSN)
+	}
+	assert.Equal(t, []uint64{1001, 1002}, lsns)
+
+	_, err = l.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestNewReader(t *testing.T) {
+	filename, err := fixtures.CreateSampleLogFile(t.TempDir())
+	require.NoError(t, err)
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+
+	for name, r := range map[string]io.Reader{
+		"buffer": bytes.NewReader(data),
+		"stream": iotest.OneByteReader(bytes.NewReader(data)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			l, err := NewReader(r)
+			require.NoError(t, err)
+			defer l.Close()
+			assert.Empty(t, l.Files())
+			assert.Equal(t, FormatTest, l.Format())
+			assert.Equal(t, uint64(1000), l.Header().StartLSN)
+
+			count := 0
+			for _, err := range l.Records() {
+				require.NoError(t, err)
+				count++
+			}
+			assert.Equal(t, 3, count)
+		})
+	}
+
+	_, err = NewReader(bytes.NewReader(nil))
+	assert.ErrorContains(t, err, "failed to read header")
+}
+
+func TestNewReaderMySQLStream(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "ib_logfile0")
+	writeMySQLLog(t, filename)
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+
+	// A pipe's length is not known, so it is read in the MySQL format
+	l, err := NewReader(io.MultiReader(bytes.NewReader(data)))
+	require.NoError(t, err)
+	defer l.Close()
+	assert.Equal(t, FormatMySQL, l.Format())
+	assert.Len(t, l.Checkpoints(), 2)
+
+	records, err := l.ReadAll()
+	require.NoError(t, err)
+	require.NotEmpty(t, records)
+	assert.Equal(t, "MLOG_1BYTE", records[0].Type.String())
+}
+
+func TestNewReaderAt(t *testing.T) {
+	filename, err := fixtures.CreateSampleLogFile(t.TempDir())
+	require.NoError(t, err)
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+
+	// The log is the second half of a larger buffer
+	buf := append(bytes.Repeat([]byte{0xff}, 100), data...)
+	l, err := NewReaderAt(io.NewSectionReader(bytes.NewReader(buf), 100, int64(len(data))), int64(len(data)), WithMaxRecords(1))
+	require.NoError(t, err)
+	defer l.Close()
+
+	records, err := l.ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, uint64(1001), records[0].LSN)
+}
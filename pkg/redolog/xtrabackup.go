@@ -0,0 +1,50 @@
+package redolog
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/yamaru/innodb-redolog-tool/internal/reader"
+)
+
+// XtraBackupCheckpointsFile is the metadata file XtraBackup writes next to
+// xtrabackup_logfile
+const XtraBackupCheckpointsFile = reader.XtraBackupCheckpointsFile
+
+// XtraBackupLog describes the redo Percona XtraBackup copied into an
+// xtrabackup_logfile
+type XtraBackupLog = reader.XtraBackupLog
+
+// XtraBackupCheckpoints is the content of xtrabackup_checkpoints
+type XtraBackupCheckpoints = reader.XtraBackupCheckpoints
+
+// ScanXtraBackupLog reads the header and every copied block of the
+// xtrabackup_logfile at path, which may be compressed or an archive member
+// as in Open. It fails for redo logs not written by XtraBackup.
+func ScanXtraBackupLog(path string) (*XtraBackupLog, error) {
+	src, err := reader.OpenInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open redo log: %w", err)
+	}
+	if closer, ok := src.(io.Closer); ok {
+		defer closer.Close()
+	}
+	log, err := reader.ScanXtraBackupLog(src)
+	if err != nil {
+		return nil, err
+	}
+	if log == nil {
+		return nil, fmt.Errorf("%s is not an xtrabackup_logfile", path)
+	}
+	return log, nil
+}
+
+// ReadXtraBackupCheckpoints reads an xtrabackup_checkpoints file
+func ReadXtraBackupCheckpoints(path string) (*XtraBackupCheckpoints, error) {
+	return reader.ReadXtraBackupCheckpoints(path)
+}
+
+// ParseXtraBackupCheckpoints parses the content of xtrabackup_checkpoints
+func ParseXtraBackupCheckpoints(r io.Reader) (*XtraBackupCheckpoints, error) {
+	return reader.ParseXtraBackupCheckpoints(r)
+}
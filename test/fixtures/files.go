@@ -1,9 +1,15 @@
 package fixtures
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // CreateSampleLogFile creates a sample redo log file for testing
@@ -143,4 +149,274 @@ func CleanupTestFiles(dir string) error {
 	}
 	
 	return nil
-}
\ No newline at end of file
+}
+
+// CreateXtraBackupLogFile creates an xtrabackup_logfile as Percona
+// XtraBackup writes it: a 2048-byte header whose creator is "xtrabkup" and
+// whose start LSN is startLSN, then the given number of copied 512-byte
+// log blocks. All blocks are full except the last, which holds lastDataLen
+// bytes including its 12-byte header. Each block starts with an MLOG_1BYTE
+// record and MLOG_MULTI_REC_END.
+func CreateXtraBackupLogFile(dir string, startLSN uint64, blocks, lastDataLen int) (string, error) {
+	const blockSize, headerSize = 512, 2048
+	data := make([]byte, headerSize+blocks*blockSize)
+	binary.BigEndian.PutUint32(data[0:], 4)
+	binary.BigEndian.PutUint64(data[8:], startLSN)
+	copy(data[16:48], "xtrabkup 240824 12:00:00")
+	for _, offset := range []int{512, 1536} {
+		binary.BigEndian.PutUint64(data[offset:], 1)
+		binary.BigEndian.PutUint64(data[offset+8:], startLSN)
+		binary.BigEndian.PutUint64(data[offset+16:], headerSize)
+	}
+
+	for i := 0; i < blocks; i++ {
+		block := data[headerSize+i*blockSize:]
+		dataLen := blockSize
+		if i == blocks-1 {
+			dataLen = lastDataLen
+		}
+		binary.BigEndian.PutUint32(block[0:], uint32(startLSN/blockSize)+uint32(i)+1)
+		binary.BigEndian.PutUint16(block[4:], uint16(dataLen))
+		binary.BigEndian.PutUint16(block[6:], 12)
+		copy(block[12:], []byte{1, 5, 3, 0x00, 0x10, 0x2B, 31})
+	}
+
+	filename := filepath.Join(dir, "xtrabackup_logfile")
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to create xtrabackup_logfile: %w", err)
+	}
+	return filename, nil
+}
+
+// CreateXtraBackupCheckpointsFile creates the xtrabackup_checkpoints file
+// of a full backup
+func CreateXtraBackupCheckpointsFile(dir string, toLSN, lastLSN uint64) (string, error) {
+	content := fmt.Sprintf("backup_type = full-backuped\nfrom_lsn = 0\nto_lsn = %d\nlast_lsn = %d\nflushed_lsn = %d\nredo_memory = 0\nredo_frames = 0\n",
+		toLSN, lastLSN, lastLSN)
+	filename := filepath.Join(dir, "xtrabackup_checkpoints")
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to create xtrabackup_checkpoints: %w", err)
+	}
+	return filename, nil
+}
+
+// mysqlCreators are the header creator fields written by each header
+// format version
+var mysqlCreators = map[uint32]string{
+	1: "MySQL 5.7.44",
+	2: "MySQL 8.0.1",
+	3: "MySQL 8.0.18",
+	4: "MySQL 8.0.27",
+	5: "MySQL 8.0.29",
+	6: "MySQL 8.0.43",
+}
+
+// CreateMySQLLogFile creates a redo log as the MySQL release writing the
+// given header format version would: a 2048-byte header holding the format,
+// startLSN, the creator and a checkpoint at startLSN, then one log block
+// holding records. Files of
+// format 6 and later are named like #innodb_redo files, older ones
+// ib_logfile0.
+func CreateMySQLLogFile(dir string, format uint32, startLSN uint64, records []byte) (string, error) {
+	const blockSize, headerSize, blockHeaderSize = 512, 2048, 12
+	if len(records) > blockSize-blockHeaderSize-4 {
+		return "", fmt.Errorf("%d bytes of records do not fit in a log block", len(records))
+	}
+	data := make([]byte, headerSize+blockSize)
+	binary.BigEndian.PutUint32(data[0:], format)
+	binary.BigEndian.PutUint64(data[8:], startLSN)
+	copy(data[16:48], mysqlCreators[format])
+	for _, offset := range []int{512, 1536} {
+		binary.BigEndian.PutUint64(data[offset:], 1)
+		binary.BigEndian.PutUint64(data[offset+8:], startLSN)
+		binary.BigEndian.PutUint64(data[offset+16:], headerSize)
+	}
+
+	block := data[headerSize:]
+	binary.BigEndian.PutUint32(block[0:], uint32(startLSN/blockSize)+1)
+	binary.BigEndian.PutUint16(block[4:], uint16(blockHeaderSize+len(records)))
+	binary.BigEndian.PutUint16(block[6:], blockHeaderSize)
+	copy(block[blockHeaderSize:], records)
+
+	name := "ib_logfile0"
+	if format >= 6 {
+		name = fmt.Sprintf("#ib_redo%d", startLSN/(1<<20))
+	}
+	filename := filepath.Join(dir, name)
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	return filename, nil
+}
+
+// MariaDB redo log formats written by CreateMariaDBLogFile
+const (
+	MariaDBFormat105 = 0x50485953 // MariaDB 10.5 to 10.7: 512-byte blocks
+	MariaDBFormat108 = 0x50687973 // MariaDB 10.8 and later: no blocks
+)
+
+// CreateMariaDBLogFile creates a MariaDB ib_logfile0 in the given format
+// whose log area starts at startLSN and holds the given mini-transactions,
+// each without its terminator. Both checkpoints point at the first one.
+func CreateMariaDBLogFile(dir string, format uint32, startLSN uint64, mtrs [][]byte) (string, error) {
+	const blockSize, checksumAt = 512, 508
+	crc := crc32.MakeTable(crc32.Castagnoli)
+	logStart, capacity := 2048, 16*blockSize
+	if format == MariaDBFormat108 {
+		logStart, capacity = 12288, 32*blockSize
+	}
+	data := make([]byte, logStart+capacity)
+	binary.BigEndian.PutUint32(data[0:], format)
+	binary.BigEndian.PutUint64(data[8:], startLSN)
+	copy(data[16:48], "MariaDB 10.5.27")
+	if format == MariaDBFormat108 {
+		copy(data[16:48], "MariaDB 10.11.10")
+	}
+	binary.BigEndian.PutUint32(data[checksumAt:], crc32.Checksum(data[:checksumAt], crc))
+
+	if format == MariaDBFormat108 {
+		// Each mini-transaction ends with the sequence bit and its CRC-32C
+		log := data[logStart:logStart]
+		for _, mtr := range mtrs {
+			log = append(log, mtr...)
+			log = append(log, 1)
+			log = binary.BigEndian.AppendUint32(log, crc32.Checksum(mtr, crc))
+		}
+		if len(log) > capacity {
+			return "", fmt.Errorf("%d bytes of mini-transactions do not fit in the log", len(log))
+		}
+		for _, offset := range []int{4096, 8192} {
+			binary.BigEndian.PutUint64(data[offset:], startLSN)
+			binary.BigEndian.PutUint64(data[offset+8:], startLSN+uint64(len(log)))
+			binary.BigEndian.PutUint32(data[offset+60:], crc32.Checksum(data[offset:offset+60], crc))
+		}
+	} else {
+		// Each mini-transaction ends with a NUL byte; the payload is split
+		// into blocks of 496 bytes
+		var log []byte
+		var starts []int
+		for _, mtr := range mtrs {
+			starts = append(starts, len(log))
+			log = append(append(log, mtr...), 0)
+		}
+		const payload = blockSize - 12 - 4
+		if (len(log)+payload-1)/payload > capacity/blockSize {
+			return "", fmt.Errorf("%d bytes of mini-transactions do not fit in the log", len(log))
+		}
+		for i := 0; i*payload < len(log); i++ {
+			block := data[logStart+i*blockSize : logStart+(i+1)*blockSize]
+			chunk := log[i*payload : min(len(log), (i+1)*payload)]
+			binary.BigEndian.PutUint32(block[0:], uint32((startLSN/blockSize+uint64(i))&0x3FFFFFFF)+1)
+			dataLen := 12 + len(chunk)
+			if len(chunk) == payload {
+				dataLen = blockSize
+			}
+			binary.BigEndian.PutUint16(block[4:], uint16(dataLen))
+			for _, start := range starts {
+				if start >= i*payload && start < i*payload+len(chunk) {
+					binary.BigEndian.PutUint16(block[6:], uint16(12+start-i*payload))
+					break
+				}
+			}
+			copy(block[12:], chunk)
+			binary.BigEndian.PutUint32(block[checksumAt:], crc32.Checksum(block[:checksumAt], crc))
+		}
+		for _, offset := range []int{512, 1536} {
+			block := data[offset : offset+blockSize]
+			binary.BigEndian.PutUint64(block[0:], 1)
+			binary.BigEndian.PutUint64(block[8:], startLSN+12)
+			binary.BigEndian.PutUint64(block[16:], uint64(logStart+12))
+			binary.BigEndian.PutUint32(block[checksumAt:], crc32.Checksum(block[:checksumAt], crc))
+		}
+	}
+
+	filename := filepath.Join(dir, "ib_logfile0")
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to create ib_logfile0: %w", err)
+	}
+	return filename, nil
+}
+
+// EncryptMySQLLogFile encrypts the log blocks of a file written by
+// CreateMySQLLogFile as innodb_redo_log_encrypt does: key and iv are
+// stored in the header encrypted with masterKey, the master key
+// INNODBKey-serverUUID-masterKeyID of the keyring
+func EncryptMySQLLogFile(filename string, masterKey []byte, serverUUID string, masterKeyID uint32, key, iv []byte) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	master, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return err
+	}
+	plain := append(append([]byte{}, key...), iv...)
+	info := append([]byte("lCC"), 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(info[3:], masterKeyID)
+	info = append(info, serverUUID...)
+	encrypted := make([]byte, len(plain))
+	for i := 0; i < len(plain); i += aes.BlockSize {
+		master.Encrypt(encrypted[i:i+aes.BlockSize], plain[i:i+aes.BlockSize])
+	}
+	info = append(info, encrypted...)
+	info = binary.BigEndian.AppendUint32(info, crc32.Checksum(plain, crc32.MakeTable(crc32.Castagnoli)))
+	copy(data[1024:], info)
+
+	// The 500 bytes after each block header are encrypted as 496 bytes,
+	// then the last 32 bytes once more
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	for start := 2048; start+512 <= len(data); start += 512 {
+		b := data[start : start+512]
+		cipher.NewCBCEncrypter(block, iv[:aes.BlockSize]).CryptBlocks(b[12:508], b[12:508])
+		cipher.NewCBCEncrypter(block, iv[:aes.BlockSize]).CryptBlocks(b[480:], b[480:])
+		b[4] |= 0x80
+	}
+	return os.WriteFile(filename, data, 0644)
+}
+
+// CreateKeyringFile creates a keyring_file plugin data file holding the
+// given AES keys
+func CreateKeyringFile(dir string, keys map[string][]byte) (string, error) {
+	data := []byte("Keyring file version:2.0")
+	for id, key := range keys {
+		size := 40 + len(id) + len("AES") + len(key)
+		size += (8 - size%8) % 8
+		entry := make([]byte, 40, size)
+		for i, n := range []int{size, len(id), len("AES"), 0, len(key)} {
+			binary.LittleEndian.PutUint64(entry[8*i:], uint64(n))
+		}
+		entry = append(append(entry, id...), "AES"...)
+		for i, b := range key {
+			entry = append(entry, b^"*305=Ljt0*!@$Hnm(*-9-w;:"[i%24])
+		}
+		data = append(data, entry[:size]...)
+	}
+	data = append(data, "EOF"...)
+	digest := sha256.Sum256(data)
+	data = append(data, digest[:]...)
+
+	filename := filepath.Join(dir, "keyring")
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to create keyring: %w", err)
+	}
+	return filename, nil
+}
+
+// CreateComponentKeyringFile creates a component_keyring_file JSON file
+// holding the given AES keys
+func CreateComponentKeyringFile(dir string, keys map[string][]byte) (string, error) {
+	var elements []string
+	for id, key := range keys {
+		elements = append(elements, fmt.Sprintf(`{"user":"","data_id":%q,"data_type":"AES","data":"%x","extension":[]}`, id, key))
+	}
+	data := fmt.Sprintf(`{"version":"1.0","elements":[%s]}`, strings.Join(elements, ","))
+
+	filename := filepath.Join(dir, "component_keyring_file")
+	if err := os.WriteFile(filename, []byte(data), 0600); err != nil {
+		return "", fmt.Errorf("failed to create keyring: %w", err)
+	}
+	return filename, nil
+}